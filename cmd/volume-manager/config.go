@@ -3,13 +3,22 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"git.containerum.net/ch/volume-manager/pkg/clients"
 	"git.containerum.net/ch/volume-manager/pkg/database"
 	"git.containerum.net/ch/volume-manager/pkg/database/postgres"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/router"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
 	"git.containerum.net/ch/volume-manager/pkg/server"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/en_US"
@@ -27,6 +36,12 @@ const (
 
 var opMode operationMode
 
+const (
+	eventsSinkWebhook = "webhook"
+	eventsSinkKafka   = "kafka"
+	eventsSinkNone    = "none"
+)
+
 func setupLogger(ctx *cli.Context) error {
 	mode := ctx.String(ModeFlag.Name)
 	switch mode {
@@ -99,6 +114,19 @@ func setupKubeAPIClient(addr string) (clients.KubeAPIClient, error) {
 	}
 }
 
+// setupProvisionerClient returns nil client if provisioner is not configured, it is optional.
+func setupProvisionerClient(addr string) clients.ProvisionerClient {
+	switch {
+	case opMode == modeDebug && addr == "":
+		return clients.NewProvisionerDummyClient()
+	case addr != "":
+		return clients.NewProvisionerHTTPClient(&url.URL{Scheme: "http", Host: addr})
+	default:
+		logrus.Warnf("provisioner address is not set, storages sync is not available")
+		return nil
+	}
+}
+
 func setupServiceClients(ctx *cli.Context) (*server.Clients, error) {
 	var errs []error
 	var serverClients server.Clients
@@ -110,6 +138,7 @@ func setupServiceClients(ctx *cli.Context) (*server.Clients, error) {
 	if serverClients.KubeAPI, err = setupKubeAPIClient(ctx.String(KubeAPIAddrFlag.Name)); err != nil {
 		errs = append(errs, err)
 	}
+	serverClients.Provisioner = setupProvisionerClient(ctx.String(ProvisionerAddrFlag.Name))
 
 	if len(errs) > 0 {
 		return nil, fmt.Errorf("clients setup errors: %v", errs)
@@ -125,3 +154,195 @@ func setupServiceClients(ctx *cli.Context) (*server.Clients, error) {
 
 	return &serverClients, nil
 }
+
+// parseRateLimits parses rate limits in format "operation=requests_per_second:burst".
+func parseRateLimits(specs []string) (map[string]middleware.RateLimit, error) {
+	ret := make(map[string]middleware.RateLimit, len(specs))
+	for _, spec := range specs {
+		var limit middleware.RateLimit
+		op := strings.SplitN(spec, "=", 2)
+		if len(op) != 2 {
+			return nil, fmt.Errorf("invalid rate limit %q", spec)
+		}
+		params := strings.SplitN(op[1], ":", 2)
+		if len(params) != 2 {
+			return nil, fmt.Errorf("invalid rate limit %q", spec)
+		}
+		var err error
+		if limit.Rate, err = strconv.ParseFloat(params[0], 64); err != nil || limit.Rate <= 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit %q", spec)
+		}
+		if limit.Burst, err = strconv.Atoi(params[1]); err != nil || limit.Burst <= 0 {
+			return nil, fmt.Errorf("invalid burst in rate limit %q", spec)
+		}
+		ret[op[0]] = limit
+	}
+	return ret, nil
+}
+
+// setupCORS returns CORS configuration. Any origin is allowed if origins are not set.
+func setupCORS(ctx *cli.Context) (router.CORSConfig, error) {
+	cfg := router.CORSConfig{
+		AllowedOrigins: ctx.StringSlice(CORSAllowedOriginsFlag.Name),
+		AllowedMethods: ctx.StringSlice(CORSAllowedMethodsFlag.Name),
+		AllowedHeaders: ctx.StringSlice(CORSAllowedHeadersFlag.Name),
+	}
+	if len(cfg.AllowedOrigins) == 0 {
+		logrus.Warnf("CORS allowed origins are not set, requests from any origin are allowed")
+		cfg.AllowedOrigins = []string{router.CORSAnyOrigin}
+	}
+	return cfg, cfg.Validate()
+}
+
+// parseBodyLimits parses request body size limits in format "operation=bytes".
+func parseBodyLimits(specs []string) (map[string]int64, error) {
+	ret := make(map[string]int64, len(specs))
+	for _, spec := range specs {
+		op := strings.SplitN(spec, "=", 2)
+		if len(op) != 2 {
+			return nil, fmt.Errorf("invalid body limit %q", spec)
+		}
+		limit, err := strconv.ParseInt(op[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in body limit %q", spec)
+		}
+		ret[op[0]] = limit
+	}
+	return ret, nil
+}
+
+// parseHandlerTimeouts parses handler time budgets in format "operation=duration", e.g. "import=10m".
+func parseHandlerTimeouts(specs []string) (map[string]time.Duration, error) {
+	ret := make(map[string]time.Duration, len(specs))
+	for _, spec := range specs {
+		op := strings.SplitN(spec, "=", 2)
+		if len(op) != 2 {
+			return nil, fmt.Errorf("invalid handler timeout %q", spec)
+		}
+		timeout, err := time.ParseDuration(op[1])
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid duration in handler timeout %q", spec)
+		}
+		ret[op[0]] = timeout
+	}
+	return ret, nil
+}
+
+// parseDeprecations parses deprecated operations in format "operation" or "operation=sunset_date", e.g. "import=2027-01-01".
+func parseDeprecations(specs []string) (map[string]middleware.Deprecation, error) {
+	ret := make(map[string]middleware.Deprecation, len(specs))
+	for _, spec := range specs {
+		op := strings.SplitN(spec, "=", 2)
+		if op[0] == "" {
+			return nil, fmt.Errorf("invalid deprecation %q", spec)
+		}
+		var deprecation middleware.Deprecation
+		if len(op) == 2 {
+			sunset, err := time.Parse("2006-01-02", op[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid sunset date in deprecation %q", spec)
+			}
+			deprecation.Sunset = sunset
+		}
+		ret[op[0]] = deprecation
+	}
+	return ret, nil
+}
+
+// parseRoleOperations parses operations allowed for roles in format "role=operation1:operation2".
+// Nil is returned if specs are empty, so default mapping is used.
+func parseRoleOperations(specs []string) (middleware.RoleOperations, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	ret := make(middleware.RoleOperations, len(specs))
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid role operations %q", spec)
+		}
+		if kv[0] != middleware.RoleUser && kv[0] != middleware.RoleViewer {
+			return nil, fmt.Errorf("invalid role in role operations %q, expected %s or %s", spec, middleware.RoleUser, middleware.RoleViewer)
+		}
+		ret[kv[0]] = append(ret[kv[0]], strings.Split(kv[1], ":")...)
+	}
+	return ret, nil
+}
+
+// parseStorageQuotas parses storage quotas in format "tenant:id=max_size", e.g. "user:*=100" or "namespace:<ns id>=500".
+func parseStorageQuotas(specs []string) ([]model.StorageQuota, error) {
+	ret := make([]model.StorageQuota, 0, len(specs))
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid storage quota %q", spec)
+		}
+		tenantID := strings.SplitN(kv[0], ":", 2)
+		if len(tenantID) != 2 || tenantID[1] == "" {
+			return nil, fmt.Errorf("invalid tenant in storage quota %q", spec)
+		}
+		quota := model.StorageQuota{Tenant: model.QuotaTenant(tenantID[0]), ID: tenantID[1]}
+		if quota.Tenant != model.QuotaTenantUser && quota.Tenant != model.QuotaTenantNamespace {
+			return nil, fmt.Errorf("unknown tenant in storage quota %q", spec)
+		}
+		var err error
+		if quota.MaxSize, err = strconv.Atoi(kv[1]); err != nil || quota.MaxSize < 0 {
+			return nil, fmt.Errorf("invalid size in storage quota %q", spec)
+		}
+		ret = append(ret, quota)
+	}
+	return ret, nil
+}
+
+// parseRequiredLabels checks that required storage labels are valid label keys.
+func parseRequiredLabels(keys []string) ([]string, error) {
+	for _, key := range keys {
+		if err := labels.ValidateKey(key); err != nil {
+			return nil, fmt.Errorf("invalid required label: %v", err)
+		}
+	}
+	return keys, nil
+}
+
+// setupEventsPublisher returns publisher batching events to configured sink.
+// Closers must be closed in returned order on shutdown: batch publisher flushes queued events to sink first.
+func setupEventsPublisher(ctx *cli.Context, db database.DB) (*events.BatchPublisher, []io.Closer, error) {
+	var sink events.Sink
+	var sinkCloser io.Closer
+	switch ctx.String(EventsSinkFlag.Name) {
+	case eventsSinkWebhook:
+		// webhooks are delivered synchronously, so events of each storage keep order and are delivered before shutdown
+		sink = events.NewWebhookSink(server.WebhookSource(db), events.WebhookConfig{
+			Workers:     ctx.Int(WebhookWorkersFlag.Name),
+			MaxAttempts: ctx.Int(WebhookMaxAttemptsFlag.Name),
+			RetryDelay:  ctx.Duration(WebhookRetryDelayFlag.Name),
+			Timeout:     ctx.Duration(WebhookTimeoutFlag.Name),
+			Log:         logrus.WithField("component", "webhooks"),
+		})
+	case eventsSinkKafka:
+		producer, err := events.NewKafkaWriterProducer(events.KafkaConfig{
+			Brokers: ctx.StringSlice(KafkaBrokersFlag.Name),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		sink, sinkCloser = events.KafkaSink{Producer: producer, Topic: ctx.String(KafkaTopicFlag.Name)}, producer
+	case eventsSinkNone:
+		sink = events.NopSink{}
+	default:
+		return nil, nil, fmt.Errorf("invalid events sink %q, expected %s, %s or %s",
+			ctx.String(EventsSinkFlag.Name), eventsSinkWebhook, eventsSinkKafka, eventsSinkNone)
+	}
+
+	publisher := events.NewBatchPublisher(sink, events.BatchConfig{
+		Window:       ctx.Duration(EventsBatchWindowFlag.Name),
+		MaxBatchSize: ctx.Int(EventsBatchSizeFlag.Name),
+		MaxAttempts:  ctx.Int(EventsMaxAttemptsFlag.Name),
+		Log:          logrus.WithField("component", "events"),
+	})
+	closers := []io.Closer{publisher}
+	if sinkCloser != nil {
+		closers = append(closers, sinkCloser)
+	}
+	return publisher, closers, nil
+}
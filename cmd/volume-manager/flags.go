@@ -1,6 +1,11 @@
 package main
 
 import (
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/router"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/server"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/urfave/cli.v2"
 )
@@ -53,11 +58,23 @@ var (
 		Value:   ":4343",
 	}
 
+	// address of gRPC StorageService listener, gRPC is disabled if it's empty
+	GRPCListenAddrFlag = cli.StringFlag{
+		Name:    "grpc_listen_addr",
+		EnvVars: []string{"GRPC_LISTEN_ADDR"},
+	}
+
 	BillingAddrFlag = cli.StringFlag{
 		Name:    "billing_addr",
 		EnvVars: []string{"BILLING_ADDR"},
 	}
 
+	// address of storage backend provisioner, storages sync is not available if not set
+	ProvisionerAddrFlag = cli.StringFlag{
+		Name:    "provisioner_addr",
+		EnvVars: []string{"PROVISIONER_ADDR"},
+	}
+
 	KubeAPIAddrFlag = cli.StringFlag{
 		Name:    "kube_api_addr",
 		EnvVars: []string{"KUBE_API_ADDR"},
@@ -66,4 +83,291 @@ var (
 	CORSFlag = cli.BoolFlag{
 		Name: "cors",
 	}
+
+	// origins allowed to make cross-origin requests, like "https://admin.example.com", "*" allows any origin
+	CORSAllowedOriginsFlag = cli.StringSliceFlag{
+		Name:    "cors_allowed_origins",
+		EnvVars: []string{"CORS_ALLOWED_ORIGINS"},
+	}
+
+	// methods and headers allowed in addition to used by API
+	CORSAllowedMethodsFlag = cli.StringSliceFlag{
+		Name:    "cors_allowed_methods",
+		EnvVars: []string{"CORS_ALLOWED_METHODS"},
+	}
+
+	CORSAllowedHeadersFlag = cli.StringSliceFlag{
+		Name:    "cors_allowed_headers",
+		EnvVars: []string{"CORS_ALLOWED_HEADERS"},
+	}
+
+	// compress responses for clients sending Accept-Encoding
+	CompressionFlag = cli.BoolFlag{
+		Name:    "compression",
+		EnvVars: []string{"COMPRESSION"},
+	}
+
+	// minimal response size in bytes to compress
+	CompressionMinSizeFlag = cli.IntFlag{
+		Name:    "compression_min_size",
+		EnvVars: []string{"COMPRESSION_MIN_SIZE"},
+		Value:   middleware.DefaultCompressionMinSize,
+	}
+
+	// enabled encodings in order of preference ("gzip", "deflate"), all supported if not set
+	CompressionEncodingsFlag = cli.StringSliceFlag{
+		Name:    "compression_encodings",
+		EnvVars: []string{"COMPRESSION_ENCODINGS"},
+	}
+
+	IdempotencyTTLFlag = cli.DurationFlag{
+		Name:    "idempotency_ttl",
+		EnvVars: []string{"IDEMPOTENCY_TTL"},
+		Value:   24 * time.Hour,
+	}
+
+	DBRetryMaxAttemptsFlag = cli.IntFlag{
+		Name:    "db_retry_max_attempts",
+		EnvVars: []string{"DB_RETRY_MAX_ATTEMPTS"},
+		Value:   server.DefaultRetryMaxAttempts,
+	}
+
+	DBRetryBaseDelayFlag = cli.DurationFlag{
+		Name:    "db_retry_base_delay",
+		EnvVars: []string{"DB_RETRY_BASE_DELAY"},
+		Value:   server.DefaultRetryBaseDelay,
+	}
+
+	StorageMinSizeFlag = cli.IntFlag{
+		Name:    "storage_min_size",
+		EnvVars: []string{"STORAGE_MIN_SIZE"},
+		Value:   server.DefaultMinStorageSize,
+	}
+
+	StorageMaxSizeFlag = cli.IntFlag{
+		Name:    "storage_max_size",
+		EnvVars: []string{"STORAGE_MAX_SIZE"},
+		Value:   server.DefaultMaxStorageSize,
+	}
+
+	// storage sizes must be multiples of it (GiB)
+	StorageSizeGranularityFlag = cli.IntFlag{
+		Name:    "storage_size_granularity",
+		EnvVars: []string{"STORAGE_SIZE_GRANULARITY"},
+		Value:   server.DefaultStorageSizeGranularity,
+	}
+
+	StorageUsageCheckIntervalFlag = cli.DurationFlag{
+		Name:    "storage_usage_check_interval",
+		EnvVars: []string{"STORAGE_USAGE_CHECK_INTERVAL"},
+		Value:   server.DefaultUsageCheckInterval,
+	}
+
+	// interval of permanent removal of storages kept in trash longer than retention, zero disables removal
+	StorageTrashPurgeIntervalFlag = cli.DurationFlag{
+		Name:    "storage_trash_purge_interval",
+		EnvVars: []string{"STORAGE_TRASH_PURGE_INTERVAL"},
+		Value:   server.DefaultTrashPurgeInterval,
+	}
+
+	StorageTrashRetentionFlag = cli.DurationFlag{
+		Name:    "storage_trash_retention",
+		EnvVars: []string{"STORAGE_TRASH_RETENTION"},
+		Value:   server.DefaultTrashRetention,
+	}
+
+	// interval of deletion attempts of terminating storages, zero disables deletion
+	StorageTerminationCheckIntervalFlag = cli.DurationFlag{
+		Name:    "storage_termination_check_interval",
+		EnvVars: []string{"STORAGE_TERMINATION_CHECK_INTERVAL"},
+		Value:   server.DefaultTerminationCheckInterval,
+	}
+
+	// time after which terminating storage with volumes reports blocking volumes in termination status
+	StorageTerminationTimeoutFlag = cli.DurationFlag{
+		Name:    "storage_termination_timeout",
+		EnvVars: []string{"STORAGE_TERMINATION_TIMEOUT"},
+		Value:   server.DefaultTerminationTimeout,
+	}
+
+	StorageOperationTimeoutFlag = cli.DurationFlag{
+		Name:    "storage_operation_timeout",
+		EnvVars: []string{"STORAGE_OPERATION_TIMEOUT"},
+		Value:   30 * time.Second,
+	}
+
+	// storage handlers time budget, 504 is returned if exceeded, zero disables limit
+	HandlerTimeoutFlag = cli.DurationFlag{
+		Name:    "handler_timeout",
+		EnvVars: []string{"HANDLER_TIMEOUT"},
+	}
+
+	// format: operation=duration, e.g. "import=10m", overrides handler_timeout for operations
+	HandlerTimeoutsFlag = cli.StringSliceFlag{
+		Name:    "handler_timeouts",
+		EnvVars: []string{"HANDLER_TIMEOUTS"},
+	}
+
+	// time to wait for in-flight requests on shutdown, requests are aborted after it
+	ShutdownDrainTimeoutFlag = cli.DurationFlag{
+		Name:    "shutdown_drain_timeout",
+		EnvVars: []string{"SHUTDOWN_DRAIN_TIMEOUT"},
+		Value:   30 * time.Second,
+	}
+
+	// number of storages fetched by one query while streaming storages list
+	StorageStreamBatchSizeFlag = cli.IntFlag{
+		Name:    "storage_stream_batch_size",
+		EnvVars: []string{"STORAGE_STREAM_BATCH_SIZE"},
+		Value:   server.DefaultStreamBatchSize,
+	}
+
+	ImportConcurrencyFlag = cli.IntFlag{
+		Name:    "import_concurrency",
+		EnvVars: []string{"IMPORT_CONCURRENCY"},
+		Value:   router.DefaultImportConcurrency,
+	}
+
+	// format: operation=requests_per_second:burst
+	RateLimitsFlag = cli.StringSliceFlag{
+		Name:    "rate_limits",
+		EnvVars: []string{"RATE_LIMITS"},
+		Value:   cli.NewStringSlice("create=5:10", "import=1:2", "bulk_delete=1:2"),
+	}
+
+	RateLimitExemptAdminsFlag = cli.BoolFlag{
+		Name:    "rate_limit_exempt_admins",
+		EnvVars: []string{"RATE_LIMIT_EXEMPT_ADMINS"},
+	}
+
+	BodyLimitsFlag = cli.StringSliceFlag{
+		Name:    "body_limits",
+		EnvVars: []string{"BODY_LIMITS"},
+	}
+
+	// format: operation or operation=sunset_date (YYYY-MM-DD)
+	DeprecatedOperationsFlag = cli.StringSliceFlag{
+		Name:    "deprecated_operations",
+		EnvVars: []string{"DEPRECATED_OPERATIONS"},
+	}
+
+	// format: role=operation1:operation2, e.g. "viewer=list:get:stats", replaces default mapping if set
+	RoleOperationsFlag = cli.StringSliceFlag{
+		Name:    "role_operations",
+		EnvVars: []string{"ROLE_OPERATIONS"},
+	}
+
+	// format: tenant:id=max_size_gib, tenant is "user" or "namespace", id "*" sets quota for all tenants of kind
+	StorageQuotasFlag = cli.StringSliceFlag{
+		Name:    "storage_quotas",
+		EnvVars: []string{"STORAGE_QUOTAS"},
+	}
+
+	// label keys every storage must have, e.g. "team,cost-center", not enforced if empty
+	StorageRequiredLabelsFlag = cli.StringSliceFlag{
+		Name:    "storage_required_labels",
+		EnvVars: []string{"STORAGE_REQUIRED_LABELS"},
+	}
+
+	// writes finished spans to debug log
+	TracingLogFlag = cli.BoolFlag{
+		Name:    "tracing_log",
+		EnvVars: []string{"TRACING_LOG"},
+	}
+
+	// storage names in requests are converted to lower case
+	StorageNamesCaseInsensitiveFlag = cli.BoolFlag{
+		Name:    "storage_names_case_insensitive",
+		EnvVars: []string{"STORAGE_NAMES_CASE_INSENSITIVE"},
+	}
+
+	// reject storage create and update requests with unknown body fields
+	StrictJSONFieldsFlag = cli.BoolFlag{
+		Name:    "strict_json_fields",
+		EnvVars: []string{"STRICT_JSON_FIELDS"},
+	}
+
+	// use camelCase field names in responses, both camelCase and snake_case accepted in requests
+	JSONCamelCaseFlag = cli.BoolFlag{
+		Name:    "json_camel_case",
+		EnvVars: []string{"JSON_CAMEL_CASE"},
+	}
+
+	// serve runtime profiles under /debug/pprof to admins
+	PprofFlag = cli.BoolFlag{
+		Name:    "pprof",
+		EnvVars: []string{"PPROF"},
+	}
+
+	// time to keep storage lists in cache, zero disables cache
+	StoragesCacheTTLFlag = cli.DurationFlag{
+		Name:    "storages_cache_ttl",
+		EnvVars: []string{"STORAGES_CACHE_TTL"},
+		Value:   server.DefaultStoragesCacheTTL,
+	}
+
+	WebhookWorkersFlag = cli.IntFlag{
+		Name:    "webhook_workers",
+		EnvVars: []string{"WEBHOOK_WORKERS"},
+		Value:   4,
+	}
+
+	// maximum number of attempts to deliver event to webhook
+	WebhookMaxAttemptsFlag = cli.IntFlag{
+		Name:    "webhook_max_attempts",
+		EnvVars: []string{"WEBHOOK_MAX_ATTEMPTS"},
+		Value:   5,
+	}
+
+	// delay before first delivery retry, it doubles with each next attempt
+	WebhookRetryDelayFlag = cli.DurationFlag{
+		Name:    "webhook_retry_delay",
+		EnvVars: []string{"WEBHOOK_RETRY_DELAY"},
+		Value:   time.Second,
+	}
+
+	WebhookTimeoutFlag = cli.DurationFlag{
+		Name:    "webhook_timeout",
+		EnvVars: []string{"WEBHOOK_TIMEOUT"},
+		Value:   10 * time.Second,
+	}
+
+	// events sink: "webhook" delivers events to registered webhooks, "kafka" produces them to kafka topic, "none" drops them
+	EventsSinkFlag = cli.StringFlag{
+		Name:    "events_sink",
+		EnvVars: []string{"EVENTS_SINK"},
+		Value:   eventsSinkWebhook,
+	}
+
+	// maximum time event waits in batch before it is flushed to sink
+	EventsBatchWindowFlag = cli.DurationFlag{
+		Name:    "events_batch_window",
+		EnvVars: []string{"EVENTS_BATCH_WINDOW"},
+		Value:   100 * time.Millisecond,
+	}
+
+	EventsBatchSizeFlag = cli.IntFlag{
+		Name:    "events_batch_size",
+		EnvVars: []string{"EVENTS_BATCH_SIZE"},
+		Value:   500,
+	}
+
+	// maximum number of attempts to flush events batch to sink
+	EventsMaxAttemptsFlag = cli.IntFlag{
+		Name:    "events_max_attempts",
+		EnvVars: []string{"EVENTS_MAX_ATTEMPTS"},
+		Value:   5,
+	}
+
+	// kafka brokers addresses, like "kafka-0:9092", required for kafka events sink
+	KafkaBrokersFlag = cli.StringSliceFlag{
+		Name:    "kafka_brokers",
+		EnvVars: []string{"KAFKA_BROKERS"},
+	}
+
+	KafkaTopicFlag = cli.StringFlag{
+		Name:    "kafka_topic",
+		EnvVars: []string{"KAFKA_TOPIC"},
+		Value:   "volume-manager.storages",
+	}
 )
@@ -3,25 +3,29 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
-	"git.containerum.net/ch/volume-manager/pkg/errors"
+	volgrpc "git.containerum.net/ch/volume-manager/pkg/grpc"
+	"git.containerum.net/ch/volume-manager/pkg/grpc/storagepb"
 	"git.containerum.net/ch/volume-manager/pkg/router"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
 	"git.containerum.net/ch/volume-manager/pkg/server"
+	"git.containerum.net/ch/volume-manager/pkg/tracing"
 	"git.containerum.net/ch/volume-manager/pkg/utils/validation"
 	"github.com/containerum/cherry/adaptors/cherrylog"
-	"github.com/containerum/cherry/adaptors/gonic"
 	"github.com/containerum/kube-client/pkg/model"
-	"github.com/containerum/utils/httputil"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/contrib/ginrus"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 	"gopkg.in/urfave/cli.v2"
 )
 
@@ -44,6 +48,20 @@ func errFuture(f func() error) <-chan error {
 	return ch
 }
 
+// stopGRPCServer waits for in-flight calls until context is done, then closes remaining connections.
+func stopGRPCServer(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
 func prettyPrintFlags(ctx *cli.Context) {
 	fmt.Printf("Starting %v %v\n", ctx.App.Name, ctx.App.Version)
 
@@ -54,7 +72,13 @@ func prettyPrintFlags(ctx *cli.Context) {
 	w.Flush()
 }
 
-const httpServerContextKey = "httpsrv"
+const (
+	httpServerContextKey = "httpsrv"
+	serverContextKey     = "srv"
+	publisherContextKey  = "publisher"
+	routerContextKey     = "router"
+	grpcServerContextKey = "grpcsrv"
+)
 
 var version string
 
@@ -72,9 +96,57 @@ func main() {
 			&DBBaseFlag,
 			&DBSSLModeFlag,
 			&ListenAddrFlag,
+			&GRPCListenAddrFlag,
 			&BillingAddrFlag,
 			&KubeAPIAddrFlag,
+			&ProvisionerAddrFlag,
 			&CORSFlag,
+			&CORSAllowedOriginsFlag,
+			&CORSAllowedMethodsFlag,
+			&CORSAllowedHeadersFlag,
+			&CompressionFlag,
+			&CompressionMinSizeFlag,
+			&CompressionEncodingsFlag,
+			&IdempotencyTTLFlag,
+			&DBRetryMaxAttemptsFlag,
+			&DBRetryBaseDelayFlag,
+			&StorageUsageCheckIntervalFlag,
+			&StorageTrashPurgeIntervalFlag,
+			&StorageTrashRetentionFlag,
+			&StorageTerminationCheckIntervalFlag,
+			&StorageTerminationTimeoutFlag,
+			&StorageMinSizeFlag,
+			&StorageMaxSizeFlag,
+			&StorageSizeGranularityFlag,
+			&StorageOperationTimeoutFlag,
+			&HandlerTimeoutFlag,
+			&HandlerTimeoutsFlag,
+			&ImportConcurrencyFlag,
+			&StorageStreamBatchSizeFlag,
+			&ShutdownDrainTimeoutFlag,
+			&RateLimitsFlag,
+			&DeprecatedOperationsFlag,
+			&RateLimitExemptAdminsFlag,
+			&BodyLimitsFlag,
+			&RoleOperationsFlag,
+			&StorageQuotasFlag,
+			&StorageRequiredLabelsFlag,
+			&TracingLogFlag,
+			&StorageNamesCaseInsensitiveFlag,
+			&StrictJSONFieldsFlag,
+			&StoragesCacheTTLFlag,
+			&JSONCamelCaseFlag,
+			&PprofFlag,
+			&WebhookWorkersFlag,
+			&WebhookMaxAttemptsFlag,
+			&WebhookRetryDelayFlag,
+			&WebhookTimeoutFlag,
+			&EventsSinkFlag,
+			&EventsBatchWindowFlag,
+			&EventsBatchSizeFlag,
+			&EventsMaxAttemptsFlag,
+			&KafkaBrokersFlag,
+			&KafkaTopicFlag,
 		},
 		Before: func(ctx *cli.Context) error {
 			prettyPrintFlags(ctx)
@@ -98,32 +170,132 @@ func main() {
 				return err
 			}
 
-			srv := server.NewServer(db, clients)
+			var tracerProvider tracing.TracerProvider = tracing.NopTracerProvider{}
+			if ctx.Bool(TracingLogFlag.Name) {
+				tracerProvider = tracing.NewTracerProvider(tracing.LogExporter{Entry: logrus.WithField("component", "tracing")})
+			}
+
+			storageQuotas, err := parseStorageQuotas(ctx.StringSlice(StorageQuotasFlag.Name))
+			if err != nil {
+				return err
+			}
+			requiredLabels, err := parseRequiredLabels(ctx.StringSlice(StorageRequiredLabelsFlag.Name))
+			if err != nil {
+				return err
+			}
+
+			publisher, publisherClosers, err := setupEventsPublisher(ctx, db)
+			if err != nil {
+				return err
+			}
 
 			g := gin.New()
-			g.Use(gonic.Recovery(errors.ErrInternal, cherrylog.NewLogrusAdapter(logrus.WithField("component", "gin_recovery"))))
+			g.Use(middleware.Recovery(cherrylog.NewLogrusAdapter(logrus.WithField("component", "gin_recovery"))))
 			g.Use(ginrus.Ginrus(logrus.StandardLogger(), time.RFC3339, true))
 			binding.Validator = &validation.GinValidatorV9{Validate: validate} // gin has no local validator
 
-			if ctx.Bool(CORSFlag.Name) {
-				corsCfg := cors.DefaultConfig()
-				corsCfg.AllowAllOrigins = true
-				corsCfg.AddAllowHeaders(
-					httputil.UserIDXHeader,
-					httputil.UserRoleXHeader,
-				)
-				g.Use(cors.New(corsCfg))
-			}
-
 			status := model.ServiceStatus{
 				Name:     ctx.App.Name,
 				Version:  ctx.App.Version,
 				StatusOK: true,
 			}
 
-			r := router.NewRouter(g, &status, &router.TranslateValidate{UniversalTranslator: translate, Validate: validate})
+			rateLimits, err := parseRateLimits(ctx.StringSlice(RateLimitsFlag.Name))
+			if err != nil {
+				return err
+			}
+
+			bodyLimits, err := parseBodyLimits(ctx.StringSlice(BodyLimitsFlag.Name))
+			if err != nil {
+				return err
+			}
+
+			deprecations, err := parseDeprecations(ctx.StringSlice(DeprecatedOperationsFlag.Name))
+			if err != nil {
+				return err
+			}
+
+			handlerTimeouts, err := parseHandlerTimeouts(ctx.StringSlice(HandlerTimeoutsFlag.Name))
+			if err != nil {
+				return err
+			}
+
+			roleOperations, err := parseRoleOperations(ctx.StringSlice(RoleOperationsFlag.Name))
+			if err != nil {
+				return err
+			}
+
+			routerCfg := router.Config{
+				IdempotencyTTL:        ctx.Duration(IdempotencyTTLFlag.Name),
+				RateLimits:            rateLimits,
+				RateLimitExemptAdmins: ctx.Bool(RateLimitExemptAdminsFlag.Name),
+				OperationTimeout:      ctx.Duration(StorageOperationTimeoutFlag.Name),
+				HandlerTimeout: middleware.HandlerTimeoutConfig{
+					Default:    ctx.Duration(HandlerTimeoutFlag.Name),
+					Operations: handlerTimeouts,
+				},
+				ImportConcurrency: ctx.Int(ImportConcurrencyFlag.Name),
+				BodyLimits:        bodyLimits,
+				TracerProvider:    tracerProvider,
+
+				StrictJSONFields: ctx.Bool(StrictJSONFieldsFlag.Name),
+				CamelCaseJSON:    ctx.Bool(JSONCamelCaseFlag.Name),
+				Deprecations:     deprecations,
+				RoleOperations:   roleOperations,
+				Pprof:            ctx.Bool(PprofFlag.Name),
+			}
+
+			if ctx.Bool(CORSFlag.Name) {
+				corsCfg, err := setupCORS(ctx)
+				if err != nil {
+					return err
+				}
+				routerCfg.CORS = &corsCfg
+			}
+
+			if ctx.Bool(CompressionFlag.Name) {
+				compressionCfg := middleware.CompressionConfig{
+					MinSize:   ctx.Int(CompressionMinSizeFlag.Name),
+					Encodings: ctx.StringSlice(CompressionEncodingsFlag.Name),
+				}
+				if err := compressionCfg.Validate(); err != nil {
+					return err
+				}
+				routerCfg.Compression = &compressionCfg
+			}
+
+			r := router.NewRouter(g, &status, &router.TranslateValidate{UniversalTranslator: translate, Validate: validate}, routerCfg)
+
+			srv := server.NewServer(db, clients, publisher, server.Config{
+				RetryMaxAttempts:   ctx.Int(DBRetryMaxAttemptsFlag.Name),
+				RetryBaseDelay:     ctx.Duration(DBRetryBaseDelayFlag.Name),
+				UsageCheckInterval: ctx.Duration(StorageUsageCheckIntervalFlag.Name),
+				MinStorageSize:     ctx.Int(StorageMinSizeFlag.Name),
+				MaxStorageSize:     ctx.Int(StorageMaxSizeFlag.Name),
+
+				StorageSizeGranularity: ctx.Int(StorageSizeGranularityFlag.Name),
+				StorageQuotas:          storageQuotas,
+				RequiredLabels:         requiredLabels,
+				TracerProvider:         tracerProvider,
+				StreamBatchSize:        ctx.Int(StorageStreamBatchSizeFlag.Name),
+				TrashPurgeInterval:     ctx.Duration(StorageTrashPurgeIntervalFlag.Name),
+				TrashRetention:         ctx.Duration(StorageTrashRetentionFlag.Name),
+
+				TerminationCheckInterval: ctx.Duration(StorageTerminationCheckIntervalFlag.Name),
+				TerminationTimeout:       ctx.Duration(StorageTerminationTimeoutFlag.Name),
+
+				// storages named as fixed path segments of HTTP API could not be got
+				ReservedStorageNames: r.StorageNameReserved,
+			})
+
 			r.SetupVolumeHandlers(srv)
-			r.SetupStorageHandlers(srv)
+			// HTTP and gRPC transports share storage actions, so they are checked, traced and cached the same way
+			var storageActions server.StorageActions = srv
+			if ctx.Bool(StorageNamesCaseInsensitiveFlag.Name) {
+				storageActions = server.CaseInsensitiveStorageNames(storageActions)
+			}
+			storageActions = server.TraceStorageActions(server.CacheStorageLists(storageActions, ctx.Duration(StoragesCacheTTLFlag.Name)), tracerProvider)
+			r.SetupStorageHandlers(storageActions)
 
 			// for graceful shutdown
 			httpsrv := &http.Server{
@@ -131,7 +303,16 @@ func main() {
 				Handler: g,
 			}
 
+			if ctx.String(GRPCListenAddrFlag.Name) != "" {
+				grpcsrv := grpc.NewServer(grpc.UnaryInterceptor(volgrpc.UnaryAuthInterceptor(roleOperations)))
+				storagepb.RegisterStorageServiceServer(grpcsrv, volgrpc.NewStorageServer(storageActions))
+				ctx.App.Metadata[grpcServerContextKey] = grpcsrv
+			}
+
 			ctx.App.Metadata[httpServerContextKey] = httpsrv
+			ctx.App.Metadata[serverContextKey] = srv
+			ctx.App.Metadata[routerContextKey] = r
+			ctx.App.Metadata[publisherContextKey] = publisherClosers
 
 			return nil
 		},
@@ -140,20 +321,55 @@ func main() {
 			errCh := errFuture(func() error {
 				return httpsrv.ListenAndServe()
 			})
+			// nil channel blocks forever if gRPC is disabled
+			var grpcErrCh <-chan error
+			grpcsrv, _ := ctx.App.Metadata[grpcServerContextKey].(*grpc.Server)
+			if grpcsrv != nil {
+				lis, err := net.Listen("tcp", ctx.String(GRPCListenAddrFlag.Name))
+				if err != nil {
+					return err
+				}
+				grpcErrCh = errFuture(func() error {
+					return grpcsrv.Serve(lis)
+				})
+			}
 
-			// Wait for interrupt signal to gracefully shutdown the server with
-			// a timeout of 5 seconds.
+			// Wait for interrupt or termination signal to gracefully shutdown the server:
+			// in-flight requests are waited for drain timeout, then aborted ones are waited for 5 seconds.
 			quit := make(chan os.Signal, 1)
-			signal.Notify(quit, os.Interrupt) // subscribe on interrupt event
+			signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 			select {
 			case err := <-errCh:
 				return err
+			case err := <-grpcErrCh:
+				return err
 			case <-quit:
 				logrus.Infoln("shutting down server...")
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				drainCtx, cancelDrain := context.WithTimeout(context.Background(), ctx.Duration(ShutdownDrainTimeoutFlag.Name))
+				defer cancelDrain()
+				if err := ctx.App.Metadata[routerContextKey].(*router.Router).Drain(drainCtx); err != nil {
+					logrus.WithError(err).Warnln("in-flight requests were not finished in drain timeout, aborting them")
+				}
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer cancel()
-				return httpsrv.Shutdown(ctx)
+				if err := httpsrv.Shutdown(shutdownCtx); err != nil {
+					return err
+				}
+				if grpcsrv != nil {
+					stopGRPCServer(shutdownCtx, grpcsrv)
+				}
+				// flush pending audit records
+				if err := ctx.App.Metadata[serverContextKey].(*server.Server).Close(); err != nil {
+					return err
+				}
+				// flush batched events, then stop sink
+				for _, closer := range ctx.App.Metadata[publisherContextKey].([]io.Closer) {
+					if err := closer.Close(); err != nil {
+						return err
+					}
+				}
+				return nil
 			}
 		},
 	}
@@ -0,0 +1,90 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+	"github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+)
+
+// ProvisionedStorage is a storage existing on storage backend
+type ProvisionedStorage struct {
+	Name   string `json:"name"`
+	Driver string `json:"driver,omitempty"`
+	// Size of storage, GiB
+	Size int `json:"size"`
+}
+
+// ProvisionerClient is an interface to storage backend provisioner.
+// Each backend kind (NFS server, Ceph cluster, etc.) may have own implementation.
+type ProvisionerClient interface {
+	// ListStorages returns all storages actually existing on backend
+	ListStorages(ctx context.Context) ([]ProvisionedStorage, error)
+}
+
+// ProvisionerHTTPClient gets storages from provisioner service which serves storages list on "GET /storages".
+type ProvisionerHTTPClient struct {
+	log    *cherrylog.LogrusAdapter
+	client *resty.Client
+}
+
+func NewProvisionerHTTPClient(u *url.URL) *ProvisionerHTTPClient {
+	log := logrus.WithField("component", "provisioner_client")
+
+	client := resty.New().
+		SetLogger(log.WriterLevel(logrus.DebugLevel)).
+		SetHostURL(u.String()).
+		SetDebug(true).
+		SetError(cherry.Err{}).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Accept", "application/json")
+	client.JSONMarshal = jsoniter.Marshal
+	client.JSONUnmarshal = jsoniter.Unmarshal
+	return &ProvisionerHTTPClient{
+		log:    cherrylog.NewLogrusAdapter(log),
+		client: client,
+	}
+}
+
+func (p *ProvisionerHTTPClient) ListStorages(ctx context.Context) ([]ProvisionedStorage, error) {
+	p.log.Debugln("list provisioned storages")
+
+	var ret []ProvisionedStorage
+	resp, err := p.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetResult(&ret).
+		Get("/storages")
+	if err != nil {
+		return nil, errors.ErrInternal().Log(err, p.log)
+	}
+	if resp.Error() != nil {
+		return nil, resp.Error().(*cherry.Err)
+	}
+	return ret, nil
+}
+
+// ProvisionerDummyClient reports fixed list of storages.
+type ProvisionerDummyClient struct {
+	log      *logrus.Entry
+	storages []ProvisionedStorage
+}
+
+func NewProvisionerDummyClient(storages ...ProvisionedStorage) *ProvisionerDummyClient {
+	return &ProvisionerDummyClient{
+		log:      logrus.WithField("component", "provisioner_client"),
+		storages: storages,
+	}
+}
+
+func (p *ProvisionerDummyClient) ListStorages(ctx context.Context) ([]ProvisionedStorage, error) {
+	p.log.Debugln("list provisioned storages")
+
+	return append([]ProvisionedStorage{}, p.storages...), nil
+}
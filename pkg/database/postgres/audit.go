@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/pg"
+)
+
+func (pgdb *PgDB) CreateStorageAuditRecords(ctx context.Context, records []model.StorageAuditRecord) error {
+	pgdb.log.Debugf("create %d storage audit records", len(records))
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	_, err := pgdb.withDeadline(ctx).Model(&records).
+		Returning("*").
+		Insert()
+	return pgdb.handleError(err)
+}
+
+func (pgdb *PgDB) StorageAuditRecords(ctx context.Context, name string) (ret []model.StorageAuditRecord, err error) {
+	pgdb.log.WithField("name", name).Debugf("get storage audit records")
+
+	ret = make([]model.StorageAuditRecord, 0)
+
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Where("storage_name = ?", name).
+		Order("timestamp ASC", "id ASC").
+		Select()
+	switch err {
+	case pg.ErrNoRows:
+		err = nil
+	default:
+		err = pgdb.handleError(err)
+	}
+
+	return
+}
+
+func (pgdb *PgDB) CreateStorageSizeRecords(ctx context.Context, records []model.StorageSizeRecord) error {
+	pgdb.log.Debugf("create %d storage size records", len(records))
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	_, err := pgdb.withDeadline(ctx).Model(&records).
+		Returning("*").
+		Insert()
+	return pgdb.handleError(err)
+}
+
+func (pgdb *PgDB) StorageSizeHistory(ctx context.Context, name string, filter model.StorageSizeHistoryFilter) (ret []model.StorageSizeRecord, err error) {
+	pgdb.log.WithField("name", name).Debugf("get storage size history")
+
+	ret = make([]model.StorageSizeRecord, 0)
+
+	query := pgdb.withDeadline(ctx).Model(&ret).
+		Where("storage_name = ?", name).
+		Order("timestamp DESC", "id DESC")
+	if filter.From != nil {
+		query = query.Where("timestamp >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("timestamp <= ?", *filter.To)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	err = query.Select()
+	switch err {
+	case pg.ErrNoRows:
+		err = nil
+	default:
+		err = pgdb.handleError(err)
+	}
+
+	// latest records are selected, but returned in chronological order
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return
+}
@@ -1,7 +1,9 @@
 package postgres
 
 import (
+	"context"
 	"io"
+	"net"
 	"strings"
 	"time"
 
@@ -61,23 +63,88 @@ type transactional interface {
 	RunInTransaction(fn func(*pg.Tx) error) error
 }
 
+// Transaction rollback errors (SQLSTATE class 40) mean transaction was not applied and may be safely retried:
+// serialization_failure (40001) and deadlock_detected (40P01).
+var transientErrorCodes = map[string]bool{
+	"40001": true,
+	"40P01": true,
+}
+
+func isTransient(err pg.Error) bool {
+	return transientErrorCodes[err.Field('C')]
+}
+
+// queryCanceledCode is SQLSTATE returned when query was cancelled, e.g. by statement_timeout.
+const queryCanceledCode = "57014"
+
+// connTimeoutSlack is added to connection timeouts so statement_timeout is hit first
+// and query is aborted on server side, not only on client side.
+const connTimeoutSlack = time.Second
+
+func isUniqueViolation(err error) bool {
+	pgErr, ok := err.(pg.Error)
+	return ok && pgErr.Field('C') == "23505"
+}
+
 func (pgdb *PgDB) handleError(err error) error {
 	if err == nil {
 		return nil
 	}
 
-	switch err.(type) {
+	switch e := err.(type) {
 	case *cherry.Err:
 		return err
+	case pg.Error:
+		switch {
+		case isTransient(e):
+			return errors.ErrDatabaseTemporary().Log(err, pgdb.log)
+		case e.Field('C') == queryCanceledCode:
+			return errors.ErrOperationTimeout().Log(err, pgdb.log)
+		}
+		return errors.ErrInternal().Log(err, pgdb.log)
+	case net.Error:
+		if e.Timeout() {
+			return errors.ErrOperationTimeout().Log(err, pgdb.log)
+		}
+		return errors.ErrInternal().Log(err, pgdb.log)
 	default:
 		return errors.ErrInternal().Log(err, pgdb.log)
 	}
 }
 
-func (pgdb *PgDB) Transactional(fn func(tx database.DB) error) error {
+// withDeadline returns database handle which fails queries not finished until context deadline.
+// Inside transaction handle is returned as is because timeout is set for whole transaction.
+func (pgdb *PgDB) withDeadline(ctx context.Context) orm.DB {
+	db, ok := pgdb.db.(*pg.DB)
+	if !ok {
+		return pgdb.db
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return pgdb.db
+	}
+	return db.WithContext(ctx).WithTimeout(time.Until(deadline) + connTimeoutSlack)
+}
+
+// Transactional runs fn in transaction. If context has deadline statements which are not finished
+// until it are cancelled by database.
+func (pgdb *PgDB) Transactional(ctx context.Context, fn func(tx database.DB) error) error {
+	if err := ctx.Err(); err != nil {
+		return errors.ErrOperationTimeout().AddDetailsErr(err)
+	}
+
 	entry := cherrylog.NewLogrusAdapter(pgdb.log.WithField("transaction_id", time.Now().UTC().Unix()))
 	dtx := &PgDB{log: entry}
-	err := pgdb.db.(transactional).RunInTransaction(func(tx *pg.Tx) error {
+	err := pgdb.withDeadline(ctx).(transactional).RunInTransaction(func(tx *pg.Tx) error {
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout := time.Until(deadline) / time.Millisecond
+			if timeout <= 0 {
+				return errors.ErrOperationTimeout().AddDetailsErr(context.DeadlineExceeded)
+			}
+			if _, err := tx.Exec("SET LOCAL statement_timeout = ?", int64(timeout)); err != nil {
+				return err
+			}
+		}
 		dtx.db = tx
 		return fn(dtx)
 	})
@@ -85,6 +152,12 @@ func (pgdb *PgDB) Transactional(fn func(tx database.DB) error) error {
 	return dtx.handleError(err)
 }
 
+// Ping checks database connection. Error is returned as is to show connection problem.
+func (pgdb *PgDB) Ping(ctx context.Context) error {
+	_, err := pgdb.withDeadline(ctx).Exec("SELECT 1")
+	return err
+}
+
 func (pgdb *PgDB) Close() error {
 	if cl, ok := pgdb.db.(io.Closer); ok {
 		return cl.Close()
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.Register(func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		ADD COLUMN IF NOT EXISTS "is_default" BOOLEAN NOT NULL DEFAULT FALSE;
+`); err != nil {
+			return err
+		}
+
+		// only one storage may be default
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`CREATE UNIQUE INDEX IF NOT EXISTS storages_single_default ON "?TableName" ("is_default") WHERE "is_default"`); err != nil {
+			return err
+		}
+
+		return nil
+	}, func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`DROP INDEX IF EXISTS storages_single_default;
+				ALTER TABLE "?TableName" 
+				  		DROP COLUMN IF EXISTS "is_default";
+`); err != nil {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.Register(func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		ADD COLUMN IF NOT EXISTS "read_only" BOOLEAN NOT NULL DEFAULT FALSE;
+`); err != nil {
+			return err
+		}
+
+		return nil
+	}, func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		DROP COLUMN IF EXISTS "read_only";
+`); err != nil {
+			return err
+		}
+		return nil
+	})
+}
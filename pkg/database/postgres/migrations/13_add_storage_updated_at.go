@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.Register(func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		ADD COLUMN IF NOT EXISTS "updated_at" Timestamp With Time Zone NOT NULL DEFAULT now();
+`); err != nil {
+			return err
+		}
+
+		// storages were not changed since creation as far as we know
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`UPDATE "?TableName" SET "updated_at" = "created_at"`); err != nil {
+			return err
+		}
+
+		return nil
+	}, func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		DROP COLUMN IF EXISTS "updated_at";
+`); err != nil {
+			return err
+		}
+		return nil
+	})
+}
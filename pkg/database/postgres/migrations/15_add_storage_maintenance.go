@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.Register(func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		ADD COLUMN IF NOT EXISTS "maintenance" BOOLEAN NOT NULL DEFAULT FALSE,
+				  		ADD COLUMN IF NOT EXISTS "maintenance_reason" TEXT;
+`); err != nil {
+			return err
+		}
+
+		return nil
+	}, func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		DROP COLUMN IF EXISTS "maintenance",
+				  		DROP COLUMN IF EXISTS "maintenance_reason";
+`); err != nil {
+			return err
+		}
+		return nil
+	})
+}
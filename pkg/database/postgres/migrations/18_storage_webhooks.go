@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/migrations"
+	"github.com/go-pg/pg/orm"
+)
+
+func init() {
+	migrations.Register(func(db migrations.DB) error {
+		if _, err := orm.CreateTable(db, &model.StorageWebhook{}, &orm.CreateTableOptions{IfNotExists: true}); err != nil {
+			return err
+		}
+
+		return nil
+	}, func(db migrations.DB) error {
+		if _, err := orm.DropTable(db, &model.StorageWebhook{}, &orm.DropTableOptions{IfExists: true}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
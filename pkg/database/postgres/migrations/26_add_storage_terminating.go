@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.Register(func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		ADD COLUMN IF NOT EXISTS "terminating" BOOLEAN NOT NULL DEFAULT FALSE,
+				  		ADD COLUMN IF NOT EXISTS "terminating_since" Timestamp With Time Zone,
+				  		ADD COLUMN IF NOT EXISTS "termination_status" TEXT;
+`); err != nil {
+			return err
+		}
+
+		return nil
+	}, func(db migrations.DB) error {
+		if _, err := db.Model(&model.Storage{}).Exec( /* language=sql*/
+			`ALTER TABLE "?TableName" 
+				  		DROP COLUMN IF EXISTS "terminating",
+				  		DROP COLUMN IF EXISTS "terminating_since",
+				  		DROP COLUMN IF EXISTS "termination_status";
+`); err != nil {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/migrations"
+	"github.com/go-pg/pg/orm"
+)
+
+func init() {
+	migrations.Register(func(db migrations.DB) error {
+		if _, err := orm.CreateTable(db, &model.StorageAuditRecord{}, &orm.CreateTableOptions{IfNotExists: true}); err != nil {
+			return err
+		}
+
+		if _, err := db.Model(&model.StorageAuditRecord{}).
+			Exec( /* language=sql */ `CREATE INDEX IF NOT EXISTS storage_audit_name_time ON "?TableName" ("storage_name", "timestamp")`); err != nil {
+			return err
+		}
+
+		return nil
+	}, func(db migrations.DB) error {
+		if _, err := orm.DropTable(db, &model.StorageAuditRecord{}, &orm.DropTableOptions{IfExists: true}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
@@ -2,42 +2,85 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
+	"time"
 
+	"git.containerum.net/ch/volume-manager/pkg/database"
 	"git.containerum.net/ch/volume-manager/pkg/errors"
 	"git.containerum.net/ch/volume-manager/pkg/models"
 	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
 )
 
 func (pgdb *PgDB) CreateStorage(ctx context.Context, storage *model.Storage) error {
 	pgdb.log.Debugf("create storage %+v", storage)
 
-	cnt, err := pgdb.db.Model(storage).
-		WherePK().
-		Count()
-	if err != nil {
-		return pgdb.handleError(err)
-	}
-
-	if cnt > 0 {
-		_, err := pgdb.db.Model(storage).
-			Where("name = ?", storage.Name).
-			Set("size = ?size").
-			Set("deleted = FALSE").
-			Update()
+	storage.ID = uuid.NewV4().String()
+	var existing model.Storage
+	err := pgdb.withDeadline(ctx).Model(&existing).
+		Column("deleted").
+		Where("name = ?", storage.Name).
+		Select()
+	switch err {
+	case pg.ErrNoRows:
+		// ok, create new storage
+	case nil:
+		if !existing.Deleted {
+			return errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", storage.Name)
+		}
+		// storage in trash is replaced by new one
+		return pgdb.replaceTrashedStorage(ctx, storage)
+	default:
 		return pgdb.handleError(err)
 	}
 
 	storage.Used = 0
-	_, err = pgdb.db.Model(storage).
+	_, err = pgdb.withDeadline(ctx).Model(storage).
 		Returning("*").
 		Insert()
+	if isUniqueViolation(err) { // storage with same name was created concurrently
+		return errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", storage.Name)
+	}
+	return pgdb.handleError(err)
+}
+
+// replaceTrashedStorage overwrites soft-deleted storage with new one. Pin, maintenance mode and timestamps
+// of old storage are reset, so new storage looks like just created one.
+func (pgdb *PgDB) replaceTrashedStorage(ctx context.Context, storage *model.Storage) error {
+	_, err := pgdb.withDeadline(ctx).Model(storage).
+		Where("name = ?", storage.Name).
+		Set("id = ?id").
+		Set("driver = ?driver").
+		Set("size = ?size").
+		Set("reserved = ?reserved").
+		Set("overcommit_ratio = ?overcommit_ratio").
+		Set("warn_threshold = ?warn_threshold").
+		Set("read_only = ?read_only").
+		Set("labels = ?labels").
+		Set("description = ?description").
+		Set("annotations = ?annotations").
+		Set("namespaces = ?namespaces").
+		Set("owner_user_id = ?owner_user_id").
+		Set("deleted = FALSE").
+		Set("delete_time = NULL").
+		Set("pinned = FALSE").
+		Set("maintenance = FALSE").
+		Set("maintenance_reason = NULL").
+		Set("created_at = now()").
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Returning("*").
+		Update()
 	return pgdb.handleError(err)
 }
 
 func (pgdb *PgDB) StorageByName(ctx context.Context, name string) (ret model.Storage, err error) {
 	pgdb.log.WithField("name", name).Debugf("get storage by name")
 
-	err = pgdb.db.Model(&ret).
+	err = pgdb.withDeadline(ctx).Model(&ret).
 		Where("name = ?", name).
 		Where("NOT deleted").
 		Select()
@@ -51,35 +94,116 @@ func (pgdb *PgDB) StorageByName(ctx context.Context, name string) (ret model.Sto
 	return
 }
 
-func (pgdb *PgDB) AllStorages(ctx context.Context) (ret []model.Storage, err error) {
-	pgdb.log.Debugf("get storage list")
+func (pgdb *PgDB) StorageByID(ctx context.Context, id string) (ret model.Storage, err error) {
+	pgdb.log.WithField("id", id).Debugf("get storage by id")
 
-	err = pgdb.db.Model(&ret).
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Where("id = ?", id).
 		Where("NOT deleted").
 		Select()
+	switch err {
+	case pg.ErrNoRows:
+		err = errors.ErrResourceNotExists().AddDetailF("storage with id %s not exists", id)
+	default:
+		err = pgdb.handleError(err)
+	}
+
+	return
+}
+
+func (pgdb *PgDB) AllStorages(ctx context.Context, filter database.StorageFilter) (ret []model.Storage, err error) {
+	pgdb.log.WithField("filter", filter).Debugf("get storage list")
+
+	f := StorageFilter(filter)
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Apply(f.Filter).
+		Select()
 	err = pgdb.handleError(err)
 	return
 }
 
+func (pgdb *PgDB) CountStorages(ctx context.Context, filter database.StorageFilter) (int, error) {
+	pgdb.log.WithField("filter", filter).Debugf("count storages")
+
+	f := StorageFilter(filter)
+	cnt, err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+		Apply(f.CountFilter).
+		Count()
+	return cnt, pgdb.handleError(err)
+}
+
+func (pgdb *PgDB) StoragesTotalSize(ctx context.Context, filter database.StorageFilter) (int, error) {
+	pgdb.log.WithField("filter", filter).Debugf("get storages total size")
+
+	f := StorageFilter(filter)
+	var total int
+	err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+		Apply(f.CountFilter).
+		Column("size").
+		WrapWith("filtered").
+		Table("filtered").
+		ColumnExpr("COALESCE(SUM(size), 0)").
+		Select(pg.Scan(&total))
+	return total, pgdb.handleError(err)
+}
+
+func (pgdb *PgDB) StorageDriverStats(ctx context.Context) ([]model.StorageDriverStats, error) {
+	pgdb.log.Debugf("get storage stats")
+
+	f := StorageFilter(database.StorageFilter{})
+	ret := make([]model.StorageDriverStats, 0)
+	err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+		Apply(f.CountFilter).
+		Column("driver", "size", "reserved", "used").
+		WrapWith("filtered").
+		Table("filtered").
+		ColumnExpr("driver").
+		ColumnExpr("COUNT(*) AS count").
+		ColumnExpr("COALESCE(SUM(size), 0) AS size").
+		ColumnExpr("COALESCE(SUM(reserved), 0) AS reserved").
+		ColumnExpr("COALESCE(SUM(size - reserved), 0) AS allocatable").
+		ColumnExpr("COALESCE(SUM(used), 0) AS used").
+		ColumnExpr("COALESCE(SUM(size - reserved - used), 0) AS free").
+		Group("driver").
+		Order("driver").
+		Select(&ret)
+	if err == pg.ErrNoRows {
+		err = nil
+	}
+	return ret, pgdb.handleError(err)
+}
+
 func (pgdb *PgDB) UpdateStorage(ctx context.Context, name string, storage model.Storage) error {
 	pgdb.log.WithField("name", name).Debugf("update storage to %+v", storage)
 
 	if storage.Name != name {
-		cnt, err := pgdb.db.Model(&storage).
+		cnt, err := pgdb.withDeadline(ctx).Model(&storage).
 			WherePK().
 			Count()
 		if err != nil {
 			return pgdb.handleError(err)
 		}
 		if cnt > 0 {
-			return errors.ErrResourceAlreadyExists().AddDetailF("storage %s already exists", storage.Name)
+			return errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", storage.Name)
 		}
 	}
 
-	result, err := pgdb.db.Model(&storage).
+	result, err := pgdb.withDeadline(ctx).Model(&storage).
 		Where("name = ?", name).
 		Set("name = ?name").
+		Set("description = ?description").
 		Set("size = ?size").
+		Set("reserved = ?reserved").
+		Set("overcommit_ratio = ?overcommit_ratio").
+		Set("warn_threshold = ?warn_threshold").
+		Set("read_only = ?read_only").
+		Set("labels = ?labels").
+		Set("annotations = ?annotations").
+		Set("namespaces = ?namespaces").
+		Set("maintenance = ?maintenance").
+		Set("maintenance_reason = ?maintenance_reason").
+		Set("version = version + 1").
+		Set("updated_at = now()").
 		Update()
 	if err != nil {
 		return pgdb.handleError(err)
@@ -90,24 +214,134 @@ func (pgdb *PgDB) UpdateStorage(ctx context.Context, name string, storage model.
 	return nil
 }
 
-func (pgdb *PgDB) DeleteStorage(ctx context.Context, storage *model.Storage) error {
-	pgdb.log.WithField("name", storage.Name).Debugf("delete storage")
+func (pgdb *PgDB) SetStorageLabels(ctx context.Context, name string, labels map[string]string) error {
+	pgdb.log.WithField("name", name).Debugf("set storage labels to %v", labels)
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name, Labels: labels}).
+		WherePK().
+		Where("NOT deleted").
+		Set("labels = ?labels").
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return nil
+}
+
+// PatchStorageLabels merges labels in database, so concurrent patches of different keys don't overwrite each other.
+func (pgdb *PgDB) PatchStorageLabels(ctx context.Context, name string, patch map[string]*string) error {
+	pgdb.log.WithField("name", name).Debugf("patch storage labels with %v", patch)
+
+	set := make(map[string]string, len(patch))
+	remove := arrayValue{}
+	for k, v := range patch {
+		if v == nil {
+			remove = append(remove, k)
+		} else {
+			set[k] = *v
+		}
+	}
+	setJSON, err := json.Marshal(set)
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name}).
+		WherePK().
+		Where("NOT deleted").
+		Set( /* language=sql */ `labels = NULLIF((COALESCE(labels, '{}') || ?::jsonb) - ?::text[], '{}')`, string(setJSON), remove).
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return nil
+}
 
-	cnt, err := pgdb.db.Model(&model.Volume{}).
-		Where("storage_name = ?", storage.Name).
+// SetStorageOwner changes owner of not deleted storage.
+func (pgdb *PgDB) SetStorageOwner(ctx context.Context, name, ownerUserID string) error {
+	pgdb.log.WithFields(logrus.Fields{
+		"name":  name,
+		"owner": ownerUserID,
+	}).Debugf("set storage owner")
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name, OwnerUserID: ownerUserID}).
+		WherePK().
 		Where("NOT deleted").
+		Set("owner_user_id = ?owner_user_id").
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return nil
+}
+
+// RenameStorage changes storage name. Volumes references are updated by storage_fk foreign key (ON UPDATE CASCADE).
+func (pgdb *PgDB) RenameStorage(ctx context.Context, oldName, newName string) error {
+	pgdb.log.WithFields(logrus.Fields{
+		"old_name": oldName,
+		"new_name": newName,
+	}).Debugf("rename storage")
+
+	cnt, err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+		Where("name = ?", newName).
 		Count()
 	if err != nil {
-		return err
+		return pgdb.handleError(err)
 	}
 	if cnt > 0 {
-		return errors.ErrStorageDelete()
+		return errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", newName)
 	}
 
-	result, err := pgdb.db.Model(storage).WherePK().
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+		Where("name = ?", oldName).
+		Where("NOT deleted").
+		Set("name = ?", newName).
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update()
+	if isUniqueViolation(err) {
+		return errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", newName)
+	}
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", oldName)
+	}
+	return nil
+}
+
+func (pgdb *PgDB) DeleteStorage(ctx context.Context, storage *model.Storage) error {
+	pgdb.log.WithField("name", storage.Name).Debugf("delete storage")
+
+	if err := pgdb.checkStorageUnused(ctx, storage.Name); err != nil {
+		return err
+	}
+
+	result, err := pgdb.withDeadline(ctx).Model(storage).WherePK().
 		Set("deleted = TRUE").
+		Set("updated_at = now()").
 		Set("delete_time = now()").
 		Set("used = 0").
+		Set("is_default = FALSE").
+		Set("terminating = FALSE").
+		Set("terminating_since = NULL").
+		Set("termination_status = NULL").
 		Returning("*").
 		Update()
 	if err != nil {
@@ -120,12 +354,250 @@ func (pgdb *PgDB) DeleteStorage(ctx context.Context, storage *model.Storage) err
 	return nil
 }
 
-func (pgdb *PgDB) LeastUsedStorage(ctx context.Context, minFree int) (ret model.Storage, err error) {
-	pgdb.log.WithField("min_free", minFree).Debugf("get least used storage with constraint")
+// checkStorageUnused returns error with list of blocking volumes if storage still has not deleted volumes.
+func (pgdb *PgDB) checkStorageUnused(ctx context.Context, name string) error {
+	var vols []model.Volume
+	err := pgdb.withDeadline(ctx).Model(&vols).
+		Column("label").
+		Where("storage_name = ?", name).
+		Where("NOT deleted").
+		Order("label").
+		Select()
+	if err != nil && err != pg.ErrNoRows {
+		return pgdb.handleError(err)
+	}
+	if len(vols) == 0 {
+		return nil
+	}
+
+	labels := make([]string, len(vols))
+	for i := range vols {
+		labels[i] = vols[i].Label
+	}
+	return errors.ErrStorageHasVolumes().AddDetailF("storage %s is used by volumes: %s", name, strings.Join(labels, ", "))
+}
+
+func (pgdb *PgDB) PurgeStorage(ctx context.Context, name string) error {
+	pgdb.log.WithField("name", name).Debugf("purge storage")
+
+	pinned, err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+		Where("name = ?", name).
+		Where("pinned").
+		Count()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if pinned > 0 {
+		return errors.ErrStoragePinned().AddDetailF("storage %s is pinned and can't be purged", name)
+	}
+	if err := pgdb.checkStorageUnused(ctx, name); err != nil {
+		return err
+	}
+
+	// deleted volumes records are in trash together with storage so purge them too
+	if _, err := pgdb.withDeadline(ctx).Model(&model.Volume{}).Exec( /* language=sql */
+		`DELETE FROM "?TableName" WHERE storage_name = ? AND deleted`, name); err != nil {
+		return pgdb.handleError(err)
+	}
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name}).
+		WherePK().
+		Delete()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+
+	return nil
+}
 
-	err = pgdb.db.Model(&ret).
-		Where("size - used >= ?", minFree).
+func (pgdb *PgDB) StorageVolumes(ctx context.Context, name string) (ret []model.Volume, err error) {
+	pgdb.log.WithField("storage_name", name).Debugf("get storage volumes")
+
+	ret = make([]model.Volume, 0)
+
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Where("storage_name = ?", name).
+		Where("NOT deleted").
+		Select()
+	switch err {
+	case pg.ErrNoRows:
+		err = nil
+	default:
+		err = pgdb.handleError(err)
+	}
+
+	return
+}
+
+func (pgdb *PgDB) StorageVolumesChangedSince(ctx context.Context, name string, since time.Time) (ret []model.Volume, err error) {
+	pgdb.log.WithFields(logrus.Fields{
+		"storage_name": name,
+		"since":        since,
+	}).Debugf("get storage volumes changed since")
+
+	ret = make([]model.Volume, 0)
+
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Where("storage_name = ?", name).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			return q.WhereOr("create_time > ?", since).
+				WhereOr("deleted AND delete_time > ?", since), nil
+		}).
+		Select()
+	switch err {
+	case pg.ErrNoRows:
+		err = nil
+	default:
+		err = pgdb.handleError(err)
+	}
+
+	return
+}
+
+func (pgdb *PgDB) RecomputeStorageUsed(ctx context.Context, name string) (int, error) {
+	pgdb.log.WithField("name", name).Debugf("recompute storage used capacity")
+
+	storage := model.Storage{Name: name}
+	result, err := pgdb.withDeadline(ctx).Model(&storage).
+		WherePK().
 		Where("NOT deleted").
+		Set( /* language=sql */ `used = (
+			SELECT COALESCE(SUM(capacity), 0)
+			FROM volumes
+			WHERE storage_name = ?TableAlias.name AND NOT deleted)`).
+		Returning("used").
+		Update()
+	if err != nil {
+		return 0, pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return 0, errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return storage.Used, nil
+}
+
+func (pgdb *PgDB) RestoreStorage(ctx context.Context, name string) error {
+	pgdb.log.WithField("name", name).Debugf("restore storage")
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name}).
+		WherePK().
+		Where("deleted").
+		Set("deleted = FALSE").
+		Set("delete_time = NULL").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("deleted storage %s not exists", name)
+	}
+
+	return nil
+}
+
+func (pgdb *PgDB) SetStorageTerminating(ctx context.Context, name string) error {
+	pgdb.log.WithField("name", name).Debugf("set storage terminating")
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name}).
+		WherePK().
+		Where("NOT deleted").
+		Set("terminating = TRUE").
+		Set("terminating_since = COALESCE(terminating_since, now())").
+		Set("termination_status = NULL").
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+
+	return nil
+}
+
+func (pgdb *PgDB) SetStoragePinned(ctx context.Context, name string, pinned bool) error {
+	pgdb.log.WithFields(logrus.Fields{
+		"name":   name,
+		"pinned": pinned,
+	}).Debugf("set storage pinned")
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name, Pinned: pinned}).
+		WherePK().
+		Where("NOT deleted").
+		Set("pinned = ?pinned").
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+
+	return nil
+}
+
+func (pgdb *PgDB) SetStorageTerminationStatus(ctx context.Context, name, status string) error {
+	pgdb.log.WithFields(logrus.Fields{
+		"name":   name,
+		"status": status,
+	}).Debugf("set storage termination status")
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name, TerminationStatus: status}).
+		WherePK().
+		Where("NOT deleted").
+		Where("terminating").
+		Set("termination_status = ?termination_status").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("terminating storage %s not exists", name)
+	}
+
+	return nil
+}
+
+func (pgdb *PgDB) TouchStorage(ctx context.Context, name string) error {
+	pgdb.log.WithField("name", name).Debugf("touch storage")
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name}).
+		WherePK().
+		Where("NOT deleted").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+
+	return nil
+}
+
+func (pgdb *PgDB) LeastUsedStorage(ctx context.Context, nsID string, minFree int) (ret model.Storage, err error) {
+	pgdb.log.WithFields(logrus.Fields{
+		"ns_id":    nsID,
+		"min_free": minFree,
+	}).Debugf("get least used storage with constraint")
+
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Where("FLOOR((size - reserved) * overcommit_ratio) - used >= ?", minFree).
+		Where("NOT deleted").
+		Where("NOT read_only").
+		Where("NOT maintenance").
+		Where("NOT terminating").
+		Where("(COALESCE(CARDINALITY(namespaces), 0) = 0 OR ? = ANY(namespaces))", nsID).
 		OrderExpr("used ASC").
 		First()
 	switch err {
@@ -137,3 +609,63 @@ func (pgdb *PgDB) LeastUsedStorage(ctx context.Context, minFree int) (ret model.
 
 	return
 }
+
+func (pgdb *PgDB) DefaultStorage(ctx context.Context) (ret model.Storage, err error) {
+	pgdb.log.Debugf("get default storage")
+
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Where("is_default").
+		Where("NOT deleted").
+		Select()
+	switch err {
+	case pg.ErrNoRows:
+		err = errors.ErrResourceNotExists().AddDetailF("default storage is not set")
+	default:
+		err = pgdb.handleError(err)
+	}
+
+	return
+}
+
+// SetDefaultStorage marks storage as default and clears flag on previous default storage.
+// Should be called in transaction to not leave service without default storage on failure.
+func (pgdb *PgDB) SetDefaultStorage(ctx context.Context, name string) error {
+	pgdb.log.WithField("name", name).Debugf("set default storage")
+
+	if _, err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+		Where("is_default").
+		Where("name != ?", name).
+		Set("is_default = FALSE").
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update(); err != nil {
+		return pgdb.handleError(err)
+	}
+
+	result, err := pgdb.withDeadline(ctx).Model(&model.Storage{Name: name}).
+		WherePK().
+		Where("NOT deleted").
+		Where("NOT is_default").
+		Set("is_default = TRUE").
+		Set("version = version + 1").
+		Set("updated_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		// storage not exists or is already default
+		cnt, err := pgdb.withDeadline(ctx).Model(&model.Storage{}).
+			Where("name = ?", name).
+			Where("NOT deleted").
+			Count()
+		if err != nil {
+			return pgdb.handleError(err)
+		}
+		if cnt == 0 {
+			return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+		}
+	}
+
+	return nil
+}
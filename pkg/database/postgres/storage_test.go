@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/go-pg/pg/orm"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// queryRecorder records formatted queries instead of running them, queries return no rows.
+type queryRecorder struct {
+	orm.Formatter
+	queries []string
+}
+
+func (db *queryRecorder) record(query interface{}, params ...interface{}) (orm.Result, error) {
+	var b []byte
+	var err error
+	switch q := query.(type) {
+	case orm.QueryAppender:
+		b, err = q.AppendQuery(nil)
+	case string:
+		b = db.FormatQuery(nil, q, params...)
+	}
+	db.queries = append(db.queries, string(b))
+	return nil, err
+}
+
+func (db *queryRecorder) Model(model ...interface{}) *orm.Query { return orm.NewQuery(db, model...) }
+func (db *queryRecorder) Select(model interface{}) error        { return orm.Select(db, model) }
+func (db *queryRecorder) Insert(model ...interface{}) error     { return orm.Insert(db, model...) }
+func (db *queryRecorder) Update(model interface{}) error        { return orm.Update(db, model) }
+func (db *queryRecorder) Delete(model interface{}) error        { return orm.Delete(db, model) }
+
+func (db *queryRecorder) Exec(query interface{}, params ...interface{}) (orm.Result, error) {
+	return db.record(query, params...)
+}
+
+func (db *queryRecorder) ExecOne(query interface{}, params ...interface{}) (orm.Result, error) {
+	return db.record(query, params...)
+}
+
+func (db *queryRecorder) Query(coll, query interface{}, params ...interface{}) (orm.Result, error) {
+	return db.record(query, params...)
+}
+
+func (db *queryRecorder) QueryOne(model, query interface{}, params ...interface{}) (orm.Result, error) {
+	return db.record(query, params...)
+}
+
+func (db *queryRecorder) CopyFrom(r io.Reader, query interface{}, params ...interface{}) (orm.Result, error) {
+	return db.record(query, params...)
+}
+
+func (db *queryRecorder) CopyTo(w io.Writer, query interface{}, params ...interface{}) (orm.Result, error) {
+	return db.record(query, params...)
+}
+
+func (db *queryRecorder) Context() context.Context { return context.Background() }
+
+func TestReplaceTrashedStorage(t *testing.T) {
+	Convey("Test storage in trash replacement", t, func() {
+		rec := &queryRecorder{}
+		pgdb := &PgDB{db: rec, log: cherrylog.NewLogrusAdapter(logrus.WithField("component", "db"))}
+
+		So(pgdb.replaceTrashedStorage(context.Background(), &model.Storage{Name: "storage-1", Size: 10, Pinned: true, Maintenance: true}), ShouldBeNil)
+		So(rec.queries, ShouldHaveLength, 1)
+		query := rec.queries[0]
+		So(query, ShouldStartWith, "UPDATE")
+		So(query, ShouldContainSubstring, `WHERE (name = 'storage-1')`)
+		So(query, ShouldContainSubstring, "deleted = FALSE")
+		So(query, ShouldContainSubstring, "pinned = FALSE")
+		So(query, ShouldContainSubstring, "delete_time = NULL")
+		So(query, ShouldContainSubstring, "maintenance = FALSE")
+		So(query, ShouldContainSubstring, "maintenance_reason = NULL")
+		So(query, ShouldContainSubstring, "created_at = now()")
+	})
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"strings"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+)
+
+var storageSortColumns = map[string]bool{
+	"name":       true,
+	"size":       true,
+	"created_at": true,
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+type StorageFilter database.StorageFilter
+
+func (f *StorageFilter) Filter(q *orm.Query) (*orm.Query, error) {
+	switch {
+	case f.DeletedBefore != nil:
+		q = q.Where("?TableAlias.deleted").Where("?TableAlias.delete_time < ?", *f.DeletedBefore)
+	case !f.WithDeleted:
+		q = q.Where("NOT ?TableAlias.deleted")
+	}
+
+	if f.Terminating {
+		q = q.Where("?TableAlias.terminating")
+	}
+	if f.Unpinned {
+		q = q.Where("NOT ?TableAlias.pinned")
+	}
+	if f.NamePrefix != "" {
+		q = q.Where("?TableAlias.name LIKE ?", likeEscaper.Replace(f.NamePrefix)+"%")
+	}
+	if f.Driver != "" {
+		q = q.Where("?TableAlias.driver = ?", f.Driver)
+	}
+	if f.MinSize > 0 {
+		q = q.Where("?TableAlias.size >= ?", f.MinSize)
+	}
+	if f.MaxSize > 0 {
+		q = q.Where("?TableAlias.size <= ?", f.MaxSize)
+	}
+	if f.CreatedAfter != nil {
+		q = q.Where("?TableAlias.created_at > ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		q = q.Where("?TableAlias.created_at < ?", *f.CreatedBefore)
+	}
+	if f.Overutilized {
+		// same as model.Storage.Overutilized
+		q = q.Where("?TableAlias.warn_threshold > 0").
+			Where("?TableAlias.used * 100 >= ?TableAlias.warn_threshold * FLOOR((?TableAlias.size - ?TableAlias.reserved) * ?TableAlias.overcommit_ratio)")
+	}
+	if f.IdleBefore != nil {
+		q = q.Where("COALESCE(?TableAlias.last_used_at, ?TableAlias.created_at) < ?", *f.IdleBefore)
+	}
+	if f.NamespaceScoped {
+		q = q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q = q.WhereOr("COALESCE(CARDINALITY(?TableAlias.namespaces), 0) = 0")
+			if len(f.Namespaces) > 0 {
+				q = q.WhereOr("?TableAlias.namespaces && ?", arrayValue(f.Namespaces))
+			}
+			return q, nil
+		})
+	}
+	if f.ExplicitNamespace != "" {
+		q = q.Where("? = ANY(?TableAlias.namespaces)", f.ExplicitNamespace)
+	}
+	if f.OwnerUserID != "" {
+		q = q.Where("?TableAlias.owner_user_id = ?", f.OwnerUserID)
+	}
+	for _, req := range f.LabelSelector {
+		q = applyLabelRequirement(q, req)
+	}
+
+	sortBy := f.SortBy
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	if !storageSortColumns[sortBy] {
+		return nil, errors.ErrRequestValidationFailed().AddDetailF("storages can't be sorted by %s", sortBy)
+	}
+	cmp, direction := ">", "ASC"
+	if f.SortDesc {
+		cmp, direction = "<", "DESC"
+	}
+
+	if f.After != "" {
+		if sortBy == "name" {
+			q = q.Where("?TableAlias.name "+cmp+" ?", f.After)
+		} else {
+			q = q.Where("(?TableAlias.?, ?TableAlias.name) "+cmp+" (?, ?)", pg.F(sortBy), f.AfterValue, f.After)
+		}
+	}
+
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+
+	if f.WithUsage {
+		q = q.ColumnExpr("?TableAlias.*").
+			Join( /* language=sql */ `LEFT JOIN (
+				SELECT storage_name, SUM(capacity) AS used_size
+				FROM volumes
+				WHERE NOT deleted
+				GROUP BY storage_name) AS usage ON usage.storage_name = ?TableAlias.name`).
+			ColumnExpr("COALESCE(usage.used_size, 0) AS used_size").
+			ColumnExpr("?TableAlias.size - ?TableAlias.reserved - COALESCE(usage.used_size, 0) AS free_size")
+	}
+
+	if sortBy != "name" {
+		q = q.OrderExpr("?TableAlias.? "+direction, pg.F(sortBy))
+	}
+	return q.OrderExpr("?TableAlias.name " + direction), nil
+}
+
+// CountFilter applies only conditions which affects total storages count.
+func (f *StorageFilter) CountFilter(q *orm.Query) (*orm.Query, error) {
+	countFilter := *f
+	countFilter.After = ""
+	countFilter.AfterValue = nil
+	countFilter.Limit = 0
+	countFilter.WithUsage = false
+	return countFilter.Filter(q)
+}
+
+// inValues is a list of values for IN operator. Unlike pg.In result it is not a struct,
+// so go-pg does not consume it as named params source in queries with ?TableAlias.
+type inValues []string
+
+func (v inValues) AppendValue(b []byte, quote int) []byte {
+	return pg.In([]string(v)).AppendValue(b, quote)
+}
+
+// arrayValue is a text array literal, see inValues.
+type arrayValue []string
+
+func (v arrayValue) AppendValue(b []byte, quote int) []byte {
+	return pg.Array([]string(v)).AppendValue(b, quote)
+}
+
+func applyLabelRequirement(q *orm.Query, req labels.Requirement) *orm.Query {
+	switch req.Operator {
+	case labels.Equals:
+		return q.Where("?TableAlias.labels->>? = ?", req.Key, req.Values[0])
+	case labels.NotEquals:
+		return q.Where("?TableAlias.labels->>? IS DISTINCT FROM ?", req.Key, req.Values[0])
+	case labels.In:
+		return q.Where("?TableAlias.labels->>? IN (?)", req.Key, inValues(req.Values))
+	case labels.NotIn:
+		return q.Where("NOT COALESCE(?TableAlias.labels->>? IN (?), FALSE)", req.Key, inValues(req.Values))
+	case labels.Exists:
+		return q.Where("?TableAlias.labels->>? IS NOT NULL", req.Key)
+	case labels.DoesNotExist:
+		return q.Where("?TableAlias.labels->>? IS NULL", req.Key)
+	default:
+		return q.Where("FALSE")
+	}
+}
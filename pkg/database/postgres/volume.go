@@ -169,3 +169,49 @@ func (pgdb *PgDB) UpdateVolume(ctx context.Context, volume *model.Volume) error
 
 	return nil
 }
+
+func (pgdb *PgDB) MoveVolume(ctx context.Context, volume *model.Volume, target string) error {
+	pgdb.log.WithFields(logrus.Fields{
+		"volume": volume.ID,
+		"source": volume.StorageName,
+		"target": target,
+	}).Debugf("move volume")
+
+	db := pgdb.withDeadline(ctx)
+
+	// reserve capacity on target in one statement same as volume creation does
+	result, err := db.Model(&model.Storage{Name: target}).
+		WherePK().
+		Where("NOT deleted").
+		Where("used + (?) <= FLOOR((size - reserved) * overcommit_ratio)", volume.Capacity).
+		Set("used = used + (?)", volume.Capacity).
+		Set("last_used_at = now()").
+		Update()
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrStorageOvercommitted().AddDetailF("storage %s has no space for volume %s (%d GiB)", target, volume.Label, volume.Capacity)
+	}
+
+	if _, err := db.Model(&model.Storage{Name: volume.StorageName}).
+		WherePK().
+		Set("used = used - (?)", volume.Capacity).
+		Set("last_used_at = now()").
+		Update(); err != nil {
+		return pgdb.handleError(err)
+	}
+
+	// raw statement is used to skip volume update hook which accounts capacity changes on the same storage
+	result, err = db.Model(volume).Exec( /* language=sql */
+		`UPDATE "?TableName" SET storage_name = ? WHERE id = ?id AND NOT deleted`, target)
+	if err != nil {
+		return pgdb.handleError(err)
+	}
+	if result.RowsAffected() <= 0 {
+		return errors.ErrResourceNotExists().AddDetailF("volume %s not exists", volume.Label)
+	}
+	volume.StorageName = target
+
+	return nil
+}
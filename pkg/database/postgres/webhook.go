@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/go-pg/pg"
+)
+
+func (pgdb *PgDB) CreateStorageWebhook(ctx context.Context, webhook *model.StorageWebhook) error {
+	pgdb.log.WithField("url", webhook.URL).Debugf("create storage webhook")
+
+	_, err := pgdb.withDeadline(ctx).Model(webhook).
+		Returning("*").
+		Insert()
+	return pgdb.handleError(err)
+}
+
+func (pgdb *PgDB) StorageWebhooks(ctx context.Context) (ret []model.StorageWebhook, err error) {
+	pgdb.log.Debugf("get storage webhooks")
+
+	ret = make([]model.StorageWebhook, 0)
+
+	err = pgdb.withDeadline(ctx).Model(&ret).
+		Order("created_at ASC", "id ASC").
+		Select()
+	switch err {
+	case pg.ErrNoRows:
+		err = nil
+	default:
+		err = pgdb.handleError(err)
+	}
+
+	return
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+)
+
+// StorageFilter contains parameters for storage list queries.
+// Zero value selects all not deleted storages.
+type StorageFilter struct {
+	// Limit is a maximum number of returned storages, zero means no limit.
+	Limit int
+	// After allows to select only storages placed after storage with provided name in selected order (keyset pagination).
+	After string
+	// AfterValue is a value of SortBy column of After storage. Not used if sorting by name.
+	AfterValue interface{}
+
+	// SortBy is a column for ordering, name used if empty. Name is also used as tie-breaker.
+	SortBy string
+	// SortDesc enables descending order.
+	SortDesc bool
+
+	// NamePrefix allows to select only storages with names started with provided string.
+	NamePrefix string
+	// MinSize and MaxSize limits storage size range, zero means no limit.
+	MinSize int
+	MaxSize int
+
+	// Driver allows to select only storages with provided driver.
+	Driver string
+
+	// CreatedAfter and CreatedBefore limits storage creation time range (exclusive), nil means no limit.
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// LabelSelector allows to select only storages with matching labels.
+	LabelSelector labels.Selector
+
+	// Overutilized allows to select only storages which usage reached warning threshold.
+	Overutilized bool
+
+	// IdleBefore allows to select only storages without volume operations since provided time.
+	// Storages never used are selected if they were created before it.
+	IdleBefore *time.Time
+
+	// NamespaceScoped enables selection of only storages available in one of Namespaces.
+	// Storages without namespaces restriction are available everywhere.
+	NamespaceScoped bool
+	Namespaces      []string
+
+	// ExplicitNamespace allows to select only storages with namespaces restriction which includes provided namespace.
+	ExplicitNamespace string
+
+	// OwnerUserID allows to select only storages owned by user.
+	OwnerUserID string
+
+	// WithDeleted enables selection of soft-deleted storages too.
+	WithDeleted bool
+	// DeletedBefore allows to select only soft-deleted storages deleted before provided time.
+	DeletedBefore *time.Time
+
+	// Terminating allows to select only terminating storages.
+	Terminating bool
+	// Unpinned allows to select only not pinned storages.
+	Unpinned bool
+
+	// WithUsage enables computation of storages UsedSize and FreeSize.
+	WithUsage bool
+}
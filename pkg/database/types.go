@@ -3,17 +3,46 @@ package database
 import (
 	"context"
 	"io"
+	"time"
 
 	"git.containerum.net/ch/volume-manager/pkg/models"
 )
 
 type DB interface {
 	StorageByName(ctx context.Context, name string) (model.Storage, error)
-	LeastUsedStorage(ctx context.Context, requestSize int) (model.Storage, error)
-	AllStorages(ctx context.Context) ([]model.Storage, error)
+	StorageByID(ctx context.Context, id string) (model.Storage, error)
+	LeastUsedStorage(ctx context.Context, nsID string, requestSize int) (model.Storage, error)
+	DefaultStorage(ctx context.Context) (model.Storage, error)
+	SetDefaultStorage(ctx context.Context, name string) error
+	AllStorages(ctx context.Context, filter StorageFilter) ([]model.Storage, error)
+	CountStorages(ctx context.Context, filter StorageFilter) (int, error)
+	StoragesTotalSize(ctx context.Context, filter StorageFilter) (int, error)
+	// StorageDriverStats returns aggregates of not deleted storages grouped by driver
+	StorageDriverStats(ctx context.Context) ([]model.StorageDriverStats, error)
 	CreateStorage(ctx context.Context, storage *model.Storage) error
 	UpdateStorage(ctx context.Context, name string, storage model.Storage) error
+	// SetStorageLabels replaces labels of not deleted storage, other fields are not changed
+	SetStorageLabels(ctx context.Context, name string, labels map[string]string) error
+	// PatchStorageLabels sets non-nil and removes nil labels of not deleted storage in one statement
+	PatchStorageLabels(ctx context.Context, name string, patch map[string]*string) error
+	RenameStorage(ctx context.Context, oldName, newName string) error
+	SetStorageOwner(ctx context.Context, name, ownerUserID string) error
 	DeleteStorage(ctx context.Context, storage *model.Storage) error
+	PurgeStorage(ctx context.Context, name string) error
+	RestoreStorage(ctx context.Context, name string) error
+	// SetStorageTerminating marks not deleted storage as terminating since now and clears termination status
+	SetStorageTerminating(ctx context.Context, name string) error
+	// SetStorageTerminationStatus sets termination status of terminating storage without changing version
+	SetStorageTerminationStatus(ctx context.Context, name, status string) error
+	// SetStoragePinned pins or unpins not deleted storage
+	SetStoragePinned(ctx context.Context, name string, pinned bool) error
+	// TouchStorage sets storage update time to now without changing other fields and version
+	TouchStorage(ctx context.Context, name string) error
+	StorageVolumes(ctx context.Context, name string) ([]model.Volume, error)
+	// StorageVolumesChangedSince returns storage volumes (deleted too) created or deleted after since
+	StorageVolumesChangedSince(ctx context.Context, name string, since time.Time) ([]model.Volume, error)
+	// RecomputeStorageUsed sets storage used capacity to total capacity of storage volumes and returns it
+	RecomputeStorageUsed(ctx context.Context, name string) (int, error)
 
 	VolumeByLabel(ctx context.Context, nsID string, label string) (model.Volume, error)
 	UserVolumes(ctx context.Context, userID string) ([]model.Volume, error)
@@ -23,7 +52,19 @@ type DB interface {
 	DeleteVolume(ctx context.Context, volume *model.Volume) error
 	DeleteVolumes(ctx context.Context, volumes []model.Volume) error
 	UpdateVolume(ctx context.Context, volume *model.Volume) error
+	// MoveVolume moves volume to target storage transferring its capacity between storages used counters
+	MoveVolume(ctx context.Context, volume *model.Volume, target string) error
 
-	Transactional(func(tx DB) error) error
+	CreateStorageAuditRecords(ctx context.Context, records []model.StorageAuditRecord) error
+	StorageAuditRecords(ctx context.Context, name string) ([]model.StorageAuditRecord, error)
+	CreateStorageSizeRecords(ctx context.Context, records []model.StorageSizeRecord) error
+	// StorageSizeHistory returns storage size changes in chronological order
+	StorageSizeHistory(ctx context.Context, name string, filter model.StorageSizeHistoryFilter) ([]model.StorageSizeRecord, error)
+
+	CreateStorageWebhook(ctx context.Context, webhook *model.StorageWebhook) error
+	StorageWebhooks(ctx context.Context) ([]model.StorageWebhook, error)
+
+	Ping(ctx context.Context) error
+	Transactional(ctx context.Context, fn func(tx DB) error) error
 	io.Closer
 }
@@ -0,0 +1,114 @@
+package errors
+
+import (
+	"github.com/containerum/cherry"
+)
+
+// Code is a stable machine-readable error code. Unlike messages and details, codes are never changed,
+// so clients may branch on them.
+type Code string
+
+// Error codes, one for each error of Errors.toml (error name without "Err" prefix in snake_case).
+const (
+	CodeAdminRequired               Code = "admin_required"
+	CodeRequiredHeadersNotProvided  Code = "required_headers_not_provided"
+	CodeRequestValidationFailed     Code = "request_validation_failed"
+	CodeInternal                    Code = "internal"
+	CodeDatabase                    Code = "database"
+	CodeResourceNotExists           Code = "resource_not_exists"
+	CodeResourceAlreadyExists       Code = "resource_already_exists"
+	CodeQuotaExceeded               Code = "quota_exceeded"
+	CodeNoFreeStorages              Code = "no_free_storages"
+	CodeStorageDelete               Code = "storage_delete"
+	CodeDownResize                  Code = "down_resize"
+	CodeIdempotencyKeyReused        Code = "idempotency_key_reused"
+	CodeIdempotentRequestInProgress Code = "idempotent_request_in_progress"
+	CodeStorageHasVolumes           Code = "storage_has_volumes"
+	CodePreconditionFailed          Code = "precondition_failed"
+	CodeServiceNotReady             Code = "service_not_ready"
+	CodeTooManyRequests             Code = "too_many_requests"
+	CodeStorageOvercommitted        Code = "storage_overcommitted"
+	CodeDatabaseTemporary           Code = "database_temporary"
+	CodeStorageAlreadyExists        Code = "storage_already_exists"
+	CodeOperationTimeout            Code = "operation_timeout"
+	CodeStorageReadOnly             Code = "storage_read_only"
+	CodeStorageNamespaceForbidden   Code = "storage_namespace_forbidden"
+	CodeStorageMaintenance          Code = "storage_maintenance"
+	CodeRequestTooLarge             Code = "request_too_large"
+	CodeStorageQuotaExceeded        Code = "storage_quota_exceeded"
+	CodeProvisionerNotConfigured    Code = "provisioner_not_configured"
+	CodeStorageShrinkBelowUsed      Code = "storage_shrink_below_used"
+	CodeServiceShuttingDown         Code = "service_shutting_down"
+	CodeRoleRequired                Code = "role_required"
+	CodeStorageTerminating          Code = "storage_terminating"
+	CodeStoragePinned               Code = "storage_pinned"
+)
+
+// codes maps error IDs to codes, errors added to Errors.toml must be added here too.
+var codes = map[cherry.ErrID]Code{
+	ErrAdminRequired().ID:               CodeAdminRequired,
+	ErrRequiredHeadersNotProvided().ID:  CodeRequiredHeadersNotProvided,
+	ErrRequestValidationFailed().ID:     CodeRequestValidationFailed,
+	ErrInternal().ID:                    CodeInternal,
+	ErrDatabase().ID:                    CodeDatabase,
+	ErrResourceNotExists().ID:           CodeResourceNotExists,
+	ErrResourceAlreadyExists().ID:       CodeResourceAlreadyExists,
+	ErrQuotaExceeded().ID:               CodeQuotaExceeded,
+	ErrNoFreeStorages().ID:              CodeNoFreeStorages,
+	ErrStorageDelete().ID:               CodeStorageDelete,
+	ErrDownResize().ID:                  CodeDownResize,
+	ErrIdempotencyKeyReused().ID:        CodeIdempotencyKeyReused,
+	ErrIdempotentRequestInProgress().ID: CodeIdempotentRequestInProgress,
+	ErrStorageHasVolumes().ID:           CodeStorageHasVolumes,
+	ErrPreconditionFailed().ID:          CodePreconditionFailed,
+	ErrServiceNotReady().ID:             CodeServiceNotReady,
+	ErrTooManyRequests().ID:             CodeTooManyRequests,
+	ErrStorageOvercommitted().ID:        CodeStorageOvercommitted,
+	ErrDatabaseTemporary().ID:           CodeDatabaseTemporary,
+	ErrStorageAlreadyExists().ID:        CodeStorageAlreadyExists,
+	ErrOperationTimeout().ID:            CodeOperationTimeout,
+	ErrStorageReadOnly().ID:             CodeStorageReadOnly,
+	ErrStorageNamespaceForbidden().ID:   CodeStorageNamespaceForbidden,
+	ErrStorageMaintenance().ID:          CodeStorageMaintenance,
+	ErrRequestTooLarge().ID:             CodeRequestTooLarge,
+	ErrStorageQuotaExceeded().ID:        CodeStorageQuotaExceeded,
+	ErrProvisionerNotConfigured().ID:    CodeProvisionerNotConfigured,
+	ErrStorageShrinkBelowUsed().ID:      CodeStorageShrinkBelowUsed,
+	ErrServiceShuttingDown().ID:         CodeServiceShuttingDown,
+	ErrRoleRequired().ID:                CodeRoleRequired,
+	ErrStorageTerminating().ID:          CodeStorageTerminating,
+	ErrStoragePinned().ID:               CodeStoragePinned,
+}
+
+// CodeOf returns code of error. Errors of other types and unknown cherry errors are internal errors.
+func CodeOf(err error) Code {
+	if cherryErr, ok := err.(*cherry.Err); ok {
+		if code, ok := codes[cherryErr.ID]; ok {
+			return code
+		}
+	}
+	return CodeInternal
+}
+
+// Envelope is a body of all error responses. It contains stable code in addition to cherry error fields.
+//
+// swagger:model ErrorEnvelope
+type Envelope struct {
+	// Stable error code, e.g. "storage_overcommitted"
+	Code Code `json:"code"`
+	*cherry.Err
+}
+
+// NewEnvelope wraps error to response envelope. Errors of other types are reported as internal errors.
+func NewEnvelope(err error) Envelope {
+	cherryErr, ok := err.(*cherry.Err)
+	if !ok {
+		cherryErr = ErrInternal().AddDetailsErr(err)
+	}
+	return Envelope{Code: CodeOf(cherryErr), Err: cherryErr}
+}
+
+// Status returns HTTP status of error response.
+func (e Envelope) Status() int {
+	return StatusCode(e.Err)
+}
@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/containerum/cherry"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEnvelope(t *testing.T) {
+	Convey("Test error codes", t, func() {
+		documented := []struct {
+			construct cherry.ErrConstruct
+			code      Code
+			status    int
+		}{
+			{ErrAdminRequired, CodeAdminRequired, 403},
+			{ErrRequiredHeadersNotProvided, CodeRequiredHeadersNotProvided, 400},
+			{ErrRequestValidationFailed, CodeRequestValidationFailed, 400},
+			{ErrInternal, CodeInternal, 500},
+			{ErrDatabase, CodeDatabase, 500},
+			{ErrResourceNotExists, CodeResourceNotExists, 404},
+			{ErrResourceAlreadyExists, CodeResourceAlreadyExists, 400},
+			{ErrQuotaExceeded, CodeQuotaExceeded, 400},
+			{ErrNoFreeStorages, CodeNoFreeStorages, 507},
+			{ErrStorageDelete, CodeStorageDelete, 400},
+			{ErrDownResize, CodeDownResize, 400},
+			{ErrIdempotencyKeyReused, CodeIdempotencyKeyReused, 422},
+			{ErrIdempotentRequestInProgress, CodeIdempotentRequestInProgress, 409},
+			{ErrStorageHasVolumes, CodeStorageHasVolumes, 409},
+			{ErrPreconditionFailed, CodePreconditionFailed, 412},
+			{ErrServiceNotReady, CodeServiceNotReady, 503},
+			{ErrTooManyRequests, CodeTooManyRequests, 429},
+			{ErrStorageOvercommitted, CodeStorageOvercommitted, 409},
+			{ErrDatabaseTemporary, CodeDatabaseTemporary, 503},
+			{ErrStorageAlreadyExists, CodeStorageAlreadyExists, 409},
+			{ErrOperationTimeout, CodeOperationTimeout, 504},
+			{ErrStorageReadOnly, CodeStorageReadOnly, 403},
+			{ErrStorageNamespaceForbidden, CodeStorageNamespaceForbidden, 403},
+			{ErrStorageMaintenance, CodeStorageMaintenance, 503},
+			{ErrRequestTooLarge, CodeRequestTooLarge, 413},
+			{ErrStorageQuotaExceeded, CodeStorageQuotaExceeded, 403},
+			{ErrProvisionerNotConfigured, CodeProvisionerNotConfigured, 501},
+			{ErrStorageShrinkBelowUsed, CodeStorageShrinkBelowUsed, 409},
+			{ErrServiceShuttingDown, CodeServiceShuttingDown, 503},
+			{ErrRoleRequired, CodeRoleRequired, 403},
+			{ErrStorageTerminating, CodeStorageTerminating, 409},
+			{ErrStoragePinned, CodeStoragePinned, 409},
+		}
+		Convey("Check every error has documented code and status", func() {
+			for _, expected := range documented {
+				envelope := NewEnvelope(expected.construct().AddDetails("details"))
+				So(envelope.Code, ShouldEqual, expected.code)
+				So(envelope.Status(), ShouldEqual, expected.status)
+				So(envelope.Details, ShouldResemble, []string{"details"})
+			}
+		})
+		Convey("Check all errors of Errors.toml have codes", func() {
+			data, err := ioutil.ReadFile("Errors.toml")
+			So(err, ShouldBeNil)
+			So(codes, ShouldHaveLength, strings.Count(string(data), "[[error]]"))
+			So(documented, ShouldHaveLength, len(codes))
+		})
+		Convey("Check other errors are internal", func() {
+			envelope := NewEnvelope(fmt.Errorf("boom"))
+			So(envelope.Code, ShouldEqual, CodeInternal)
+			So(envelope.Status(), ShouldEqual, 500)
+			So(envelope.Details, ShouldResemble, []string{"boom"})
+		})
+	})
+}
@@ -145,6 +145,264 @@ func ErrDownResize(params ...func(*cherry.Err)) *cherry.Err {
 	}
 	return err
 }
+
+func ErrIdempotencyKeyReused(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Idempotency key already used with different request", StatusHTTP: 422, ID: cherry.ErrID{SID: "volume-manager", Kind: 0xc}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrIdempotentRequestInProgress(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Request with same idempotency key is in progress", StatusHTTP: 409, ID: cherry.ErrID{SID: "volume-manager", Kind: 0xd}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageHasVolumes(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage is used by volumes", StatusHTTP: 409, ID: cherry.ErrID{SID: "volume-manager", Kind: 0xe}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrPreconditionFailed(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Resource was modified", StatusHTTP: 412, ID: cherry.ErrID{SID: "volume-manager", Kind: 0xf}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrServiceNotReady(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Service is not ready", StatusHTTP: 503, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x10}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrTooManyRequests(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Too many requests", StatusHTTP: 429, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x11}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageOvercommitted(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage capacity exceeded", StatusHTTP: 409, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x12}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrDatabaseTemporary(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Temporary database error, try again later", StatusHTTP: 503, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x13}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageAlreadyExists(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage already exists", StatusHTTP: 409, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x14}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrOperationTimeout(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Operation timed out", StatusHTTP: 504, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x15}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageReadOnly(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage is read-only", StatusHTTP: 403, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x16}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageNamespaceForbidden(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage is not available in namespace", StatusHTTP: 403, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x17}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageMaintenance(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage is under maintenance", StatusHTTP: 503, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x18}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrRequestTooLarge(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Request body is too large", StatusHTTP: 413, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x19}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageQuotaExceeded(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage quota exceeded", StatusHTTP: 403, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x1a}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrProvisionerNotConfigured(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage provisioner is not configured", StatusHTTP: 501, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x1b}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrStorageShrinkBelowUsed(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage size can't be less than used capacity", StatusHTTP: 409, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x1c}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+func ErrServiceShuttingDown(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Service is shutting down, try again later", StatusHTTP: 503, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x1d}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+// ErrRoleRequired error
+// Operation requires other role, e.g. admin for storage changes
+func ErrRoleRequired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "User role is not allowed to perform operation", StatusHTTP: 403, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x1e}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+// ErrStorageTerminating error
+// Storage is terminating and does not accept new volumes
+func ErrStorageTerminating(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage is being deleted", StatusHTTP: 409, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x1f}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+
+// ErrStoragePinned error
+// Pinned storage is protected from purge
+func ErrStoragePinned(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Storage is pinned", StatusHTTP: 409, ID: cherry.ErrID{SID: "volume-manager", Kind: 0x20}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
 func renderTemplate(templText string) string {
 	buf := &bytes.Buffer{}
 	templ, err := template.New("").Parse(templText)
@@ -0,0 +1,19 @@
+package errors
+
+import (
+	"net/http"
+
+	"github.com/containerum/cherry"
+)
+
+// StatusCode classifies error by HTTP status: status of cherry error is returned as is,
+// errors of other types are treated as internal errors. Nil error is 200.
+func StatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if cherryErr, ok := err.(*cherry.Err); ok && cherryErr.StatusHTTP != 0 {
+		return cherryErr.StatusHTTP
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,219 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sink receives batches of events. Events in batch are ordered by publish time.
+type Sink interface {
+	Flush(ctx context.Context, batch []StorageEvent) error
+}
+
+// NopSink drops all batches.
+type NopSink struct{}
+
+func (NopSink) Flush(ctx context.Context, batch []StorageEvent) error {
+	return nil
+}
+
+// PublisherSink publishes batched events one by one in batch order.
+// Failed batch is retried from its first event, so publisher should deliver events before returning,
+// otherwise order and single delivery of events are not guaranteed.
+type PublisherSink struct {
+	Publisher Publisher
+}
+
+func (s PublisherSink) Flush(ctx context.Context, batch []StorageEvent) error {
+	for _, event := range batch {
+		if err := s.Publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchConfig configures events batching. Zero fields are replaced with defaults.
+type BatchConfig struct {
+	// Window is a maximum time event waits in batch before flush
+	Window time.Duration
+	// MaxBatchSize is a number of events which are flushed immediately, without waiting for window end
+	MaxBatchSize int
+	// QueueSize is a number of events waiting for batching, Publish blocks if queue is full
+	QueueSize int
+	// MaxAttempts is a maximum number of flush attempts of one batch
+	MaxAttempts int
+	// RetryDelay is a delay before second attempt, delay is doubled for each next attempt
+	RetryDelay time.Duration
+	// Timeout limits duration of one flush attempt
+	Timeout time.Duration
+	Log     *logrus.Entry
+}
+
+func (cfg BatchConfig) withDefaults() BatchConfig {
+	if cfg.Window <= 0 {
+		cfg.Window = 100 * time.Millisecond
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = 500
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 4096
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Log == nil {
+		cfg.Log = logrus.NewEntry(logrus.StandardLogger())
+	}
+	return cfg
+}
+
+// BatchPublisher coalesces events published during window and flushes them to sink in bulk.
+// Batches are flushed one at a time in publish order, so events of each storage reach sink in order they were published.
+// If sink is slow, queue fills up and Publish waits for free space (or context cancellation), slowing down producers.
+type BatchPublisher struct {
+	sink Sink
+	cfg  BatchConfig
+
+	events    chan StorageEvent
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBatchPublisher starts batching. Publisher must be closed with Close.
+func NewBatchPublisher(sink Sink, cfg BatchConfig) *BatchPublisher {
+	cfg = cfg.withDefaults()
+	p := &BatchPublisher{
+		sink:   sink,
+		cfg:    cfg,
+		events: make(chan StorageEvent, cfg.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish enqueues event for batching. If queue is full, it waits until event is enqueued, context is done or publisher is closed.
+func (p *BatchPublisher) Publish(ctx context.Context, event StorageEvent) error {
+	select {
+	case <-p.stop:
+		return fmt.Errorf("batch publisher closed")
+	default:
+	}
+	select {
+	case p.events <- event:
+		return nil
+	default:
+	}
+	p.cfg.Log.WithField("name", event.Name).Debugf("events queue is full, waiting for sink")
+	select {
+	case p.events <- event:
+		return nil
+	case <-p.stop:
+		return fmt.Errorf("batch publisher closed")
+	case <-ctx.Done():
+		return fmt.Errorf("events queue is full: %v", ctx.Err())
+	}
+}
+
+// Close flushes queued events and stops batching. Failed batches are not retried after close.
+func (p *BatchPublisher) Close() error {
+	p.closeOnce.Do(func() { close(p.stop) })
+	<-p.done
+	return nil
+}
+
+// run collects events to batch until window ends or batch is full, then flushes it.
+// Events are not collected during flush, so they stay in queue and apply backpressure.
+func (p *BatchPublisher) run() {
+	defer close(p.done)
+
+	batch := make([]StorageEvent, 0, p.cfg.MaxBatchSize)
+	// stopped timer, started by first event in batch
+	window := time.NewTimer(time.Hour)
+	window.Stop()
+	defer window.Stop()
+	flush := func() {
+		if !window.Stop() {
+			select {
+			case <-window.C:
+			default:
+			}
+		}
+		if len(batch) > 0 {
+			p.flush(batch)
+			// sink may keep flushed batch
+			batch = make([]StorageEvent, 0, p.cfg.MaxBatchSize)
+		}
+	}
+
+	for {
+		select {
+		case event := <-p.events:
+			if len(batch) == 0 {
+				window.Reset(p.cfg.Window)
+			}
+			batch = append(batch, event)
+			if len(batch) >= p.cfg.MaxBatchSize {
+				flush()
+			}
+		case <-window.C:
+			flush()
+		case <-p.stop:
+			// events enqueued before close are flushed
+			for {
+				select {
+				case event := <-p.events:
+					batch = append(batch, event)
+					if len(batch) >= p.cfg.MaxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush sends batch to sink, retrying with exponential backoff. Batch is dropped after last failed attempt
+// or if publisher is closed, so batches flushed on close are attempted once.
+func (p *BatchPublisher) flush(batch []StorageEvent) {
+	log := p.cfg.Log.WithField("events", len(batch))
+	delay := p.cfg.RetryDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		err := p.sink.Flush(ctx, batch)
+		cancel()
+		if err == nil {
+			log.Debugf("events batch flushed")
+			return
+		}
+		if attempt >= p.cfg.MaxAttempts {
+			log.WithError(err).Errorf("events batch flush failed after %d attempts", attempt)
+			return
+		}
+		log.WithError(err).Warnf("events batch flush attempt %d failed, retry in %v", attempt, delay)
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-p.stop:
+			log.WithError(err).Errorf("events batch flush failed, publisher closed")
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// recordingSink records batches, flushes wait for release if it is set.
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]StorageEvent
+	release chan struct{}
+}
+
+func (s *recordingSink) Flush(ctx context.Context, batch []StorageEvent) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *recordingSink) Batches() [][]StorageEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]StorageEvent(nil), s.batches...)
+}
+
+type kafkaProducerFunc func(ctx context.Context, messages []KafkaMessage) error
+
+func (f kafkaProducerFunc) SendMessages(ctx context.Context, messages []KafkaMessage) error {
+	return f(ctx, messages)
+}
+
+func TestBatchPublisher(t *testing.T) {
+	Convey("Test batch publisher", t, func() {
+		ops := []Operation{StorageCreated, StorageUpdated, StorageDeleted}
+
+		Convey("events are batched and keep order of each storage", func() {
+			sink := &recordingSink{}
+			p := NewBatchPublisher(sink, BatchConfig{Window: time.Hour, MaxBatchSize: 4})
+			for _, op := range ops {
+				for _, name := range []string{"a", "b"} {
+					So(p.Publish(context.Background(), StorageEvent{Operation: op, Name: name}), ShouldBeNil)
+				}
+			}
+			So(p.Close(), ShouldBeNil)
+
+			batches := sink.Batches()
+			So(batches, ShouldHaveLength, 2)
+			So(batches[0], ShouldHaveLength, 4)
+			So(batches[1], ShouldHaveLength, 2)
+			byName := map[string][]Operation{}
+			for _, batch := range batches {
+				for _, event := range batch {
+					byName[event.Name] = append(byName[event.Name], event.Operation)
+				}
+			}
+			So(byName["a"], ShouldResemble, ops)
+			So(byName["b"], ShouldResemble, ops)
+		})
+
+		Convey("publish waits for slow sink", func() {
+			sink := &recordingSink{release: make(chan struct{})}
+			p := NewBatchPublisher(sink, BatchConfig{Window: time.Millisecond, MaxBatchSize: 1, QueueSize: 1})
+			// first event is being flushed, second one fills queue
+			So(p.Publish(context.Background(), StorageEvent{Operation: StorageCreated, Name: "a"}), ShouldBeNil)
+			time.Sleep(10 * time.Millisecond)
+			So(p.Publish(context.Background(), StorageEvent{Operation: StorageUpdated, Name: "a"}), ShouldBeNil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			So(p.Publish(ctx, StorageEvent{Operation: StorageDeleted, Name: "a"}), ShouldNotBeNil)
+
+			close(sink.release)
+			So(p.Close(), ShouldBeNil)
+			So(sink.Batches(), ShouldHaveLength, 2)
+		})
+
+		Convey("kafka sink keys messages by storage name", func() {
+			var messages []KafkaMessage
+			sink := KafkaSink{Topic: "storages", Producer: kafkaProducerFunc(func(ctx context.Context, batch []KafkaMessage) error {
+				messages = append(messages, batch...)
+				return nil
+			})}
+			batch := []StorageEvent{{Operation: StorageCreated, Name: "a"}, {Operation: StorageDeleted, Name: "b"}}
+			So(sink.Flush(context.Background(), batch), ShouldBeNil)
+
+			So(messages, ShouldHaveLength, 2)
+			for i, message := range messages {
+				So(message.Topic, ShouldEqual, "storages")
+				So(string(message.Key), ShouldEqual, batch[i].Name)
+				So(message.Headers[KafkaEventHeader], ShouldEqual, string(batch[i].Operation))
+				var event StorageEvent
+				So(json.Unmarshal(message.Value, &event), ShouldBeNil)
+				So(event, ShouldResemble, batch[i])
+			}
+
+			failing := KafkaSink{Producer: kafkaProducerFunc(func(ctx context.Context, batch []KafkaMessage) error {
+				return fmt.Errorf("broker is not available")
+			})}
+			So(failing.Flush(context.Background(), batch), ShouldNotBeNil)
+		})
+
+		Convey("kafka writer producer requires brokers and rejects messages after close", func() {
+			_, err := NewKafkaWriterProducer(KafkaConfig{})
+			So(err, ShouldNotBeNil)
+
+			producer, err := NewKafkaWriterProducer(KafkaConfig{Brokers: []string{"127.0.0.1:9092"}})
+			So(err, ShouldBeNil)
+			So(producer.SendMessages(context.Background(), nil), ShouldBeNil)
+			So(producer.Close(), ShouldBeNil)
+			So(producer.SendMessages(context.Background(), []KafkaMessage{{Topic: "storages", Key: []byte("a")}}), ShouldNotBeNil)
+		})
+	})
+}
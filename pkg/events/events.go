@@ -0,0 +1,80 @@
+// Package events contains storage lifecycle events and publishers for them.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Operation string
+
+const (
+	StorageCreated Operation = "storage_created"
+	StorageUpdated Operation = "storage_updated"
+	StorageDeleted Operation = "storage_deleted"
+
+	// StorageUsageWarning is emitted when storage usage reaches its warning threshold
+	StorageUsageWarning Operation = "storage_usage_warning"
+	// StorageReconciled is emitted when external controller marks storage as reconciled
+	StorageReconciled Operation = "storage_reconciled"
+	// StorageTerminating is emitted when storage with volumes is marked for deletion
+	StorageTerminating Operation = "storage_terminating"
+)
+
+// Operations contains all known event operations.
+var Operations = []Operation{StorageCreated, StorageUpdated, StorageDeleted, StorageUsageWarning, StorageReconciled, StorageTerminating}
+
+// IsKnown checks that operation is one of Operations.
+func (op Operation) IsKnown() bool {
+	for _, known := range Operations {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+// StorageEvent describes storage lifecycle change
+type StorageEvent struct {
+	Operation Operation `json:"operation"`
+	Name      string    `json:"name"`
+	UserID    string    `json:"user_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Publisher delivers events to other services.
+type Publisher interface {
+	Publish(ctx context.Context, event StorageEvent) error
+}
+
+// NopPublisher drops all events.
+type NopPublisher struct{}
+
+func (NopPublisher) Publish(ctx context.Context, event StorageEvent) error {
+	return nil
+}
+
+// MemoryPublisher keeps published events in memory. Useful for tests.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []StorageEvent
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, event StorageEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events returns copy of published events.
+func (p *MemoryPublisher) Events() []StorageEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]StorageEvent(nil), p.events...)
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaEventHeader contains event operation
+const KafkaEventHeader = "event"
+
+// KafkaMessage is a message produced to Kafka topic.
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// KafkaProducer sends messages to Kafka, it's implemented by adapter of Kafka client.
+// Messages with the same key must be written in order they are passed,
+// e.g. producer has one in-flight request per partition or is idempotent.
+type KafkaProducer interface {
+	SendMessages(ctx context.Context, messages []KafkaMessage) error
+}
+
+// KafkaSink produces each event of batch as JSON message keyed by storage name,
+// so events of one storage go to the same partition and keep their order.
+type KafkaSink struct {
+	Producer KafkaProducer
+	Topic    string
+}
+
+func (s KafkaSink) Flush(ctx context.Context, batch []StorageEvent) error {
+	messages := make([]KafkaMessage, 0, len(batch))
+	for _, event := range batch {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("marshal %s event failed: %v", event.Operation, err)
+		}
+		messages = append(messages, KafkaMessage{
+			Topic:   s.Topic,
+			Key:     []byte(event.Name),
+			Value:   value,
+			Headers: map[string]string{KafkaEventHeader: string(event.Operation)},
+		})
+	}
+	return s.Producer.SendMessages(ctx, messages)
+}
+
+// KafkaConfig configures KafkaWriterProducer. Zero fields are replaced with defaults.
+type KafkaConfig struct {
+	// Brokers are used to discover topic partitions, at least one is required
+	Brokers []string
+	// RequiredAcks is a number of replicas acknowledges of produce request, -1 waits for all in-sync replicas
+	RequiredAcks int
+	// WriteTimeout limits duration of produce request
+	WriteTimeout time.Duration
+}
+
+func (cfg KafkaConfig) withDefaults() KafkaConfig {
+	if cfg.RequiredAcks == 0 {
+		cfg.RequiredAcks = -1
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	return cfg
+}
+
+// KafkaWriterProducer implements KafkaProducer with kafka-go writers, one writer per topic.
+// Messages are partitioned by key hash and written synchronously,
+// so messages with the same key are written in order they are passed.
+// Failed messages are not retried by writer, BatchPublisher retries whole batch.
+type KafkaWriterProducer struct {
+	cfg KafkaConfig
+
+	mu      sync.Mutex
+	writers map[string]*kafka.Writer
+	closed  bool
+}
+
+// NewKafkaWriterProducer returns producer, writers are created on first message to topic.
+// Producer must be closed with Close.
+func NewKafkaWriterProducer(cfg KafkaConfig) (*KafkaWriterProducer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are not set")
+	}
+	return &KafkaWriterProducer{
+		cfg:     cfg.withDefaults(),
+		writers: make(map[string]*kafka.Writer),
+	}, nil
+}
+
+func (p *KafkaWriterProducer) writer(topic string) (*kafka.Writer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("kafka producer closed")
+	}
+	w, ok := p.writers[topic]
+	if !ok {
+		w = kafka.NewWriter(kafka.WriterConfig{
+			Brokers:     p.cfg.Brokers,
+			Topic:       topic,
+			Balancer:    &kafka.Hash{},
+			MaxAttempts: 1,
+			// messages are already batched by BatchPublisher, writer batch is flushed right after them
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: p.cfg.RequiredAcks,
+			WriteTimeout: p.cfg.WriteTimeout,
+		})
+		p.writers[topic] = w
+	}
+	return w, nil
+}
+
+// SendMessages writes messages grouped by topic. Order of messages of one topic is kept.
+func (p *KafkaWriterProducer) SendMessages(ctx context.Context, messages []KafkaMessage) error {
+	var topics []string
+	byTopic := make(map[string][]kafka.Message)
+	for _, msg := range messages {
+		if _, ok := byTopic[msg.Topic]; !ok {
+			topics = append(topics, msg.Topic)
+		}
+		headers := make([]kafka.Header, 0, len(msg.Headers))
+		for k, v := range msg.Headers {
+			headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		byTopic[msg.Topic] = append(byTopic[msg.Topic], kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	}
+	for _, topic := range topics {
+		w, err := p.writer(topic)
+		if err != nil {
+			return err
+		}
+		if err := w.WriteMessages(ctx, byTopic[topic]...); err != nil {
+			return fmt.Errorf("write to topic %s failed: %v", topic, err)
+		}
+	}
+	return nil
+}
+
+// Close closes writers, sending messages fails after close.
+func (p *KafkaWriterProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var firstErr error
+	for topic, w := range p.writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close writer of topic %s failed: %v", topic, err)
+		}
+	}
+	p.writers = nil
+	return firstErr
+}
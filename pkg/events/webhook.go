@@ -0,0 +1,342 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// WebhookIDHeader contains ID of webhook receiving event
+	WebhookIDHeader = "X-Webhook-ID"
+	// WebhookEventHeader contains event operation
+	WebhookEventHeader = "X-Webhook-Event"
+	// WebhookSignatureHeader contains payload signature in form "sha256=<hex HMAC-SHA256 of body keyed by webhook secret>"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Webhook is an HTTP subscription to events.
+type Webhook struct {
+	ID     string
+	URL    string
+	Secret string
+	// Operations of delivered events, all events are delivered if empty
+	Operations []Operation
+}
+
+// Matches checks if event with operation should be delivered to webhook.
+func (w Webhook) Matches(op Operation) bool {
+	if len(w.Operations) == 0 {
+		return true
+	}
+	for _, subscribed := range w.Operations {
+		if subscribed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSource returns current webhook subscriptions.
+type WebhookSource func(ctx context.Context) ([]Webhook, error)
+
+// Sign returns value of WebhookSignatureHeader for payload.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookConfig configures webhooks delivery. Zero fields are replaced with defaults.
+type WebhookConfig struct {
+	// Workers is a number of concurrent deliveries
+	Workers int
+	// QueueSize is a number of events waiting for delivery, events are dropped if queue is full
+	QueueSize int
+	// MaxAttempts is a maximum number of delivery attempts to one webhook
+	MaxAttempts int
+	// RetryDelay is a delay before second attempt, delay is doubled for each next attempt
+	RetryDelay time.Duration
+	// Timeout limits duration of one delivery attempt
+	Timeout time.Duration
+	Log     *logrus.Entry
+}
+
+func (cfg WebhookConfig) withDefaults() WebhookConfig {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Log == nil {
+		cfg.Log = logrus.NewEntry(logrus.StandardLogger())
+	}
+	return cfg
+}
+
+type webhookDelivery struct {
+	webhook Webhook
+	event   StorageEvent
+	payload []byte
+}
+
+// webhookClient makes deliveries with retries, it is shared by WebhookPublisher and WebhookSink.
+type webhookClient struct {
+	cfg    WebhookConfig
+	client *http.Client
+}
+
+func newWebhookClient(cfg WebhookConfig) webhookClient {
+	return webhookClient{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// matchingDeliveries returns deliveries of event to each matching webhook.
+func (c webhookClient) matchingDeliveries(webhooks []Webhook, event StorageEvent) ([]webhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var ret []webhookDelivery
+	for _, webhook := range webhooks {
+		if webhook.Matches(event.Operation) {
+			ret = append(ret, webhookDelivery{webhook: webhook, event: event, payload: payload})
+		}
+	}
+	return ret, nil
+}
+
+// WebhookPublisher posts events as JSON to subscribed webhooks.
+// Events are delivered asynchronously so Publish never waits for webhooks.
+type WebhookPublisher struct {
+	webhookClient
+	source WebhookSource
+
+	events     chan StorageEvent
+	deliveries chan webhookDelivery
+	stop       chan struct{}
+	closeOnce  sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewWebhookPublisher starts delivery workers. Publisher must be closed with Close.
+func NewWebhookPublisher(source WebhookSource, cfg WebhookConfig) *WebhookPublisher {
+	cfg = cfg.withDefaults()
+	p := &WebhookPublisher{
+		webhookClient: newWebhookClient(cfg),
+		source:        source,
+		events:        make(chan StorageEvent, cfg.QueueSize),
+		deliveries:    make(chan webhookDelivery),
+		stop:          make(chan struct{}),
+	}
+	p.wg.Add(1 + cfg.Workers)
+	go p.dispatch()
+	for i := 0; i < cfg.Workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Publish enqueues event for delivery. Error is returned if queue is full or publisher is closed.
+func (p *WebhookPublisher) Publish(ctx context.Context, event StorageEvent) error {
+	select {
+	case <-p.stop:
+		return fmt.Errorf("webhook publisher closed")
+	default:
+	}
+	select {
+	case p.events <- event:
+		return nil
+	default:
+		return fmt.Errorf("webhook events queue is full")
+	}
+}
+
+// Close stops delivery. Queued events and pending retries are dropped.
+func (p *WebhookPublisher) Close() error {
+	p.closeOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// dispatch sends each queued event to workers once for each matching webhook.
+func (p *WebhookPublisher) dispatch() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case event := <-p.events:
+			ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+			webhooks, err := p.source(ctx)
+			cancel()
+			if err != nil {
+				p.cfg.Log.WithError(err).Errorf("get webhooks for %s event failed", event.Operation)
+				continue
+			}
+			deliveries, err := p.matchingDeliveries(webhooks, event)
+			if err != nil {
+				p.cfg.Log.WithError(err).Errorf("marshal %s event failed", event.Operation)
+				continue
+			}
+			for _, d := range deliveries {
+				select {
+				case p.deliveries <- d:
+				case <-p.stop:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *WebhookPublisher) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case d := <-p.deliveries:
+			p.deliver(d, p.stop)
+		}
+	}
+}
+
+// WebhookSink posts batched events to subscribed webhooks synchronously, so Flush returns after batch is delivered.
+// Events of one storage are delivered one at a time in batch order, different storages are delivered by up to Workers goroutines.
+// Delivery failures are logged and dropped after MaxAttempts, so Flush fails only if webhooks can't be listed, before any event is sent,
+// and retried batch never duplicates delivered events. Deliveries are limited by Timeout of each attempt, not by Flush context.
+type WebhookSink struct {
+	webhookClient
+	source WebhookSource
+}
+
+// NewWebhookSink creates sink delivering to webhooks from source. QueueSize is not used.
+func NewWebhookSink(source WebhookSource, cfg WebhookConfig) *WebhookSink {
+	return &WebhookSink{webhookClient: newWebhookClient(cfg.withDefaults()), source: source}
+}
+
+func (s *WebhookSink) Flush(ctx context.Context, batch []StorageEvent) error {
+	webhooks, err := s.source(ctx)
+	if err != nil {
+		return fmt.Errorf("get webhooks failed: %v", err)
+	}
+
+	// deliveries of each storage in batch order
+	var names []string
+	byName := make(map[string][]webhookDelivery)
+	for _, event := range batch {
+		deliveries, err := s.matchingDeliveries(webhooks, event)
+		if err != nil {
+			s.cfg.Log.WithError(err).Errorf("marshal %s event failed", event.Operation)
+			continue
+		}
+		if len(deliveries) == 0 {
+			continue
+		}
+		if _, ok := byName[event.Name]; !ok {
+			names = append(names, event.Name)
+		}
+		byName[event.Name] = append(byName[event.Name], deliveries...)
+	}
+
+	queue := make(chan string, len(names))
+	for _, name := range names {
+		queue <- name
+	}
+	close(queue)
+	workers := s.cfg.Workers
+	if workers > len(names) {
+		workers = len(names)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for name := range queue {
+				for _, d := range byName[name] {
+					s.deliver(d, nil)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliver posts event to webhook, retrying with exponential backoff until stop is closed.
+func (c webhookClient) deliver(d webhookDelivery, stop <-chan struct{}) {
+	log := c.cfg.Log.WithFields(logrus.Fields{
+		"webhook": d.webhook.ID,
+		"event":   d.event.Operation,
+		"name":    d.event.Name,
+	})
+	delay := c.cfg.RetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := c.post(d)
+		if err == nil {
+			log.Debugf("event delivered")
+			return
+		}
+		if !retry || attempt >= c.cfg.MaxAttempts {
+			log.WithError(err).Errorf("event delivery failed after %d attempts", attempt)
+			return
+		}
+		log.WithError(err).Debugf("event delivery attempt %d failed, retry in %v", attempt, delay)
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-stop:
+			return
+		}
+	}
+}
+
+// post makes one delivery attempt. Network errors, timeouts, rate limits and server errors are retriable.
+func (c webhookClient) post(d webhookDelivery) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, d.webhook.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, d.webhook.ID)
+	req.Header.Set(WebhookEventHeader, string(d.event.Operation))
+	req.Header.Set(WebhookSignatureHeader, Sign(d.webhook.Secret, d.payload))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	Convey("Test webhook publisher", t, func() {
+		var mu sync.Mutex
+		var requests []webhookRequest
+		failures := 2
+		received := make(chan struct{}, 10)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			requests = append(requests, webhookRequest{header: r.Header, body: body})
+			fail := failures > 0
+			failures--
+			mu.Unlock()
+			if fail {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			received <- struct{}{}
+		}))
+		defer srv.Close()
+
+		webhooks := []Webhook{
+			{ID: "all", URL: srv.URL, Secret: "secret"},
+			{ID: "deleted", URL: srv.URL + "/deleted", Secret: "secret", Operations: []Operation{StorageDeleted}},
+		}
+		p := NewWebhookPublisher(func(ctx context.Context) ([]Webhook, error) {
+			return webhooks, nil
+		}, WebhookConfig{RetryDelay: time.Millisecond})
+		defer p.Close()
+
+		event := StorageEvent{Operation: StorageCreated, Name: "storage", Timestamp: time.Now().UTC()}
+		So(p.Publish(context.Background(), event), ShouldBeNil)
+
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("event was not delivered")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		So(requests, ShouldHaveLength, 3)
+		last := requests[2]
+		So(last.header.Get(WebhookIDHeader), ShouldEqual, "all")
+		So(last.header.Get(WebhookEventHeader), ShouldEqual, string(StorageCreated))
+		So(last.header.Get(WebhookSignatureHeader), ShouldEqual, Sign("secret", last.body))
+
+		var delivered StorageEvent
+		So(json.Unmarshal(last.body, &delivered), ShouldBeNil)
+		So(delivered.Name, ShouldEqual, event.Name)
+		So(delivered.Operation, ShouldEqual, event.Operation)
+	})
+}
+
+func TestWebhookSink(t *testing.T) {
+	Convey("Test webhook sink", t, func() {
+		var mu sync.Mutex
+		var delivered []StorageEvent
+		attempts := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event StorageEvent
+			json.NewDecoder(r.Body).Decode(&event)
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			// first attempt of first event fails, next events of storage must wait for its retry
+			if attempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			delivered = append(delivered, event)
+		}))
+		defer srv.Close()
+
+		webhooks := []Webhook{{ID: "all", URL: srv.URL, Secret: "secret"}}
+		sink := NewWebhookSink(func(ctx context.Context) ([]Webhook, error) {
+			return webhooks, nil
+		}, WebhookConfig{Workers: 2, RetryDelay: 10 * time.Millisecond})
+
+		Convey("events are delivered once in order of each storage before close returns", func() {
+			p := NewBatchPublisher(sink, BatchConfig{Window: time.Hour})
+			ops := []Operation{StorageCreated, StorageUpdated, StorageDeleted}
+			for _, op := range ops {
+				for _, name := range []string{"a", "b"} {
+					So(p.Publish(context.Background(), StorageEvent{Operation: op, Name: name}), ShouldBeNil)
+				}
+			}
+			So(p.Close(), ShouldBeNil)
+
+			mu.Lock()
+			defer mu.Unlock()
+			So(attempts, ShouldEqual, 7)
+			byName := map[string][]Operation{}
+			for _, event := range delivered {
+				byName[event.Name] = append(byName[event.Name], event.Operation)
+			}
+			So(byName["a"], ShouldResemble, ops)
+			So(byName["b"], ShouldResemble, ops)
+		})
+		Convey("flush fails without deliveries if webhooks can't be listed", func() {
+			sink.source = func(ctx context.Context) ([]Webhook, error) {
+				return nil, fmt.Errorf("db unavailable")
+			}
+			So(sink.Flush(context.Background(), []StorageEvent{{Operation: StorageCreated, Name: "a"}}), ShouldNotBeNil)
+			mu.Lock()
+			defer mu.Unlock()
+			So(attempts, ShouldBeZeroValue)
+		})
+	})
+}
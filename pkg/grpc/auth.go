@@ -0,0 +1,90 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/server"
+	"github.com/containerum/utils/httputil"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// methodOperations maps StorageService methods to operations of HTTP API, so roles are checked same way.
+var methodOperations = map[string]string{
+	"/volumemanager.StorageService/Create": "create",
+	"/volumemanager.StorageService/Get":    "get",
+	"/volumemanager.StorageService/List":   "list",
+	"/volumemanager.StorageService/Update": "update",
+	"/volumemanager.StorageService/Delete": "delete",
+}
+
+// UnaryAuthInterceptor authenticates calls by user metadata, same as middleware.RequiredUserHeaders does for HTTP headers,
+// and rejects calls which are not allowed for user role by roles mapping.
+// User ID and role are saved to context with httputil context keys, so server actions see them as for HTTP requests.
+func UnaryAuthInterceptor(roles middleware.RoleOperations) ggrpc.UnaryServerInterceptor {
+	if roles == nil {
+		roles = middleware.DefaultRoleOperations
+	}
+	return func(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		get := func(header string) string {
+			if values := md.Get(header); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+
+		role := get(httputil.UserRoleXHeader)
+		if role == "" {
+			return nil, statusError(errors.ErrRequiredHeadersNotProvided().AddDetails(httputil.UserRoleXHeader))
+		}
+		operation, ok := methodOperations[info.FullMethod]
+		if !ok {
+			return nil, statusError(errors.ErrInternal().AddDetailF("unknown method %s", info.FullMethod))
+		}
+		if err := checkRole(role, operation, roles); err != nil {
+			return nil, statusError(err)
+		}
+		userID := get(httputil.UserIDXHeader)
+		if role == middleware.RoleUser {
+			var missing []string
+			for _, header := range []string{httputil.UserIDXHeader, httputil.UserNamespacesXHeader} {
+				if get(header) == "" {
+					missing = append(missing, header)
+				}
+			}
+			if len(missing) > 0 {
+				return nil, statusError(errors.ErrRequiredHeadersNotProvided().AddDetails(missing...))
+			}
+			userNs, err := middleware.ParseUserHeaderData(get(httputil.UserNamespacesXHeader))
+			if err != nil {
+				return nil, statusError(errors.ErrRequestValidationFailed().AddDetailF("%v: %v", httputil.UserNamespacesXHeader, err))
+			}
+			namespaces := make([]string, 0, len(userNs))
+			for _, ns := range userNs {
+				namespaces = append(namespaces, ns.ID)
+			}
+			ctx = server.WithUserNamespaces(ctx, namespaces)
+		}
+
+		ctx = context.WithValue(ctx, httputil.UserRoleContextKey, role)
+		if userID != "" {
+			ctx = context.WithValue(ctx, httputil.UserIDContextKey, userID)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func checkRole(role, operation string, roles middleware.RoleOperations) error {
+	allowed := roles.Roles(operation)
+	for _, r := range allowed {
+		if r == role {
+			return nil
+		}
+	}
+	return errors.ErrRoleRequired().
+		AddDetailF("operation %s requires role %s, got %q", operation, strings.Join(allowed, " or "), role)
+}
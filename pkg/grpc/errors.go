@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusCode classifies error from pkg/errors by its HTTP status, same as it is reported by HTTP API.
+// Name collisions are reported as AlreadyExists, other conflicts are failed preconditions.
+func StatusCode(err error) codes.Code {
+	switch err {
+	case nil:
+		return codes.OK
+	case context.Canceled:
+		return codes.Canceled
+	case context.DeadlineExceeded:
+		return codes.DeadlineExceeded
+	}
+	if cherry.In(err, errors.ErrResourceAlreadyExists(), errors.ErrStorageAlreadyExists()) {
+		return codes.AlreadyExists
+	}
+	switch errors.StatusCode(err) {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// Status converts error to gRPC status. Message starts with stable error code (see errors.Code),
+// cherry error message and details follow it.
+func Status(err error) *status.Status {
+	code := StatusCode(err)
+	switch err {
+	case nil:
+		return status.New(code, "")
+	case context.Canceled, context.DeadlineExceeded:
+		return status.New(code, err.Error())
+	}
+	envelope := errors.NewEnvelope(err)
+	msg := fmt.Sprintf("%s: %s", envelope.Code, envelope.Message)
+	if len(envelope.Details) > 0 {
+		msg += ": " + strings.Join(envelope.Details, "; ")
+	}
+	return status.New(code, msg)
+}
+
+// statusError converts error returned by StorageActions to gRPC status error, nil error stays nil.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return Status(err).Err()
+}
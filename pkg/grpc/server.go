@@ -0,0 +1,167 @@
+// Package grpc implements gRPC StorageService (see storagepb/storage.proto) on top of server.StorageActions.
+package grpc
+
+import (
+	"context"
+	"strconv"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/grpc/storagepb"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/server"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+)
+
+const (
+	defaultStoragesLimit = 50
+	maxStoragesLimit     = 500
+)
+
+// StorageServer serves StorageService by delegating to storage actions, same as storage HTTP handlers.
+// Calls must be authenticated by UnaryAuthInterceptor.
+type StorageServer struct {
+	acts server.StorageActions
+}
+
+var _ storagepb.StorageServiceServer = &StorageServer{}
+
+func NewStorageServer(acts server.StorageActions) *StorageServer {
+	return &StorageServer{acts: acts}
+}
+
+func (s *StorageServer) Create(ctx context.Context, req *storagepb.CreateStorageRequest) (*storagepb.CreateStorageResponse, error) {
+	if req.GetStorage() == nil {
+		return nil, statusError(errors.ErrRequestValidationFailed().AddDetails("Field storage: is required"))
+	}
+	storage := model.Storage{
+		Name:            req.Storage.Name,
+		Size:            int(req.Storage.Size),
+		Used:            int(req.Storage.Used),
+		OvercommitRatio: req.Storage.OvercommitRatio,
+		Labels:          req.Storage.Labels,
+	}
+	if _, err := s.acts.CreateStorage(ctx, storage); err != nil {
+		return nil, statusError(err)
+	}
+	return &storagepb.CreateStorageResponse{}, nil
+}
+
+func (s *StorageServer) Get(ctx context.Context, req *storagepb.GetStorageRequest) (*storagepb.Storage, error) {
+	storage, err := s.acts.GetStorage(ctx, req.Name)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return storageToProto(storage), nil
+}
+
+func (s *StorageServer) List(ctx context.Context, req *storagepb.ListStoragesRequest) (*storagepb.ListStoragesResponse, error) {
+	if req.Limit < 0 {
+		return nil, statusError(errors.ErrRequestValidationFailed().AddDetails("limit must be positive integer"))
+	}
+	// zero limit without cursor means unpaginated list, same as absent query params
+	var pages model.StoragePagination
+	if req.Limit > 0 || req.Cursor != "" {
+		pages = model.StoragePagination{Limit: int(req.Limit), Cursor: req.Cursor}
+		if pages.Limit == 0 {
+			pages.Limit = defaultStoragesLimit
+		}
+		if pages.Limit > maxStoragesLimit {
+			pages.Limit = maxStoragesLimit
+		}
+	}
+
+	filter := model.StorageListFilter{NamePrefix: req.NamePrefix}
+	var err error
+	if req.LabelSelector != "" {
+		if filter.LabelSelector, err = labels.Parse(req.LabelSelector); err != nil {
+			return nil, statusError(errors.ErrRequestValidationFailed().AddDetailsErr(err))
+		}
+	}
+	if req.Sort != "" {
+		if filter.Sort, err = model.ParseStorageSort(req.Sort); err != nil {
+			return nil, statusError(errors.ErrRequestValidationFailed().AddDetailsErr(err))
+		}
+	}
+	if namespaces, ok := server.UserNamespaces(ctx); ok {
+		filter.NamespaceScoped = true
+		filter.Namespaces = namespaces
+	}
+
+	page, err := s.acts.GetStoragesFiltered(ctx, filter, pages)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	ret := &storagepb.ListStoragesResponse{
+		Storages:   make([]*storagepb.Storage, 0, len(page.Storages)),
+		NextCursor: page.NextCursor,
+		Total:      int32(page.Total),
+	}
+	for _, storage := range page.Storages {
+		ret.Storages = append(ret.Storages, storageToProto(storage))
+	}
+	return ret, nil
+}
+
+func (s *StorageServer) Update(ctx context.Context, req *storagepb.UpdateStorageRequest) (*storagepb.UpdateStorageResponse, error) {
+	var cond model.ETagCondition
+	if req.IfMatchVersion != 0 {
+		cond = model.ETagCondition{`"` + strconv.FormatInt(req.IfMatchVersion, 10) + `"`}
+	}
+
+	// proto3 has no field presence, so zero fields are not applied
+	var update model.UpdateStorageRequest
+	patch := req.GetStorage()
+	if patch == nil {
+		patch = &storagepb.Storage{}
+	}
+	changed := false
+	if patch.Name != "" && patch.Name != req.Name {
+		update.Name, changed = &patch.Name, true
+	}
+	if patch.Size != 0 {
+		size := int(patch.Size)
+		update.Size, changed = &size, true
+	}
+	if patch.Used != 0 {
+		used := int(patch.Used)
+		update.Used, changed = &used, true
+	}
+	if patch.OvercommitRatio != 0 {
+		update.OvercommitRatio, changed = &patch.OvercommitRatio, true
+	}
+	if len(patch.Labels) > 0 {
+		update.Labels, changed = patch.Labels, true
+	}
+
+	// all fields are updated in one transaction, so version condition applies to whole update
+	if changed {
+		if err := s.acts.UpdateStorage(ctx, req.Name, update, cond, false); err != nil {
+			return nil, statusError(err)
+		}
+	}
+	return &storagepb.UpdateStorageResponse{}, nil
+}
+
+func (s *StorageServer) Delete(ctx context.Context, req *storagepb.DeleteStorageRequest) (*storagepb.DeleteStorageResponse, error) {
+	var err error
+	if req.Force {
+		err = s.acts.PurgeStorage(ctx, req.Name, req.Cascade)
+	} else {
+		err = s.acts.DeleteStorage(ctx, req.Name, req.Cascade)
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &storagepb.DeleteStorageResponse{}, nil
+}
+
+func storageToProto(storage model.Storage) *storagepb.Storage {
+	return &storagepb.Storage{
+		Name:            storage.Name,
+		Size:            int64(storage.Size),
+		Used:            int64(storage.Used),
+		OvercommitRatio: storage.OvercommitRatio,
+		Version:         int64(storage.Version),
+		Labels:          storage.Labels,
+	}
+}
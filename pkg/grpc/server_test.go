@@ -0,0 +1,218 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/grpc/storagepb"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/server"
+	"github.com/containerum/utils/httputil"
+	. "github.com/smartystreets/goconvey/convey"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// storageActions keeps storages in memory, other actions are not implemented.
+type storageActions struct {
+	server.StorageActions
+
+	storages map[string]model.Storage
+	// owner of last created storage
+	owner string
+	// applied updates
+	updates []model.UpdateStorageRequest
+}
+
+func (a *storageActions) CreateStorage(ctx context.Context, storage model.Storage) (model.Storage, error) {
+	if _, ok := a.storages[storage.Name]; ok {
+		return model.Storage{}, errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", storage.Name)
+	}
+	a.owner, _ = ctx.Value(httputil.UserIDContextKey).(string)
+	storage.Version = 1
+	a.storages[storage.Name] = storage
+	return storage, nil
+}
+
+func (a *storageActions) GetStorage(ctx context.Context, name string) (model.Storage, error) {
+	storage, ok := a.storages[name]
+	if !ok {
+		return model.Storage{}, errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return storage, nil
+}
+
+func (a *storageActions) GetStoragesFiltered(ctx context.Context, filter model.StorageListFilter, pages model.StoragePagination) (model.StoragesPage, error) {
+	var page model.StoragesPage
+	for _, storage := range a.storages {
+		if !strings.HasPrefix(storage.Name, filter.NamePrefix) {
+			continue
+		}
+		if filter.NamespaceScoped {
+			visible := false
+			for _, ns := range filter.Namespaces {
+				visible = visible || storage.AvailableIn(ns)
+			}
+			if !visible {
+				continue
+			}
+		}
+		page.Storages = append(page.Storages, storage)
+	}
+	page.Total = len(page.Storages)
+	return page, nil
+}
+
+func (a *storageActions) UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) error {
+	storage, ok := a.storages[name]
+	if !ok {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	if !cond.Matches(storage) {
+		return errors.ErrPreconditionFailed().AddDetailF("storage %s version is %d", name, storage.Version)
+	}
+	a.updates = append(a.updates, req)
+	return nil
+}
+
+func (a *storageActions) PurgeStorage(ctx context.Context, name string, cascade bool) error {
+	if a.storages[name].Pinned {
+		return errors.ErrStoragePinned().AddDetailF("storage %s is pinned and can't be purged", name)
+	}
+	delete(a.storages, name)
+	return nil
+}
+
+func withUser(role, userID string, namespaces ...string) context.Context {
+	md := metadata.Pairs(httputil.UserRoleXHeader, role)
+	if userID != "" {
+		md.Set(httputil.UserIDXHeader, userID)
+	}
+	if len(namespaces) > 0 {
+		var data []string
+		for _, ns := range namespaces {
+			data = append(data, `{"id":"`+ns+`","access":"owner"}`)
+		}
+		md.Set(httputil.UserNamespacesXHeader, base64.StdEncoding.EncodeToString([]byte("["+strings.Join(data, ",")+"]")))
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func TestStorageServer(t *testing.T) {
+	Convey("Test gRPC storage server", t, func() {
+		acts := &storageActions{storages: map[string]model.Storage{
+			"shared": {Name: "shared", Size: 10, Version: 3},
+			"team-a": {Name: "team-a", Size: 10, Namespaces: []string{"a"}},
+			"pinned": {Name: "pinned", Size: 10, Pinned: true},
+		}}
+		srv := ggrpc.NewServer(ggrpc.UnaryInterceptor(UnaryAuthInterceptor(middleware.DefaultRoleOperations)))
+		storagepb.RegisterStorageServiceServer(srv, NewStorageServer(acts))
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		go srv.Serve(lis)
+		defer srv.Stop()
+
+		conn, err := ggrpc.Dial(lis.Addr().String(), ggrpc.WithInsecure())
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		client := storagepb.NewStorageServiceClient(conn)
+		admin := withUser(middleware.RoleAdmin, "admin-id")
+
+		Convey("storage is created and got by admin", func() {
+			_, err := client.Create(admin, &storagepb.CreateStorageRequest{Storage: &storagepb.Storage{
+				Name: "created", Size: 20, OvercommitRatio: 1.5, Labels: map[string]string{"team": "a"},
+			}})
+			So(err, ShouldBeNil)
+			So(acts.owner, ShouldEqual, "admin-id")
+
+			storage, err := client.Get(admin, &storagepb.GetStorageRequest{Name: "created"})
+			So(err, ShouldBeNil)
+			So(storage.Size, ShouldEqual, 20)
+			So(storage.OvercommitRatio, ShouldEqual, 1.5)
+			So(storage.Version, ShouldEqual, 1)
+			So(storage.Labels, ShouldResemble, map[string]string{"team": "a"})
+		})
+
+		Convey("errors are translated to status codes", func() {
+			_, err := client.Get(admin, &storagepb.GetStorageRequest{Name: "missing"})
+			So(status.Code(err), ShouldEqual, codes.NotFound)
+
+			_, err = client.Create(admin, &storagepb.CreateStorageRequest{Storage: &storagepb.Storage{Name: "shared", Size: 1}})
+			So(status.Code(err), ShouldEqual, codes.AlreadyExists)
+			So(status.Convert(err).Message(), ShouldStartWith, string(errors.CodeStorageAlreadyExists))
+
+			_, err = client.Delete(admin, &storagepb.DeleteStorageRequest{Name: "pinned", Force: true})
+			So(status.Code(err), ShouldEqual, codes.FailedPrecondition)
+
+			_, err = client.Create(admin, &storagepb.CreateStorageRequest{})
+			So(status.Code(err), ShouldEqual, codes.InvalidArgument)
+		})
+
+		Convey("storage is updated by one call under version condition", func() {
+			_, err := client.Update(admin, &storagepb.UpdateStorageRequest{Name: "shared", IfMatchVersion: 2, Storage: &storagepb.Storage{Size: 20}})
+			So(status.Code(err), ShouldEqual, codes.FailedPrecondition)
+			So(acts.updates, ShouldBeEmpty)
+
+			_, err = client.Update(admin, &storagepb.UpdateStorageRequest{Name: "shared", IfMatchVersion: 3, Storage: &storagepb.Storage{
+				Name: "renamed", Size: 20, Labels: map[string]string{"team": "b"},
+			}})
+			So(err, ShouldBeNil)
+			So(acts.updates, ShouldHaveLength, 1)
+			So(*acts.updates[0].Name, ShouldEqual, "renamed")
+			So(*acts.updates[0].Size, ShouldEqual, 20)
+			So(acts.updates[0].Labels, ShouldResemble, map[string]string{"team": "b"})
+		})
+
+		Convey("calls are authorized by role", func() {
+			_, err := client.Get(context.Background(), &storagepb.GetStorageRequest{Name: "shared"})
+			So(status.Code(err), ShouldEqual, codes.InvalidArgument)
+
+			viewer := withUser(middleware.RoleViewer, "")
+			_, err = client.Get(viewer, &storagepb.GetStorageRequest{Name: "shared"})
+			So(err, ShouldBeNil)
+			_, err = client.Delete(viewer, &storagepb.DeleteStorageRequest{Name: "shared"})
+			So(status.Code(err), ShouldEqual, codes.PermissionDenied)
+
+			_, err = client.List(withUser(middleware.RoleUser, "user-id"), &storagepb.ListStoragesRequest{})
+			So(status.Code(err), ShouldEqual, codes.InvalidArgument)
+		})
+
+		Convey("users list only storages of their namespaces", func() {
+			resp, err := client.List(withUser(middleware.RoleUser, "user-id", "b"), &storagepb.ListStoragesRequest{})
+			So(err, ShouldBeNil)
+			var names []string
+			for _, storage := range resp.Storages {
+				names = append(names, storage.Name)
+			}
+			So(names, ShouldNotContain, "team-a")
+			So(names, ShouldContain, "shared")
+
+			resp, err = client.List(withUser(middleware.RoleUser, "user-id", "a"), &storagepb.ListStoragesRequest{NamePrefix: "team"})
+			So(err, ShouldBeNil)
+			So(resp.Storages, ShouldHaveLength, 1)
+			So(resp.Total, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestStatusCode(t *testing.T) {
+	Convey("Test errors translation to status codes", t, func() {
+		So(StatusCode(nil), ShouldEqual, codes.OK)
+		So(StatusCode(errors.ErrRequestValidationFailed()), ShouldEqual, codes.InvalidArgument)
+		So(StatusCode(errors.ErrAdminRequired()), ShouldEqual, codes.PermissionDenied)
+		So(StatusCode(errors.ErrResourceAlreadyExists()), ShouldEqual, codes.AlreadyExists)
+		So(StatusCode(errors.ErrStorageHasVolumes()), ShouldEqual, codes.FailedPrecondition)
+		So(StatusCode(errors.ErrPreconditionFailed()), ShouldEqual, codes.FailedPrecondition)
+		So(StatusCode(errors.ErrTooManyRequests()), ShouldEqual, codes.ResourceExhausted)
+		So(StatusCode(errors.ErrServiceNotReady()), ShouldEqual, codes.Unavailable)
+		So(StatusCode(errors.ErrDatabase()), ShouldEqual, codes.Internal)
+		So(StatusCode(context.Canceled), ShouldEqual, codes.Canceled)
+	})
+}
@@ -0,0 +1,766 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: pkg/grpc/storagepb/storage.proto
+
+package storagepb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Storage struct {
+	Name                 string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size                 int64             `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Used                 int64             `protobuf:"varint,3,opt,name=used,proto3" json:"used,omitempty"`
+	OvercommitRatio      float64           `protobuf:"fixed64,4,opt,name=overcommit_ratio,json=overcommitRatio,proto3" json:"overcommit_ratio,omitempty"`
+	Version              int64             `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	Labels               map[string]string `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Storage) Reset()         { *m = Storage{} }
+func (m *Storage) String() string { return proto.CompactTextString(m) }
+func (*Storage) ProtoMessage()    {}
+func (*Storage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{0}
+}
+func (m *Storage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Storage.Unmarshal(m, b)
+}
+func (m *Storage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Storage.Marshal(b, m, deterministic)
+}
+func (dst *Storage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Storage.Merge(dst, src)
+}
+func (m *Storage) XXX_Size() int {
+	return xxx_messageInfo_Storage.Size(m)
+}
+func (m *Storage) XXX_DiscardUnknown() {
+	xxx_messageInfo_Storage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Storage proto.InternalMessageInfo
+
+func (m *Storage) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Storage) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Storage) GetUsed() int64 {
+	if m != nil {
+		return m.Used
+	}
+	return 0
+}
+
+func (m *Storage) GetOvercommitRatio() float64 {
+	if m != nil {
+		return m.OvercommitRatio
+	}
+	return 0
+}
+
+func (m *Storage) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Storage) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+type CreateStorageRequest struct {
+	Storage              *Storage `protobuf:"bytes,1,opt,name=storage,proto3" json:"storage,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateStorageRequest) Reset()         { *m = CreateStorageRequest{} }
+func (m *CreateStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateStorageRequest) ProtoMessage()    {}
+func (*CreateStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{1}
+}
+func (m *CreateStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateStorageRequest.Unmarshal(m, b)
+}
+func (m *CreateStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateStorageRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateStorageRequest.Merge(dst, src)
+}
+func (m *CreateStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateStorageRequest.Size(m)
+}
+func (m *CreateStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateStorageRequest proto.InternalMessageInfo
+
+func (m *CreateStorageRequest) GetStorage() *Storage {
+	if m != nil {
+		return m.Storage
+	}
+	return nil
+}
+
+type CreateStorageResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateStorageResponse) Reset()         { *m = CreateStorageResponse{} }
+func (m *CreateStorageResponse) String() string { return proto.CompactTextString(m) }
+func (*CreateStorageResponse) ProtoMessage()    {}
+func (*CreateStorageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{2}
+}
+func (m *CreateStorageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateStorageResponse.Unmarshal(m, b)
+}
+func (m *CreateStorageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateStorageResponse.Marshal(b, m, deterministic)
+}
+func (dst *CreateStorageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateStorageResponse.Merge(dst, src)
+}
+func (m *CreateStorageResponse) XXX_Size() int {
+	return xxx_messageInfo_CreateStorageResponse.Size(m)
+}
+func (m *CreateStorageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateStorageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateStorageResponse proto.InternalMessageInfo
+
+type GetStorageRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStorageRequest) Reset()         { *m = GetStorageRequest{} }
+func (m *GetStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStorageRequest) ProtoMessage()    {}
+func (*GetStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{3}
+}
+func (m *GetStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStorageRequest.Unmarshal(m, b)
+}
+func (m *GetStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStorageRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStorageRequest.Merge(dst, src)
+}
+func (m *GetStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStorageRequest.Size(m)
+}
+func (m *GetStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStorageRequest proto.InternalMessageInfo
+
+func (m *GetStorageRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ListStoragesRequest struct {
+	Limit                int32    `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor               string   `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	NamePrefix           string   `protobuf:"bytes,3,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	LabelSelector        string   `protobuf:"bytes,4,opt,name=label_selector,json=labelSelector,proto3" json:"label_selector,omitempty"`
+	Sort                 string   `protobuf:"bytes,5,opt,name=sort,proto3" json:"sort,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListStoragesRequest) Reset()         { *m = ListStoragesRequest{} }
+func (m *ListStoragesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListStoragesRequest) ProtoMessage()    {}
+func (*ListStoragesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{4}
+}
+func (m *ListStoragesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListStoragesRequest.Unmarshal(m, b)
+}
+func (m *ListStoragesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListStoragesRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListStoragesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListStoragesRequest.Merge(dst, src)
+}
+func (m *ListStoragesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListStoragesRequest.Size(m)
+}
+func (m *ListStoragesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListStoragesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListStoragesRequest proto.InternalMessageInfo
+
+func (m *ListStoragesRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListStoragesRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ListStoragesRequest) GetNamePrefix() string {
+	if m != nil {
+		return m.NamePrefix
+	}
+	return ""
+}
+
+func (m *ListStoragesRequest) GetLabelSelector() string {
+	if m != nil {
+		return m.LabelSelector
+	}
+	return ""
+}
+
+func (m *ListStoragesRequest) GetSort() string {
+	if m != nil {
+		return m.Sort
+	}
+	return ""
+}
+
+type ListStoragesResponse struct {
+	Storages             []*Storage `protobuf:"bytes,1,rep,name=storages,proto3" json:"storages,omitempty"`
+	NextCursor           string     `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	Total                int32      `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ListStoragesResponse) Reset()         { *m = ListStoragesResponse{} }
+func (m *ListStoragesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListStoragesResponse) ProtoMessage()    {}
+func (*ListStoragesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{5}
+}
+func (m *ListStoragesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListStoragesResponse.Unmarshal(m, b)
+}
+func (m *ListStoragesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListStoragesResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListStoragesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListStoragesResponse.Merge(dst, src)
+}
+func (m *ListStoragesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListStoragesResponse.Size(m)
+}
+func (m *ListStoragesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListStoragesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListStoragesResponse proto.InternalMessageInfo
+
+func (m *ListStoragesResponse) GetStorages() []*Storage {
+	if m != nil {
+		return m.Storages
+	}
+	return nil
+}
+
+func (m *ListStoragesResponse) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+func (m *ListStoragesResponse) GetTotal() int32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+type UpdateStorageRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// fields are applied only if set
+	Storage *Storage `protobuf:"bytes,2,opt,name=storage,proto3" json:"storage,omitempty"`
+	// expected storage version, 0 means no check (same as If-Match header)
+	IfMatchVersion       int64    `protobuf:"varint,3,opt,name=if_match_version,json=ifMatchVersion,proto3" json:"if_match_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateStorageRequest) Reset()         { *m = UpdateStorageRequest{} }
+func (m *UpdateStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateStorageRequest) ProtoMessage()    {}
+func (*UpdateStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{6}
+}
+func (m *UpdateStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateStorageRequest.Unmarshal(m, b)
+}
+func (m *UpdateStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateStorageRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateStorageRequest.Merge(dst, src)
+}
+func (m *UpdateStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateStorageRequest.Size(m)
+}
+func (m *UpdateStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateStorageRequest proto.InternalMessageInfo
+
+func (m *UpdateStorageRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *UpdateStorageRequest) GetStorage() *Storage {
+	if m != nil {
+		return m.Storage
+	}
+	return nil
+}
+
+func (m *UpdateStorageRequest) GetIfMatchVersion() int64 {
+	if m != nil {
+		return m.IfMatchVersion
+	}
+	return 0
+}
+
+type UpdateStorageResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateStorageResponse) Reset()         { *m = UpdateStorageResponse{} }
+func (m *UpdateStorageResponse) String() string { return proto.CompactTextString(m) }
+func (*UpdateStorageResponse) ProtoMessage()    {}
+func (*UpdateStorageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{7}
+}
+func (m *UpdateStorageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateStorageResponse.Unmarshal(m, b)
+}
+func (m *UpdateStorageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateStorageResponse.Marshal(b, m, deterministic)
+}
+func (dst *UpdateStorageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateStorageResponse.Merge(dst, src)
+}
+func (m *UpdateStorageResponse) XXX_Size() int {
+	return xxx_messageInfo_UpdateStorageResponse.Size(m)
+}
+func (m *UpdateStorageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateStorageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateStorageResponse proto.InternalMessageInfo
+
+type DeleteStorageRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Cascade              bool     `protobuf:"varint,2,opt,name=cascade,proto3" json:"cascade,omitempty"`
+	Force                bool     `protobuf:"varint,3,opt,name=force,proto3" json:"force,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteStorageRequest) Reset()         { *m = DeleteStorageRequest{} }
+func (m *DeleteStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteStorageRequest) ProtoMessage()    {}
+func (*DeleteStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{8}
+}
+func (m *DeleteStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteStorageRequest.Unmarshal(m, b)
+}
+func (m *DeleteStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteStorageRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteStorageRequest.Merge(dst, src)
+}
+func (m *DeleteStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteStorageRequest.Size(m)
+}
+func (m *DeleteStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteStorageRequest proto.InternalMessageInfo
+
+func (m *DeleteStorageRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DeleteStorageRequest) GetCascade() bool {
+	if m != nil {
+		return m.Cascade
+	}
+	return false
+}
+
+func (m *DeleteStorageRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+type DeleteStorageResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteStorageResponse) Reset()         { *m = DeleteStorageResponse{} }
+func (m *DeleteStorageResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteStorageResponse) ProtoMessage()    {}
+func (*DeleteStorageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_storage_6aac1edb43d5c662, []int{9}
+}
+func (m *DeleteStorageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteStorageResponse.Unmarshal(m, b)
+}
+func (m *DeleteStorageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteStorageResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteStorageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteStorageResponse.Merge(dst, src)
+}
+func (m *DeleteStorageResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteStorageResponse.Size(m)
+}
+func (m *DeleteStorageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteStorageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteStorageResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*Storage)(nil), "volumemanager.Storage")
+	proto.RegisterMapType((map[string]string)(nil), "volumemanager.Storage.LabelsEntry")
+	proto.RegisterType((*CreateStorageRequest)(nil), "volumemanager.CreateStorageRequest")
+	proto.RegisterType((*CreateStorageResponse)(nil), "volumemanager.CreateStorageResponse")
+	proto.RegisterType((*GetStorageRequest)(nil), "volumemanager.GetStorageRequest")
+	proto.RegisterType((*ListStoragesRequest)(nil), "volumemanager.ListStoragesRequest")
+	proto.RegisterType((*ListStoragesResponse)(nil), "volumemanager.ListStoragesResponse")
+	proto.RegisterType((*UpdateStorageRequest)(nil), "volumemanager.UpdateStorageRequest")
+	proto.RegisterType((*UpdateStorageResponse)(nil), "volumemanager.UpdateStorageResponse")
+	proto.RegisterType((*DeleteStorageRequest)(nil), "volumemanager.DeleteStorageRequest")
+	proto.RegisterType((*DeleteStorageResponse)(nil), "volumemanager.DeleteStorageResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// StorageServiceClient is the client API for StorageService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type StorageServiceClient interface {
+	Create(ctx context.Context, in *CreateStorageRequest, opts ...grpc.CallOption) (*CreateStorageResponse, error)
+	Get(ctx context.Context, in *GetStorageRequest, opts ...grpc.CallOption) (*Storage, error)
+	List(ctx context.Context, in *ListStoragesRequest, opts ...grpc.CallOption) (*ListStoragesResponse, error)
+	Update(ctx context.Context, in *UpdateStorageRequest, opts ...grpc.CallOption) (*UpdateStorageResponse, error)
+	Delete(ctx context.Context, in *DeleteStorageRequest, opts ...grpc.CallOption) (*DeleteStorageResponse, error)
+}
+
+type storageServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewStorageServiceClient(cc *grpc.ClientConn) StorageServiceClient {
+	return &storageServiceClient{cc}
+}
+
+func (c *storageServiceClient) Create(ctx context.Context, in *CreateStorageRequest, opts ...grpc.CallOption) (*CreateStorageResponse, error) {
+	out := new(CreateStorageResponse)
+	err := c.cc.Invoke(ctx, "/volumemanager.StorageService/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageServiceClient) Get(ctx context.Context, in *GetStorageRequest, opts ...grpc.CallOption) (*Storage, error) {
+	out := new(Storage)
+	err := c.cc.Invoke(ctx, "/volumemanager.StorageService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageServiceClient) List(ctx context.Context, in *ListStoragesRequest, opts ...grpc.CallOption) (*ListStoragesResponse, error) {
+	out := new(ListStoragesResponse)
+	err := c.cc.Invoke(ctx, "/volumemanager.StorageService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageServiceClient) Update(ctx context.Context, in *UpdateStorageRequest, opts ...grpc.CallOption) (*UpdateStorageResponse, error) {
+	out := new(UpdateStorageResponse)
+	err := c.cc.Invoke(ctx, "/volumemanager.StorageService/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageServiceClient) Delete(ctx context.Context, in *DeleteStorageRequest, opts ...grpc.CallOption) (*DeleteStorageResponse, error) {
+	out := new(DeleteStorageResponse)
+	err := c.cc.Invoke(ctx, "/volumemanager.StorageService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StorageServiceServer is the server API for StorageService service.
+type StorageServiceServer interface {
+	Create(context.Context, *CreateStorageRequest) (*CreateStorageResponse, error)
+	Get(context.Context, *GetStorageRequest) (*Storage, error)
+	List(context.Context, *ListStoragesRequest) (*ListStoragesResponse, error)
+	Update(context.Context, *UpdateStorageRequest) (*UpdateStorageResponse, error)
+	Delete(context.Context, *DeleteStorageRequest) (*DeleteStorageResponse, error)
+}
+
+func RegisterStorageServiceServer(s *grpc.Server, srv StorageServiceServer) {
+	s.RegisterService(&_StorageService_serviceDesc, srv)
+}
+
+func _StorageService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/volumemanager.StorageService/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServiceServer).Create(ctx, req.(*CreateStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/volumemanager.StorageService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServiceServer).Get(ctx, req.(*GetStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStoragesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/volumemanager.StorageService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServiceServer).List(ctx, req.(*ListStoragesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/volumemanager.StorageService/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServiceServer).Update(ctx, req.(*UpdateStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/volumemanager.StorageService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServiceServer).Delete(ctx, req.(*DeleteStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _StorageService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "volumemanager.StorageService",
+	HandlerType: (*StorageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _StorageService_Create_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _StorageService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _StorageService_List_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _StorageService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _StorageService_Delete_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/grpc/storagepb/storage.proto",
+}
+
+func init() {
+	proto.RegisterFile("pkg/grpc/storagepb/storage.proto", fileDescriptor_storage_6aac1edb43d5c662)
+}
+
+var fileDescriptor_storage_6aac1edb43d5c662 = []byte{
+	// 599 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x4f, 0x6f, 0xd3, 0x4e,
+	0x10, 0x95, 0xe3, 0x26, 0x69, 0x26, 0x6a, 0x7f, 0xfd, 0x2d, 0xa6, 0x58, 0xb9, 0x60, 0xb9, 0x45,
+	0x84, 0x4b, 0x8a, 0xc2, 0x05, 0x7a, 0x41, 0xa2, 0xa0, 0x72, 0x28, 0x02, 0x6d, 0x04, 0x87, 0x5e,
+	0xac, 0xad, 0x3b, 0x09, 0x56, 0xed, 0xac, 0xd9, 0xdd, 0x44, 0x2d, 0xb7, 0x5e, 0xf8, 0x1a, 0x88,
+	0x6f, 0x8a, 0xf6, 0x8f, 0xfb, 0xc7, 0x31, 0x51, 0x6f, 0x33, 0x4f, 0x2f, 0x6f, 0xe7, 0xcd, 0x9b,
+	0x18, 0xa2, 0xf2, 0x62, 0x76, 0x30, 0x13, 0x65, 0x7a, 0x20, 0x15, 0x17, 0x6c, 0x86, 0xe5, 0x59,
+	0x55, 0x8d, 0x4a, 0xc1, 0x15, 0x27, 0x5b, 0x4b, 0x9e, 0x2f, 0x0a, 0x2c, 0xd8, 0x9c, 0xcd, 0x50,
+	0xc4, 0xd7, 0x2d, 0xe8, 0x4e, 0x2c, 0x81, 0x10, 0xd8, 0x98, 0xb3, 0x02, 0x43, 0x2f, 0xf2, 0x86,
+	0x3d, 0x6a, 0x6a, 0x8d, 0xc9, 0xec, 0x27, 0x86, 0xad, 0xc8, 0x1b, 0xfa, 0xd4, 0xd4, 0x1a, 0x5b,
+	0x48, 0x3c, 0x0f, 0x7d, 0x8b, 0xe9, 0x9a, 0xbc, 0x80, 0x1d, 0xbe, 0x44, 0x91, 0xf2, 0xa2, 0xc8,
+	0x54, 0x22, 0x98, 0xca, 0x78, 0xb8, 0x11, 0x79, 0x43, 0x8f, 0xfe, 0x77, 0x8b, 0x53, 0x0d, 0x93,
+	0x10, 0xba, 0x4b, 0x14, 0x32, 0xe3, 0xf3, 0xb0, 0x6d, 0x14, 0xaa, 0x96, 0x1c, 0x42, 0x27, 0x67,
+	0x67, 0x98, 0xcb, 0xb0, 0x13, 0xf9, 0xc3, 0xfe, 0x38, 0x1e, 0xdd, 0x1b, 0x76, 0xe4, 0x06, 0x1d,
+	0x9d, 0x18, 0xd2, 0x87, 0xb9, 0x12, 0x57, 0xd4, 0xfd, 0x62, 0xf0, 0x06, 0xfa, 0x77, 0x60, 0xb2,
+	0x03, 0xfe, 0x05, 0x5e, 0x39, 0x2b, 0xba, 0x24, 0x01, 0xb4, 0x97, 0x2c, 0x5f, 0x58, 0x2b, 0x3d,
+	0x6a, 0x9b, 0xc3, 0xd6, 0x6b, 0x2f, 0xfe, 0x08, 0xc1, 0x91, 0x40, 0xa6, 0xd0, 0xe9, 0x53, 0xfc,
+	0xb1, 0x40, 0xa9, 0xc8, 0x4b, 0xe8, 0xba, 0xdd, 0x19, 0x9d, 0xfe, 0x78, 0xb7, 0x79, 0x1e, 0x5a,
+	0xd1, 0xe2, 0x27, 0xf0, 0xb8, 0xa6, 0x24, 0x4b, 0x3e, 0x97, 0x18, 0x3f, 0x87, 0xff, 0x8f, 0x51,
+	0xd5, 0xf4, 0x1b, 0xf6, 0x1d, 0xff, 0xf6, 0xe0, 0xd1, 0x49, 0x26, 0x2b, 0xaa, 0xac, 0xb8, 0x01,
+	0xb4, 0xf3, 0xac, 0xc8, 0x94, 0x21, 0xb7, 0xa9, 0x6d, 0xc8, 0x2e, 0x74, 0xd2, 0x85, 0x90, 0x5c,
+	0x38, 0x53, 0xae, 0x23, 0x4f, 0xa1, 0xaf, 0xd5, 0x92, 0x52, 0xe0, 0x34, 0xbb, 0x34, 0x41, 0xf5,
+	0x28, 0x68, 0xe8, 0x8b, 0x41, 0xc8, 0x33, 0xd8, 0x36, 0x7b, 0x4b, 0x24, 0xe6, 0x98, 0x2a, 0x2e,
+	0x4c, 0x58, 0x3d, 0xba, 0x65, 0xd0, 0x89, 0x03, 0x4d, 0xfa, 0x5c, 0x28, 0x93, 0x53, 0x8f, 0x9a,
+	0x3a, 0xbe, 0xf6, 0x20, 0xb8, 0x3f, 0xa1, 0xf5, 0x48, 0xc6, 0xb0, 0xe9, 0xf6, 0x20, 0x43, 0x2f,
+	0xf2, 0xd7, 0xec, 0xeb, 0x86, 0x67, 0x06, 0xc5, 0x4b, 0x95, 0xdc, 0x73, 0x01, 0x1a, 0x3a, 0xb2,
+	0x4e, 0x02, 0x68, 0x2b, 0xae, 0x58, 0x6e, 0x3c, 0xb4, 0xa9, 0x6d, 0xe2, 0x5f, 0x1e, 0x04, 0x5f,
+	0xcb, 0xf3, 0xd5, 0xc8, 0x9a, 0x4e, 0xf8, 0x4e, 0x8c, 0xad, 0x07, 0xc5, 0x48, 0x86, 0xb0, 0x93,
+	0x4d, 0x93, 0x82, 0xa9, 0xf4, 0x7b, 0x52, 0x9d, 0xaa, 0x3d, 0xf6, 0xed, 0x6c, 0xfa, 0x49, 0xc3,
+	0xdf, 0x2c, 0xaa, 0x03, 0xaf, 0xcd, 0xe1, 0x02, 0x3f, 0x85, 0xe0, 0x3d, 0xe6, 0xf8, 0xa0, 0x01,
+	0x43, 0xe8, 0xa6, 0x4c, 0xa6, 0xec, 0xdc, 0x0e, 0xb8, 0x49, 0xab, 0x56, 0xbb, 0x9f, 0x72, 0x91,
+	0xa2, 0x79, 0x7d, 0x93, 0xda, 0x46, 0x3f, 0x5a, 0xd3, 0xb6, 0x8f, 0x8e, 0xff, 0xf8, 0xb0, 0xed,
+	0xb0, 0x09, 0x8a, 0x65, 0x96, 0x22, 0x99, 0x40, 0xc7, 0x5e, 0x24, 0xd9, 0xab, 0xb9, 0x6e, 0x3a,
+	0xf9, 0xc1, 0xfe, 0x7a, 0x92, 0x4b, 0xfa, 0x2d, 0xf8, 0xc7, 0xa8, 0x48, 0x54, 0x23, 0xaf, 0x5c,
+	0xf8, 0xe0, 0x1f, 0x9b, 0x26, 0x9f, 0x61, 0x43, 0x9f, 0x10, 0xa9, 0xff, 0xc1, 0x1b, 0x2e, 0x7f,
+	0xb0, 0xb7, 0x96, 0xe3, 0x26, 0x9a, 0x40, 0xc7, 0xe6, 0xb0, 0x62, 0xb3, 0xe9, 0x4c, 0x06, 0xfb,
+	0xeb, 0x49, 0xb7, 0xa2, 0x76, 0xcf, 0x2b, 0xa2, 0x4d, 0xd1, 0x0e, 0xf6, 0xd7, 0x93, 0xac, 0xe8,
+	0xbb, 0xfe, 0x69, 0xef, 0xe6, 0xd3, 0x7c, 0xd6, 0x31, 0xdf, 0xe4, 0x57, 0x7f, 0x07, 0x00, 0x51,
+	0x9f, 0xfa, 0xff, 0xb7, 0x05, 0x00, 0x00,
+}
@@ -0,0 +1,217 @@
+// Package metrics contains minimal prometheus-compatible metrics (counters and histograms)
+// exposed in prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are default histogram buckets (in seconds) suitable for request latencies.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector is implemented by metrics which can be exposed by Registry.
+type Collector interface {
+	writeTo(w *bufio.Writer)
+}
+
+// Registry holds set of metrics to expose.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// DefaultRegistry used by package-level functions.
+var DefaultRegistry = NewRegistry()
+
+func (r *Registry) MustRegister(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Expose writes all registered metrics in prometheus text format.
+func (r *Registry) Expose(w io.Writer) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range r.collectors {
+		c.writeTo(bw)
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Expose(w)
+	})
+}
+
+func MustRegister(collectors ...Collector) {
+	DefaultRegistry.MustRegister(collectors...)
+}
+
+func Handler() http.Handler {
+	return DefaultRegistry.Handler()
+}
+
+type vec struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (v *vec) writeHeader(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, typ)
+}
+
+func (v *vec) formatLabels(key string, extra ...string) string {
+	var pairs []string
+	if len(v.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, v.labels[i]+"="+strconv.Quote(value))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	vec
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		vec:    vec{name: name, help: help, labels: labels},
+		values: make(map[string]float64),
+	}
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) writeTo(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w, "counter")
+	keys := make(map[string]struct{}, len(c.values))
+	for k := range c.values {
+		keys[k] = struct{}{}
+	}
+	for _, k := range sortedKeys(keys) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.formatLabels(k), formatFloat(c.values[k]))
+	}
+}
+
+type histogramValue struct {
+	buckets []uint64 // not cumulative
+	sum     float64
+	count   uint64
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	vec
+	bounds []float64
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	return &HistogramVec{
+		vec:    vec{name: name, help: help, labels: labels},
+		bounds: bounds,
+		values: make(map[string]*histogramValue),
+	}
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{buckets: make([]uint64, len(h.bounds))}
+		h.values[key] = hv
+	}
+	if i := sort.SearchFloat64s(h.bounds, value); i < len(h.bounds) {
+		hv.buckets[i]++
+	}
+	hv.sum += value
+	hv.count++
+}
+
+func (h *HistogramVec) writeTo(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w, "histogram")
+	keys := make(map[string]struct{}, len(h.values))
+	for k := range h.values {
+		keys[k] = struct{}{}
+	}
+	for _, k := range sortedKeys(keys) {
+		hv := h.values[k]
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += hv.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(k, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(k, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.formatLabels(k), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.formatLabels(k), hv.count)
+	}
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+)
+
+const (
+	StorageCreated   = "created"
+	StorageUnchanged = "unchanged"
+)
+
+// StorageApplyResponse -- response after applying desired storages list.
+// Applied storages have "created", "updated", "unchanged" or "deleted" (pruned) status.
+//
+// swagger:model
+type StorageApplyResponse struct {
+	Applied []StorageApplyResult `json:"applied"`
+	Failed  []StorageApplyResult `json:"failed"`
+}
+
+// StorageApplyResult -- apply result for one storage
+//
+// swagger:model
+type StorageApplyResult struct {
+	Name string `json:"name"`
+	// One of "created", "updated", "unchanged", "deleted", "invalid", "in-use", "error", "rolled-back"
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Machine-readable error code (cherry error ID), set only for failed storages
+	Code string `json:"code,omitempty"`
+}
+
+func NewStorageApplyResponse() StorageApplyResponse {
+	return StorageApplyResponse{
+		Applied: []StorageApplyResult{},
+		Failed:  []StorageApplyResult{},
+	}
+}
+
+func (resp *StorageApplyResponse) ApplySuccessful(name, status string) {
+	resp.Applied = append(resp.Applied, StorageApplyResult{
+		Name:   name,
+		Status: status,
+	})
+}
+
+func (resp *StorageApplyResponse) ApplyFailed(name string, err error) {
+	cherryErr, ok := err.(*cherry.Err)
+	if !ok {
+		cherryErr = errors.ErrInternal()
+	}
+	status := StorageUpdateError
+	switch {
+	case cherry.Equals(cherryErr, errors.ErrRequestValidationFailed()),
+		cherry.Equals(cherryErr, errors.ErrQuotaExceeded()),
+		cherry.Equals(cherryErr, errors.ErrStorageShrinkBelowUsed()):
+		status = StorageUpdateInvalid
+	case cherry.Equals(cherryErr, errors.ErrStorageHasVolumes()):
+		status = StorageInUse
+	}
+	resp.Failed = append(resp.Failed, StorageApplyResult{
+		Name:    name,
+		Status:  status,
+		Message: err.Error(),
+		Code:    cherryErr.ID.String(),
+	})
+}
+
+// RollBack moves all applied storages to failed list with "rolled-back" status.
+func (resp *StorageApplyResponse) RollBack() {
+	for _, applied := range resp.Applied {
+		resp.Failed = append(resp.Failed, StorageApplyResult{
+			Name:    applied.Name,
+			Status:  StorageRolledBack,
+			Message: "changes discarded because of other storages apply failure",
+		})
+	}
+	resp.Applied = []StorageApplyResult{}
+}
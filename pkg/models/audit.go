@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+const (
+	AuditCreate  = "create"
+	AuditImport  = "import"
+	AuditUpdate  = "update"
+	AuditPatch   = "patch"
+	AuditDelete  = "delete"
+	AuditPurge   = "purge"
+	AuditRestore = "restore"
+	// AuditTerminate is written when storage with volumes is marked for deletion, AuditDelete follows when it is deleted
+	AuditTerminate = "terminate"
+
+	AuditSetDefault  = "set_default"
+	AuditRename      = "rename"
+	AuditClone       = "clone"
+	AuditMaintenance = "maintenance"
+	AuditRecompute   = "recompute_usage"
+	AuditSync        = "sync"
+	AuditMigrate     = "migrate_volumes"
+	AuditLabels      = "update_labels"
+	AuditTransfer    = "transfer_owner"
+	AuditPin         = "pin"
+	AuditUnpin       = "unpin"
+)
+
+// StorageAuditRecord describes one mutating operation on storage
+//
+// swagger:model
+type StorageAuditRecord struct {
+	tableName struct{} `sql:"storage_audit"`
+
+	ID int64 `sql:"id,pk" json:"id"`
+
+	StorageName string `sql:"storage_name,notnull" json:"storage_name"`
+
+	// One of "create", "import", "update", "patch", "delete", "purge", "restore", "set_default", "rename", "clone", "maintenance", "recompute_usage", "sync", "migrate_volumes", "update_labels", "transfer_owner", "pin", "unpin"
+	Operation string `sql:"operation,notnull" json:"operation"`
+
+	// swagger:strfmt uuid
+	UserID string `sql:"user_id" json:"user_id,omitempty"`
+
+	// ID of admin made request on behalf of UserID, empty if user was not substituted
+	// swagger:strfmt uuid
+	ImpersonatorID string `sql:"impersonator_id,type:uuid" json:"impersonator_id,omitempty"`
+
+	// Storage state before operation, empty for created storages
+	Before *Storage `sql:"before,type:jsonb" json:"before,omitempty"`
+
+	// Storage state after operation, empty for deleted storages
+	After *Storage `sql:"after,type:jsonb" json:"after,omitempty"`
+
+	Timestamp time.Time `sql:"timestamp,notnull" json:"timestamp"`
+}
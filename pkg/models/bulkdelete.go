@@ -0,0 +1,88 @@
+package model
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+)
+
+// BulkDeleteStoragesRequest -- request to delete several storages
+//
+// swagger:model
+type BulkDeleteStoragesRequest struct {
+	Names []string `json:"names" binding:"required,min=1"`
+}
+
+const (
+	StorageDeleted     = "deleted"
+	StorageNotFound    = "not-found"
+	StorageInUse       = "in-use"
+	StorageDeleteError = "error"
+	// StorageRolledBack is set for storages which deletion was discarded because of atomic operation failure
+	StorageRolledBack = "rolled-back"
+)
+
+// StorageBulkDeleteResponse -- response after bulk storages deletion
+//
+// swagger:model
+type StorageBulkDeleteResponse struct {
+	Deleted []StorageBulkDeleteResult `json:"deleted"`
+	Failed  []StorageBulkDeleteResult `json:"failed"`
+}
+
+// StorageBulkDeleteResult -- deletion result for one storage
+//
+// swagger:model
+type StorageBulkDeleteResult struct {
+	Name string `json:"name"`
+	// One of "deleted", "not-found", "in-use", "error", "rolled-back"
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Machine-readable error code (cherry error ID), set only for failed deletions
+	Code string `json:"code,omitempty"`
+}
+
+func NewStorageBulkDeleteResponse() StorageBulkDeleteResponse {
+	return StorageBulkDeleteResponse{
+		Deleted: []StorageBulkDeleteResult{},
+		Failed:  []StorageBulkDeleteResult{},
+	}
+}
+
+func (resp *StorageBulkDeleteResponse) DeleteSuccessful(name string) {
+	resp.Deleted = append(resp.Deleted, StorageBulkDeleteResult{
+		Name:   name,
+		Status: StorageDeleted,
+	})
+}
+
+func (resp *StorageBulkDeleteResponse) DeleteFailed(name string, err error) {
+	cherryErr, ok := err.(*cherry.Err)
+	if !ok {
+		cherryErr = errors.ErrInternal()
+	}
+	status := StorageDeleteError
+	switch {
+	case cherry.Equals(cherryErr, errors.ErrResourceNotExists()):
+		status = StorageNotFound
+	case cherry.Equals(cherryErr, errors.ErrStorageHasVolumes()):
+		status = StorageInUse
+	}
+	resp.Failed = append(resp.Failed, StorageBulkDeleteResult{
+		Name:    name,
+		Status:  status,
+		Message: err.Error(),
+		Code:    cherryErr.ID.String(),
+	})
+}
+
+// RollBack moves all deleted storages to failed list with "rolled-back" status.
+func (resp *StorageBulkDeleteResponse) RollBack() {
+	for _, deleted := range resp.Deleted {
+		resp.Failed = append(resp.Failed, StorageBulkDeleteResult{
+			Name:    deleted.Name,
+			Status:  StorageRolledBack,
+			Message: "deletion discarded because of other storages deletion failure",
+		})
+	}
+	resp.Deleted = []StorageBulkDeleteResult{}
+}
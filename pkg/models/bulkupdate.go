@@ -0,0 +1,95 @@
+package model
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+)
+
+// BulkUpdateStoragesRequest -- request to patch several storages
+//
+// swagger:model
+type BulkUpdateStoragesRequest struct {
+	Updates []StorageBulkUpdateEntry `json:"updates" binding:"required,min=1,dive"`
+}
+
+// StorageBulkUpdateEntry -- partial update of one storage, same as PatchStorage request
+//
+// swagger:model
+type StorageBulkUpdateEntry struct {
+	Name  string              `json:"name" binding:"required"`
+	Patch PatchStorageRequest `json:"patch"`
+}
+
+const (
+	StorageUpdated     = "updated"
+	StorageUpdateError = "error"
+	// StorageUpdateInvalid is set for storages which patch can't be applied (e.g. size is out of limits)
+	StorageUpdateInvalid = "invalid"
+)
+
+// StorageBulkUpdateResponse -- response after bulk storages update
+//
+// swagger:model
+type StorageBulkUpdateResponse struct {
+	Updated []StorageBulkUpdateResult `json:"updated"`
+	Failed  []StorageBulkUpdateResult `json:"failed"`
+}
+
+// StorageBulkUpdateResult -- update result for one storage
+//
+// swagger:model
+type StorageBulkUpdateResult struct {
+	Name string `json:"name"`
+	// One of "updated", "not-found", "invalid", "error", "rolled-back"
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Machine-readable error code (cherry error ID), set only for failed updates
+	Code string `json:"code,omitempty"`
+}
+
+func NewStorageBulkUpdateResponse() StorageBulkUpdateResponse {
+	return StorageBulkUpdateResponse{
+		Updated: []StorageBulkUpdateResult{},
+		Failed:  []StorageBulkUpdateResult{},
+	}
+}
+
+func (resp *StorageBulkUpdateResponse) UpdateSuccessful(name string) {
+	resp.Updated = append(resp.Updated, StorageBulkUpdateResult{
+		Name:   name,
+		Status: StorageUpdated,
+	})
+}
+
+func (resp *StorageBulkUpdateResponse) UpdateFailed(name string, err error) {
+	cherryErr, ok := err.(*cherry.Err)
+	if !ok {
+		cherryErr = errors.ErrInternal()
+	}
+	status := StorageUpdateError
+	switch {
+	case cherry.Equals(cherryErr, errors.ErrResourceNotExists()):
+		status = StorageNotFound
+	case cherry.Equals(cherryErr, errors.ErrRequestValidationFailed()),
+		cherry.Equals(cherryErr, errors.ErrQuotaExceeded()):
+		status = StorageUpdateInvalid
+	}
+	resp.Failed = append(resp.Failed, StorageBulkUpdateResult{
+		Name:    name,
+		Status:  status,
+		Message: err.Error(),
+		Code:    cherryErr.ID.String(),
+	})
+}
+
+// RollBack moves all updated storages to failed list with "rolled-back" status.
+func (resp *StorageBulkUpdateResponse) RollBack() {
+	for _, updated := range resp.Updated {
+		resp.Failed = append(resp.Failed, StorageBulkUpdateResult{
+			Name:    updated.Name,
+			Status:  StorageRolledBack,
+			Message: "update discarded because of other storages update failure",
+		})
+	}
+	resp.Updated = []StorageBulkUpdateResult{}
+}
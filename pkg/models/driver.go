@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/containerum/kube-client/pkg/model"
+)
+
+// Storage backend drivers
+const (
+	StorageDriverNFS     = "nfs"
+	StorageDriverCephRBD = "ceph-rbd"
+	StorageDriverLocal   = "local"
+)
+
+// StorageDrivers lists supported storage drivers. Storages created before drivers introduction have empty driver.
+var StorageDrivers = []string{StorageDriverNFS, StorageDriverCephRBD, StorageDriverLocal}
+
+// sharedStorageDrivers contains drivers which volumes may be mounted on several nodes at once.
+var sharedStorageDrivers = map[string]bool{
+	StorageDriverNFS: true,
+}
+
+// ValidateStorageDriver checks that driver is empty or one of StorageDrivers.
+func ValidateStorageDriver(driver string) error {
+	if driver == "" {
+		return nil
+	}
+	for _, known := range StorageDrivers {
+		if driver == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown driver %q, supported drivers: %s", driver, strings.Join(StorageDrivers, ", "))
+}
+
+// VolumeAccessMode returns access mode of volumes created on storage.
+// Empty mode is returned for storages without driver.
+func (s Storage) VolumeAccessMode() model.PersistentVolumeAccessMode {
+	switch {
+	case s.Driver == "":
+		return ""
+	case sharedStorageDrivers[s.Driver]:
+		return model.ReadWriteMany
+	default:
+		return model.ReadWriteOnce
+	}
+}
+
+// SupportsAccessMode checks that storage volumes can be used with access mode.
+// Volumes of not shared storages (e.g. local) can't be mounted on several nodes.
+func (s Storage) SupportsAccessMode(mode model.PersistentVolumeAccessMode) bool {
+	if s.Driver == "" || sharedStorageDrivers[s.Driver] {
+		return true
+	}
+	return mode == "" || mode == model.ReadWriteOnce
+}
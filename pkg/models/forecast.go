@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+const (
+	ForecastGrowing          = "growing"
+	ForecastNotGrowing       = "not_growing"
+	ForecastFull             = "full"
+	ForecastInsufficientData = "insufficient_data"
+)
+
+// StorageUsageSample is storage used capacity at some moment.
+//
+// swagger:ignore
+type StorageUsageSample struct {
+	Time time.Time
+	Used int
+}
+
+// StorageCapacityForecast -- linear projection of storage used capacity
+//
+// swagger:model
+type StorageCapacityForecast struct {
+	StorageName string `json:"storage_name"`
+	// Capacity available for volumes (GiB) according to reservation and overcommit ratio
+	Capacity int `json:"capacity"`
+	Used     int `json:"used"`
+	// Start of usage history window forecast is based on
+	Since time.Time `json:"since"`
+	// Number of usage samples in window
+	Samples int `json:"samples"`
+	// One of "growing", "not_growing", "full", "insufficient_data"
+	Status string `json:"status"`
+	// Explanation of status if forecast is not available
+	Message string `json:"message,omitempty"`
+	// Used capacity growth rate (GiB per day), not set if there is not enough data
+	GrowthPerDay *float64 `json:"growth_per_day,omitempty"`
+	// Days until storage is full at current growth rate, set only for growing storages
+	DaysUntilFull *float64 `json:"days_until_full,omitempty"`
+	// Projected time when storage becomes full, set only for growing storages expected to be full in 100 years
+	FullAt *time.Time `json:"full_at,omitempty"`
+}
@@ -0,0 +1,197 @@
+package model
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/kube-client/pkg/model"
+)
+
+// DefaultImportStorageSize is used for imported storages without explicit size
+const DefaultImportStorageSize = 100
+
+// StorageImportEntry -- storage to import.
+// Plain JSON string is also accepted as storage name for backward compatibility.
+// Storages export returns entries in the same format.
+//
+// swagger:model
+type StorageImportEntry struct {
+	Name string `json:"name"`
+
+	Description string `json:"description,omitempty"`
+
+	// Storage size, DefaultImportStorageSize used if omitted
+	Size *int `json:"size,omitempty"`
+
+	Reserved int `json:"reserved,omitempty"`
+
+	OvercommitRatio *float64 `json:"overcommit_ratio,omitempty"`
+
+	WarnThreshold *int `json:"warn_threshold,omitempty"`
+
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	Driver string `json:"driver,omitempty"`
+
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// NewStorageImportEntry creates import entry which recreates storage on import.
+func NewStorageImportEntry(storage Storage) StorageImportEntry {
+	ret := StorageImportEntry{
+		Name:        storage.Name,
+		Description: storage.Description,
+		Size:        &storage.Size,
+		Reserved:    storage.Reserved,
+		ReadOnly:    storage.ReadOnly,
+		Labels:      storage.Labels,
+		Annotations: storage.Annotations,
+
+		Driver:     storage.Driver,
+		Namespaces: storage.Namespaces,
+	}
+	if storage.OvercommitRatio != 0 {
+		ret.OvercommitRatio = &storage.OvercommitRatio
+	}
+	if storage.WarnThreshold != 0 {
+		ret.WarnThreshold = &storage.WarnThreshold
+	}
+	return ret
+}
+
+func (e *StorageImportEntry) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*e = StorageImportEntry{Name: name}
+		return nil
+	}
+	type plainEntry StorageImportEntry
+	return json.Unmarshal(data, (*plainEntry)(e))
+}
+
+func (e StorageImportEntry) Storage() Storage {
+	size := DefaultImportStorageSize
+	if e.Size != nil {
+		size = *e.Size
+	}
+	ret := Storage{
+		Name:        e.Name,
+		Description: e.Description,
+		Size:        size,
+		Reserved:    e.Reserved,
+		ReadOnly:    e.ReadOnly,
+		Labels:      e.Labels,
+		Annotations: e.Annotations,
+
+		Driver:     e.Driver,
+		Namespaces: e.Namespaces,
+	}
+	if e.OvercommitRatio != nil {
+		ret.OvercommitRatio = *e.OvercommitRatio
+	}
+	if e.WarnThreshold != nil {
+		ret.WarnThreshold = *e.WarnThreshold
+	}
+	return ret
+}
+
+// StorageImportResponse -- response after storages import
+//
+// swagger:model
+type StorageImportResponse struct {
+	Imported []StorageImportResult `json:"imported"`
+	Failed   []StorageImportResult `json:"failed"`
+	// Set if atomic import failed and all storages were discarded.
+	// First failed entry is the one caused import failure.
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+// Storage import failure statuses
+const (
+	StorageImportAlreadyExists = "already-exists"
+	StorageImportError         = "error"
+	// StorageImportRolledBack is set for storages discarded because of atomic import failure
+	StorageImportRolledBack = "rolled-back"
+)
+
+// StorageImportResult -- import result for one storage
+//
+// swagger:model
+type StorageImportResult struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	// One of "already-exists", "error", "rolled-back", set only for failed imports
+	Status string `json:"status,omitempty"`
+	// Machine-readable error code (cherry error ID), set only for failed imports
+	Code string `json:"code,omitempty"`
+	// HTTP status of entry import as if storage was created by separate request:
+	// 201 for imported storages, 409 for existing ones, 400 for invalid entries, 424 for rolled back storages
+	StatusCode int `json:"status_code"`
+	// Line of malformed CSV row, set only for CSV import
+	Line int `json:"line,omitempty"`
+}
+
+func NewStorageImportResponse() StorageImportResponse {
+	return StorageImportResponse{
+		Imported: []StorageImportResult{},
+		Failed:   []StorageImportResult{},
+	}
+}
+
+func (resp *StorageImportResponse) ImportSuccessful(name string) {
+	resp.Imported = append(resp.Imported, StorageImportResult{
+		Name:       name,
+		Message:    model.ImportSuccessfulMessage,
+		StatusCode: http.StatusCreated,
+	})
+}
+
+func (resp *StorageImportResponse) ImportFailed(name string, err error) {
+	cherryErr, ok := err.(*cherry.Err)
+	if !ok {
+		cherryErr = errors.ErrInternal()
+	}
+	status := StorageImportError
+	if cherry.Equals(cherryErr, errors.ErrStorageAlreadyExists()) {
+		status = StorageImportAlreadyExists
+	}
+	resp.Failed = append(resp.Failed, StorageImportResult{
+		Name:       name,
+		Message:    err.Error(),
+		Status:     status,
+		Code:       cherryErr.ID.String(),
+		StatusCode: errors.StatusCode(cherryErr),
+	})
+}
+
+// ImportLineFailed reports failure of CSV import row.
+func (resp *StorageImportResponse) ImportLineFailed(line int, name string, err error) {
+	resp.ImportFailed(name, err)
+	resp.Failed[len(resp.Failed)-1].Line = line
+}
+
+// RollBack reports atomic import failure: storage failedName is reported with error,
+// other storages are reported as rolled back. Imported list becomes empty.
+func (resp *StorageImportResponse) RollBack(names []string, failedName string, err error) {
+	resp.Imported = []StorageImportResult{}
+	resp.Failed = []StorageImportResult{}
+	resp.ImportFailed(failedName, err)
+	for _, name := range names {
+		if name == failedName {
+			continue
+		}
+		resp.Failed = append(resp.Failed, StorageImportResult{
+			Name:       name,
+			Message:    "import discarded because of storage " + failedName + " import failure",
+			Status:     StorageImportRolledBack,
+			StatusCode: http.StatusFailedDependency,
+		})
+	}
+	resp.RolledBack = true
+}
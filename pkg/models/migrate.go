@@ -0,0 +1,73 @@
+package model
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+)
+
+// StorageMigrateRequest -- request to move volumes of storage to other storage
+//
+// swagger:model
+type StorageMigrateRequest struct {
+	// Name of storage which receives volumes
+	Target string `json:"target" binding:"required"`
+	// IDs of volumes to migrate, all storage volumes are migrated if empty
+	VolumeIDs []string `json:"volume_ids,omitempty" binding:"omitempty,dive,uuid"`
+}
+
+// StorageMigrateResponse -- response after volumes migration
+//
+// swagger:model
+type StorageMigrateResponse struct {
+	Source   string                 `json:"source"`
+	Target   string                 `json:"target"`
+	Migrated []StorageMigrateResult `json:"migrated"`
+	Failed   []StorageMigrateResult `json:"failed"`
+}
+
+// StorageMigrateResult -- migration result for one volume
+//
+// swagger:model
+type StorageMigrateResult struct {
+	// swagger:strfmt uuid
+	VolumeID    string `json:"volume_id"`
+	Label       string `json:"label,omitempty"`
+	NamespaceID string `json:"namespace_id,omitempty"`
+	Capacity    int    `json:"capacity,omitempty"`
+	Message     string `json:"message,omitempty"`
+	// Machine-readable error code (cherry error ID), set only for failed volumes
+	Code string `json:"code,omitempty"`
+}
+
+func NewStorageMigrateResponse(source, target string) StorageMigrateResponse {
+	return StorageMigrateResponse{
+		Source:   source,
+		Target:   target,
+		Migrated: []StorageMigrateResult{},
+		Failed:   []StorageMigrateResult{},
+	}
+}
+
+func (resp *StorageMigrateResponse) MigrateSuccessful(volume Volume) {
+	resp.Migrated = append(resp.Migrated, StorageMigrateResult{
+		VolumeID:    volume.ID,
+		Label:       volume.Label,
+		NamespaceID: volume.NamespaceID,
+		Capacity:    volume.Capacity,
+	})
+}
+
+func (resp *StorageMigrateResponse) MigrateFailed(volume Volume, err error) {
+	cherryErr, ok := err.(*cherry.Err)
+	if !ok {
+		cherryErr = errors.ErrInternal()
+	}
+	resp.Failed = append(resp.Failed, StorageMigrateResult{
+		VolumeID:    volume.ID,
+		Label:       volume.Label,
+		NamespaceID: volume.NamespaceID,
+		Capacity:    volume.Capacity,
+		Message:     err.Error(),
+		Code:        cherryErr.ID.String(),
+	})
+}
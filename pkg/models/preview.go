@@ -0,0 +1,49 @@
+package model
+
+import "reflect"
+
+// StorageFieldChange -- value of storage field before and after change
+//
+// swagger:model
+type StorageFieldChange struct {
+	// Name of field in storage JSON representation
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// StorageUpdatePreview -- changes which storage update would make, nothing is persisted
+//
+// swagger:model
+type StorageUpdatePreview struct {
+	Name string `json:"name"`
+	// Changed fields, empty if update changes nothing
+	Changes []StorageFieldChange `json:"changes"`
+}
+
+// DiffStorages returns changes of fields which can be set by storage update. Fields maintained by service
+// (version, timestamps, used capacity) are not compared.
+func DiffStorages(before, after Storage) []StorageFieldChange {
+	fields := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"name", before.Name, after.Name},
+		{"description", before.Description, after.Description},
+		{"size", before.Size, after.Size},
+		{"reserved", before.Reserved, after.Reserved},
+		{"overcommit_ratio", before.OvercommitRatio, after.OvercommitRatio},
+		{"warn_threshold", before.WarnThreshold, after.WarnThreshold},
+		{"read_only", before.ReadOnly, after.ReadOnly},
+		{"namespaces", before.Namespaces, after.Namespaces},
+		{"labels", before.Labels, after.Labels},
+		{"annotations", before.Annotations, after.Annotations},
+	}
+	ret := make([]StorageFieldChange, 0)
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.old, field.new) {
+			ret = append(ret, StorageFieldChange{Field: field.name, Old: field.old, New: field.new})
+		}
+	}
+	return ret
+}
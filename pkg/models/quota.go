@@ -0,0 +1,41 @@
+package model
+
+// QuotaTenant is a kind of storage quota owner
+type QuotaTenant string
+
+const (
+	// QuotaTenantUser limits total size of storages created by user
+	QuotaTenantUser QuotaTenant = "user"
+	// QuotaTenantNamespace limits total size of storages dedicated to namespace (see Storage.Namespaces)
+	QuotaTenantNamespace QuotaTenant = "namespace"
+)
+
+// QuotaAnyTenant is an ID of quota applied to tenants without own quota
+const QuotaAnyTenant = "*"
+
+// StorageQuota limits total size of tenant storages.
+type StorageQuota struct {
+	Tenant QuotaTenant
+	ID     string
+	// MaxSize is a maximum total size of storages, GiB
+	MaxSize int
+}
+
+// StorageQuotaUsage represents usage of tenant storage quota
+//
+// swagger:model
+type StorageQuotaUsage struct {
+	Tenant QuotaTenant `json:"tenant"`
+	ID     string      `json:"id"`
+	// Total size of tenant storages, GiB
+	Used int `json:"used"`
+	// Maximum total size of tenant storages, GiB
+	Limit int `json:"limit"`
+}
+
+// StorageQuotasResponse contains quotas applied to caller
+//
+// swagger:model
+type StorageQuotasResponse struct {
+	Quotas []StorageQuotaUsage `json:"quotas"`
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// StorageSizeRecord describes storage size change
+//
+// swagger:model
+type StorageSizeRecord struct {
+	tableName struct{} `sql:"storage_size_history"`
+
+	ID int64 `sql:"id,pk" json:"id"`
+
+	StorageName string `sql:"storage_name,notnull" json:"storage_name"`
+
+	// Size before change, zero for created storages
+	OldSize int `sql:"old_size,notnull" json:"old_size"`
+
+	Size int `sql:"size,notnull" json:"size"`
+
+	// Audit operation changed size, e.g. "create" or "update"
+	Operation string `sql:"operation,notnull" json:"operation"`
+
+	// swagger:strfmt uuid
+	UserID string `sql:"user_id" json:"user_id,omitempty"`
+
+	Timestamp time.Time `sql:"timestamp,notnull" json:"timestamp"`
+}
+
+// StorageSizeHistoryFilter limits storage size history records.
+type StorageSizeHistoryFilter struct {
+	// Records made before From are skipped if set
+	From *time.Time
+	// Records made after To are skipped if set
+	To *time.Time
+	// Maximal number of records (latest are returned), no limit if zero
+	Limit int
+}
+
+// SizeRecord returns size change record for audit record, false if storage size was not changed by operation.
+func (record StorageAuditRecord) SizeRecord() (StorageSizeRecord, bool) {
+	if record.After == nil || (record.Before != nil && record.Before.Size == record.After.Size) {
+		return StorageSizeRecord{}, false
+	}
+	ret := StorageSizeRecord{
+		StorageName: record.StorageName,
+		Size:        record.After.Size,
+		Operation:   record.Operation,
+		UserID:      record.UserID,
+		Timestamp:   record.Timestamp,
+	}
+	if record.Before != nil {
+		ret.OldSize = record.Before.Size
+	}
+	return ret, true
+}
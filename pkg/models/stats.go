@@ -0,0 +1,52 @@
+package model
+
+// StorageDriverStats contains aggregates of storages with the same driver
+//
+// swagger:model
+type StorageDriverStats struct {
+	Driver string `sql:"driver" json:"driver"`
+	Count  int    `sql:"count" json:"count"`
+	// Total size of storages, GiB
+	Size int `sql:"size" json:"size"`
+	// Total reserved capacity of storages, GiB
+	Reserved int `sql:"reserved" json:"reserved"`
+	// Total capacity of storages available for volumes (Size - Reserved), GiB
+	Allocatable int `sql:"allocatable" json:"allocatable"`
+	// Total used capacity of storages, GiB
+	Used int `sql:"used" json:"used"`
+	// Total free capacity of storages (Allocatable - Used), GiB
+	Free int `sql:"free" json:"free"`
+}
+
+// StorageStats contains cluster-wide aggregates of not deleted storages
+//
+// swagger:model
+type StorageStats struct {
+	Count int `json:"count"`
+	// Total size of storages, GiB
+	Size int `json:"size"`
+	// Total reserved capacity of storages, GiB
+	Reserved int `json:"reserved"`
+	// Total capacity of storages available for volumes, GiB
+	Allocatable int `json:"allocatable"`
+	// Total used capacity of storages, GiB
+	Used int `json:"used"`
+	// Total free capacity of storages, GiB
+	Free int `json:"free"`
+	// Aggregates by storage driver, sorted by driver
+	Drivers []StorageDriverStats `json:"drivers"`
+}
+
+// NewStorageStats sums driver aggregates.
+func NewStorageStats(drivers []StorageDriverStats) StorageStats {
+	ret := StorageStats{Drivers: drivers}
+	for _, d := range drivers {
+		ret.Count += d.Count
+		ret.Size += d.Size
+		ret.Reserved += d.Reserved
+		ret.Allocatable += d.Allocatable
+		ret.Used += d.Used
+		ret.Free += d.Free
+	}
+	return ret
+}
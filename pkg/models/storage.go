@@ -1,9 +1,17 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"git.containerum.net/ch/volume-manager/pkg/utils/units"
 	"github.com/go-pg/pg/orm"
 )
 
@@ -13,17 +21,204 @@ import (
 type Storage struct {
 	tableName struct{} `sql:"storages"`
 
-	Name string `sql:"name,pk,notnull" json:"name" binding:"required"`
+	// Storage ID generated on creation, it does not change on rename
+	//
+	// swagger:strfmt uuid
+	ID string `sql:"id,type:uuid,notnull,unique" json:"id,omitempty" schema:"read_only"`
 
+	Name string `sql:"name,pk,notnull" json:"name" binding:"required" schema:"dns_label"`
+
+	// Human-readable description, at most MaxStorageDescriptionLength characters
+	Description string `sql:"description" json:"description,omitempty" binding:"max=1024"`
+
+	// Size in Gi, may be sent as string with unit, e.g. "100Gi" or "2Ti"
 	Size int `sql:"size,notnull" json:"size" binding:"gt=0"`
 
-	Used int `sql:"used,notnull" json:"used" binding:"gte=0,ltecsfield=Size"`
+	Used int `sql:"used,notnull" json:"used" binding:"gte=0"`
+
+	// Capacity in Gi held back from volumes allocation as a safety margin, must be less than size
+	Reserved int `sql:"reserved,notnull" json:"reserved,omitempty" binding:"omitempty,gte=0,ltfield=Size"`
+
+	// Capacity in Gi available for volumes (Size - Reserved), computed on response
+	Allocatable int `sql:"-" json:"allocatable" schema:"read_only"`
+
+	// Storage backend driver, one of "nfs", "ceph-rbd", "local". Can't be changed after creation.
+	Driver string `sql:"driver,notnull" json:"driver,omitempty" schema:"storage_driver"`
+
+	// Allowed ratio of total volumes size to storage size, values greater than 1 allow oversubscription
+	OvercommitRatio float64 `sql:"overcommit_ratio,notnull,default:1" json:"overcommit_ratio" binding:"omitempty,gt=0"`
+
+	Volumes []*Volume `pg:"fk:storage_id" sql:"-" json:"volumes" schema:"read_only"`
+
+	// Total capacity of storage volumes, computed on request
+	UsedSize *int `sql:"-" json:"used_size,omitempty" schema:"read_only"`
+
+	// Free capacity of storage (Size - Reserved - UsedSize), computed on request
+	FreeSize *int `sql:"-" json:"free_size,omitempty" schema:"read_only"`
+
+	Deleted bool `sql:"deleted,notnull" json:"deleted,omitempty" schema:"read_only"`
+
+	DeleteTime *time.Time `sql:"delete_time" json:"delete_time,omitempty" schema:"read_only"`
+
+	// Storage revision, incremented on every update
+	Version int `sql:"version,notnull,default:1" json:"version" schema:"read_only"`
+
+	CreatedAt time.Time `sql:"created_at,notnull,default:now()" json:"created_at" schema:"read_only"`
+
+	// Time of last storage change
+	UpdatedAt time.Time `sql:"updated_at,notnull,default:now()" json:"updated_at" schema:"read_only"`
+
+	// Time of last volume operation (create, resize, delete or move) on storage, not set if there were none
+	LastUsedAt *time.Time `sql:"last_used_at" json:"last_used_at,omitempty" schema:"read_only"`
+
+	// Arbitrary key/value metadata, e.g. "team": "payments"
+	Labels map[string]string `sql:"labels,type:jsonb" json:"labels,omitempty" schema:"labels"`
+
+	// Free-form metadata, e.g. description or ticket link. Annotations can't be used in selectors and
+	// have no per-value length limit, but their total size is limited.
+	Annotations map[string]string `sql:"annotations,type:jsonb" json:"annotations,omitempty" schema:"annotations"`
+
+	// Storage used for volumes created without storage name, only one storage may be default
+	IsDefault bool `sql:"is_default,notnull" json:"is_default" schema:"read_only"`
+
+	// Read-only storage does not accept new volumes
+	ReadOnly bool `sql:"read_only,notnull" json:"read_only"`
+
+	// Usage percentage of storage capacity to warn about, zero disables warnings
+	WarnThreshold int `sql:"warn_threshold,notnull" json:"warn_threshold,omitempty" binding:"omitempty,gte=0,lte=100"`
+
+	// Storage under maintenance does not accept new volumes, existing volumes keep working
+	Maintenance bool `sql:"maintenance,notnull" json:"maintenance" schema:"read_only"`
+
+	MaintenanceReason string `sql:"maintenance_reason" json:"maintenance_reason,omitempty" schema:"read_only"`
+
+	// Pinned storage is never purged, neither from trash by retention nor by request. It can be set only by admin
+	Pinned bool `sql:"pinned,notnull" json:"pinned" schema:"read_only"`
+
+	// Terminating storage is waiting for deletion until its volumes are gone, it does not accept new volumes
+	Terminating bool `sql:"terminating,notnull" json:"terminating" schema:"read_only"`
+
+	// Time of storage deletion request
+	TerminatingSince *time.Time `sql:"terminating_since" json:"terminating_since,omitempty" schema:"read_only"`
+
+	// Reason of delayed deletion, set if storage still has volumes after termination timeout
+	TerminationStatus string `sql:"termination_status" json:"termination_status,omitempty" schema:"read_only"`
+
+	// IDs of namespaces allowed to see and use storage, empty list means storage is available everywhere
+	Namespaces []string `sql:"namespaces,array" json:"namespaces,omitempty"`
+
+	// ID of user created storage, storage size is counted in user quota
+	OwnerUserID string `sql:"owner_user_id,type:uuid" json:"owner_user_id,omitempty" schema:"read_only"`
+}
+
+// Clone returns new storage with the same configuration. Volumes, usage and default flag are not copied.
+func (s Storage) Clone(name string) Storage {
+	var namespaces []string
+	if s.Namespaces != nil {
+		namespaces = append([]string{}, s.Namespaces...)
+	}
+	return Storage{
+		Name:            name,
+		Description:     s.Description,
+		Driver:          s.Driver,
+		Size:            s.Size,
+		Reserved:        s.Reserved,
+		OvercommitRatio: s.OvercommitRatio,
+		ReadOnly:        s.ReadOnly,
+		WarnThreshold:   s.WarnThreshold,
+		Labels:          copyMap(s.Labels),
+		Annotations:     copyMap(s.Annotations),
+		Namespaces:      namespaces,
+	}
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	ret := make(map[string]string, len(m))
+	for k, v := range m {
+		ret[k] = v
+	}
+	return ret
+}
+
+// AvailableIn checks that storage may be used in namespace.
+func (s Storage) AvailableIn(nsID string) bool {
+	if len(s.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range s.Namespaces {
+		if ns == nsID {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceError returns error for rejected volume creation on storage under maintenance.
+func (s Storage) MaintenanceError() error {
+	err := errors.ErrStorageMaintenance().AddDetailF("storage %s does not accept new volumes", s.Name)
+	if s.MaintenanceReason != "" {
+		err = err.AddDetailF("maintenance reason: %s", s.MaintenanceReason)
+	}
+	return err
+}
 
-	Volumes []*Volume `pg:"fk:storage_id" sql:"-" json:"volumes"`
+// TerminatingError returns error for rejected volume creation on terminating storage.
+func (s Storage) TerminatingError() error {
+	return errors.ErrStorageTerminating().AddDetailF("storage %s is being deleted and does not accept new volumes", s.Name)
+}
 
-	Deleted bool `sql:"deleted,notnull" json:"deleted,omitempty"`
+// Capacity returns maximum total size of volumes which can be placed on storage according to overcommit ratio.
+// Reserved capacity is not available for volumes.
+func (s Storage) Capacity() int {
+	ratio := s.OvercommitRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	return int(math.Floor(float64(s.Size-s.Reserved) * ratio))
+}
 
-	DeleteTime *time.Time `sql:"delete_time" json:"delete_time,omitempty"`
+// UsagePercent returns percentage of storage capacity used by volumes.
+func (s Storage) UsagePercent() float64 {
+	capacity := s.Capacity()
+	if capacity <= 0 {
+		return 0
+	}
+	return float64(s.Used) * 100 / float64(capacity)
+}
+
+// Overutilized checks that storage usage reached warning threshold.
+func (s Storage) Overutilized() bool {
+	return s.WarnThreshold > 0 && s.Used*100 >= s.WarnThreshold*s.Capacity()
+}
+
+// ETag returns entity tag of current storage revision.
+func (s Storage) ETag() string {
+	return `"` + strconv.Itoa(s.Version) + `"`
+}
+
+// MarshalJSON fills computed allocatable capacity.
+func (s Storage) MarshalJSON() ([]byte, error) {
+	type plainStorage Storage
+	plain := plainStorage(s)
+	plain.Allocatable = s.Size - s.Reserved
+	return json.Marshal(plain)
+}
+
+// UnmarshalJSON accepts size as number or as string with unit (see units.ParseSize).
+func (s *Storage) UnmarshalJSON(data []byte) error {
+	type plainStorage Storage
+	aux := struct {
+		*plainStorage
+		Size units.Size `json:"size"`
+	}{plainStorage: (*plainStorage)(s), Size: units.Size(s.Size)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	s.Size = int(aux.Size)
+	return nil
 }
 
 func (s *Storage) BeforeInsert(db orm.DB) error {
@@ -32,14 +227,14 @@ func (s *Storage) BeforeInsert(db orm.DB) error {
 		return err
 	}
 	if cnt > 0 {
-		return errors.ErrResourceAlreadyExists().AddDetailF("storage %s already exists", s.Name)
+		return errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", s.Name)
 	}
 	return nil
 }
 
 func (s *Storage) BeforeUpdate(db orm.DB) error {
-	if s.Size < s.Used {
-		return errors.ErrQuotaExceeded().AddDetailF("storage quota exceeded (%d GiB)", s.Used-s.Size)
+	if capacity := s.Capacity(); capacity < s.Used {
+		return errors.ErrQuotaExceeded().AddDetailF("storage quota exceeded (%d GiB)", s.Used-capacity)
 	}
 	return nil
 }
@@ -48,7 +243,254 @@ func (s *Storage) BeforeUpdate(db orm.DB) error {
 //
 // swagger:model
 type UpdateStorageRequest struct {
-	Name *string `json:"name,omitempty"`
-	Size *int    `json:"size,omitempty" binding:"omitempty,gt=0,gtecsfield=Used"`
-	Used *int    `json:"used,omitempty"`
+	Name *string `json:"name,omitempty" schema:"dns_label"`
+	// Size in Gi, may be sent as string with unit, e.g. "100Gi" or "2Ti"
+	Size *int `json:"size,omitempty" binding:"omitempty,gt=0,gtecsfield=Used"`
+	Used *int `json:"used,omitempty"`
+
+	// Reserved capacity in Gi, must be less than size
+	Reserved *int `json:"reserved,omitempty" binding:"omitempty,gte=0"`
+
+	OvercommitRatio *float64 `json:"overcommit_ratio,omitempty" binding:"omitempty,gt=0"`
+
+	WarnThreshold *int `json:"warn_threshold,omitempty" binding:"omitempty,gte=0,lte=100"`
+
+	ReadOnly *bool `json:"read_only,omitempty"`
+
+	// Allowed namespaces to set, empty list makes storage available everywhere
+	Namespaces *[]string `json:"namespaces,omitempty"`
+
+	// Labels to set instead of current ones, labels are not changed if not set
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// UnmarshalJSON accepts size as number or as string with unit (see units.ParseSize).
+func (r *UpdateStorageRequest) UnmarshalJSON(data []byte) error {
+	type plainRequest UpdateStorageRequest
+	aux := struct {
+		*plainRequest
+		Size *units.Size `json:"size,omitempty"`
+	}{plainRequest: (*plainRequest)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Size != nil {
+		size := int(*aux.Size)
+		r.Size = &size
+	}
+	return nil
+}
+
+// RenameStorageRequest represents request object for storage renaming
+//
+// swagger:model
+type RenameStorageRequest struct {
+	NewName string `json:"new_name" binding:"required"`
+}
+
+// TransferStorageRequest represents request object for storage ownership transfer
+//
+// swagger:model
+type TransferStorageRequest struct {
+	// ID of new storage owner
+	// swagger:strfmt uuid
+	OwnerUserID string `json:"owner_user_id" binding:"required,uuid"`
+}
+
+// StorageMaintenanceRequest represents request object for switching storage maintenance mode
+//
+// swagger:model
+type StorageMaintenanceRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+
+	// Reason shown to users trying to create volumes, ignored if maintenance is disabled
+	Reason string `json:"reason,omitempty"`
+}
+
+// CloneStorageRequest represents request object for storage cloning
+//
+// swagger:model
+type CloneStorageRequest struct {
+	TargetName string `json:"target_name" binding:"required"`
+}
+
+// PatchStorageRequest represents request object for partial storage update.
+// Only non-nil fields are applied.
+//
+// swagger:model
+type PatchStorageRequest struct {
+	Size *int `json:"size,omitempty" binding:"omitempty,gt=0"`
+
+	// Description to set, empty string removes description
+	Description *string `json:"description,omitempty" binding:"omitempty,max=1024"`
+
+	// Reserved capacity in Gi, must be less than size
+	Reserved *int `json:"reserved,omitempty" binding:"omitempty,gte=0"`
+
+	OvercommitRatio *float64 `json:"overcommit_ratio,omitempty" binding:"omitempty,gt=0"`
+
+	WarnThreshold *int `json:"warn_threshold,omitempty" binding:"omitempty,gte=0,lte=100"`
+
+	ReadOnly *bool `json:"read_only,omitempty"`
+
+	// Allowed namespaces to set, empty list makes storage available everywhere
+	Namespaces *[]string `json:"namespaces,omitempty"`
+
+	// Labels to set, null value removes label
+	Labels map[string]*string `json:"labels,omitempty"`
+
+	// Annotations to set, null value removes annotation
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+// MaxStorageDescriptionLength is a maximal length of storage description in characters, must match binding tags.
+const MaxStorageDescriptionLength = 1024
+
+// IsEmpty checks that request contains no changes.
+func (r PatchStorageRequest) IsEmpty() bool {
+	v := reflect.ValueOf(r)
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Field(i).IsNil() {
+			return false
+		}
+	}
+	return true
+}
+
+// StorageLabels -- storage labels after labels update
+//
+// swagger:model
+type StorageLabels struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// StoragePagination contains parameters for storage list pagination.
+// Zero Limit means that all storages should be returned.
+//
+// swagger:ignore
+type StoragePagination struct {
+	Limit  int
+	Cursor string
+}
+
+// StorageListFilter contains parameters for storage list filtering.
+// Zero values means no filtering by corresponding parameter.
+//
+// swagger:ignore
+type StorageListFilter struct {
+	NamePrefix string
+	MinSize    int
+	MaxSize    int
+
+	// Select only storages with driver
+	Driver string
+
+	// Skip UsedSize and FreeSize computation
+	SkipUsage bool
+
+	// Include soft-deleted storages
+	ShowDeleted bool
+
+	Sort StorageSort
+
+	LabelSelector labels.Selector
+
+	// Select only storages created in time window
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Select only storages available in one of Namespaces
+	NamespaceScoped bool
+	Namespaces      []string
+}
+
+const (
+	StorageSortByName      = "name"
+	StorageSortBySize      = "size"
+	StorageSortByCreatedAt = "created_at"
+)
+
+// StorageSort describes storages list ordering. Zero value means ordering by name ascending.
+//
+// swagger:ignore
+type StorageSort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseStorageSort parses sort spec like "name", "-size" ("-" means descending order).
+func ParseStorageSort(spec string) (StorageSort, error) {
+	sort := StorageSort{Field: strings.TrimPrefix(spec, "-"), Desc: strings.HasPrefix(spec, "-")}
+	switch sort.Field {
+	case StorageSortByName, StorageSortBySize, StorageSortByCreatedAt:
+		return sort, nil
+	default:
+		return StorageSort{}, fmt.Errorf("unknown sort key %q, expected one of: %s, %s, %s",
+			spec, StorageSortByName, StorageSortBySize, StorageSortByCreatedAt)
+	}
+}
+
+func (s StorageSort) String() string {
+	field := s.Field
+	if field == "" {
+		field = StorageSortByName
+	}
+	if s.Desc {
+		return "-" + field
+	}
+	return field
+}
+
+// StoragesPage represents one page of storages list
+//
+// swagger:model
+type StoragesPage struct {
+	Storages []Storage `json:"storages"`
+	// Opaque token to get next page, empty on last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total number of storages
+	Total int `json:"total"`
+	// HasPrev is set if page is not the first one, PrevCursor is empty if previous page is the first one
+	HasPrev    bool   `json:"-"`
+	PrevCursor string `json:"-"`
+}
+
+// StoragesCount represents number of storages
+//
+// swagger:model
+type StoragesCount struct {
+	Total int `json:"total"`
+}
+
+// ETagCondition is a list of entity tags from If-Match header.
+// Nil condition matches any storage.
+//
+// swagger:ignore
+type ETagCondition []string
+
+// Matches checks that storage revision satisfies condition.
+func (c ETagCondition) Matches(storage Storage) bool {
+	if c == nil {
+		return true
+	}
+	etag := storage.ETag()
+	for _, tag := range c {
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// StorageUsageRecompute describes storage used capacity correction
+//
+// swagger:model
+type StorageUsageRecompute struct {
+	Name string `json:"name"`
+	// Used capacity before recomputation, GiB
+	UsedBefore int `json:"used_before"`
+	// Used capacity computed from storage volumes, GiB
+	UsedAfter int `json:"used_after"`
+	// Difference between recorded and actual used capacity (UsedBefore - UsedAfter)
+	Drift int `json:"drift"`
 }
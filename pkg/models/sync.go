@@ -0,0 +1,46 @@
+package model
+
+// StorageSyncStatus is a kind of difference between storage in database and on backend
+type StorageSyncStatus string
+
+const (
+	// StorageSyncMissing means that storage exists in database but not on backend
+	StorageSyncMissing StorageSyncStatus = "missing"
+	// StorageSyncExtra means that storage exists on backend but not in database
+	StorageSyncExtra StorageSyncStatus = "extra"
+	// StorageSyncMismatched means that storage parameters in database differ from backend
+	StorageSyncMismatched StorageSyncStatus = "mismatched"
+)
+
+// StorageSyncState contains storage parameters compared on sync
+type StorageSyncState struct {
+	Driver string `json:"driver,omitempty"`
+	Size   int    `json:"size"`
+}
+
+// StorageSyncEntry describes difference of one storage
+//
+// swagger:model
+type StorageSyncEntry struct {
+	Name   string            `json:"name"`
+	Status StorageSyncStatus `json:"status"`
+	// Storage parameters in database
+	Expected *StorageSyncState `json:"expected,omitempty"`
+	// Storage parameters on backend
+	Actual *StorageSyncState `json:"actual,omitempty"`
+	// Names of differing parameters for mismatched storage
+	Fields []string `json:"fields,omitempty"`
+	// Database was corrected to match backend
+	Applied bool `json:"applied"`
+	// Reason of correction failure
+	Error string `json:"error,omitempty"`
+}
+
+// StorageSyncReport contains differences between database and storage backend
+//
+// swagger:model
+type StorageSyncReport struct {
+	// Corrections were requested
+	Apply   bool               `json:"apply"`
+	Entries []StorageSyncEntry `json:"entries"`
+}
@@ -40,12 +40,37 @@ func (v *Volume) BeforeInsert(db orm.DB) error {
 		return errors.ErrResourceAlreadyExists().AddDetailF("volume %s already exists", v.Label)
 	}
 
-	_, err = db.Model(&Storage{Name: v.StorageName}).
+	// check and update in one statement to avoid races between concurrent bindings
+	result, err := db.Model(&Storage{Name: v.StorageName}).
 		WherePK().
+		Where("used + (?) <= FLOOR((size - reserved) * overcommit_ratio)", v.Capacity).
+		Where("NOT read_only").
+		Where("NOT maintenance").
+		Where("NOT terminating").
 		Set("used = used + (?)", v.Capacity).
+		Set("last_used_at = now()").
 		Update()
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() <= 0 {
+		storage := Storage{Name: v.StorageName}
+		if err := db.Model(&storage).Column("read_only", "maintenance", "maintenance_reason", "terminating").WherePK().Select(); err != nil {
+			return err
+		}
+		if storage.ReadOnly {
+			return errors.ErrStorageReadOnly().AddDetailF("storage %s does not accept new volumes", v.StorageName)
+		}
+		if storage.Maintenance {
+			return storage.MaintenanceError()
+		}
+		if storage.Terminating {
+			return storage.TerminatingError()
+		}
+		return errors.ErrStorageOvercommitted().AddDetailF("storage %s has no space for volume %s (%d GiB)", v.StorageName, v.Label, v.Capacity)
+	}
 
-	return err
+	return nil
 }
 
 func (v *Volume) BeforeUpdate(db orm.DB) error {
@@ -54,6 +79,7 @@ func (v *Volume) BeforeUpdate(db orm.DB) error {
 		_, err = db.Model(&Storage{Name: v.StorageName}).
 			WherePK().
 			Set("used = used - ?", v.Capacity).
+			Set("last_used_at = now()").
 			Update()
 	} else {
 		var oldVol Volume
@@ -68,13 +94,22 @@ func (v *Volume) BeforeUpdate(db orm.DB) error {
 			Select(); err != nil {
 			return err
 		}
-		if oldStorage.Used-oldVol.Capacity+v.Capacity > oldStorage.Size {
-			return errors.ErrNoFreeStorages()
+		if oldStorage.ReadOnly && v.Capacity > oldVol.Capacity {
+			return errors.ErrStorageReadOnly().AddDetailF("storage %s does not accept volumes growth", v.StorageName)
 		}
-		_, err = db.Model(&Storage{Name: v.StorageName}).
+		if oldStorage.Used-oldVol.Capacity+v.Capacity > oldStorage.Capacity() {
+			return errors.ErrStorageOvercommitted().AddDetailF("storage %s has no space to resize volume %s", v.StorageName, v.Label)
+		}
+		var result orm.Result
+		result, err = db.Model(&Storage{Name: v.StorageName}).
 			WherePK().
+			Where("used - ? + ? <= FLOOR((size - reserved) * overcommit_ratio)", oldVol.Capacity, v.Capacity).
 			Set("used = used - ? + ?", oldVol.Capacity, v.Capacity).
+			Set("last_used_at = now()").
 			Update(v)
+		if err == nil && result.RowsAffected() <= 0 {
+			err = errors.ErrStorageOvercommitted().AddDetailF("storage %s has no space to resize volume %s", v.StorageName, v.Label)
+		}
 	}
 	return err
 }
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// StorageWebhook is a subscription to storage events delivered by HTTP POST requests
+//
+// swagger:model
+type StorageWebhook struct {
+	tableName struct{} `sql:"storage_webhooks"`
+
+	// swagger:strfmt uuid
+	ID string `sql:"id,pk,type:uuid,default:uuid_generate_v4()" json:"id"`
+
+	// URL receiving events
+	URL string `sql:"url,notnull" json:"url"`
+
+	// Names of delivered events, e.g. "storage_created", all events are delivered if empty
+	Events []string `sql:"events,array" json:"events,omitempty"`
+
+	// Key of payload HMAC-SHA256 signature, returned only on webhook creation
+	Secret string `sql:"secret,notnull" json:"secret,omitempty"`
+
+	CreatedAt time.Time `sql:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+// CreateStorageWebhookRequest represents request object for webhook registration
+//
+// swagger:model
+type CreateStorageWebhookRequest struct {
+	URL string `json:"url" binding:"required,url"`
+
+	// Names of delivered events, all events are delivered if empty
+	Events []string `json:"events,omitempty"`
+
+	// Key of payload signature, random key is generated if omitted
+	Secret string `json:"secret,omitempty" binding:"omitempty,min=16"`
+}
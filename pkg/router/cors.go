@@ -0,0 +1,89 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/tracing"
+	"github.com/containerum/utils/httputil"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSAnyOrigin allows requests from any origin
+const CORSAnyOrigin = "*"
+
+// CORSConfig configures handling of cross-origin requests
+type CORSConfig struct {
+	// AllowedOrigins lists origins allowed to make requests, CORSAnyOrigin allows any origin
+	AllowedOrigins []string
+	// AllowedMethods and AllowedHeaders are added to DefaultCORSMethods and DefaultCORSHeaders
+	AllowedMethods []string
+	AllowedHeaders []string
+}
+
+// DefaultCORSMethods are methods used by API.
+var DefaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// DefaultCORSHeaders are request headers used by API.
+var DefaultCORSHeaders = []string{
+	"Origin",
+	"Content-Type",
+	"Content-Length",
+	"Accept",
+	httputil.UserIDXHeader,
+	httputil.UserRoleXHeader,
+	httputil.UserNamespacesXHeader,
+	httputil.UserVolumesXHeader,
+	middleware.IdempotencyKeyHeader,
+	ifMatchHeader,
+	ifNoneMatchHeader,
+	tracing.TraceParentHeader,
+}
+
+// corsExposedHeaders are response headers available to scripts.
+var corsExposedHeaders = []string{eTagHeader, totalCountHeader, linkHeader}
+
+// Validate checks that origins list is not empty and each origin is CORSAnyOrigin or an URL with http(s) scheme.
+func (cfg CORSConfig) Validate() error {
+	if len(cfg.AllowedOrigins) == 0 {
+		return fmt.Errorf("CORS allowed origins list is empty")
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin != CORSAnyOrigin && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("CORS origin %q must be %q or start with http:// or https://", origin, CORSAnyOrigin)
+		}
+	}
+	return nil
+}
+
+// newCORSMiddleware returns middleware which handles preflight requests and adds CORS headers to responses.
+// Requests from not allowed origins are rejected with 403. Config must be valid.
+func newCORSMiddleware(cfg CORSConfig) gin.HandlerFunc {
+	corsCfg := cors.Config{
+		AllowMethods:  append(append([]string{}, DefaultCORSMethods...), cfg.AllowedMethods...),
+		AllowHeaders:  append(append([]string{}, DefaultCORSHeaders...), cfg.AllowedHeaders...),
+		ExposeHeaders: corsExposedHeaders,
+		MaxAge:        12 * time.Hour,
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == CORSAnyOrigin {
+			corsCfg.AllowAllOrigins = true
+		}
+	}
+	if !corsCfg.AllowAllOrigins {
+		corsCfg.AllowOrigins = cfg.AllowedOrigins
+	}
+	return cors.New(corsCfg)
+}
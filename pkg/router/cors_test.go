@@ -0,0 +1,81 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/server"
+	"git.containerum.net/ch/volume-manager/pkg/utils/validation"
+	"github.com/appleboy/gofight"
+	kubeModel "github.com/containerum/kube-client/pkg/model"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/universal-translator"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCORS(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+
+	db := &storagesDB{storages: map[string]model.Storage{"storage-1": {Name: "storage-1", Size: 10}}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{
+		CORS: &CORSConfig{AllowedOrigins: []string{"https://admin.example.com"}},
+	})
+	r.SetupStorageHandlers(srv)
+
+	const origin = "https://admin.example.com"
+	preflight := func(origin, path string) (ret gofight.HTTPResponse) {
+		gofight.New().OPTIONS(path).
+			SetHeader(gofight.H{
+				"Origin":                         origin,
+				"Access-Control-Request-Method":  http.MethodPatch,
+				"Access-Control-Request-Headers": headers.UserIDXHeader + ", " + headers.UserRoleXHeader,
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test CORS", t, func() {
+		Convey("Check preflight request without user headers", func() {
+			resp := preflight(origin, "/storages/storage-1")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.HeaderMap.Get("Access-Control-Allow-Origin"), ShouldEqual, origin)
+			So(resp.HeaderMap.Get("Access-Control-Allow-Methods"), ShouldContainSubstring, http.MethodPatch)
+			allowed := strings.ToLower(resp.HeaderMap.Get("Access-Control-Allow-Headers"))
+			So(allowed, ShouldContainSubstring, strings.ToLower(headers.UserIDXHeader))
+			So(allowed, ShouldContainSubstring, strings.ToLower(headers.UserRoleXHeader))
+		})
+		Convey("Check not allowed origin is rejected", func() {
+			So(preflight("https://evil.example.com", "/storages/storage-1").Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Check response headers", func() {
+			gofight.New().GET("/storages/storage-1").
+				SetHeader(gofight.H{
+					"Origin":                origin,
+					headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+					headers.UserRoleXHeader: "admin",
+				}).
+				Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+					So(r.Code, ShouldEqual, http.StatusOK)
+					So(r.HeaderMap.Get("Access-Control-Allow-Origin"), ShouldEqual, origin)
+					So(r.HeaderMap.Get("Access-Control-Expose-Headers"), ShouldContainSubstring, "Etag")
+				})
+		})
+		Convey("Check config validation", func() {
+			So(CORSConfig{}.Validate(), ShouldNotBeNil)
+			So(CORSConfig{AllowedOrigins: []string{"admin.example.com"}}.Validate(), ShouldNotBeNil)
+			So(CORSConfig{AllowedOrigins: []string{CORSAnyOrigin}}.Validate(), ShouldBeNil)
+		})
+	})
+}
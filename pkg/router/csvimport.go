@@ -0,0 +1,163 @@
+package router
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"git.containerum.net/ch/volume-manager/pkg/utils/units"
+	"git.containerum.net/ch/volume-manager/pkg/utils/validation"
+)
+
+const mimeCSV = "text/csv"
+
+// csvImportColumns contains columns of storages import CSV in default order
+var csvImportColumns = []string{"name", "size", "labels"}
+
+// csvRowError describes malformed CSV row.
+type csvRowError struct {
+	Line int
+	// Name from row, may be empty or invalid
+	Name string
+	Err  error
+}
+
+// parseImportCSV parses storages import entries from CSV with columns "name", "size" and "labels".
+// First row is a header if it starts with "name", other columns may be reordered or omitted then.
+// Size may have unit (see units.ParseSize), labels are written as "key=value,key2=value2".
+// Malformed rows are returned as row errors, error is returned only if CSV can't be read at all.
+func parseImportCSV(r io.Reader) ([]model.StorageImportEntry, []csvRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []model.StorageImportEntry
+	var rowErrs []csvRowError
+	columns := csvImportColumns
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			rowErrs = append(rowErrs, csvRowError{Line: parseErr.StartLine, Err: errors.ErrRequestValidationFailed().AddDetailsErr(parseErr.Err)})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			if columns, err = parseCSVHeader(record); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			continue
+		}
+
+		entry, err := parseCSVRow(columns, record)
+		if err == nil {
+			err = validateCSVEntry(entry)
+		}
+		if err != nil {
+			rowErrs = append(rowErrs, csvRowError{Line: line, Name: entry.Name, Err: err})
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rowErrs, nil
+}
+
+func parseCSVHeader(record []string) ([]string, error) {
+	known := make(map[string]bool, len(csvImportColumns))
+	for _, column := range csvImportColumns {
+		known[column] = true
+	}
+	seen := make(map[string]bool, len(record))
+	columns := make([]string, 0, len(record))
+	for _, column := range record {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch {
+		case !known[column]:
+			return nil, fmt.Errorf("unknown column %q, known columns: %s", column, strings.Join(csvImportColumns, ", "))
+		case seen[column]:
+			return nil, fmt.Errorf("column %q is listed several times", column)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func parseCSVRow(columns, record []string) (model.StorageImportEntry, error) {
+	var entry model.StorageImportEntry
+	if len(record) != len(columns) {
+		if len(record) > 0 {
+			entry.Name = strings.TrimSpace(record[0])
+		}
+		return entry, errors.ErrRequestValidationFailed().AddDetailF("expected %d fields (%s), got %d",
+			len(columns), strings.Join(columns, ", "), len(record))
+	}
+	for i, column := range columns {
+		value := strings.TrimSpace(record[i])
+		switch column {
+		case "name":
+			entry.Name = value
+		case "size":
+			if value == "" {
+				continue
+			}
+			size, err := units.ParseSize(value)
+			if err == nil && size <= 0 {
+				err = fmt.Errorf("size must be positive")
+			}
+			if err != nil {
+				return entry, errors.ErrRequestValidationFailed().AddDetailsErr(err)
+			}
+			entry.Size = &size
+		case "labels":
+			labels, err := parseCSVLabels(value)
+			if err != nil {
+				return entry, errors.ErrRequestValidationFailed().AddDetailsErr(err)
+			}
+			entry.Labels = labels
+		}
+	}
+	return entry, nil
+}
+
+// parseCSVLabels parses labels written as "key=value,key2=value2".
+func parseCSVLabels(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	ret := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", strings.TrimSpace(pair))
+		}
+		key := strings.TrimSpace(kv[0])
+		if _, ok := ret[key]; ok {
+			return nil, fmt.Errorf("label %q is listed several times", key)
+		}
+		ret[key] = strings.TrimSpace(kv[1])
+	}
+	return ret, nil
+}
+
+// validateCSVEntry checks row entry format, so invalid rows are reported with line numbers.
+// Entries are validated by server too. Names may be case-insensitive, so name case is not checked here.
+func validateCSVEntry(entry model.StorageImportEntry) error {
+	if err := validation.DNSLabel(strings.ToLower(entry.Name)); err != nil {
+		return errors.ErrRequestValidationFailed().AddDetailsErr(err)
+	}
+	if err := labels.Validate(entry.Labels); err != nil {
+		return errors.ErrRequestValidationFailed().AddDetailsErr(err)
+	}
+	return nil
+}
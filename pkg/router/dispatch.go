@@ -0,0 +1,64 @@
+package router
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type dispatchedHandler struct {
+	operation string
+	handlers  []gin.HandlerFunc
+}
+
+// segmentDispatcher routes requests by fixed value of path param.
+// Gin router can't register paths like "/storages/stats" next to "/storages/:name"
+// because of wildcard conflicts, so such paths registered as "/storages/:name" and dispatched here.
+type segmentDispatcher struct {
+	param    string
+	handlers map[string]dispatchedHandler
+	fallback []gin.HandlerFunc
+}
+
+// newSegmentDispatcher creates dispatcher. Fallback handlers chain is called for not registered segments,
+// if it is empty 404 is returned.
+func newSegmentDispatcher(param string, fallback ...gin.HandlerFunc) *segmentDispatcher {
+	return &segmentDispatcher{
+		param:    param,
+		handlers: make(map[string]dispatchedHandler),
+		fallback: fallback,
+	}
+}
+
+// handle registers handlers chain for fixed path segment. Operation is used as metrics label.
+// Handlers are called sequentially until one of them aborts request, so they must not call ctx.Next().
+func (d *segmentDispatcher) handle(segment, operation string, handlers ...gin.HandlerFunc) {
+	d.handlers[segment] = dispatchedHandler{operation: operation, handlers: handlers}
+}
+
+func (d *segmentDispatcher) reserved(segment string) bool {
+	_, ok := d.handlers[segment]
+	return ok
+}
+
+func (d *segmentDispatcher) dispatch(ctx *gin.Context) {
+	if h, ok := d.handlers[ctx.Param(d.param)]; ok {
+		middleware.SetStorageOperation(ctx, h.operation)
+		runChain(ctx, h.handlers)
+		return
+	}
+	if len(d.fallback) > 0 {
+		runChain(ctx, d.fallback)
+		return
+	}
+	middleware.AbortWithError(ctx, errors.ErrResourceNotExists().AddDetailF("path %s not found", ctx.Request.URL.Path))
+}
+
+func runChain(ctx *gin.Context, handlers []gin.HandlerFunc) {
+	for _, handler := range handlers {
+		if ctx.IsAborted() {
+			return
+		}
+		handler(ctx)
+	}
+}
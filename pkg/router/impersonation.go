@@ -0,0 +1,33 @@
+package router
+
+import (
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/server"
+	"github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// substitutedUserIDParam is a query parameter used by admins to act on behalf of another user (SubstitutedUserID)
+const substitutedUserIDParam = "user-id"
+
+// impersonationAudit checks user substitution made with SubstitutedUserID parameter. Substitution of other user
+// is rejected for non-admins. Real admin ID is saved to request context, so audit records of storage operations
+// contain both admin and impersonated user.
+func impersonationAudit(ctx *gin.Context) {
+	userID, set := ctx.GetQuery(substitutedUserIDParam)
+	adminID := middleware.GetHeader(ctx, httputil.UserIDXHeader)
+	if !set || userID == adminID {
+		return
+	}
+	if middleware.GetHeader(ctx, httputil.UserRoleXHeader) != middleware.RoleAdmin {
+		middleware.AbortWithError(ctx, errors.ErrAdminRequired().AddDetailF("only admin can act on behalf of another user"))
+		return
+	}
+	middleware.GetLogger(ctx).WithFields(logrus.Fields{
+		"admin_id": adminID,
+		"user_id":  userID,
+	}).Info("admin acts on behalf of user")
+	ctx.Request = ctx.Request.WithContext(server.ContextWithImpersonator(ctx.Request.Context(), adminID))
+}
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+)
+
+const DefaultImportConcurrency = 8
+
+// importStorages imports entries using at most concurrency parallel workers.
+// Every entry name appears in response exactly once: entries with duplicated names are not imported
+// and reported as one failure, entries not dispatched before context cancellation are reported as failed.
+func importStorages(ctx context.Context, entries []model.StorageImportEntry, concurrency int,
+	importFn func(ctx context.Context, entry model.StorageImportEntry) error) model.StorageImportResponse {
+	resp := model.NewStorageImportResponse()
+	var mu sync.Mutex
+
+	// names may be case-insensitive, so names differing by case are duplicates too
+	counts := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		counts[strings.ToLower(entry.Name)]++
+	}
+	unique := make([]model.StorageImportEntry, 0, len(entries))
+	for _, entry := range entries {
+		switch counts[strings.ToLower(entry.Name)] {
+		case 0: // duplicate already reported
+		case 1:
+			unique = append(unique, entry)
+		default:
+			resp.ImportFailed(entry.Name, errors.ErrRequestValidationFailed().AddDetailF("storage %s is listed several times", entry.Name))
+			counts[strings.ToLower(entry.Name)] = 0
+		}
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan model.StorageImportEntry)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range jobs {
+				err := importFn(ctx, entry)
+				mu.Lock()
+				if err != nil {
+					resp.ImportFailed(entry.Name, err)
+				} else {
+					resp.ImportSuccessful(entry.Name)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i, entry := range unique {
+		if ctx.Err() == nil {
+			select {
+			case jobs <- entry:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		mu.Lock()
+		for _, skipped := range unique[i:] {
+			resp.ImportFailed(skipped.Name, errors.ErrOperationTimeout().AddDetailF("import cancelled: %v", ctx.Err()))
+		}
+		mu.Unlock()
+		break
+	}
+	close(jobs)
+	wg.Wait()
+
+	return resp
+}
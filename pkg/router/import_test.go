@@ -0,0 +1,116 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImportStorages(t *testing.T) {
+	entries := func(names ...string) []model.StorageImportEntry {
+		ret := make([]model.StorageImportEntry, len(names))
+		for i, name := range names {
+			ret[i] = model.StorageImportEntry{Name: name}
+		}
+		return ret
+	}
+	resultNames := func(results []model.StorageImportResult) []string {
+		ret := make([]string, 0, len(results))
+		for _, r := range results {
+			ret = append(ret, r.Name)
+		}
+		return ret
+	}
+
+	Convey("Test concurrent storages import", t, func() {
+		Convey("Check concurrency is bounded", func() {
+			var running, maxRunning int32
+			var names []string
+			for i := 0; i < 20; i++ {
+				names = append(names, fmt.Sprintf("storage-%d", i))
+			}
+			resp := importStorages(context.Background(), entries(names...), 3, func(ctx context.Context, entry model.StorageImportEntry) error {
+				cur := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if cur <= m || atomic.CompareAndSwapInt32(&maxRunning, m, cur) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+			So(resultNames(resp.Imported), ShouldHaveLength, 20)
+			So(resp.Failed, ShouldBeEmpty)
+			So(maxRunning, ShouldBeLessThanOrEqualTo, 3)
+		})
+		Convey("Check failures and duplicates are reported once", func() {
+			resp := importStorages(context.Background(), entries("a", "b", "b", "c"), 2, func(ctx context.Context, entry model.StorageImportEntry) error {
+				if entry.Name == "c" {
+					return fmt.Errorf("import failed")
+				}
+				return nil
+			})
+			So(resultNames(resp.Imported), ShouldResemble, []string{"a"})
+			So(resultNames(resp.Failed), ShouldHaveLength, 2)
+			So(resultNames(resp.Failed), ShouldContain, "b")
+			So(resultNames(resp.Failed), ShouldContain, "c")
+		})
+		Convey("Check cancellation stops dispatching", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			resp := importStorages(ctx, entries("a", "b", "c", "d"), 1, func(ctx context.Context, entry model.StorageImportEntry) error {
+				if entry.Name == "b" {
+					cancel()
+				}
+				return nil
+			})
+			So(len(resp.Imported)+len(resp.Failed), ShouldEqual, 4)
+			So(resultNames(resp.Imported), ShouldContain, "a")
+			So(resultNames(resp.Imported), ShouldContain, "b")
+			So(resultNames(resp.Failed), ShouldContain, "d")
+		})
+	})
+}
+
+func TestParseImportCSV(t *testing.T) {
+	Convey("Test storages import CSV parsing", t, func() {
+		Convey("Check default columns", func() {
+			entries, rowErrs, err := parseImportCSV(strings.NewReader("storage-1,10,team=payments\nstorage-2,,\n"))
+			So(err, ShouldBeNil)
+			So(rowErrs, ShouldBeEmpty)
+			So(entries, ShouldHaveLength, 2)
+			So(*entries[0].Size, ShouldEqual, 10)
+			So(entries[0].Labels, ShouldResemble, map[string]string{"team": "payments"})
+			So(entries[1].Size, ShouldBeNil)
+		})
+		Convey("Check malformed rows are reported with line numbers", func() {
+			input := "name,size\n" +
+				"storage-1,10\n" +
+				"storage-2,10,extra\n" +
+				"storage-3,-1\n" +
+				"storage-\"4,10\n" +
+				"storage-5,10\n"
+			entries, rowErrs, err := parseImportCSV(strings.NewReader(input))
+			So(err, ShouldBeNil)
+			So(entries, ShouldHaveLength, 2)
+			So(entries[1].Name, ShouldEqual, "storage-5")
+			So(rowErrs, ShouldHaveLength, 3)
+			for i, line := range []int{3, 4, 5} {
+				So(rowErrs[i].Line, ShouldEqual, line)
+			}
+			So(rowErrs[0].Name, ShouldEqual, "storage-2")
+		})
+		Convey("Check invalid header", func() {
+			_, _, err := parseImportCSV(strings.NewReader("name,color\n"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
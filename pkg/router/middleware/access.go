@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"sort"
+	"strings"
+
 	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
-	"github.com/containerum/cherry/adaptors/gonic"
 	kubeModel "github.com/containerum/kube-client/pkg/model"
 	headers "github.com/containerum/utils/httputil"
 	"github.com/gin-gonic/gin"
@@ -31,11 +33,60 @@ var (
 const (
 	RoleUser  = "user"
 	RoleAdmin = "admin"
+	// RoleViewer is a read-only operator role
+	RoleViewer = "viewer"
 )
 
+// RoleOperations maps user roles to storage operations (by metrics label) allowed for them.
+// Admins are allowed to perform all operations regardless of mapping.
+type RoleOperations map[string][]string
+
+// DefaultRoleOperations allows viewers to perform read-only storage operations and users to list storages
+// of their namespaces. Webhooks listing is not allowed for viewers because it exposes webhook secrets.
+var DefaultRoleOperations = RoleOperations{
+	RoleUser: {"list"},
+	RoleViewer: {
+		"list", "get", "get_by_id", "list_by_type", "overutilized", "idle", "schema",
+		"quota", "stats", "audit", "history", "forecast", "volumes", "export",
+	},
+}
+
+// Roles returns sorted roles allowed to perform operation, admin role is always included.
+func (ro RoleOperations) Roles(operation string) []string {
+	ret := []string{RoleAdmin}
+	for role, operations := range ro {
+		if role == RoleAdmin {
+			continue
+		}
+		for _, op := range operations {
+			if op == operation {
+				ret = append(ret, role)
+				break
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// RequireRole aborts request with ErrRoleRequired if user role is not allowed to perform operation.
+func RequireRole(operation string, roles RoleOperations) gin.HandlerFunc {
+	allowed := roles.Roles(operation)
+	return func(ctx *gin.Context) {
+		role := GetHeader(ctx, headers.UserRoleXHeader)
+		for _, r := range allowed {
+			if r == role {
+				return
+			}
+		}
+		AbortWithError(ctx, volErrors.ErrRoleRequired().
+			AddDetailF("operation %s requires role %s, got %q", operation, strings.Join(allowed, " or "), role))
+	}
+}
+
 func IsAdmin(ctx *gin.Context) {
 	if role := GetHeader(ctx, headers.UserRoleXHeader); role != RoleAdmin {
-		gonic.Gonic(volErrors.ErrAdminRequired(), ctx)
+		AbortWithError(ctx, volErrors.ErrAdminRequired())
 		return
 	}
 }
@@ -67,10 +118,10 @@ func CheckAccess(ctx *gin.Context, level []kubeModel.AccessLevel) {
 			if ok := containsAccess(userNsData.Access, level...); ok {
 				return
 			}
-			gonic.Gonic(volErrors.ErrRequestValidationFailed().AddDetailF("access error"), ctx)
+			AbortWithError(ctx, volErrors.ErrRequestValidationFailed().AddDetailF("access error"))
 			return
 		}
-		gonic.Gonic(volErrors.ErrResourceNotExists().AddDetails("namespace is not found"), ctx)
+		AbortWithError(ctx, volErrors.ErrResourceNotExists().AddDetails("namespace is not found"))
 		return
 	}
 }
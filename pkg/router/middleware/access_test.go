@@ -78,3 +78,44 @@ func TestIsAdmin(t *testing.T) {
 		})
 	})
 }
+
+func TestRequireRole(t *testing.T) {
+	roles := RoleOperations{RoleViewer: {"get"}}
+	e := gin.New()
+	e.GET("/get", RequireRole("get", roles), func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusOK)
+	})
+	e.DELETE("/delete", RequireRole("delete", roles), func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusOK)
+	})
+	request := func(method, path, role string) (ret gofight.HTTPResponse) {
+		req := gofight.New()
+		if method == http.MethodGet {
+			req = req.GET(path)
+		} else {
+			req = req.DELETE(path)
+		}
+		req.SetHeader(gofight.H{headers.UserRoleXHeader: role}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	Convey("Test RequireRole Middleware", t, func() {
+		Convey("Check admin may perform all operations", func() {
+			So(request(http.MethodGet, "/get", RoleAdmin).Code, ShouldEqual, http.StatusOK)
+			So(request(http.MethodDelete, "/delete", RoleAdmin).Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Check viewer may perform only listed operations", func() {
+			So(request(http.MethodGet, "/get", RoleViewer).Code, ShouldEqual, http.StatusOK)
+			resp := request(http.MethodDelete, "/delete", RoleViewer)
+			So(resp.Code, ShouldEqual, http.StatusForbidden)
+			So(resp.Body.String(), ShouldContainSubstring, "requires role admin")
+		})
+		Convey("Check required roles are reported", func() {
+			resp := request(http.MethodGet, "/get", RoleUser)
+			So(resp.Code, ShouldEqual, http.StatusForbidden)
+			So(resp.Body.String(), ShouldContainSubstring, "requires role admin or viewer")
+		})
+	})
+}
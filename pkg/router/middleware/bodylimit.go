@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+	"github.com/gin-gonic/gin"
+)
+
+// BodyTooLargeError is returned from request body reader when body exceeds MaxBodySize limit.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body must not exceed %d bytes", e.Limit)
+}
+
+// CherryErr converts error to API error.
+func (e *BodyTooLargeError) CherryErr() *cherry.Err {
+	return volErrors.ErrRequestTooLarge().AddDetails(e.Error())
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// limit is reached, check that body has no more data
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, &BodyTooLargeError{Limit: b.limit}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// MaxBodySize limits request body size. Requests with larger Content-Length are rejected immediately,
+// otherwise body reading fails with BodyTooLargeError after limit is exceeded. Non-positive limit disables limiting.
+func MaxBodySize(limit int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if limit <= 0 || ctx.Request.Body == nil {
+			return
+		}
+		if ctx.Request.ContentLength > limit {
+			AbortWithError(ctx, (&BodyTooLargeError{Limit: limit}).CherryErr())
+			return
+		}
+		ctx.Request.Body = &limitedBody{ReadCloser: ctx.Request.Body, limit: limit, remaining: limit}
+	}
+}
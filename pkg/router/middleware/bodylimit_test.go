@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/appleboy/gofight"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	e := gin.New()
+	e.POST("/test", MaxBodySize(8), func(c *gin.Context) {
+		if _, err := ioutil.ReadAll(c.Request.Body); err != nil {
+			tooLarge, ok := err.(*BodyTooLargeError)
+			if !ok {
+				c.AbortWithStatus(http.StatusBadRequest)
+				return
+			}
+			c.AbortWithStatusJSON(tooLarge.CherryErr().StatusHTTP, tooLarge.CherryErr())
+			return
+		}
+		c.AbortWithStatus(http.StatusOK)
+	})
+	request := func(body string) int {
+		var code int
+		gofight.New().POST("/test").
+			SetBody(body).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				code = r.Code
+			})
+		return code
+	}
+
+	Convey("Test MaxBodySize middleware", t, func() {
+		Convey("Check small body is accepted", func() {
+			So(request("12345678"), ShouldEqual, http.StatusOK)
+		})
+		Convey("Check body with large content length is rejected", func() {
+			So(request("123456789"), ShouldEqual, http.StatusRequestEntityTooLarge)
+		})
+		Convey("Check reading is stopped at limit", func() {
+			body := &limitedBody{ReadCloser: ioutil.NopCloser(bytes.NewReader([]byte("123456789"))), limit: 8, remaining: 8}
+			data, err := ioutil.ReadAll(body)
+			So(data, ShouldResemble, []byte("12345678"))
+			So(err, ShouldResemble, &BodyTooLargeError{Limit: 8})
+		})
+	})
+}
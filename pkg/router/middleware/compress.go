@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressionEncodings contains supported response encodings in order of preference.
+var CompressionEncodings = []string{EncodingGzip, EncodingDeflate}
+
+// DefaultCompressionMinSize is a minimal response size to compress if not set in config.
+const DefaultCompressionMinSize = 1024
+
+// CompressionConfig configures response compression.
+type CompressionConfig struct {
+	// MinSize is a minimal size of response body to compress, smaller responses are sent as is.
+	// Streamed responses are compressed since first flush regardless of size.
+	// DefaultCompressionMinSize is used if not positive.
+	MinSize int
+	// Encodings contains enabled encodings (see CompressionEncodings) in order of preference.
+	// All supported encodings are enabled if empty.
+	Encodings []string
+}
+
+// Validate checks that all encodings are supported.
+func (cfg CompressionConfig) Validate() error {
+	for _, encoding := range cfg.Encodings {
+		if compressors[encoding] == nil {
+			return fmt.Errorf("unsupported compression encoding %q, supported: %s", encoding, strings.Join(CompressionEncodings, ", "))
+		}
+	}
+	return nil
+}
+
+var compressors = map[string]func(w io.Writer) io.WriteCloser{
+	EncodingGzip: func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	},
+	EncodingDeflate: func(w io.Writer) io.WriteCloser {
+		// level is valid, so error is impossible
+		ret, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return ret
+	},
+}
+
+// acceptedEncoding returns first of encodings accepted by Accept-Encoding header value, empty string if none.
+func acceptedEncoding(header string, encodings []string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		ok := true
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				ok = err == nil && q > 0
+			}
+		}
+		accepted[name] = ok
+	}
+	for _, encoding := range encodings {
+		if ok, found := accepted[encoding]; found {
+			if ok {
+				return encoding
+			}
+			continue
+		}
+		if accepted["*"] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter buffers response until MinSize reached, then compresses buffered and following data.
+// Response is compressed immediately on flush, so streams are not buffered.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+
+	buf        bytes.Buffer
+	compressor io.WriteCloser
+	// passThrough is set if response must be sent as is
+	passThrough bool
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	switch {
+	case w.compressor != nil:
+		return w.compressor.Write(data)
+	case w.passThrough:
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf.Write(data)
+	if w.buf.Len() >= w.minSize {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// start decides if response may be compressed and writes buffered data.
+func (w *compressWriter) start() error {
+	status := w.ResponseWriter.Status()
+	header := w.ResponseWriter.Header()
+	if w.ResponseWriter.Written() || header.Get("Content-Encoding") != "" ||
+		status == http.StatusNoContent || status == http.StatusNotModified {
+		w.passThrough = true
+	} else {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.compressor = compressors[w.encoding](w.ResponseWriter)
+	}
+	data := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func (w *compressWriter) Flush() {
+	if w.compressor == nil && !w.passThrough {
+		if err := w.start(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish writes buffered small response as is or completes compressed stream.
+func (w *compressWriter) finish() error {
+	if w.compressor != nil {
+		return w.compressor.Close()
+	}
+	if w.buf.Len() > 0 {
+		data := w.buf.Bytes()
+		w.buf = bytes.Buffer{}
+		_, err := w.ResponseWriter.Write(data)
+		return err
+	}
+	return nil
+}
+
+// Compression compresses responses for clients accepting one of enabled encodings.
+func Compression(cfg CompressionConfig) gin.HandlerFunc {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = DefaultCompressionMinSize
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = CompressionEncodings
+	}
+	return func(ctx *gin.Context) {
+		ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(ctx.GetHeader("Accept-Encoding"), cfg.Encodings)
+		if encoding == "" || ctx.Request.Method == http.MethodHead {
+			ctx.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: ctx.Writer, encoding: encoding, minSize: cfg.MinSize}
+		ctx.Writer = writer
+		defer func() {
+			if err := writer.finish(); err != nil {
+				GetLogger(ctx).WithError(err).Warn("response compression failed")
+			}
+			ctx.Writer = writer.ResponseWriter
+		}()
+
+		ctx.Next()
+	}
+}
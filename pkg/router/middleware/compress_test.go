@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("storage ", 1024)
+	recorder := httptest.NewRecorder()
+	var flushedSize int
+
+	e := gin.New()
+	e.Use(Compression(CompressionConfig{MinSize: 100, Encodings: []string{EncodingGzip}}))
+	e.GET("/small", func(c *gin.Context) {
+		c.String(http.StatusOK, "small")
+	})
+	e.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+	})
+	e.GET("/stream", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("line\n")
+		c.Writer.Flush()
+		flushedSize = recorder.Body.Len()
+		c.Writer.WriteString("line\n")
+	})
+
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		recorder = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		e.ServeHTTP(recorder, req)
+		return recorder
+	}
+	decompress := func(resp *httptest.ResponseRecorder) string {
+		reader, err := gzip.NewReader(resp.Body)
+		So(err, ShouldBeNil)
+		data, err := ioutil.ReadAll(reader)
+		So(err, ShouldBeNil)
+		return string(data)
+	}
+
+	Convey("Test response compression", t, func() {
+		Convey("Check small responses are not compressed", func() {
+			resp := request("/small", "gzip")
+			So(resp.Header().Get("Content-Encoding"), ShouldBeEmpty)
+			So(resp.Body.String(), ShouldEqual, "small")
+		})
+		Convey("Check large responses are compressed", func() {
+			resp := request("/large", "deflate;q=0.5, gzip")
+			So(resp.Header().Get("Content-Encoding"), ShouldEqual, EncodingGzip)
+			So(resp.Header().Get("Vary"), ShouldEqual, "Accept-Encoding")
+			So(resp.Body.Len(), ShouldBeLessThan, len(large))
+			So(decompress(resp), ShouldEqual, large)
+		})
+		Convey("Check encoding must be accepted", func() {
+			for _, acceptEncoding := range []string{"", "deflate", "gzip;q=0"} {
+				resp := request("/large", acceptEncoding)
+				So(resp.Header().Get("Content-Encoding"), ShouldBeEmpty)
+				So(resp.Body.String(), ShouldEqual, large)
+			}
+		})
+		Convey("Check streams are compressed without buffering", func() {
+			resp := request("/stream", "gzip")
+			So(flushedSize, ShouldBeGreaterThan, 0)
+			So(resp.Header().Get("Content-Encoding"), ShouldEqual, EncodingGzip)
+			So(decompress(resp), ShouldEqual, "line\nline\n")
+		})
+		Convey("Check config validation", func() {
+			So(CompressionConfig{Encodings: []string{EncodingDeflate}}.Validate(), ShouldBeNil)
+			So(CompressionConfig{Encodings: []string{"br"}}.Validate(), ShouldNotBeNil)
+		})
+	})
+}
@@ -4,4 +4,8 @@ const (
 	UserNamespaces = "user-namespaces"
 	UserRole       = "user-role"
 	UserID         = "user-id"
+	RequestID      = "request-id"
+	LogEntry       = "log-entry"
+
+	StorageOperation = "storage-operation"
 )
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation response headers
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+	WarningHeader     = "Warning"
+	LinkHeader        = "Link"
+)
+
+// Deprecation describes deprecated storage operation.
+type Deprecation struct {
+	// Since is a time operation became deprecated, "true" is reported if not set
+	Since time.Time
+	// Sunset is a time after which operation may be removed, not reported if not set
+	Sunset time.Time
+	// Link is an URL of document describing deprecation and migration, not reported if not set
+	Link string
+	// Message is a human-readable warning text, generated from operation name and sunset if not set
+	Message string
+}
+
+// headers returns deprecation headers values for operation.
+func (d Deprecation) headers(operation string) http.Header {
+	ret := make(http.Header)
+	if d.Since.IsZero() {
+		ret.Set(DeprecationHeader, "true")
+	} else {
+		ret.Set(DeprecationHeader, "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	}
+	message := d.Message
+	if message == "" {
+		message = fmt.Sprintf("operation %s is deprecated", operation)
+		if !d.Sunset.IsZero() {
+			message += " and will be removed after " + d.Sunset.UTC().Format(http.TimeFormat)
+		}
+	}
+	if !d.Sunset.IsZero() {
+		ret.Set(SunsetHeader, d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	// 299 is "miscellaneous persistent warning" code, "-" means unknown agent (RFC 7234, section 5.5)
+	ret.Set(WarningHeader, "299 - "+strconv.Quote(message))
+	if d.Link != "" {
+		ret.Add(LinkHeader, fmt.Sprintf("<%s>; rel=\"deprecation\"", d.Link))
+	}
+	return ret
+}
+
+type deprecationWriter struct {
+	gin.ResponseWriter
+	ctx          *gin.Context
+	deprecations map[string]Deprecation
+	done         bool
+}
+
+// addHeaders adds deprecation headers once before response headers are sent.
+// Operation is taken from context when response is written, so it may be set by inner handlers.
+func (w *deprecationWriter) addHeaders() {
+	if w.done {
+		return
+	}
+	w.done = true
+	operation := w.ctx.GetString(StorageOperation)
+	deprecation, ok := w.deprecations[operation]
+	if !ok || w.ResponseWriter.Written() {
+		return
+	}
+	for key, values := range deprecation.headers(operation) {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+}
+
+func (w *deprecationWriter) WriteHeader(code int) {
+	w.addHeaders()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *deprecationWriter) WriteHeaderNow() {
+	w.addHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *deprecationWriter) Write(data []byte) (int, error) {
+	w.addHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *deprecationWriter) WriteString(s string) (int, error) {
+	w.addHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Deprecations adds Deprecation, Sunset, Warning and Link headers to responses of deprecated storage operations
+// (see StorageMetrics). Deprecations are keyed by operation. Handlers are not affected.
+func Deprecations(deprecations map[string]Deprecation) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if len(deprecations) == 0 {
+			return
+		}
+		writer := &deprecationWriter{ResponseWriter: ctx.Writer, ctx: ctx, deprecations: deprecations}
+		ctx.Writer = writer
+		ctx.Next()
+		// operation may be known only after handler finished, e.g. for responses without body
+		writer.addHeaders()
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/appleboy/gofight"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeprecations(t *testing.T) {
+	sunset := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	e := gin.New()
+	e.Use(Deprecations(map[string]Deprecation{
+		"import": {Sunset: sunset, Link: "https://example.com/migration"},
+		"delete": {Message: "use bulk delete"},
+	}))
+	e.POST("/import", StorageMetrics("import"), func(c *gin.Context) {
+		c.JSON(http.StatusAccepted, gin.H{})
+	})
+	e.DELETE("/delete", StorageMetrics("delete"), func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+	})
+	e.GET("/get", StorageMetrics("get"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	request := func(method, path string) http.Header {
+		var ret http.Header
+		req := gofight.New()
+		switch method {
+		case http.MethodPost:
+			req = req.POST(path)
+		case http.MethodDelete:
+			req = req.DELETE(path)
+		default:
+			req = req.GET(path)
+		}
+		req.Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			ret = r.HeaderMap
+		})
+		return ret
+	}
+
+	Convey("Test Deprecations middleware", t, func() {
+		Convey("Check deprecated operation with sunset", func() {
+			headers := request(http.MethodPost, "/import")
+			So(headers.Get(DeprecationHeader), ShouldEqual, "true")
+			So(headers.Get(SunsetHeader), ShouldEqual, "Fri, 01 Jan 2027 00:00:00 GMT")
+			So(headers.Get(WarningHeader), ShouldEqual,
+				`299 - "operation import is deprecated and will be removed after Fri, 01 Jan 2027 00:00:00 GMT"`)
+			So(headers.Get(LinkHeader), ShouldEqual, `<https://example.com/migration>; rel="deprecation"`)
+		})
+		Convey("Check response without body", func() {
+			headers := request(http.MethodDelete, "/delete")
+			So(headers.Get(DeprecationHeader), ShouldEqual, "true")
+			So(headers.Get(SunsetHeader), ShouldBeEmpty)
+			So(headers.Get(WarningHeader), ShouldEqual, `299 - "use bulk delete"`)
+		})
+		Convey("Check not deprecated operation", func() {
+			headers := request(http.MethodGet, "/get")
+			So(headers.Get(DeprecationHeader), ShouldBeEmpty)
+			So(headers.Get(WarningHeader), ShouldBeEmpty)
+		})
+	})
+}
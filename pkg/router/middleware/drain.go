@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// Drainer tracks in-flight requests for graceful shutdown. After draining started new requests are rejected
+// with 503, so load balancer can route them to other instances.
+type Drainer struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	// idle is closed when draining started and there are no in-flight requests
+	idle chan struct{}
+	// abort is closed when in-flight requests were not finished in time, their contexts are cancelled then
+	abort chan struct{}
+}
+
+// NewDrainer creates drainer accepting requests.
+func NewDrainer() *Drainer {
+	return &Drainer{
+		idle:  make(chan struct{}),
+		abort: make(chan struct{}),
+	}
+}
+
+// Draining reports that draining is started.
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+func (d *Drainer) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inFlight++
+	return true
+}
+
+func (d *Drainer) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if d.draining && d.inFlight == 0 {
+		close(d.idle)
+	}
+}
+
+// Middleware counts in-flight requests and rejects new ones during draining.
+// Context of request is cancelled if Drain stops waiting for it.
+func (d *Drainer) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !d.begin() {
+			ctx.Header("Connection", "close")
+			AbortWithError(ctx, volErrors.ErrServiceShuttingDown())
+			return
+		}
+		defer d.end()
+
+		reqCtx, cancel := context.WithCancel(ctx.Request.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-d.abort:
+				cancel()
+			case <-reqCtx.Done():
+			}
+		}()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		ctx.Next()
+	}
+}
+
+// Drain stops accepting requests and waits for in-flight requests until ctx is done.
+// If waiting is interrupted, contexts of in-flight requests are cancelled and ctx error is returned.
+// Drain must be called once.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	if d.inFlight == 0 {
+		close(d.idle)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		close(d.abort)
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/appleboy/gofight"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDrainer(t *testing.T) {
+	setup := func() (*gin.Engine, *Drainer, chan struct{}, chan error) {
+		drainer := NewDrainer()
+		started, release := make(chan struct{}), make(chan struct{})
+		results := make(chan error, 1)
+		e := gin.New()
+		e.Use(drainer.Middleware())
+		e.GET("/slow", func(c *gin.Context) {
+			close(started)
+			select {
+			case <-release:
+				results <- nil
+			case <-c.Request.Context().Done():
+				results <- c.Request.Context().Err()
+			}
+			c.Status(http.StatusOK)
+		})
+		e.GET("/fast", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		go gofight.New().GET("/slow").Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {})
+		<-started
+		return e, drainer, release, results
+	}
+	request := func(e *gin.Engine, path string) int {
+		var code int
+		gofight.New().GET(path).Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			code = r.Code
+		})
+		return code
+	}
+
+	Convey("Test Drainer", t, func() {
+		Convey("Check in-flight requests are waited", func() {
+			e, drainer, release, results := setup()
+			So(request(e, "/fast"), ShouldEqual, http.StatusOK)
+
+			drained := make(chan error, 1)
+			go func() { drained <- drainer.Drain(context.Background()) }()
+			for !drainer.Draining() {
+				time.Sleep(time.Millisecond)
+			}
+			So(request(e, "/fast"), ShouldEqual, http.StatusServiceUnavailable)
+
+			close(release)
+			So(<-results, ShouldBeNil)
+			So(<-drained, ShouldBeNil)
+		})
+		Convey("Check in-flight requests are aborted after timeout", func() {
+			_, drainer, _, results := setup()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			So(errors.Is(drainer.Drain(ctx), context.DeadlineExceeded), ShouldBeTrue)
+			So(errors.Is(<-results, context.Canceled), ShouldBeTrue)
+		})
+		Convey("Check drain without requests", func() {
+			So(NewDrainer().Drain(context.Background()), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/textproto"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/containerum/cherry"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/universal-translator"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// AbortWithError aborts request with error response envelope (see errors.Envelope).
+// All error responses must be written by it, so clients always get error code.
+func AbortWithError(ctx *gin.Context, err error) {
+	envelope := volErrors.NewEnvelope(err)
+	ctx.AbortWithStatusJSON(envelope.Status(), envelope)
+}
+
+// Recovery converts panics to internal error responses.
+func Recovery(log cherry.ErrorLogger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				cherryErr, ok := r.(*cherry.Err)
+				if !ok {
+					cherryErr = volErrors.ErrInternal().Log(fmt.Errorf("%v", r), log)
+				}
+				AbortWithError(ctx, cherryErr)
+			}
+		}()
+
+		ctx.Next()
+	}
+}
+
+// RequireHeaders ensures that headers are set, same as httputil.RequireHeaders but with error envelope.
+func RequireHeaders(names ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		err := volErrors.ErrRequiredHeadersNotProvided()
+		missing := false
+		for _, name := range names {
+			if ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(name)) == "" {
+				err.AddDetailF("required header %s was not provided", name)
+				missing = true
+			}
+		}
+		if missing {
+			AbortWithError(ctx, err)
+		}
+	}
+}
+
+// SubstituteUser replaces user ID in context with "user-id" query parameter if it set and user is admin,
+// same as httputil.SubstituteUserMiddleware but with error envelope.
+func SubstituteUser(validate *validator.Validate, translator *ut.UniversalTranslator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role := ctx.GetHeader(textproto.CanonicalMIMEHeaderKey(headers.UserRoleXHeader))
+		if userID, set := ctx.GetQuery("user-id"); set && role == RoleAdmin {
+			if vErr := validate.VarCtx(ctx.Request.Context(), userID, "uuid"); vErr != nil {
+				t, _ := translator.FindTranslator(headers.GetAcceptedLanguages(ctx.Request.Context())...)
+				AbortWithError(ctx, volErrors.ErrRequestValidationFailed().
+					AddDetailF("Parameter \"user-id\": %s", vErr.(validator.ValidationErrors).Translate(t)))
+				return
+			}
+			ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), headers.UserIDContextKey, userID))
+		}
+	}
+}
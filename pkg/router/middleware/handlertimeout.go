@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerTimeoutConfig configures handlers time budgets.
+type HandlerTimeoutConfig struct {
+	// Default is a time budget of operations not listed in Operations, zero means no limit
+	Default time.Duration
+	// Operations contains time budgets of storage operations (by metrics label), zero disables limit for operation
+	Operations map[string]time.Duration
+}
+
+func (cfg HandlerTimeoutConfig) timeout(operation string) time.Duration {
+	if timeout, ok := cfg.Operations[operation]; ok {
+		return timeout
+	}
+	return cfg.Default
+}
+
+// handlerBudgetKey is a context key of *handlerBudget
+const handlerBudgetKey = "handler-budget"
+
+// handlerBudget notifies HandlerTimeout about operation resolved by handler, so operation budget is applied.
+type handlerBudget struct {
+	operations chan string
+}
+
+func (b *handlerBudget) setOperation(operation string) {
+	select {
+	case <-b.operations:
+	default:
+	}
+	b.operations <- operation
+}
+
+// SetStorageOperation saves storage operation (metrics label) to context and applies its time budget if HandlerTimeout is used.
+func SetStorageOperation(ctx *gin.Context, operation string) {
+	ctx.Set(StorageOperation, operation)
+	if budget, ok := ctx.Value(handlerBudgetKey).(*handlerBudget); ok {
+		budget.setOperation(operation)
+	}
+}
+
+// timeoutWriter passes response to underlying writer until timeout response is written, later writes are discarded.
+// Handler has own headers map which is copied to response on first write, so headers of timeout response are not mixed with it.
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	mu       sync.Mutex
+	header   http.Header
+	timedOut bool
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	header := make(http.Header, len(w.Header()))
+	for k, v := range w.Header() {
+		header[k] = v
+	}
+	return &timeoutWriter{ResponseWriter: w, header: header}
+}
+
+// copyHeader replaces response headers by handler headers, must be called with locked mutex.
+func (w *timeoutWriter) copyHeader() {
+	if w.ResponseWriter.Written() {
+		return
+	}
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range w.header {
+		dst[k] = v
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.copyHeader()
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.copyHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.copyHeader()
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Status()
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Size()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Written()
+}
+
+// timeout writes timeout error response unless handler already started writing response,
+// following handler writes are discarded in both cases.
+func (w *timeoutWriter) timeout(operation string, budget time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	if w.ResponseWriter.Written() {
+		return
+	}
+	envelope := volErrors.NewEnvelope(volErrors.ErrOperationTimeout().
+		AddDetailF("operation %s exceeded time budget %v", operation, budget))
+	// envelope always can be encoded
+	body, _ := json.Marshal(envelope)
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(envelope.Status())
+	w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
+
+// finish copies handler headers to response if it was not written by handler (e.g. only status was set).
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.copyHeader()
+	}
+}
+
+// HandlerTimeout responds with ErrOperationTimeout if handler exceeded time budget of its operation,
+// request context is cancelled then, so handler may observe it and stop. Handler responses written after timeout are discarded.
+// Budget is counted from request start, operation budget is applied as soon as operation is set by SetStorageOperation
+// (e.g. by StorageMetrics), Default budget is used before that.
+// Timeout response is flushed immediately, but middleware returns only after handler finished, so context is not reused while in use.
+func HandlerTimeout(cfg HandlerTimeoutConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		budget := &handlerBudget{operations: make(chan string, 1)}
+		ctx.Set(handlerBudgetKey, budget)
+		operation := ctx.GetString(StorageOperation)
+
+		cancelCtx, cancel := context.WithCancel(ctx.Request.Context())
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(cancelCtx)
+		writer := newTimeoutWriter(ctx.Writer)
+		ctx.Writer = writer
+
+		done := make(chan struct{})
+		var panicked interface{}
+		go func() {
+			defer close(done)
+			defer func() {
+				panicked = recover()
+			}()
+			ctx.Next()
+		}()
+
+		// stopped timer, started by setTimer if operation has budget
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+		setTimer := func() {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			if timeout := cfg.timeout(operation); timeout > 0 {
+				timer.Reset(timeout - time.Since(start))
+			}
+		}
+		setTimer()
+
+	wait:
+		for {
+			select {
+			case <-done:
+				break wait
+			case operation = <-budget.operations:
+				setTimer()
+			case <-timer.C:
+				select {
+				case <-done:
+				default:
+					writer.timeout(operation, cfg.timeout(operation))
+					cancel()
+					<-done
+				}
+				break wait
+			}
+		}
+
+		writer.finish()
+		ctx.Writer = writer.ResponseWriter
+		if panicked != nil {
+			panic(panicked)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	"github.com/appleboy/gofight"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHandlerTimeout(t *testing.T) {
+	var cancelled bool
+	e := gin.New()
+	e.Use(HandlerTimeout(HandlerTimeoutConfig{
+		Default:    20 * time.Millisecond,
+		Operations: map[string]time.Duration{"unlimited": 0},
+	}))
+	e.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Test", "fast")
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	e.GET("/status", func(c *gin.Context) {
+		c.Header("X-Test", "status")
+		c.Status(http.StatusAccepted)
+	})
+	e.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		cancelled = true
+		c.Header("X-Test", "slow")
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	e.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		<-c.Request.Context().Done()
+		c.String(http.StatusOK, " response")
+	})
+	e.GET("/unlimited", StorageMetrics("unlimited"), func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	e.GET("/panic", func(c *gin.Context) {
+		panic("handler panic")
+	})
+
+	request := func(path string) (ret gofight.HTTPResponse) {
+		gofight.New().GET(path).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test HandlerTimeout middleware", t, func() {
+		Convey("Check fast handlers responses are passed", func() {
+			resp := request("/fast")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.HeaderMap.Get("X-Test"), ShouldEqual, "fast")
+			So(resp.Body.String(), ShouldEqual, `{"ok":true}`)
+
+			resp = request("/status")
+			So(resp.Code, ShouldEqual, http.StatusAccepted)
+			So(resp.HeaderMap.Get("X-Test"), ShouldEqual, "status")
+		})
+		Convey("Check timeout response", func() {
+			cancelled = false
+			resp := request("/slow")
+			So(cancelled, ShouldBeTrue)
+			So(resp.Code, ShouldEqual, http.StatusGatewayTimeout)
+			So(resp.HeaderMap.Get("X-Test"), ShouldBeEmpty)
+			var envelope volErrors.Envelope
+			So(json.Unmarshal(resp.Body.Bytes(), &envelope), ShouldBeNil)
+			So(envelope.Code, ShouldEqual, volErrors.CodeOperationTimeout)
+		})
+		Convey("Check started responses are not overwritten", func() {
+			resp := request("/written")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.Body.String(), ShouldEqual, "partial")
+		})
+		Convey("Check operation budget overrides default", func() {
+			So(request("/unlimited").Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Check handler panics are propagated", func() {
+			So(func() { request("/panic") }, ShouldPanicWith, "handler panic")
+		})
+	})
+}
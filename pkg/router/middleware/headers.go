@@ -7,7 +7,6 @@ import (
 	"net/textproto"
 
 	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
-	"github.com/containerum/cherry/adaptors/gonic"
 	"github.com/containerum/kube-client/pkg/model"
 	headers "github.com/containerum/utils/httputil"
 	"github.com/gin-gonic/gin"
@@ -23,7 +22,7 @@ var (
 
 type UserHeaderDataMap map[string]model.UserHeaderData
 
-//ParseUserHeaderData decodes headers for substitutions
+// ParseUserHeaderData decodes headers for substitutions
 func ParseUserHeaderData(str string) (UserHeaderDataMap, error) {
 	data, err := base64.StdEncoding.DecodeString(str)
 	if err != nil {
@@ -48,14 +47,14 @@ func RequiredUserHeaders() gin.HandlerFunc {
 		log.WithField("Headers", ctx.Request.Header).Debug("Header list")
 		notFoundHeaders := requireHeaders(ctx, headers.UserRoleXHeader)
 		if len(notFoundHeaders) > 0 {
-			gonic.Gonic(volErrors.ErrRequiredHeadersNotProvided().AddDetails(notFoundHeaders...), ctx)
+			AbortWithError(ctx, volErrors.ErrRequiredHeadersNotProvided().AddDetails(notFoundHeaders...))
 			return
 		}
 		/* Check User-Role and User-Namespace, X-User-Volume */
 		role := GetHeader(ctx, headers.UserRoleXHeader)
 		if isUser, err := checkIsUserRole(role); err != nil {
 			log.WithField("Value", role).WithError(err).Warn("Check User-Role Error")
-			gonic.Gonic(volErrors.ErrRequestValidationFailed().AddDetailF("invalid role %s", role), ctx)
+			AbortWithError(ctx, volErrors.ErrRequestValidationFailed().AddDetailF("invalid role %s", role))
 		} else {
 			//User-Role: user, check User-Namespace, X-User-Volume
 			if isUser {
@@ -65,13 +64,13 @@ func RequiredUserHeaders() gin.HandlerFunc {
 					headers.UserIDXHeader,
 				)
 				if len(notFoundHeaders) > 0 {
-					gonic.Gonic(volErrors.ErrRequiredHeadersNotProvided().AddDetails(notFoundHeaders...), ctx)
+					AbortWithError(ctx, volErrors.ErrRequiredHeadersNotProvided().AddDetails(notFoundHeaders...))
 					return
 				}
 				userNs, errNs := checkUserNamespace(GetHeader(ctx, headers.UserNamespacesXHeader))
 				if errNs != nil {
 					log.WithField("Value", GetHeader(ctx, headers.UserNamespacesXHeader)).WithError(errNs).Warn("Check User-Namespace header Error")
-					gonic.Gonic(volErrors.ErrRequestValidationFailed().AddDetails(fmt.Sprintf("%v: %v", headers.UserNamespacesXHeader, errNs)), ctx)
+					AbortWithError(ctx, volErrors.ErrRequestValidationFailed().AddDetails(fmt.Sprintf("%v: %v", headers.UserNamespacesXHeader, errNs)))
 					return
 				}
 				ctx.Set(UserNamespaces, userNs)
@@ -83,7 +82,7 @@ func RequiredUserHeaders() gin.HandlerFunc {
 
 func checkIsUserRole(userRole string) (bool, error) {
 	switch userRole {
-	case "", RoleAdmin:
+	case "", RoleAdmin, RoleViewer:
 		return false, nil
 	case RoleUser:
 		return true, nil
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyRecord struct {
+	requestHash [sha256.Size]byte
+	done        bool
+	status      int
+	contentType string
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// IdempotencyStore keeps responses of requests with idempotency keys for configured TTL.
+type IdempotencyStore struct {
+	ttl         time.Duration
+	mu          sync.Mutex
+	records     map[string]*idempotencyRecord
+	lastCleanup time.Time
+}
+
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:         ttl,
+		records:     make(map[string]*idempotencyRecord),
+		lastCleanup: time.Now(),
+	}
+}
+
+// begin returns stored record for key or reserves key for new request if there is no record.
+func (s *IdempotencyStore) begin(key string, requestHash [sha256.Size]byte) (rec idempotencyRecord, found bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for k, v := range s.records {
+			if now.After(v.expires) {
+				delete(s.records, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	if stored, ok := s.records[key]; ok && now.Before(stored.expires) {
+		return *stored, true
+	}
+	s.records[key] = &idempotencyRecord{requestHash: requestHash, expires: now.Add(s.ttl)}
+	return idempotencyRecord{}, false
+}
+
+func (s *IdempotencyStore) complete(key string, status int, contentType string, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok {
+		return
+	}
+	rec.done = true
+	rec.status = status
+	rec.contentType = contentType
+	rec.header = header
+	rec.body = body
+	rec.expires = time.Now().Add(s.ttl)
+}
+
+func (s *IdempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// requestHash identifies request by its method, path, query and body.
+func requestHash(req *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	for _, part := range []string{req.Method, req.URL.Path, req.URL.RawQuery} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// changedHeaders returns response headers set or changed after before snapshot was taken.
+func changedHeaders(before, after http.Header) http.Header {
+	changed := make(http.Header)
+	for k, v := range after {
+		if !reflect.DeepEqual(before[k], v) {
+			changed[k] = append([]string(nil), v...)
+		}
+	}
+	return changed
+}
+
+// Idempotent replays stored successful response for repeated requests with the same Idempotency-Key header.
+// Keys are scoped per user. Reusing key with different method, path, query or request body causes 422 error.
+// Headers set by handler (i.e. ETag) are replayed with response body.
+func Idempotent(store *IdempotencyStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idempotencyKey := GetHeader(ctx, IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			return
+		}
+		key := GetHeader(ctx, headers.UserIDXHeader) + "\x00" + idempotencyKey
+
+		body, err := ioutil.ReadAll(ctx.Request.Body)
+		if tooLarge, ok := err.(*BodyTooLargeError); ok {
+			AbortWithError(ctx, tooLarge.CherryErr())
+			return
+		}
+		if err != nil {
+			AbortWithError(ctx, volErrors.ErrRequestValidationFailed().AddDetailsErr(err))
+			return
+		}
+		ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		hash := requestHash(ctx.Request, body)
+
+		if rec, found := store.begin(key, hash); found {
+			switch {
+			case rec.requestHash != hash:
+				AbortWithError(ctx, volErrors.ErrIdempotencyKeyReused())
+			case !rec.done:
+				AbortWithError(ctx, volErrors.ErrIdempotentRequestInProgress())
+			default:
+				for k, v := range rec.header {
+					ctx.Writer.Header()[k] = v
+				}
+				ctx.Data(rec.status, rec.contentType, rec.body)
+				ctx.Abort()
+			}
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		headersBefore := changedHeaders(nil, recorder.Header())
+
+		// key must not stay reserved if handler panics, otherwise retries get "in progress" error until TTL expires
+		completed := false
+		defer func() {
+			if !completed {
+				store.release(key)
+			}
+		}()
+
+		ctx.Next()
+
+		if status := recorder.Status(); status >= http.StatusOK && status < http.StatusMultipleChoices {
+			store.complete(key, status, recorder.Header().Get("Content-Type"),
+				changedHeaders(headersBefore, recorder.Header()), recorder.body.Bytes())
+			completed = true
+		}
+	}
+}
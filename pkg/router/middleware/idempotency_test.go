@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/appleboy/gofight"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIdempotent(t *testing.T) {
+	calls := 0
+	shouldPanic := false
+	e := gin.New()
+	e.Use(func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+		c.Next()
+	})
+	handler := func(c *gin.Context) {
+		calls++
+		if shouldPanic {
+			panic("handler failed")
+		}
+		c.Header("ETag", `"1"`)
+		c.String(http.StatusCreated, "created %d", calls)
+	}
+	store := NewIdempotencyStore(time.Minute)
+	e.POST("/test", Idempotent(store), handler)
+	e.PUT("/test", Idempotent(store), handler)
+
+	type response struct {
+		code int
+		etag string
+		body string
+	}
+	request := func(method, path, key, body string) response {
+		var resp response
+		r := gofight.New()
+		switch method {
+		case http.MethodPost:
+			r = r.POST(path)
+		case http.MethodPut:
+			r = r.PUT(path)
+		}
+		r.SetHeader(gofight.H{IdempotencyKeyHeader: key}).
+			SetBody(body).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				resp = response{code: r.Code, etag: r.HeaderMap.Get("ETag"), body: r.Body.String()}
+			})
+		return resp
+	}
+
+	Convey("Test Idempotent middleware", t, func() {
+		Convey("Check response is replayed with headers", func() {
+			first := request(http.MethodPost, "/test", "replay", "body")
+			So(first, ShouldResemble, response{code: http.StatusCreated, etag: `"1"`, body: "created 1"})
+			So(request(http.MethodPost, "/test", "replay", "body"), ShouldResemble, first)
+			So(calls, ShouldEqual, 1)
+		})
+		Convey("Check key reuse with different request is rejected", func() {
+			So(request(http.MethodPost, "/test", "reuse", "body").code, ShouldEqual, http.StatusCreated)
+			So(request(http.MethodPost, "/test", "reuse", "other").code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(request(http.MethodPut, "/test", "reuse", "body").code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(request(http.MethodPost, "/test?dry_run=true", "reuse", "body").code, ShouldEqual, http.StatusUnprocessableEntity)
+		})
+		Convey("Check key is released after handler panic", func() {
+			shouldPanic = true
+			So(request(http.MethodPost, "/test", "panic", "body").code, ShouldEqual, http.StatusInternalServerError)
+			shouldPanic = false
+			So(request(http.MethodPost, "/test", "panic", "body").code, ShouldEqual, http.StatusCreated)
+		})
+	})
+}
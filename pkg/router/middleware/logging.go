@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const RequestIDXHeader = "X-Request-ID"
+
+// RequestLogger saves logrus entry with request ID and user ID fields to context.
+// Request ID taken from X-Request-ID header or generated if not provided, it is echoed back in response headers.
+func RequestLogger(entry *logrus.Entry) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := GetHeader(ctx, RequestIDXHeader)
+		if requestID == "" {
+			requestID = uuid.NewV4().String()
+		}
+		ctx.Header(RequestIDXHeader, requestID)
+		ctx.Set(RequestID, requestID)
+
+		ctx.Set(LogEntry, entry.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"user_id":    GetHeader(ctx, headers.UserIDXHeader),
+		}))
+	}
+}
+
+// GetLogger returns request-scoped log entry or standard logger entry if RequestLogger was not used.
+func GetLogger(ctx *gin.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(LogEntry).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+var (
+	storageOperationsTotal = metrics.NewCounterVec(
+		"volume_manager_storage_operations_total",
+		"Total number of storage operations.",
+		"operation", "outcome",
+	)
+
+	storageOperationDuration = metrics.NewHistogramVec(
+		"volume_manager_storage_operation_duration_seconds",
+		"Storage operation handlers latency.",
+		metrics.DefBuckets,
+		"operation",
+	)
+)
+
+func init() {
+	metrics.MustRegister(storageOperationsTotal, storageOperationDuration)
+}
+
+// StorageMetrics records count (by outcome) and latency of storage operation handler.
+// Handler may override operation label by setting StorageOperation value in context.
+// Operation is saved to context so outer middlewares (e.g. Tracing) can use it.
+func StorageMetrics(operation string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		SetStorageOperation(ctx, operation)
+
+		ctx.Next()
+
+		operation := ctx.GetString(StorageOperation)
+
+		outcome := OutcomeSuccess
+		if ctx.Writer.Status() >= http.StatusBadRequest {
+			outcome = OutcomeError
+		}
+		storageOperationsTotal.Inc(operation, outcome)
+		storageOperationDuration.Observe(time.Since(start).Seconds(), operation)
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+const RetryAfterHeader = "Retry-After"
+
+// RateLimit describes token bucket parameters.
+type RateLimit struct {
+	// Rate is a number of requests per second
+	Rate float64
+	// Burst is a maximum number of requests which can be done at once
+	Burst int
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter limits requests rate per user using token bucket algorithm.
+type RateLimiter struct {
+	limit        RateLimit
+	exemptAdmins bool
+
+	mu          sync.Mutex
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+// NewRateLimiter creates rate limiter. If exemptAdmins is set requests with admin role are not limited.
+func NewRateLimiter(limit RateLimit, exemptAdmins bool) *RateLimiter {
+	return &RateLimiter{
+		limit:        limit,
+		exemptAdmins: exemptAdmins,
+		buckets:      make(map[string]*tokenBucket),
+		lastCleanup:  time.Now(),
+	}
+}
+
+// take takes token from user bucket. Returns time to wait for the next token if bucket is empty.
+func (l *RateLimiter) take(key string, now time.Time) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	burst := float64(l.limit.Burst)
+	// full buckets are the same as absent ones
+	fillTime := time.Duration(burst / l.limit.Rate * float64(time.Second))
+	if now.Sub(l.lastCleanup) > time.Minute {
+		for k, v := range l.buckets {
+			if now.Sub(v.lastSeen) > fillTime {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: burst, lastSeen: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*l.limit.Rate)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.limit.Rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// RateLimited rejects requests with 429 status if user (identified by X-User-ID header) exceeds rate limit.
+// Nil limiter disables limiting.
+func RateLimited(limiter *RateLimiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if limiter == nil {
+			return
+		}
+		if limiter.exemptAdmins && GetHeader(ctx, headers.UserRoleXHeader) == "admin" {
+			return
+		}
+
+		ok, retryAfter := limiter.take(GetHeader(ctx, headers.UserIDXHeader), time.Now())
+		if !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			ctx.Header(RetryAfterHeader, strconv.Itoa(seconds))
+			AbortWithError(ctx, volErrors.ErrTooManyRequests().AddDetailF("retry after %d seconds", seconds))
+		}
+	}
+}
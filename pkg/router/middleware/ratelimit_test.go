@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/appleboy/gofight"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimited(t *testing.T) {
+	newEngine := func(exemptAdmins bool) *gin.Engine {
+		e := gin.New()
+		e.GET("/test", RateLimited(NewRateLimiter(RateLimit{Rate: 0.01, Burst: 2}, exemptAdmins)), func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusOK)
+		})
+		return e
+	}
+	request := func(e *gin.Engine, userID, role string) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().GET("/test").
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   userID,
+				headers.UserRoleXHeader: role,
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test RateLimited middleware", t, func() {
+		Convey("Check requests over burst are rejected", func() {
+			e := newEngine(false)
+			So(request(e, "user1", "user").Code, ShouldEqual, http.StatusOK)
+			So(request(e, "user1", "user").Code, ShouldEqual, http.StatusOK)
+			resp := request(e, "user1", "user")
+			So(resp.Code, ShouldEqual, http.StatusTooManyRequests)
+			So(resp.HeaderMap.Get(RetryAfterHeader), ShouldEqual, "100")
+		})
+		Convey("Check users are limited separately", func() {
+			e := newEngine(false)
+			So(request(e, "user1", "user").Code, ShouldEqual, http.StatusOK)
+			So(request(e, "user1", "user").Code, ShouldEqual, http.StatusOK)
+			So(request(e, "user2", "user").Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Check admins exemption", func() {
+			e := newEngine(true)
+			for i := 0; i < 3; i++ {
+				So(request(e, "admin1", "admin").Code, ShouldEqual, http.StatusOK)
+			}
+			So(request(e, "user1", "user").Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
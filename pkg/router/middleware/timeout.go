@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	volErrors "git.containerum.net/ch/volume-manager/pkg/errors"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// OperationTimeoutHeader allows admins to override operation timeout, value is a duration like "5m".
+const OperationTimeoutHeader = "X-Operation-Timeout"
+
+// OperationTimeout limits request processing time by deadline of request context.
+// Zero timeout means no limit unless it is set by OperationTimeoutHeader.
+func OperationTimeout(defaultTimeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		timeout := defaultTimeout
+		if value := GetHeader(ctx, OperationTimeoutHeader); value != "" {
+			if GetHeader(ctx, headers.UserRoleXHeader) != RoleAdmin {
+				AbortWithError(ctx, volErrors.ErrAdminRequired().AddDetailF("%s header is allowed only for admins", OperationTimeoutHeader))
+				return
+			}
+			override, err := time.ParseDuration(value)
+			if err != nil || override <= 0 {
+				AbortWithError(ctx, volErrors.ErrRequestValidationFailed().AddDetailF("invalid %s header value %q", OperationTimeoutHeader, value))
+				return
+			}
+			timeout = override
+		}
+		if timeout <= 0 {
+			return
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(timeoutCtx)
+
+		ctx.Next()
+	}
+}
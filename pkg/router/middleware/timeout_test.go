@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/appleboy/gofight"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	var deadline time.Duration
+	e := gin.New()
+	e.GET("/test", OperationTimeout(time.Minute), func(c *gin.Context) {
+		deadline = 0
+		if d, ok := c.Request.Context().Deadline(); ok {
+			deadline = time.Until(d)
+		}
+		c.AbortWithStatus(http.StatusOK)
+	})
+	request := func(role, timeout string) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		h := gofight.H{headers.UserRoleXHeader: role}
+		if timeout != "" {
+			h[OperationTimeoutHeader] = timeout
+		}
+		gofight.New().GET("/test").
+			SetHeader(h).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test OperationTimeout middleware", t, func() {
+		Convey("Check default timeout is set", func() {
+			So(request(RoleUser, "").Code, ShouldEqual, http.StatusOK)
+			So(deadline, ShouldBeBetween, 59*time.Second, time.Minute)
+		})
+		Convey("Check admin can override timeout", func() {
+			So(request(RoleAdmin, "5m").Code, ShouldEqual, http.StatusOK)
+			So(deadline, ShouldBeBetween, 4*time.Minute, 5*time.Minute)
+			So(request(RoleAdmin, "").Code, ShouldEqual, http.StatusOK)
+			So(deadline, ShouldBeLessThanOrEqualTo, time.Minute)
+		})
+		Convey("Check override is rejected for users", func() {
+			So(request(RoleUser, "5m").Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Check invalid value is rejected", func() {
+			So(request(RoleAdmin, "soon").Code, ShouldEqual, http.StatusBadRequest)
+			So(request(RoleAdmin, "-1s").Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/volume-manager/pkg/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// Tracing starts span for each request. Trace context from incoming headers is used as span parent.
+// Span is tagged with storage operation (see StorageMetrics), storage name from URL and outcome.
+func Tracing(provider tracing.TracerProvider) gin.HandlerFunc {
+	tracer := provider.Tracer("router")
+	return func(ctx *gin.Context) {
+		reqCtx := tracing.Extract(ctx.Request.Context(), ctx.Request.Header)
+		reqCtx, span := tracer.Start(reqCtx, "HTTP "+ctx.Request.Method)
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		ctx.Next()
+
+		if op := ctx.GetString(StorageOperation); op != "" {
+			span.SetName("storage." + op)
+			span.SetAttributes(tracing.String(tracing.AttributeOperation, op))
+		}
+		if name := ctx.Param("name"); name != "" {
+			span.SetAttributes(tracing.String(tracing.AttributeStorageName, name))
+		}
+		outcome := tracing.OutcomeSuccess
+		if ctx.Writer.Status() >= http.StatusBadRequest {
+			outcome = tracing.OutcomeError
+			if err := ctx.Errors.Last(); err != nil {
+				span.RecordError(err)
+			}
+		}
+		span.SetAttributes(tracing.String(tracing.AttributeOutcome, outcome))
+	}
+}
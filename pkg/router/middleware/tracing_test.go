@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"git.containerum.net/ch/volume-manager/pkg/tracing"
+	"github.com/appleboy/gofight"
+	"github.com/gin-gonic/gin"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	var handlerSpan tracing.SpanContext
+	e := gin.New()
+	e.Use(Tracing(tracing.NewTracerProvider(exporter)))
+	e.GET("/storages/:name", StorageMetrics("get"), func(c *gin.Context) {
+		handlerSpan = tracing.SpanContextFromContext(c.Request.Context())
+		if c.Param("name") == "missing" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusOK)
+	})
+	request := func(name string) {
+		gofight.New().GET("/storages/"+name).
+			SetHeader(gofight.H{tracing.TraceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {})
+	}
+
+	Convey("Test Tracing middleware", t, func() {
+		exporter.Reset()
+		Convey("Check request span is tagged", func() {
+			request("storage")
+			spans := exporter.Spans()
+			So(spans, ShouldHaveLength, 1)
+			So(spans[0].Name, ShouldEqual, "storage.get")
+			So(spans[0].Context, ShouldResemble, handlerSpan)
+			So(spans[0].Parent.SpanID.String(), ShouldEqual, "00f067aa0ba902b7")
+			So(spans[0].Attributes[tracing.AttributeStorageName], ShouldEqual, "storage")
+			So(spans[0].Attributes[tracing.AttributeOperation], ShouldEqual, "get")
+			So(spans[0].Attributes[tracing.AttributeOutcome], ShouldEqual, tracing.OutcomeSuccess)
+		})
+		Convey("Check failed request outcome", func() {
+			request("missing")
+			spans := exporter.Spans()
+			So(spans, ShouldHaveLength, 1)
+			So(spans[0].Attributes[tracing.AttributeOutcome], ShouldEqual, tracing.OutcomeError)
+		})
+	})
+}
@@ -2,9 +2,22 @@ package router
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/server"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStoragesLimit = 50
+	maxStoragesLimit     = 500
 )
 
 func getFilters(values url.Values) []string {
@@ -31,3 +44,233 @@ func getPaginationParams(values url.Values) (page, perPage int, err error) {
 	}
 	return
 }
+
+// userNamespaces returns IDs of namespaces from user headers.
+func userNamespaces(ctx *gin.Context) []string {
+	nsList, _ := ctx.Value(middleware.UserNamespaces).(middleware.UserHeaderDataMap)
+	ret := make([]string, 0, len(nsList))
+	for _, ns := range nsList {
+		ret = append(ret, ns.ID)
+	}
+	return ret
+}
+
+// saveUserNamespaces saves namespaces from user headers to request context, so server checks storages visibility.
+func saveUserNamespaces(ctx *gin.Context) {
+	if _, ok := ctx.Get(middleware.UserNamespaces); ok {
+		ctx.Request = ctx.Request.WithContext(server.WithUserNamespaces(ctx.Request.Context(), userNamespaces(ctx)))
+	}
+}
+
+// getStoragePaginationParams parses "limit" and "cursor" query params.
+// If none of them provided, unpaginated list should be returned.
+func getStoragePaginationParams(values url.Values) (pages model.StoragePagination, paginated bool, err error) {
+	limitStr, cursor := values.Get("limit"), values.Get("cursor")
+	if limitStr == "" && cursor == "" {
+		return model.StoragePagination{}, false, nil
+	}
+
+	pages = model.StoragePagination{
+		Limit:  defaultStoragesLimit,
+		Cursor: cursor,
+	}
+	if limitStr != "" {
+		pages.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || pages.Limit <= 0 {
+			err = fmt.Errorf("limit must be positive integer")
+			return
+		}
+	}
+	if pages.Limit > maxStoragesLimit {
+		pages.Limit = maxStoragesLimit
+	}
+	return pages, true, nil
+}
+
+// setPaginationLinks sets RFC 5988 "Link" header with "first", "prev" and "next" links of storages page.
+// Links are relative to request path and keep all request query params except cursor.
+func setPaginationLinks(ctx *gin.Context, page model.StoragesPage) {
+	link := func(cursor, rel string) string {
+		values := ctx.Request.URL.Query()
+		values.Del("cursor")
+		if cursor != "" {
+			values.Set("cursor", cursor)
+		}
+		ret := url.URL{Path: ctx.Request.URL.Path, RawQuery: values.Encode()}
+		return fmt.Sprintf("<%s>; rel=%q", ret.String(), rel)
+	}
+	links := []string{link("", "first")}
+	if page.HasPrev {
+		links = append(links, link(page.PrevCursor, "prev"))
+	}
+	if page.NextCursor != "" {
+		links = append(links, link(page.NextCursor, "next"))
+	}
+	ctx.Header(linkHeader, strings.Join(links, ", "))
+}
+
+// getStorageFilterParams parses storage list filtering query params.
+// Returns false if no filter params provided.
+func getStorageFilterParams(values url.Values) (filter model.StorageListFilter, filtered bool, err error) {
+	filter.NamePrefix = values.Get("name_prefix")
+	filtered = filter.NamePrefix != ""
+	for param, target := range map[string]*int{
+		"min_size": &filter.MinSize,
+		"max_size": &filter.MaxSize,
+	} {
+		str := values.Get(param)
+		if str == "" {
+			continue
+		}
+		*target, err = strconv.Atoi(str)
+		if err != nil || *target < 0 {
+			err = fmt.Errorf("%s must be non-negative integer", param)
+			return
+		}
+		filtered = true
+	}
+
+	if filter.SkipUsage, err = getBoolParam(values, "skip_usage"); err != nil {
+		return
+	}
+	filtered = filtered || filter.SkipUsage
+
+	if filter.ShowDeleted, err = getBoolParam(values, "show_deleted"); err != nil {
+		return
+	}
+	filtered = filtered || filter.ShowDeleted
+
+	if selector := values.Get("label_selector"); selector != "" {
+		if filter.LabelSelector, err = labels.Parse(selector); err != nil {
+			return
+		}
+		filtered = true
+	}
+
+	for param, target := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		str := values.Get(param)
+		if str == "" {
+			continue
+		}
+		t, parseErr := time.Parse(time.RFC3339, str)
+		if parseErr != nil {
+			err = fmt.Errorf("%s must be RFC3339 timestamp", param)
+			return
+		}
+		*target = &t
+		filtered = true
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		if filter.Sort, err = model.ParseStorageSort(sort); err != nil {
+			return
+		}
+		filtered = true
+	}
+	return
+}
+
+// getSizeHistoryParams parses "from", "to" (RFC3339 timestamps) and "limit" query params of storage size history.
+func getSizeHistoryParams(values url.Values) (filter model.StorageSizeHistoryFilter, errs requestErrors) {
+	for param, target := range map[string]**time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		str := values.Get(param)
+		if str == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			errs.add(newFieldError(param, fmt.Errorf("%s must be RFC3339 timestamp", param)))
+			continue
+		}
+		*target = &t
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		errs.add(newFieldError("to", fmt.Errorf("to must not be before from")))
+	}
+	if str := values.Get("limit"); str != "" {
+		limit, err := strconv.Atoi(str)
+		if err != nil || limit <= 0 {
+			errs.add(newFieldError("limit", fmt.Errorf("limit must be positive integer")))
+		}
+		filter.Limit = limit
+	}
+	return
+}
+
+const maxForecastDays = 365
+
+// getForecastWindow parses "days" query param of capacity forecast, server.DefaultForecastWindow is used if not provided.
+func getForecastWindow(values url.Values) (time.Duration, error) {
+	str := values.Get("days")
+	if str == "" {
+		return server.DefaultForecastWindow, nil
+	}
+	days, err := strconv.Atoi(str)
+	if err != nil || days <= 0 || days > maxForecastDays {
+		return 0, newFieldError("days", fmt.Errorf("days must be integer in range [1, %d]", maxForecastDays))
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// getBoolParam parses boolean query param, returns false if param not provided.
+func getBoolParam(values url.Values, name string) (bool, error) {
+	str := values.Get(name)
+	if str == "" {
+		return false, nil
+	}
+	ret, err := strconv.ParseBool(str)
+	if err != nil {
+		return false, fmt.Errorf("%s must be boolean", name)
+	}
+	return ret, nil
+}
+
+const (
+	eTagHeader        = "ETag"
+	ifMatchHeader     = "If-Match"
+	ifNoneMatchHeader = "If-None-Match"
+	totalCountHeader  = "X-Total-Count"
+	linkHeader        = "Link"
+)
+
+// notModified checks If-None-Match header against storage revision. Weak comparison is used, so weak tags may match.
+// If header matches, ETag and 304 status are set and request should be finished without body.
+func notModified(ctx *gin.Context, storage model.Storage) bool {
+	header := ctx.GetHeader(ifNoneMatchHeader)
+	if header == "" {
+		return false
+	}
+	etag := storage.ETag()
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			ctx.Header(eTagHeader, etag)
+			ctx.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// getETagCondition parses If-Match header. Returns nil condition if header is absent.
+// Weak entity tags never match because If-Match requires strong comparison.
+func getETagCondition(ctx *gin.Context) model.ETagCondition {
+	header := ctx.GetHeader(ifMatchHeader)
+	if header == "" {
+		return nil
+	}
+	cond := model.ETagCondition{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !strings.HasPrefix(tag, "W/") {
+			cond = append(cond, tag)
+		}
+	}
+	return cond
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const pprofPrefix = "/debug/pprof"
+
+// setupPprof registers runtime profiling endpoints, available for admins only.
+// Importing net/http/pprof also registers handlers in http.DefaultServeMux, it is never served,
+// so profiles are not reachable unless enabled in config.
+func (r *Router) setupPprof() {
+	group := r.engine.Group(pprofPrefix, middleware.IsAdmin)
+	group.GET("/*profile", pprofHandler)
+	// symbol lookup accepts addresses in body
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+}
+
+// pprofHandler dispatches profiles by name, named profiles (e.g. "heap", "goroutine") and index are served by pprof.Index.
+func pprofHandler(ctx *gin.Context) {
+	switch strings.Trim(ctx.Param("profile"), "/") {
+	case "cmdline":
+		pprof.Cmdline(ctx.Writer, ctx.Request)
+	case "profile":
+		pprof.Profile(ctx.Writer, ctx.Request)
+	case "symbol":
+		pprof.Symbol(ctx.Writer, ctx.Request)
+	case "trace":
+		pprof.Trace(ctx.Writer, ctx.Request)
+	default:
+		pprof.Index(ctx.Writer, ctx.Request)
+	}
+}
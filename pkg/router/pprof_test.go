@@ -0,0 +1,59 @@
+package router
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"git.containerum.net/ch/volume-manager/pkg/utils/validation"
+	"github.com/appleboy/gofight"
+	kubeModel "github.com/containerum/kube-client/pkg/model"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/universal-translator"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPprof(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	tv := &TranslateValidate{UniversalTranslator: translate, Validate: validate}
+
+	enabled, disabled := gin.New(), gin.New()
+	NewRouter(enabled, &kubeModel.ServiceStatus{}, tv, Config{Pprof: true})
+	NewRouter(disabled, &kubeModel.ServiceStatus{}, tv, Config{})
+
+	request := func(e *gin.Engine, path, role string) (ret gofight.HTTPResponse) {
+		h := gofight.H{
+			headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader: role,
+		}
+		if role == "user" {
+			h[headers.UserNamespacesXHeader] = base64.StdEncoding.EncodeToString([]byte(`[{"id": "ns-1", "access": "owner"}]`))
+		}
+		gofight.New().GET(path).
+			SetHeader(h).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test pprof endpoints", t, func() {
+		Convey("Check profiles are served to admins", func() {
+			So(request(enabled, "/debug/pprof/", "admin").Code, ShouldEqual, http.StatusOK)
+			resp := request(enabled, "/debug/pprof/goroutine?debug=1", "admin")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.Body.String(), ShouldContainSubstring, "goroutine profile")
+			So(request(enabled, "/debug/pprof/cmdline", "admin").Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Check users are rejected", func() {
+			So(request(enabled, "/debug/pprof/heap", "user").Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Check endpoints are not registered by default", func() {
+			So(request(disabled, "/debug/pprof/heap", "admin").Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
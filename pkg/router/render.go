@@ -0,0 +1,109 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/utils/jsoncase"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	mimeYAML   = "application/yaml"
+	mimeXYAML  = "application/x-yaml"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// render writes response in format requested by Accept header.
+// YAML is written if requested, JSON otherwise.
+func render(ctx *gin.Context, code int, obj interface{}) {
+	renderFormat(ctx, code, ctx.NegotiateFormat(binding.MIMEJSON, mimeYAML, mimeXYAML), obj)
+}
+
+// camelCaseJSONKey is a context key set if responses must have camelCase field names
+const camelCaseJSONKey = "camel_case_json"
+
+// camelCaseJSON marks requests to use camelCase field names in responses and to accept them in requests.
+func camelCaseJSON(ctx *gin.Context) {
+	ctx.Set(camelCaseJSONKey, true)
+}
+
+// marshalJSON marshals object to JSON with field names according to request naming.
+func marshalJSON(ctx *gin.Context, obj interface{}) ([]byte, error) {
+	if ctx.GetBool(camelCaseJSONKey) {
+		return jsoncase.Marshal(obj)
+	}
+	return json.Marshal(obj)
+}
+
+// renderFormat writes response in provided format (MIME type), JSON is used for unknown formats.
+func renderFormat(ctx *gin.Context, code int, format string, obj interface{}) {
+	switch format {
+	case mimeYAML, mimeXYAML:
+		data, err := marshalYAML(ctx, obj)
+		if err != nil {
+			middleware.AbortWithError(ctx, errors.ErrInternal().AddDetailsErr(err))
+			return
+		}
+		ctx.Data(code, format+"; charset=utf-8", data)
+	default:
+		if !ctx.GetBool(camelCaseJSONKey) {
+			ctx.JSON(code, obj)
+			return
+		}
+		data, err := marshalJSON(ctx, obj)
+		if err != nil {
+			middleware.AbortWithError(ctx, errors.ErrInternal().AddDetailsErr(err))
+			return
+		}
+		ctx.Data(code, binding.MIMEJSON+"; charset=utf-8", data)
+	}
+}
+
+// marshalYAML marshals object to YAML with the same field names as in JSON.
+func marshalYAML(ctx *gin.Context, obj interface{}) ([]byte, error) {
+	jsonData, err := marshalJSON(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(jsonData, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// jsonBinding returns binding for JSON request body. If camelCase naming enabled, both camelCase
+// and canonical field names are accepted.
+func jsonBinding(ctx *gin.Context) binding.Binding {
+	if ctx.GetBool(camelCaseJSONKey) {
+		return camelCaseJSONBinding{}
+	}
+	return binding.JSON
+}
+
+type camelCaseJSONBinding struct{}
+
+func (camelCaseJSONBinding) Name() string {
+	return "json"
+}
+
+func (camelCaseJSONBinding) Bind(req *http.Request, obj interface{}) error {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	data, err = jsoncase.Normalize(data, reflect.TypeOf(obj))
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return binding.JSON.Bind(req, obj)
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/utils/jsonschema"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"git.containerum.net/ch/volume-manager/pkg/utils/validation"
+	"github.com/gin-gonic/gin"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// schemaGenerator contains constraints checked by handlers in addition to binding tags.
+var schemaGenerator = jsonschema.Generator{
+	Fragments: map[string]jsonschema.Schema{
+		"dns_label": {
+			Pattern:   validation.DNSLabelPattern,
+			MaxLength: intPtr(validation.DNSLabelMaxLength),
+		},
+		"labels": {
+			PropertyNames: &jsonschema.Schema{
+				Pattern:   labels.KeyPattern,
+				MaxLength: intPtr(labels.MaxKeyLength),
+			},
+			AdditionalProperties: &jsonschema.Schema{
+				Type:      "string",
+				Pattern:   labels.ValuePattern,
+				MaxLength: intPtr(labels.MaxValueLength),
+			},
+		},
+		"annotations": {
+			PropertyNames: &jsonschema.Schema{
+				Pattern:   labels.KeyPattern,
+				MaxLength: intPtr(labels.MaxKeyLength),
+			},
+			AdditionalProperties: &jsonschema.Schema{Type: "string"},
+		},
+		"storage_driver": {
+			Enum: model.StorageDrivers,
+		},
+		"read_only": {
+			ReadOnly: true,
+		},
+	},
+}
+
+// storageSchema is generated once, models can't change at runtime.
+var storageSchema = schemaGenerator.Definitions(map[string]interface{}{
+	"Storage":              model.Storage{},
+	"UpdateStorageRequest": model.UpdateStorageRequest{},
+})
+
+func (sh *storageHandlers) getStorageSchemaHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, storageSchema)
+}
@@ -1,171 +1,2063 @@
 package router
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/events"
 	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
 	"git.containerum.net/ch/volume-manager/pkg/server"
-	kubeClientModel "github.com/containerum/kube-client/pkg/model"
+	"git.containerum.net/ch/volume-manager/pkg/utils/jsoncase"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"git.containerum.net/ch/volume-manager/pkg/utils/projection"
+	"github.com/containerum/cherry"
 	"github.com/containerum/utils/httputil"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
-	"github.com/sirupsen/logrus"
+	"github.com/satori/go.uuid"
 )
 
 type storageHandlers struct {
 	tv   *TranslateValidate
 	acts server.StorageActions
+
+	importConcurrency int
+	// strictJSON enables rejection of unknown fields in storage create and update requests
+	strictJSON bool
+}
+
+// bindStorageJSON binds storage create or update request body, unknown fields are reported in strict mode.
+func (sh *storageHandlers) bindStorageJSON(ctx *gin.Context, obj interface{}, errs *requestErrors) error {
+	if sh.strictJSON {
+		return bindStrictJSON(ctx, obj, errs)
+	}
+	return bindJSON(ctx, obj, errs)
+}
+
+// StorageNameReserved checks if storage name is a fixed path segment like "stats" or "by-id":
+// paths "/storages/stats" and "/storages/by-id/..." are dispatched to other handlers, so storage with such name could not be got.
+// It must be set as server.Config.ReservedStorageNames, reserved names are known after SetupStorageHandlers.
+func (r *Router) StorageNameReserved(name string) bool {
+	for _, d := range r.storageSegments {
+		if d.reserved(name) {
+			return true
+		}
+	}
+	return false
 }
 
 func (sh *storageHandlers) createStorageHandler(ctx *gin.Context) {
 	var req model.Storage
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	var errs requestErrors
+	if err := sh.bindStorageJSON(ctx, &req, &errs); err != nil {
 		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
 		return
 	}
-	if err := sh.acts.CreateStorage(ctx.Request.Context(), req); err != nil {
+	// server checks these fields too, they are checked here to report all field problems at once
+	errs.add(newFieldError("labels", labels.Validate(req.Labels)))
+	errs.add(newFieldError("annotations", labels.ValidateAnnotations(req.Annotations)))
+	errs.add(newFieldError("driver", model.ValidateStorageDriver(req.Driver)))
+	dryRun, err := getBoolParam(ctx.Request.URL.Query(), "dry_run")
+	errs.add(err)
+	getIfExists, err := getBoolParam(ctx.Request.URL.Query(), "get_if_exists")
+	errs.add(err)
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	if dryRun {
+		storage, err := sh.acts.CreateStorageDryRun(ctx.Request.Context(), req)
+		if err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+			return
+		}
+		render(ctx, http.StatusOK, storage)
+		return
+	}
+
+	if getIfExists {
+		storage, created, err := sh.acts.CreateOrGetStorage(ctx.Request.Context(), req)
+		if err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+			return
+		}
+		code := http.StatusOK
+		if created {
+			code = http.StatusCreated
+		}
+		render(ctx, code, storage)
+		return
+	}
+
+	storage, err := sh.acts.CreateStorage(ctx.Request.Context(), req)
+	if err != nil {
 		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
 		return
 	}
 
-	ctx.Status(http.StatusCreated)
+	ctx.Header(eTagHeader, storage.ETag())
+	render(ctx, http.StatusCreated, storage)
 }
 
 func (sh *storageHandlers) importStoragesHandler(ctx *gin.Context) {
-	var req []string
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	var req []model.StorageImportEntry
+	var rowErrs []csvRowError
+	if ctx.ContentType() == mimeCSV {
+		var err error
+		if req, rowErrs, err = parseImportCSV(ctx.Request.Body); err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+			return
+		}
+	} else if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	for _, entry := range req {
+		if err := checkImportEntryParams(entry); err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+			return
+		}
+	}
+
+	atomic, err := getBoolParam(ctx.Request.URL.Query(), "atomic")
+	if err != nil {
 		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
 		return
 	}
 
-	resp := kubeClientModel.ImportResponse{
-		Imported: []kubeClientModel.ImportResult{},
-		Failed:   []kubeClientModel.ImportResult{},
+	log := middleware.GetLogger(ctx)
+	if atomic {
+		storages := make([]model.Storage, 0, len(req))
+		names := make([]string, 0, len(req))
+		for _, entry := range req {
+			storages = append(storages, entry.Storage())
+			names = append(names, entry.Name)
+		}
+		if len(rowErrs) > 0 {
+			resp := model.NewStorageImportResponse()
+			resp.RollBack(names, rowErrs[0].Name, rowErrs[0].Err)
+			resp.Failed[0].Line = rowErrs[0].Line
+			for _, rowErr := range rowErrs[1:] {
+				resp.ImportLineFailed(rowErr.Line, rowErr.Name, rowErr.Err)
+			}
+			render(ctx, http.StatusAccepted, resp)
+			return
+		}
+		resp, err := sh.acts.ImportStoragesAtomic(ctx.Request.Context(), storages)
+		if err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+			return
+		}
+		if resp.RolledBack {
+			log.WithField("name", resp.Failed[0].Name).Warnf("atomic storages import rolled back: %s", resp.Failed[0].Message)
+		}
+		render(ctx, http.StatusAccepted, resp)
+		return
 	}
 
-	for _, r := range req {
-		store := model.Storage{
-			Name: r,
-			Size: 100,
+	resp := importStorages(ctx.Request.Context(), req, sh.importConcurrency, func(reqCtx context.Context, entry model.StorageImportEntry) error {
+		if err := sh.acts.ImportStorage(reqCtx, entry.Storage()); err != nil {
+			log.WithError(err).WithField("name", entry.Name).Warn("storage import failed")
+			return err
 		}
+		return nil
+	})
+	for _, rowErr := range rowErrs {
+		resp.ImportLineFailed(rowErr.Line, rowErr.Name, rowErr.Err)
+	}
 
-		if err := sh.acts.CreateStorage(ctx.Request.Context(), store); err != nil {
-			logrus.Warn(err)
-			resp.ImportFailed(r, "", err.Error())
-		} else {
-			resp.ImportSuccessful(r, "")
+	render(ctx, http.StatusAccepted, resp)
+}
+
+// checkImportEntryParams checks numeric params and driver of entry, invalid entry fails whole request.
+func checkImportEntryParams(entry model.StorageImportEntry) error {
+	if entry.Size != nil && *entry.Size <= 0 {
+		return fmt.Errorf("storage %q: size must be positive", entry.Name)
+	}
+	if entry.Reserved < 0 {
+		return fmt.Errorf("storage %q: reserved capacity must not be negative", entry.Name)
+	}
+	if entry.OvercommitRatio != nil && *entry.OvercommitRatio <= 0 {
+		return fmt.Errorf("storage %q: overcommit ratio must be positive", entry.Name)
+	}
+	if entry.WarnThreshold != nil && (*entry.WarnThreshold < 0 || *entry.WarnThreshold > 100) {
+		return fmt.Errorf("storage %q: warn threshold must be in range [0, 100]", entry.Name)
+	}
+	if err := model.ValidateStorageDriver(entry.Driver); err != nil {
+		return fmt.Errorf("storage %q: %v", entry.Name, err)
+	}
+	return nil
+}
+
+var exportFormats = map[string]string{
+	"json": binding.MIMEJSON,
+	"yaml": mimeYAML,
+}
+
+func (sh *storageHandlers) exportStoragesHandler(ctx *gin.Context) {
+	format := ctx.NegotiateFormat(binding.MIMEJSON, mimeYAML, mimeXYAML)
+	if formatParam := ctx.Query("format"); formatParam != "" {
+		var ok bool
+		if format, ok = exportFormats[formatParam]; !ok {
+			ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, fmt.Errorf("format must be one of: json, yaml")))
+			return
 		}
 	}
+	includeDeleted, err := getBoolParam(ctx.Request.URL.Query(), "include_deleted")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	entries, err := sh.acts.ExportStorages(ctx.Request.Context(), includeDeleted)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	renderFormat(ctx, http.StatusOK, format, entries)
+}
+
+// storageListFilter returns storage list filter from query params. Non-admins see only storages available in their namespaces.
+func storageListFilter(ctx *gin.Context) (filter model.StorageListFilter, filtered bool, err error) {
+	filter, filtered, err = getStorageFilterParams(ctx.Request.URL.Query())
+	if err != nil {
+		return
+	}
+	if driver := ctx.GetString(storageDriverKey); driver != "" {
+		filter.Driver = driver
+		filtered = true
+	}
+	if middleware.GetHeader(ctx, httputil.UserRoleXHeader) == middleware.RoleUser {
+		filter.NamespaceScoped = true
+		filter.Namespaces = userNamespaces(ctx)
+		filtered = true
+	}
+	return
+}
 
-	ctx.JSON(http.StatusAccepted, resp)
+// storageDriverKey is a context key of driver selected by storages list path
+const storageDriverKey = "storage_driver"
+
+// getStoragesByTypeHandler lists storages same as getStoragesHandler, but only storages with driver from path.
+func (sh *storageHandlers) getStoragesByTypeHandler(ctx *gin.Context) {
+	driver := ctx.Param("action")
+	if err := model.ValidateStorageDriver(driver); err != nil || driver == "" {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, requestErrors{newFieldError("type",
+			fmt.Errorf("unknown storage type %q, valid types: %s", driver, strings.Join(model.StorageDrivers, ", ")))}))
+		return
+	}
+	ctx.Set(storageDriverKey, driver)
+	sh.getStoragesHandler(ctx)
 }
 
 func (sh *storageHandlers) getStoragesHandler(ctx *gin.Context) {
-	storages, err := sh.acts.GetStorages(ctx.Request.Context())
+	countOnly, err := getBoolParam(ctx.Request.URL.Query(), "count_only")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if countOnly {
+		if total, ok := sh.countStorages(ctx); ok {
+			render(ctx, http.StatusOK, model.StoragesCount{Total: total})
+		}
+		return
+	}
+
+	pages, paginated, err := getStoragePaginationParams(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	filter, filtered, err := storageListFilter(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	var fields *projection.Projection
+	if list, ok := ctx.GetQuery("fields"); ok {
+		if ctx.GetBool(camelCaseJSONKey) {
+			list = jsoncase.ToSnake(list)
+		}
+		p, err := projection.Parse(list, model.Storage{})
+		if err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, newFieldError("fields", err)))
+			return
+		}
+		fields = &p
+	}
+
+	if ctx.NegotiateFormat(binding.MIMEJSON, mimeYAML, mimeXYAML, mimeNDJSON) == mimeNDJSON {
+		if paginated {
+			ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, fmt.Errorf("pagination is not supported for %s response", mimeNDJSON)))
+			return
+		}
+		sh.streamStorages(ctx, filter, fields)
+		return
+	}
+
+	var page model.StoragesPage
+	if filtered {
+		page, err = sh.acts.GetStoragesFiltered(ctx.Request.Context(), filter, pages)
+	} else {
+		page, err = sh.acts.GetStorages(ctx.Request.Context(), pages)
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	if paginated {
+		setPaginationLinks(ctx, page)
+	}
+
+	if fields == nil {
+		if !paginated {
+			render(ctx, http.StatusOK, page.Storages)
+			return
+		}
+		render(ctx, http.StatusOK, page)
+		return
+	}
+
+	projected := make([]interface{}, 0, len(page.Storages))
+	for _, storage := range page.Storages {
+		object, err := projectStorage(ctx, fields, storage)
+		if err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.HandleError(errors.ErrInternal().AddDetailsErr(err)))
+			return
+		}
+		projected = append(projected, object)
+	}
+	if !paginated {
+		render(ctx, http.StatusOK, projected)
+		return
+	}
+	render(ctx, http.StatusOK, projectedStoragesPage{
+		Storages:   projected,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
+}
+
+// projectedStoragesPage is a StoragesPage with projected storages
+type projectedStoragesPage struct {
+	Storages   []interface{} `json:"storages"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Total      int           `json:"total"`
+}
+
+// projectStorage applies fields projection to storage. Field names are converted to camelCase if it is enabled.
+func projectStorage(ctx *gin.Context, fields *projection.Projection, storage model.Storage) (interface{}, error) {
+	object, err := fields.Apply(storage)
+	if err != nil || !ctx.GetBool(camelCaseJSONKey) {
+		return object, err
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	data, err = jsoncase.Camelize(data, reflect.TypeOf(storage))
+	return json.RawMessage(data), err
+}
+
+// streamStorages writes storages as JSON Lines, one storage per line. Response is flushed after each batch
+// of storages fetched from database. Errors occurred after response is started can't be reported to client,
+// so stream is just interrupted.
+func (sh *storageHandlers) streamStorages(ctx *gin.Context, filter model.StorageListFilter, fields *projection.Projection) {
+	started := false
+	start := func() {
+		if !started {
+			started = true
+			ctx.Header("Content-Type", mimeNDJSON+"; charset=utf-8")
+			ctx.Status(http.StatusOK)
+		}
+	}
+	err := sh.acts.StreamStorages(ctx.Request.Context(), filter, func(batch []model.Storage) error {
+		start()
+		for _, storage := range batch {
+			var obj interface{} = storage
+			if fields != nil {
+				object, err := projectStorage(ctx, fields, storage)
+				if err != nil {
+					return errors.ErrInternal().AddDetailsErr(err)
+				}
+				obj = object
+			}
+			line, err := marshalJSON(ctx, obj)
+			if err != nil {
+				return err
+			}
+			if _, err := ctx.Writer.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		ctx.Writer.Flush()
+		return nil
+	})
+	switch {
+	case err == nil:
+		start()
+	case !started:
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+	default:
+		log := middleware.GetLogger(ctx)
+		if ctx.Request.Context().Err() != nil {
+			log.WithError(err).Info("storages stream interrupted by request cancellation")
+		} else {
+			log.WithError(err).Warn("storages stream failed")
+		}
+		ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+// countStorages counts storages matching request filter. If false returned, request is already aborted.
+func (sh *storageHandlers) countStorages(ctx *gin.Context) (int, bool) {
+	filter, _, err := storageListFilter(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return 0, false
+	}
+	total, err := sh.acts.CountStorages(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return 0, false
+	}
+	return total, true
+}
+
+func (sh *storageHandlers) headStoragesHandler(ctx *gin.Context) {
+	if total, ok := sh.countStorages(ctx); ok {
+		ctx.Header(totalCountHeader, strconv.Itoa(total))
+		ctx.Status(http.StatusOK)
+	}
+}
+
+func (sh *storageHandlers) getStorageHandler(ctx *gin.Context) {
+	storage, err := sh.acts.GetStorage(ctx.Request.Context(), ctx.Param("name"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	if notModified(ctx, storage) {
+		return
+	}
+	ctx.Header(eTagHeader, storage.ETag())
+	render(ctx, http.StatusOK, storage)
+}
+
+// getStorageByIDHandler handles "/storages/by-id/:id" path registered as "/storages/:name/:action".
+func (sh *storageHandlers) getStorageByIDHandler(ctx *gin.Context) {
+	id := ctx.Param("action")
+	if _, err := uuid.FromString(id); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, requestErrors{newFieldError("id", fmt.Errorf("id must be UUID"))}))
+		return
+	}
+
+	storage, err := sh.acts.GetStorageByID(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	if notModified(ctx, storage) {
+		return
+	}
+	ctx.Header(eTagHeader, storage.ETag())
+	render(ctx, http.StatusOK, storage)
+}
+
+func (sh *storageHandlers) getOverutilizedStoragesHandler(ctx *gin.Context) {
+	storages, err := sh.acts.GetOverutilizedStorages(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusOK, storages)
+}
+
+func (sh *storageHandlers) getIdleStoragesHandler(ctx *gin.Context) {
+	since, err := time.ParseDuration(ctx.Query("since"))
+	if err == nil && since <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, requestErrors{newFieldError("since",
+			fmt.Errorf("since must be positive duration, e.g. \"720h\": %v", err))}))
+		return
+	}
+
+	storages, err := sh.acts.GetIdleStorages(ctx.Request.Context(), since)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusOK, storages)
+}
+
+// getStorageQuotasHandler returns quotas of request user and namespaces. Admins may request quotas of any namespace,
+// other roles get quotas only of their own namespaces: requested namespaces missing in user headers are ignored.
+func (sh *storageHandlers) getStorageQuotasHandler(ctx *gin.Context) {
+	namespaces := userNamespaces(ctx)
+	if requested := ctx.QueryArray("namespace"); len(requested) > 0 {
+		if middleware.GetHeader(ctx, httputil.UserRoleXHeader) == middleware.RoleAdmin {
+			namespaces = append(namespaces, requested...)
+		} else {
+			own := make(map[string]bool, len(namespaces))
+			for _, ns := range namespaces {
+				own[ns] = true
+			}
+			namespaces = namespaces[:0]
+			for _, ns := range requested {
+				if own[ns] {
+					namespaces = append(namespaces, ns)
+				}
+			}
+		}
+	}
+	quotas, err := sh.acts.GetStorageQuotas(ctx.Request.Context(), namespaces)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusOK, quotas)
+}
+
+func (sh *storageHandlers) getStorageStatsHandler(ctx *gin.Context) {
+	stats, err := sh.acts.GetStorageStats(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusOK, stats)
+}
+
+func (sh *storageHandlers) getStorageAuditHandler(ctx *gin.Context) {
+	records, err := sh.acts.GetStorageAudit(ctx.Request.Context(), ctx.Param("name"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusOK, records)
+}
+
+func (sh *storageHandlers) getStorageSizeHistoryHandler(ctx *gin.Context) {
+	filter, errs := getSizeHistoryParams(ctx.Request.URL.Query())
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	records, err := sh.acts.GetStorageSizeHistory(ctx.Request.Context(), ctx.Param("name"), filter)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusOK, records)
+}
+
+func (sh *storageHandlers) getStorageForecastHandler(ctx *gin.Context) {
+	window, err := getForecastWindow(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	forecast, err := sh.acts.GetStorageForecast(ctx.Request.Context(), ctx.Param("name"), window)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusOK, forecast)
+}
+
+func (sh *storageHandlers) getStorageVolumesHandler(ctx *gin.Context) {
+	volumes, err := sh.acts.GetStorageVolumes(ctx.Request.Context(), ctx.Param("name"))
 	if err != nil {
 		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, storages)
+	render(ctx, http.StatusOK, volumes)
 }
 
 func (sh *storageHandlers) updateStorageHandler(ctx *gin.Context) {
 	var req model.UpdateStorageRequest
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	var errs requestErrors
+	if err := sh.bindStorageJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+	allowShrink, err := sh.allowShrinkParam(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	preview, err := getBoolParam(ctx.Request.URL.Query(), "preview")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if preview {
+		diff, err := sh.acts.PreviewStorageUpdate(ctx.Request.Context(), ctx.Param("name"), req, getETagCondition(ctx), allowShrink)
+		if err != nil {
+			ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+			return
+		}
+		render(ctx, http.StatusOK, diff)
+		return
+	}
+	if err := sh.acts.UpdateStorage(ctx.Request.Context(), ctx.Param("name"), req, getETagCondition(ctx), allowShrink); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) patchStorageHandler(ctx *gin.Context) {
+	var req model.PatchStorageRequest
+	var errs requestErrors
+	if err := sh.bindStorageJSON(ctx, &req, &errs); err != nil {
 		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
 		return
 	}
-	if err := sh.acts.UpdateStorage(ctx.Request.Context(), ctx.Param("name"), req); err != nil {
+	if req.IsEmpty() {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, fmt.Errorf("no fields to update provided")))
+		return
+	}
+	validatePatchLabels(&errs, "", req.Labels)
+	validatePatchAnnotations(&errs, "", req.Annotations)
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+	allowShrink, err := sh.allowShrinkParam(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	if err := sh.acts.PatchStorage(ctx.Request.Context(), ctx.Param("name"), req, getETagCondition(ctx), allowShrink); err != nil {
 		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
 		return
 	}
 	ctx.Status(http.StatusAccepted)
 }
 
-func (sh *storageHandlers) deleteStorageHandler(ctx *gin.Context) {
-	if err := sh.acts.DeleteStorage(ctx.Request.Context(), ctx.Param("name")); err != nil {
-		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
-		return
-	}
-	ctx.Status(http.StatusAccepted)
-}
+func (sh *storageHandlers) replaceStorageLabelsHandler(ctx *gin.Context) {
+	var req map[string]string
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	errs.add(newFieldError("labels", labels.Validate(req)))
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+	storage, err := sh.acts.ReplaceStorageLabels(ctx.Request.Context(), ctx.Param("name"), req, getETagCondition(ctx))
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	renderStorageLabels(ctx, storage)
+}
+
+func (sh *storageHandlers) patchStorageLabelsHandler(ctx *gin.Context) {
+	var req map[string]*string
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if len(req) == 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, fmt.Errorf("no labels to update provided")))
+		return
+	}
+	validatePatchLabels(&errs, "", req)
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+	storage, err := sh.acts.PatchStorageLabels(ctx.Request.Context(), ctx.Param("name"), req, getETagCondition(ctx))
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	renderStorageLabels(ctx, storage)
+}
+
+// renderStorageLabels writes labels of updated storage, storage without labels has empty labels object.
+func renderStorageLabels(ctx *gin.Context, storage model.Storage) {
+	resp := model.StorageLabels{Labels: storage.Labels}
+	if resp.Labels == nil {
+		resp.Labels = map[string]string{}
+	}
+	ctx.Header(eTagHeader, storage.ETag())
+	render(ctx, http.StatusOK, resp)
+}
+
+// allowShrinkParam returns "allow_shrink" query param. Only admins may shrink storage below used capacity.
+func (sh *storageHandlers) allowShrinkParam(ctx *gin.Context) (bool, error) {
+	allowShrink, err := getBoolParam(ctx.Request.URL.Query(), "allow_shrink")
+	if err != nil {
+		return false, errors.ErrRequestValidationFailed().AddDetailsErr(err)
+	}
+	if allowShrink && middleware.GetHeader(ctx, httputil.UserRoleXHeader) != middleware.RoleAdmin {
+		return false, errors.ErrAdminRequired()
+	}
+	return allowShrink, nil
+}
+
+func validatePatchLabels(errs *requestErrors, prefix string, patch map[string]*string) {
+	for key, value := range patch {
+		err := labels.ValidateKey(key)
+		if err == nil && value != nil {
+			err = labels.ValidateValue(*value)
+		}
+		errs.add(newFieldError(prefix+"labels["+key+"]", err))
+	}
+}
+
+// validatePatchAnnotations checks annotations keys, total size is checked by server after patch is applied.
+func validatePatchAnnotations(errs *requestErrors, prefix string, patch map[string]*string) {
+	for key := range patch {
+		errs.add(newFieldError(prefix+"annotations["+key+"]", labels.ValidateKey(key)))
+	}
+}
+
+func (sh *storageHandlers) deleteStorageHandler(ctx *gin.Context) {
+	force, err := getBoolParam(ctx.Request.URL.Query(), "force")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	cascade, err := getBoolParam(ctx.Request.URL.Query(), "cascade")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	idempotent, err := getBoolParam(ctx.Request.URL.Query(), "idempotent")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	if force {
+		err = sh.acts.PurgeStorage(ctx.Request.Context(), ctx.Param("name"), cascade)
+	} else {
+		err = sh.acts.DeleteStorage(ctx.Request.Context(), ctx.Param("name"), cascade)
+	}
+	switch {
+	case err == nil:
+		ctx.Status(http.StatusAccepted)
+	case idempotent && cherry.Equals(err, errors.ErrResourceNotExists()):
+		// storage is already absent, so desired state is reached; 204 tells that nothing was deleted
+		ctx.Status(http.StatusNoContent)
+	default:
+		// without "idempotent" missing storage is reported as 404, so callers can detect it
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+	}
+}
+
+func (sh *storageHandlers) bulkDeleteStoragesHandler(ctx *gin.Context) {
+	var req model.BulkDeleteStoragesRequest
+	if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	atomic, err := getBoolParam(ctx.Request.URL.Query(), "atomic")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	resp, err := sh.acts.DeleteStorages(ctx.Request.Context(), req.Names, atomic)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusAccepted, resp)
+}
+
+func (sh *storageHandlers) bulkUpdateStoragesHandler(ctx *gin.Context) {
+	var req model.BulkUpdateStoragesRequest
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	atomic, err := getBoolParam(ctx.Request.URL.Query(), "atomic")
+	errs.add(err)
+	seen := make(map[string]bool, len(req.Updates))
+	for i, update := range req.Updates {
+		prefix := fmt.Sprintf("updates[%d].", i)
+		// names may be case-insensitive, so names differing by case are duplicates too
+		key := strings.ToLower(update.Name)
+		if seen[key] {
+			errs.add(newFieldError(prefix+"name", fmt.Errorf("storage %s is listed several times", update.Name)))
+		}
+		seen[key] = true
+		if update.Patch.IsEmpty() {
+			errs.add(newFieldError(prefix+"patch", fmt.Errorf("no fields to update provided")))
+		}
+		validatePatchLabels(&errs, prefix+"patch.", update.Patch.Labels)
+		validatePatchAnnotations(&errs, prefix+"patch.", update.Patch.Annotations)
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	resp, err := sh.acts.UpdateStorages(ctx.Request.Context(), req.Updates, atomic)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusAccepted, resp)
+}
+
+func (sh *storageHandlers) applyStoragesHandler(ctx *gin.Context) {
+	var req []model.StorageImportEntry
+	if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	var errs requestErrors
+	seen := make(map[string]bool, len(req))
+	for i, entry := range req {
+		prefix := fmt.Sprintf("[%d].", i)
+		// names may be case-insensitive, so names differing by case are duplicates too
+		key := strings.ToLower(entry.Name)
+		if seen[key] {
+			errs.add(newFieldError(prefix+"name", fmt.Errorf("storage %s is listed several times", entry.Name)))
+		}
+		seen[key] = true
+		errs.add(checkImportEntryParams(entry))
+	}
+	prune, err := getBoolParam(ctx.Request.URL.Query(), "prune")
+	errs.add(err)
+	atomic, err := getBoolParam(ctx.Request.URL.Query(), "atomic")
+	errs.add(err)
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	resp, err := sh.acts.ApplyStorages(ctx.Request.Context(), req, prune, atomic)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+
+	render(ctx, http.StatusAccepted, resp)
+}
+
+func (sh *storageHandlers) renameStorageHandler(ctx *gin.Context) {
+	var req model.RenameStorageRequest
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	if err := sh.acts.RenameStorage(ctx.Request.Context(), ctx.Param("name"), req.NewName); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) transferStorageHandler(ctx *gin.Context) {
+	var req model.TransferStorageRequest
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	if err := sh.acts.TransferStorage(ctx.Request.Context(), ctx.Param("name"), req.OwnerUserID); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) cloneStorageHandler(ctx *gin.Context) {
+	var req model.CloneStorageRequest
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	if err := sh.acts.CloneStorage(ctx.Request.Context(), ctx.Param("name"), req.TargetName); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusCreated)
+}
+
+func (sh *storageHandlers) migrateVolumesHandler(ctx *gin.Context) {
+	var req model.StorageMigrateRequest
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	resp, err := sh.acts.MigrateVolumes(ctx.Request.Context(), ctx.Param("name"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	render(ctx, http.StatusAccepted, resp)
+}
+
+func (sh *storageHandlers) storageMaintenanceHandler(ctx *gin.Context) {
+	var req model.StorageMaintenanceRequest
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	if err := sh.acts.SetStorageMaintenance(ctx.Request.Context(), ctx.Param("name"), req); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) pinStorageHandler(ctx *gin.Context) {
+	if err := sh.acts.SetStoragePinned(ctx.Request.Context(), ctx.Param("name"), true); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) unpinStorageHandler(ctx *gin.Context) {
+	if err := sh.acts.SetStoragePinned(ctx.Request.Context(), ctx.Param("name"), false); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) recomputeStorageUsageHandler(ctx *gin.Context) {
+	ret, err := sh.acts.RecomputeUsage(ctx.Request.Context(), ctx.Param("name"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	render(ctx, http.StatusOK, ret[0])
+}
+
+func (sh *storageHandlers) recomputeStoragesUsageHandler(ctx *gin.Context) {
+	ret, err := sh.acts.RecomputeUsage(ctx.Request.Context(), "")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	render(ctx, http.StatusOK, ret)
+}
+
+func (sh *storageHandlers) createStorageWebhookHandler(ctx *gin.Context) {
+	var req model.CreateStorageWebhookRequest
+	var errs requestErrors
+	if err := bindJSON(ctx, &req, &errs); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	for i, event := range req.Events {
+		if !events.Operation(event).IsKnown() {
+			errs.add(newFieldError(fmt.Sprintf("events[%d]", i), fmt.Errorf("unknown event %q", event)))
+		}
+	}
+	if len(errs) > 0 {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, errs))
+		return
+	}
+
+	webhook, err := sh.acts.CreateStorageWebhook(ctx.Request.Context(), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	render(ctx, http.StatusCreated, webhook)
+}
+
+func (sh *storageHandlers) getStorageWebhooksHandler(ctx *gin.Context) {
+	webhooks, err := sh.acts.GetStorageWebhooks(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	render(ctx, http.StatusOK, webhooks)
+}
+
+func (sh *storageHandlers) syncStoragesHandler(ctx *gin.Context) {
+	apply, err := getBoolParam(ctx.Request.URL.Query(), "apply")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+
+	report, err := sh.acts.SyncStorages(ctx.Request.Context(), apply)
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	render(ctx, http.StatusOK, report)
+}
+
+func (sh *storageHandlers) restoreStorageHandler(ctx *gin.Context) {
+	if err := sh.acts.RestoreStorage(ctx.Request.Context(), ctx.Param("name")); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) touchStorageHandler(ctx *gin.Context) {
+	reconciled, err := getBoolParam(ctx.Request.URL.Query(), "reconciled")
+	if err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.BadRequest(ctx, err))
+		return
+	}
+	if err := sh.acts.Touch(ctx.Request.Context(), ctx.Param("name"), reconciled); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (sh *storageHandlers) setDefaultStorageHandler(ctx *gin.Context) {
+	if err := sh.acts.SetDefaultStorage(ctx.Request.Context(), ctx.Param("name")); err != nil {
+		ctx.AbortWithStatusJSON(sh.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+// SetupStorageHandlers registers storage handlers over acts. Tracing and caching of acts are set up by caller,
+// so the same actions can be shared with other transports.
+func (r *Router) SetupStorageHandlers(acts server.StorageActions) {
+	handlers := &storageHandlers{
+		tv:                r.tv,
+		acts:              acts,
+		importConcurrency: r.importConcurrency,
+		strictJSON:        r.strictJSON,
+	}
+	r.readiness = acts.Ping
+
+	group := r.engine.Group("/storages", middleware.OperationTimeout(r.operationTimeout), r.timeLimited())
+
+	// swagger:operation POST /storages Storages CreateStorage
+	//
+	// Create storage.
+	// If strict JSON fields mode is enabled, request with unknown body fields is rejected (400).
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: Idempotency-Key
+	//    in: header
+	//    type: string
+	//    description: repeated requests with same key return original response
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/Storage'
+	//  - name: dry_run
+	//    in: query
+	//    type: boolean
+	//    description: validate request without creating storage
+	//  - name: get_if_exists
+	//    in: query
+	//    type: boolean
+	//    description: return existing storage with the same name instead of conflict error
+	// responses:
+	//   '200':
+	//     description: storage which would be created in dry run mode or existing storage if "get_if_exists" is set
+	//     schema:
+	//       $ref: '#/definitions/Storage'
+	//   '201':
+	//     description: created storage with generated ID
+	//     schema:
+	//       $ref: '#/definitions/Storage'
+	//   default:
+	//     $ref: '#/responses/error'
+	group.POST("", middleware.StorageMetrics("create"), r.authorized("create"), r.rateLimited("create"), r.bodyLimited("create"),
+		middleware.Idempotent(r.idempotency), handlers.createStorageHandler)
+
+	// swagger:operation GET /storages Storages GetStorages
+	//
+	// Get storage list.
+	// If "limit" or "cursor" provided, returns StoragesPage instead of plain array.
+	// Paginated response also has "Link" header with "first", "prev" and "next" page links keeping other query params.
+	// Non-admin users see only storages available in their namespaces.
+	// With "Accept: application/x-ndjson" storages are streamed one per line, pagination is not supported then.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	//  - application/x-ndjson
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: limit
+	//    in: query
+	//    type: integer
+	//    minimum: 1
+	//    maximum: 500
+	//    default: 50
+	//  - name: cursor
+	//    in: query
+	//    type: string
+	//    description: opaque token from "next_cursor" of previous page
+	//  - name: name_prefix
+	//    in: query
+	//    type: string
+	//  - name: min_size
+	//    in: query
+	//    type: integer
+	//    minimum: 0
+	//  - name: max_size
+	//    in: query
+	//    type: integer
+	//    minimum: 0
+	//  - name: skip_usage
+	//    in: query
+	//    type: boolean
+	//    description: do not compute "used_size" and "free_size"
+	//  - name: show_deleted
+	//    in: query
+	//    type: boolean
+	//    description: include soft-deleted storages
+	//  - name: label_selector
+	//    in: query
+	//    type: string
+	//    description: Kubernetes-style label selector, e.g. "team=payments,tier!=bronze,env in (prod,stage),!legacy"
+	//  - name: created_after
+	//    in: query
+	//    type: string
+	//    format: date-time
+	//    description: select storages created after this time (RFC3339)
+	//  - name: created_before
+	//    in: query
+	//    type: string
+	//    format: date-time
+	//    description: select storages created before this time (RFC3339)
+	//  - name: sort
+	//    in: query
+	//    type: string
+	//    enum: [name, -name, size, -size, created_at, -created_at]
+	//    default: name
+	//    description: sort key, "-" prefix means descending order
+	//  - name: count_only
+	//    in: query
+	//    type: boolean
+	//    description: return only number of matching storages as StoragesCount
+	//  - name: fields
+	//    in: query
+	//    type: string
+	//    description: comma-separated list of Storage fields to return, e.g. "name,size"
+	// responses:
+	//   '200':
+	//     description: storages list
+	//     headers:
+	//       Link:
+	//         type: string
+	//         description: RFC 5988 pagination links, only for paginated response
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/Storage'
+	//   default:
+	//     $ref: '#/responses/error'
+	r.engine.GET("/storages", middleware.StorageMetrics("list"), r.authorized("list"), r.rateLimited("list"),
+		middleware.OperationTimeout(r.operationTimeout), r.timeLimited(), handlers.getStoragesHandler)
+
+	// swagger:operation HEAD /storages Storages CountStorages
+	//
+	// Get number of storages in X-Total-Count header.
+	// Accepts same filters as storages list.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	// responses:
+	//   '200':
+	//     description: storages counted
+	//     headers:
+	//       X-Total-Count:
+	//         type: integer
+	//         description: number of matching storages
+	//   default:
+	//     $ref: '#/responses/error'
+	r.engine.HEAD("/storages", middleware.StorageMetrics("count"), r.authorized("count"), r.rateLimited("count"),
+		middleware.OperationTimeout(r.operationTimeout), r.timeLimited(), handlers.headStoragesHandler)
+
+	// swagger:operation GET /storages/{name} Storages GetStorage
+	//
+	// Get storage.
+	// Users get 404 for storages not available in their namespaces, same as these storages are not listed.
+	// If "If-None-Match" header contains current storage revision, 304 is returned without body.
+	// Revision is not changed when used capacity changes by volumes, so cached storage may have outdated "used".
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: If-None-Match
+	//    in: header
+	//    type: string
+	//    description: storage revisions (ETag values) known by client
+	// responses:
+	//   '200':
+	//     description: storage
+	//     schema:
+	//       $ref: '#/definitions/Storage'
+	//     headers:
+	//       ETag:
+	//         type: string
+	//         description: storage revision
+	//   '304':
+	//     description: storage revision matches If-None-Match header
+	//     headers:
+	//       ETag:
+	//         type: string
+	//         description: storage revision
+	//   default:
+	//     $ref: '#/responses/error'
+	getActions := newSegmentDispatcher("name", r.authorized("get"), r.rateLimited("get"), handlers.getStorageHandler)
+
+	// swagger:operation GET /storages/overutilized Storages GetOverutilizedStorages
+	//
+	// Get storages which usage reached warning threshold.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	// responses:
+	//   '200':
+	//     description: overutilized storages
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/Storage'
+	//   default:
+	//     $ref: '#/responses/error'
+	getActions.handle("overutilized", "overutilized", r.authorized("overutilized"), r.rateLimited("overutilized"), handlers.getOverutilizedStoragesHandler)
+
+	// swagger:operation GET /storages/idle Storages GetIdleStorages
+	//
+	// Get storages without volume operations (create, resize, delete or move) during provided period.
+	// Storages never used are returned if they were created before period started.
+	// Pinned storages are not returned, they are exempt from reclaim.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: since
+	//    in: query
+	//    type: string
+	//    required: true
+	//    description: period without activity, e.g. "720h"
+	// responses:
+	//   '200':
+	//     description: idle storages
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/Storage'
+	//   default:
+	//     $ref: '#/responses/error'
+	getActions.handle("idle", "idle", r.authorized("idle"), r.rateLimited("idle"), handlers.getIdleStoragesHandler)
+
+	// swagger:operation GET /storages/schema Storages GetStorageSchema
+	//
+	// Get JSON Schema (draft-07) of Storage and UpdateStorageRequest models.
+	// Schema is generated from models validation rules.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	// responses:
+	//   '200':
+	//     description: JSON Schema document, models are placed in "definitions"
+	//     schema:
+	//       type: object
+	//   default:
+	//     $ref: '#/responses/error'
+	getActions.handle("schema", "schema", r.authorized("schema"), r.rateLimited("schema"), handlers.getStorageSchemaHandler)
+
+	// swagger:operation GET /storages/quota Storages GetStorageQuotas
+	//
+	// Get usage of storage quotas applied to user, user namespaces and requested namespaces.
+	// Only tenants with configured quota are listed. Non-admin roles get quotas only of requested namespaces
+	// available to them, so if namespaces are requested, other namespaces of user are not listed.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: namespace
+	//    in: query
+	//    type: array
+	//    items:
+	//      type: string
+	//    collectionFormat: multi
+	//    description: IDs of namespaces to get quotas of
+	// responses:
+	//   '200':
+	//     description: storage quotas usage
+	//     schema:
+	//       $ref: '#/definitions/StorageQuotasResponse'
+	//   default:
+	//     $ref: '#/responses/error'
+	getActions.handle("quota", "quota", r.authorized("quota"), r.rateLimited("quota"), handlers.getStorageQuotasHandler)
+
+	// swagger:operation GET /storages/stats Storages GetStorageStats
+	//
+	// Get total count, size, used and free capacity of not deleted storages with breakdown by driver.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	// responses:
+	//   '200':
+	//     description: storages aggregates
+	//     schema:
+	//       $ref: '#/definitions/StorageStats'
+	//   default:
+	//     $ref: '#/responses/error'
+	getActions.handle("stats", "stats", r.authorized("stats"), r.rateLimited("stats"), handlers.getStorageStatsHandler)
+
+	// swagger:operation GET /storages/webhooks Storages GetStorageWebhooks
+	//
+	// Get registered storage events webhooks. Secrets are not returned.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	// responses:
+	//   '200':
+	//     description: storage webhooks
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/StorageWebhook'
+	//   default:
+	//     $ref: '#/responses/error'
+	getActions.handle("webhooks", "webhooks", r.authorized("webhooks"), r.rateLimited("webhooks"), handlers.getStorageWebhooksHandler)
+
+	group.GET("/:name", middleware.StorageMetrics("get"), getActions.dispatch)
+
+	// swagger:operation GET /storages/{name}/audit Storages GetStorageAudit
+	//
+	// Get storage changes history.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	// responses:
+	//   '200':
+	//     description: storage audit records, oldest first
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/StorageAuditRecord'
+	//   default:
+	//     $ref: '#/responses/error'
+	storageGetActions := newSegmentDispatcher("action")
+	storageGetActions.handle("audit", "audit", r.authorized("audit"), r.rateLimited("audit"), handlers.getStorageAuditHandler)
+
+	// swagger:operation GET /storages/{name}/history Storages GetStorageSizeHistory
+	//
+	// Get storage size changes timeline.
+	// Records are written on storage creation and on each operation changing storage size.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: from
+	//    in: query
+	//    type: string
+	//    format: date-time
+	//    description: skip changes made before this time
+	//  - name: to
+	//    in: query
+	//    type: string
+	//    format: date-time
+	//    description: skip changes made after this time
+	//  - name: limit
+	//    in: query
+	//    type: integer
+	//    description: maximal number of records, latest changes are returned
+	// responses:
+	//   '200':
+	//     description: storage size changes, oldest first
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/StorageSizeRecord'
+	//   default:
+	//     $ref: '#/responses/error'
+	storageGetActions.handle("history", "history", r.authorized("history"), r.rateLimited("history"), handlers.getStorageSizeHistoryHandler)
+
+	// swagger:operation GET /storages/{name}/forecast Storages GetStorageForecast
+	//
+	// Get storage capacity forecast.
+	// Used capacity history is restored from volumes created and deleted during window and fitted with line,
+	// so resizes and migrations of volumes are not taken into account.
+	// If history is too short, "insufficient_data" status is returned without growth rate.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: days
+	//    in: query
+	//    type: integer
+	//    minimum: 1
+	//    maximum: 365
+	//    default: 30
+	//    description: usage history window
+	// responses:
+	//   '200':
+	//     description: storage capacity forecast
+	//     schema:
+	//       $ref: '#/definitions/StorageCapacityForecast'
+	//   default:
+	//     $ref: '#/responses/error'
+	storageGetActions.handle("forecast", "forecast", r.authorized("forecast"), r.rateLimited("forecast"), handlers.getStorageForecastHandler)
+
+	// swagger:operation GET /storages/{name}/volumes Storages GetStorageVolumes
+	//
+	// Get volumes placed on storage.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	// responses:
+	//   '200':
+	//     description: storage volumes
+	//     schema:
+	//       $ref: '#/definitions/VolumesList'
+	//   default:
+	//     $ref: '#/responses/error'
+	storageGetActions.handle("volumes", "volumes", r.authorized("volumes"), r.rateLimited("volumes"), handlers.getStorageVolumesHandler)
+
+	// swagger:operation GET /storages/by-id/{id} Storages GetStorageByID
+	//
+	// Get storage by ID. Unlike name, ID does not change on storage rename.
+	// Users get 404 for storages not available in their namespaces.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: id
+	//    in: path
+	//    type: string
+	//    format: uuid
+	//    required: true
+	//  - name: If-None-Match
+	//    in: header
+	//    type: string
+	//    description: storage revisions (ETag values) known by client
+	// responses:
+	//   '200':
+	//     description: storage
+	//     schema:
+	//       $ref: '#/definitions/Storage'
+	//     headers:
+	//       ETag:
+	//         type: string
+	//         description: storage revision
+	//   '304':
+	//     description: storage revision matches If-None-Match header
+	//   default:
+	//     $ref: '#/responses/error'
+	nestedGetActions := newSegmentDispatcher("name", storageGetActions.dispatch)
+	nestedGetActions.handle("by-id", "get_by_id", r.authorized("get_by_id"), r.rateLimited("get"), handlers.getStorageByIDHandler)
+
+	// swagger:operation GET /storages/by-type/{type} Storages GetStoragesByType
+	//
+	// Get storages with driver (backend type).
+	// Accepts the same filtering, sorting and pagination parameters as storages list.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	//  - application/x-ndjson
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: type
+	//    in: path
+	//    type: string
+	//    enum: [nfs, ceph-rbd, local]
+	//    required: true
+	// responses:
+	//   '200':
+	//     description: storages list, StoragesPage if "limit" or "cursor" provided
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/Storage'
+	//   default:
+	//     $ref: '#/responses/error'
+	nestedGetActions.handle("by-type", "list_by_type", r.authorized("list_by_type"), r.rateLimited("list"), handlers.getStoragesByTypeHandler)
+
+	group.GET("/:name/:action", middleware.StorageMetrics("get"), nestedGetActions.dispatch)
+	r.storageSegments = []*segmentDispatcher{getActions, nestedGetActions}
+
+	// swagger:operation PUT /storages/{name} Storages UpdateStorage
+	//
+	// Update storage.
+	// If strict JSON fields mode is enabled, request with unknown body fields is rejected (400).
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/UpdateStorageRequest'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: If-Match
+	//    in: header
+	//    type: string
+	//    description: update storage only if its ETag matches, 412 returned otherwise
+	//  - name: allow_shrink
+	//    in: query
+	//    type: boolean
+	//    description: allow admin to set size less than used capacity, 409 returned otherwise
+	//  - name: preview
+	//    in: query
+	//    type: boolean
+	//    description: validate update and return changed fields without persisting anything
+	// responses:
+	//   '200':
+	//     description: changes update would make (preview=true)
+	//     schema:
+	//       $ref: '#/definitions/StorageUpdatePreview'
+	//   '202':
+	//     description: storage updated
+	//   default:
+	//     $ref: '#/responses/error'
+	group.PUT("/:name", middleware.StorageMetrics("update"), r.authorized("update"), r.rateLimited("update"), handlers.updateStorageHandler)
+
+	// swagger:operation PATCH /storages/{name} Storages PatchStorage
+	//
+	// Partially update storage. Only provided fields are changed.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/PatchStorageRequest'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: If-Match
+	//    in: header
+	//    type: string
+	//    description: update storage only if its ETag matches, 412 returned otherwise
+	//  - name: allow_shrink
+	//    in: query
+	//    type: boolean
+	//    description: allow admin to set size less than used capacity, 409 returned otherwise
+	// responses:
+	//   '202':
+	//     description: storage updated
+	//   default:
+	//     $ref: '#/responses/error'
+	group.PATCH("/:name", middleware.StorageMetrics("patch"), r.authorized("patch"), r.rateLimited("patch"), handlers.patchStorageHandler)
+
+	// swagger:operation PUT /storages/{name}/labels Storages ReplaceStorageLabels
+	//
+	// Replace all storage labels. Other storage fields are not changed.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    description: new labels, empty object removes all labels
+	//    schema:
+	//      type: object
+	//      additionalProperties:
+	//        type: string
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: If-Match
+	//    in: header
+	//    type: string
+	//    description: update labels only if storage ETag matches, 412 returned otherwise
+	// responses:
+	//   '200':
+	//     description: storage labels after update
+	//     schema:
+	//       $ref: '#/definitions/StorageLabels'
+	//   default:
+	//     $ref: '#/responses/error'
+	group.PUT("/:name/labels", middleware.StorageMetrics("replace_labels"), r.authorized("replace_labels"), r.rateLimited("replace_labels"), handlers.replaceStorageLabelsHandler)
+
+	// swagger:operation PATCH /storages/{name}/labels Storages PatchStorageLabels
+	//
+	// Set or remove individual storage labels. Labels missing in request are not changed.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    description: labels to set, null value removes label
+	//    schema:
+	//      type: object
+	//      additionalProperties:
+	//        type: string
+	//        x-nullable: true
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: If-Match
+	//    in: header
+	//    type: string
+	//    description: update labels only if storage ETag matches, 412 returned otherwise
+	// responses:
+	//   '200':
+	//     description: storage labels after update
+	//     schema:
+	//       $ref: '#/definitions/StorageLabels'
+	//   default:
+	//     $ref: '#/responses/error'
+	group.PATCH("/:name/labels", middleware.StorageMetrics("patch_labels"), r.authorized("patch_labels"), r.rateLimited("patch_labels"), handlers.patchStorageLabelsHandler)
+
+	// swagger:operation DELETE /storages/{name} Storages DeleteStorage
+	//
+	// Delete storage.
+	// Storage is moved to trash and can be restored unless "force" is set.
+	// Storage which still has volumes is marked as terminating unless "cascade" or "force" is set:
+	// it rejects new volumes (409) and is deleted after all its volumes are gone. If volumes remain
+	// after termination timeout, "termination_status" of storage describes them.
+	// With "force" storage which still has volumes can't be deleted (409 returned) unless "cascade" is set.
+	// Pinned storage can't be deleted with "force" (409 returned), it still can be moved to trash.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: force
+	//    in: query
+	//    type: boolean
+	//    description: delete storage permanently
+	//  - name: cascade
+	//    in: query
+	//    type: boolean
+	//    description: delete storage volumes too, so storage is deleted immediately
+	//  - name: idempotent
+	//    in: query
+	//    type: boolean
+	//    description: treat missing storage as deleted (204 returned instead of 404), for safe retries
+	// responses:
+	//   '202':
+	//     description: storage deleted or marked as terminating
+	//   '204':
+	//     description: storage not exists, returned only if "idempotent" is set
+	//   default:
+	//     $ref: '#/responses/error'
+	group.DELETE("/:name", middleware.StorageMetrics("delete"), r.authorized("delete"), r.rateLimited("delete"), handlers.deleteStorageHandler)
+
+	// swagger:operation POST /storages/{name}/restore Storages RestoreStorage
+	//
+	// Restore deleted storage.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	// responses:
+	//   '202':
+	//     description: storage restored
+	//   default:
+	//     $ref: '#/responses/error'
+	group.POST("/:name/restore", middleware.StorageMetrics("restore"), r.authorized("restore"), r.rateLimited("restore"), handlers.restoreStorageHandler)
+
+	// swagger:operation POST /storages/{name}/touch Storages TouchStorage
+	//
+	// Refresh storage update time without changing other fields, e.g. to mark storage as reconciled by external controller.
+	// Storage is not validated and no audit record is written.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: reconciled
+	//    in: query
+	//    type: boolean
+	//    description: publish "storage_reconciled" event
+	// responses:
+	//   '202':
+	//     description: storage touched
+	//   default:
+	//     $ref: '#/responses/error'
+	group.POST("/:name/touch", middleware.StorageMetrics("touch"), r.authorized("touch"), r.rateLimited("touch"), handlers.touchStorageHandler)
 
-func (r *Router) SetupStorageHandlers(acts server.StorageActions) {
-	handlers := &storageHandlers{tv: r.tv, acts: acts}
+	// swagger:operation POST /storages/{name}/rename Storages RenameStorage
+	//
+	// Rename storage. Storage volumes are moved to new name.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/RenameStorageRequest'
+	// responses:
+	//   '202':
+	//     description: storage renamed
+	//   default:
+	//     $ref: '#/responses/error'
+	group.POST("/:name/rename", middleware.StorageMetrics("rename"), r.authorized("rename"), r.rateLimited("rename"), handlers.renameStorageHandler)
 
-	group := r.engine.Group("/storages", httputil.RequireAdminRole(errors.ErrAdminRequired))
+	// swagger:operation POST /storages/{name}/transfer Storages TransferStorage
+	//
+	// Transfer storage ownership to other user. Storage size is counted in new owner quota.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/TransferStorageRequest'
+	// responses:
+	//   '202':
+	//     description: storage ownership transferred
+	//   default:
+	//     $ref: '#/responses/error'
+	group.POST("/:name/transfer", middleware.StorageMetrics("transfer"), r.authorized("transfer"), r.rateLimited("transfer"), handlers.transferStorageHandler)
 
-	// swagger:operation POST /storages Storages CreateStorage
+	// swagger:operation POST /storages/{name}/clone Storages CloneStorage
 	//
-	// Create storage.
+	// Create storage with configuration (size, labels, annotations, flags) of existing one.
+	// Volumes are not copied.
 	//
 	// ---
 	// parameters:
 	//  - $ref: '#/parameters/UserIDHeader'
 	//  - $ref: '#/parameters/UserRoleHeader'
 	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
 	//  - name: body
 	//    in: body
 	//    required: true
 	//    schema:
-	//      $ref: '#/definitions/Storage'
+	//      $ref: '#/definitions/CloneStorageRequest'
 	// responses:
 	//   '201':
-	//     description: storage created
+	//     description: storage cloned
 	//   default:
 	//     $ref: '#/responses/error'
-	group.POST("", handlers.createStorageHandler)
+	group.POST("/:name/clone", middleware.StorageMetrics("clone"), r.authorized("clone"), r.rateLimited("clone"), handlers.cloneStorageHandler)
 
-	// swagger:operation GET /storages Storages GetStorages
+	// swagger:operation POST /storages/{name}/migrate Storages MigrateStorageVolumes
 	//
-	// Get storage list.
+	// Move all (or selected by IDs) volumes of storage to target storage.
+	// Target must accept new volumes and have free capacity for all selected volumes.
+	// Every volume is moved in own transaction, response contains result for each volume.
 	//
 	// ---
 	// parameters:
 	//  - $ref: '#/parameters/UserIDHeader'
 	//  - $ref: '#/parameters/UserRoleHeader'
 	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/StorageMigrateRequest'
 	// responses:
-	//   '200':
-	//     description: storages list
+	//   '202':
+	//     description: volumes migration results
 	//     schema:
-	//       type: array
-	//       items:
-	//         $ref: '#/definitions/Storage'
+	//       $ref: '#/definitions/StorageMigrateResponse'
 	//   default:
 	//     $ref: '#/responses/error'
-	group.GET("", handlers.getStoragesHandler)
+	group.POST("/:name/migrate", middleware.StorageMetrics("migrate"), r.authorized("migrate"), r.rateLimited("migrate"), handlers.migrateVolumesHandler)
 
-	// swagger:operation PUT /storages/{name} Storages UpdateStorage
+	// swagger:operation POST /storages/{name}/maintenance Storages SetStorageMaintenance
 	//
-	// Update storage.
+	// Enable or disable storage maintenance mode.
+	// Storage under maintenance rejects new volumes, existing volumes keep working.
 	//
 	// ---
 	// parameters:
 	//  - $ref: '#/parameters/UserIDHeader'
 	//  - $ref: '#/parameters/UserRoleHeader'
 	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
 	//  - name: body
 	//    in: body
 	//    required: true
 	//    schema:
-	//      $ref: '#/definitions/UpdateStorageRequest'
+	//      $ref: '#/definitions/StorageMaintenanceRequest'
+	// responses:
+	//   '202':
+	//     description: storage maintenance mode changed
+	//   default:
+	//     $ref: '#/responses/error'
+	group.POST("/:name/maintenance", middleware.StorageMetrics("maintenance"), r.authorized("maintenance"), r.rateLimited("maintenance"), handlers.storageMaintenanceHandler)
+
+	// swagger:operation POST /storages/{name}/recompute Storages RecomputeStorageUsage
+	//
+	// Recalculate storage used capacity from its volumes.
+	// Fixes usage drift caused by volume records changed out-of-band.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	// responses:
+	//   '200':
+	//     description: used capacity before and after recomputation
+	//     schema:
+	//       $ref: '#/definitions/StorageUsageRecompute'
+	//   default:
+	//     $ref: '#/responses/error'
+	group.POST("/:name/recompute", middleware.StorageMetrics("recompute"), r.authorized("recompute"), r.rateLimited("recompute"), handlers.recomputeStorageUsageHandler)
+
+	// swagger:operation PUT /storages/{name}/default Storages SetDefaultStorage
+	//
+	// Make storage default for volumes created without storage name.
+	// Previous default storage is unset.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
 	//  - name: name
 	//    in: path
 	//    type: string
 	//    required: true
 	// responses:
 	//   '202':
-	//     description: storage updated
+	//     description: default storage set
 	//   default:
 	//     $ref: '#/responses/error'
-	group.PUT("/:name", handlers.updateStorageHandler)
+	group.PUT("/:name/default", middleware.StorageMetrics("set_default"), r.authorized("set_default"), r.rateLimited("set_default"), handlers.setDefaultStorageHandler)
 
-	// swagger:operation DELETE /storages/{name} Storages DeleteStorage
+	// swagger:operation PUT /storages/{name}/pin Storages PinStorage
 	//
-	// Delete storage.
+	// Pin storage, so it is never purged: neither from trash after retention nor by delete with "force".
+	// Pinning requires admin role regardless of role operations config.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: name
+	//    in: path
+	//    type: string
+	//    required: true
+	// responses:
+	//   '202':
+	//     description: storage pinned
+	//   default:
+	//     $ref: '#/responses/error'
+	group.PUT("/:name/pin", middleware.StorageMetrics("pin"), middleware.IsAdmin, r.rateLimited("pin"), handlers.pinStorageHandler)
+
+	// swagger:operation DELETE /storages/{name}/pin Storages UnpinStorage
+	//
+	// Unpin storage, so it may be purged again.
+	// Unpinning requires admin role regardless of role operations config.
 	//
 	// ---
 	// parameters:
@@ -178,25 +2070,245 @@ func (r *Router) SetupStorageHandlers(acts server.StorageActions) {
 	//    required: true
 	// responses:
 	//   '202':
-	//     description: storage deleted
+	//     description: storage unpinned
+	//   default:
+	//     $ref: '#/responses/error'
+	group.DELETE("/:name/pin", middleware.StorageMetrics("unpin"), middleware.IsAdmin, r.rateLimited("unpin"), handlers.unpinStorageHandler)
+
+	// Collection-level actions are dispatched by "name" param value.
+	postActions := newSegmentDispatcher("name")
+
+	// swagger:operation POST /storages/bulk-delete Storages BulkDeleteStorages
+	//
+	// Delete several storages.
+	// Storages which still have volumes are reported as "in-use".
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/BulkDeleteStoragesRequest'
+	//  - name: atomic
+	//    in: query
+	//    type: boolean
+	//    description: delete all storages or none of them
+	// responses:
+	//   '202':
+	//     description: storages deletion result
+	//     schema:
+	//       $ref: '#/definitions/StorageBulkDeleteResponse'
+	//   default:
+	//     $ref: '#/responses/error'
+	postActions.handle("bulk-delete", "bulk_delete", r.authorized("bulk_delete"), r.rateLimited("bulk_delete"), handlers.bulkDeleteStoragesHandler)
+
+	// swagger:operation POST /storages/bulk-update Storages BulkUpdateStorages
+	//
+	// Patch several storages. Each patch changes only provided fields, same as PatchStorage.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/BulkUpdateStoragesRequest'
+	//  - name: atomic
+	//    in: query
+	//    type: boolean
+	//    description: update all storages or none of them
+	// responses:
+	//   '202':
+	//     description: storages update result
+	//     schema:
+	//       $ref: '#/definitions/StorageBulkUpdateResponse'
+	//   default:
+	//     $ref: '#/responses/error'
+	postActions.handle("bulk-update", "bulk_update", r.authorized("bulk_update"), r.rateLimited("bulk_update"), handlers.bulkUpdateStoragesHandler)
+
+	// swagger:operation POST /storages/apply Storages ApplyStorages
+	//
+	// Make storages match desired list: missing storages are created, changed ones are updated.
+	// Entries have the same format as storages export. Size, overcommit ratio and warn threshold
+	// of existing storages are not changed if omitted, other fields are set as listed.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      type: array
+	//      items:
+	//        $ref: '#/definitions/StorageImportEntry'
+	//  - name: prune
+	//    in: query
+	//    type: boolean
+	//    description: delete storages missing in list, storages with volumes are not deleted
+	//  - name: atomic
+	//    in: query
+	//    type: boolean
+	//    description: apply all changes or none of them
+	// responses:
+	//   '202':
+	//     description: storages apply result
+	//     schema:
+	//       $ref: '#/definitions/StorageApplyResponse'
+	//   default:
+	//     $ref: '#/responses/error'
+	postActions.handle("apply", "apply", r.authorized("apply"), r.rateLimited("apply"), r.bodyLimited("apply"), handlers.applyStoragesHandler)
+
+	// swagger:operation POST /storages/recompute Storages RecomputeStoragesUsage
+	//
+	// Recalculate used capacity of all storages from their volumes.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	// responses:
+	//   '200':
+	//     description: used capacity of each storage before and after recomputation
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/StorageUsageRecompute'
+	//   default:
+	//     $ref: '#/responses/error'
+	postActions.handle("recompute", "recompute", r.authorized("recompute"), r.rateLimited("recompute"), handlers.recomputeStoragesUsageHandler)
+
+	// swagger:operation POST /storages/sync Storages SyncStorages
+	//
+	// Compare storages with storages existing on backend (reported by provisioner).
+	// With "apply" database is corrected to match backend: missing storages are deleted unless they have volumes,
+	// extra storages are created, mismatched sizes are updated. Driver mismatch is only reported.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: apply
+	//    in: query
+	//    type: boolean
+	//    description: correct database to match backend
+	// responses:
+	//   '200':
+	//     description: differences between database and backend
+	//     schema:
+	//       $ref: '#/definitions/StorageSyncReport'
+	//   default:
+	//     $ref: '#/responses/error'
+	postActions.handle("sync", "sync", r.authorized("sync"), r.rateLimited("sync"), handlers.syncStoragesHandler)
+
+	// swagger:operation POST /storages/webhooks Storages CreateStorageWebhook
+	//
+	// Register webhook receiving storage events.
+	// Events are POSTed to webhook URL as JSON, body is signed with webhook secret:
+	// header X-Webhook-Signature contains "sha256=" and hex HMAC-SHA256 of body.
+	// Failed deliveries are retried with exponential backoff.
+	//
+	// ---
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - $ref: '#/parameters/SubstitutedUserID'
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      $ref: '#/definitions/CreateStorageWebhookRequest'
+	// responses:
+	//   '201':
+	//     description: webhook registered, response contains webhook secret
+	//     schema:
+	//       $ref: '#/definitions/StorageWebhook'
 	//   default:
 	//     $ref: '#/responses/error'
-	group.DELETE("/:name", handlers.deleteStorageHandler)
+	postActions.handle("webhooks", "create_webhook", r.authorized("create_webhook"), r.rateLimited("create_webhook"), handlers.createStorageWebhookHandler)
+
+	group.POST("/:name", middleware.StorageMetrics("action"), postActions.dispatch)
 
 	// swagger:operation POST /import/storages Storages ImportStorages
 	//
 	// Import storages.
+	// In atomic mode storages are imported in one transaction which is rolled back on first failure,
+	// response then contains no imported storages and failing storage is the first failed one.
+	// With "Content-Type: text/csv" body is CSV with columns "name", "size" (e.g. "100Gi") and "labels"
+	// ("key=value,key2=value2"), optional header row sets columns order. Malformed rows are reported
+	// as failed with line number.
+	// Operations listed in "deprecated_operations" config get Deprecation, Sunset and Warning response headers.
 	//
 	// ---
+	// consumes:
+	//  - application/json
+	//  - text/csv
 	// parameters:
 	//  - $ref: '#/parameters/UserIDHeader'
 	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - name: atomic
+	//    in: query
+	//    type: boolean
+	//    description: import all storages or none of them
+	//  - name: body
+	//    in: body
+	//    required: true
+	//    schema:
+	//      type: array
+	//      items:
+	//        $ref: '#/definitions/StorageImportEntry'
 	// responses:
 	//   '202':
 	//     description: storages imported
 	//     schema:
-	//       $ref: '#/definitions/ImportResponse'
+	//       $ref: '#/definitions/StorageImportResponse'
+	//   default:
+	//     $ref: '#/responses/error'
+	r.engine.POST("/import/storages", middleware.StorageMetrics("import"), r.authorized("import"), r.rateLimited("import"), r.bodyLimited("import"),
+		middleware.OperationTimeout(r.operationTimeout), r.timeLimited(), handlers.importStoragesHandler)
+
+	// swagger:operation GET /export/storages Storages ExportStorages
+	//
+	// Export storages in format accepted by storages import.
+	// Only JSON export can be imported back.
+	//
+	// ---
+	// produces:
+	//  - application/json
+	//  - application/yaml
+	// parameters:
+	//  - $ref: '#/parameters/UserIDHeader'
+	//  - $ref: '#/parameters/UserRoleHeader'
+	//  - name: format
+	//    in: query
+	//    type: string
+	//    enum: [json, yaml]
+	//    description: response format, overrides Accept header
+	//  - name: include_deleted
+	//    in: query
+	//    type: boolean
+	//    description: export storages from trash too
+	// responses:
+	//   '200':
+	//     description: storages
+	//     schema:
+	//       type: array
+	//       items:
+	//         $ref: '#/definitions/StorageImportEntry'
 	//   default:
 	//     $ref: '#/responses/error'
-	r.engine.POST("/import/storages", handlers.importStoragesHandler)
+	r.engine.GET("/export/storages",
+		middleware.StorageMetrics("export"), r.authorized("export"), r.rateLimited("export"),
+		middleware.OperationTimeout(r.operationTimeout), r.timeLimited(), handlers.exportStoragesHandler)
 }
@@ -0,0 +1,2555 @@
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/clients"
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/server"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"git.containerum.net/ch/volume-manager/pkg/utils/validation"
+	"github.com/appleboy/gofight"
+	"github.com/containerum/cherry"
+	kubeModel "github.com/containerum/kube-client/pkg/model"
+	headers "github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/universal-translator"
+	"github.com/satori/go.uuid"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// storagesDB keeps storages in memory. Not implemented DB methods panic.
+type storagesDB struct {
+	database.DB
+
+	mu       sync.Mutex
+	storages map[string]model.Storage
+	volumes  []model.Volume
+	audit    []model.StorageAuditRecord
+	sizes    []model.StorageSizeRecord
+
+	txMu sync.Mutex
+}
+
+func (db *storagesDB) CreateStorage(ctx context.Context, storage *model.Storage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.storages[storage.Name]; ok {
+		return errors.ErrStorageAlreadyExists().AddDetailF("storage %s already exists", storage.Name)
+	}
+	storage.ID = uuid.NewV4().String()
+	db.storages[storage.Name] = *storage
+	return nil
+}
+
+func (db *storagesDB) StorageByID(ctx context.Context, id string) (model.Storage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, storage := range db.storages {
+		if storage.ID == id {
+			return storage, nil
+		}
+	}
+	return model.Storage{}, errors.ErrResourceNotExists().AddDetailF("storage with id %s not exists", id)
+}
+
+func (db *storagesDB) StorageByName(ctx context.Context, name string) (model.Storage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage, ok := db.storages[name]
+	if !ok {
+		return model.Storage{}, errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return storage, nil
+}
+
+func (db *storagesDB) UpdateStorage(ctx context.Context, name string, storage model.Storage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.storages[name]; !ok {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	delete(db.storages, name)
+	db.storages[storage.Name] = storage
+	return nil
+}
+
+func (db *storagesDB) SetStoragePinned(ctx context.Context, name string, pinned bool) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage, ok := db.storages[name]
+	if !ok {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	storage.Pinned = pinned
+	db.storages[name] = storage
+	return nil
+}
+
+func (db *storagesDB) SetStorageLabels(ctx context.Context, name string, labels map[string]string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage, ok := db.storages[name]
+	if !ok {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	storage.Labels = labels
+	storage.Version++
+	db.storages[name] = storage
+	return nil
+}
+
+func (db *storagesDB) PatchStorageLabels(ctx context.Context, name string, patch map[string]*string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage, ok := db.storages[name]
+	if !ok {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	labels := make(map[string]string)
+	for k, v := range storage.Labels {
+		labels[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(labels, k)
+		} else {
+			labels[k] = *v
+		}
+	}
+	storage.Labels = labels
+	storage.Version++
+	db.storages[name] = storage
+	return nil
+}
+
+func (db *storagesDB) DeleteStorage(ctx context.Context, storage *model.Storage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.storages, storage.Name)
+	return nil
+}
+
+func (db *storagesDB) AllStorages(ctx context.Context, filter database.StorageFilter) ([]model.Storage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var ret []model.Storage
+	for _, storage := range db.storages {
+		if filter.NamespaceScoped && !storageAvailableIn(storage, filter.Namespaces) {
+			continue
+		}
+		if filter.After != "" && (storage.Name == filter.After || (storage.Name < filter.After) != filter.SortDesc) {
+			continue
+		}
+		if filter.Driver != "" && storage.Driver != filter.Driver {
+			continue
+		}
+		if filter.Unpinned && storage.Pinned {
+			continue
+		}
+		if filter.IdleBefore != nil {
+			lastUsed := storage.CreatedAt
+			if storage.LastUsedAt != nil {
+				lastUsed = *storage.LastUsedAt
+			}
+			if !lastUsed.Before(*filter.IdleBefore) {
+				continue
+			}
+		}
+		ret = append(ret, storage)
+	}
+	// only sorting by name is supported
+	sort.Slice(ret, func(i, j int) bool { return (ret[i].Name < ret[j].Name) != filter.SortDesc })
+	if filter.Limit > 0 && len(ret) > filter.Limit {
+		ret = ret[:filter.Limit]
+	}
+	return ret, nil
+}
+
+func storageAvailableIn(storage model.Storage, namespaces []string) bool {
+	if len(storage.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range namespaces {
+		if storage.AvailableIn(ns) {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *storagesDB) CountStorages(ctx context.Context, filter database.StorageFilter) (int, error) {
+	storages, err := db.AllStorages(ctx, filter)
+	return len(storages), err
+}
+
+func (db *storagesDB) StoragesTotalSize(ctx context.Context, filter database.StorageFilter) (int, error) {
+	storages, err := db.AllStorages(ctx, filter)
+	total := 0
+	for _, storage := range storages {
+		if filter.OwnerUserID != "" && storage.OwnerUserID != filter.OwnerUserID {
+			continue
+		}
+		if filter.ExplicitNamespace != "" && (len(storage.Namespaces) == 0 || !storage.AvailableIn(filter.ExplicitNamespace)) {
+			continue
+		}
+		total += storage.Size
+	}
+	return total, err
+}
+
+func (db *storagesDB) StorageVolumes(ctx context.Context, name string) ([]model.Volume, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var ret []model.Volume
+	for _, vol := range db.volumes {
+		if vol.StorageName == name {
+			ret = append(ret, vol)
+		}
+	}
+	return ret, nil
+}
+
+func (db *storagesDB) MoveVolume(ctx context.Context, volume *model.Volume, target string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	targetStorage, source := db.storages[target], db.storages[volume.StorageName]
+	if targetStorage.Used+volume.Capacity > targetStorage.Capacity() {
+		return errors.ErrStorageOvercommitted().AddDetailF("storage %s has no space for volume %s", target, volume.Label)
+	}
+	targetStorage.Used += volume.Capacity
+	source.Used -= volume.Capacity
+	db.storages[target], db.storages[volume.StorageName] = targetStorage, source
+	for i := range db.volumes {
+		if db.volumes[i].ID == volume.ID {
+			db.volumes[i].StorageName = target
+		}
+	}
+	volume.StorageName = target
+	return nil
+}
+
+func (db *storagesDB) CreateStorageAuditRecords(ctx context.Context, records []model.StorageAuditRecord) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.audit = append(db.audit, records...)
+	return nil
+}
+
+func (db *storagesDB) SetStorageOwner(ctx context.Context, name, ownerUserID string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage, ok := db.storages[name]
+	if !ok || storage.Deleted {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	storage.OwnerUserID = ownerUserID
+	storage.Version++
+	db.storages[name] = storage
+	return nil
+}
+
+func (db *storagesDB) TouchStorage(ctx context.Context, name string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage, ok := db.storages[name]
+	if !ok || storage.Deleted {
+		return errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	storage.UpdatedAt = time.Now()
+	db.storages[name] = storage
+	return nil
+}
+
+func (db *storagesDB) CreateStorageSizeRecords(ctx context.Context, records []model.StorageSizeRecord) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.sizes = append(db.sizes, records...)
+	return nil
+}
+
+func (db *storagesDB) StorageSizeHistory(ctx context.Context, name string, filter model.StorageSizeHistoryFilter) ([]model.StorageSizeRecord, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	ret := make([]model.StorageSizeRecord, 0)
+	for _, record := range db.sizes {
+		if record.StorageName != name ||
+			filter.From != nil && record.Timestamp.Before(*filter.From) ||
+			filter.To != nil && record.Timestamp.After(*filter.To) {
+			continue
+		}
+		ret = append(ret, record)
+	}
+	if filter.Limit > 0 && len(ret) > filter.Limit {
+		ret = ret[len(ret)-filter.Limit:]
+	}
+	return ret, nil
+}
+
+// Transactional runs transactions one by one and restores storages if fn failed.
+func (db *storagesDB) Transactional(ctx context.Context, fn func(tx database.DB) error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	db.mu.Lock()
+	snapshot := make(map[string]model.Storage, len(db.storages))
+	for name, storage := range db.storages {
+		snapshot[name] = storage
+	}
+	db.mu.Unlock()
+
+	err := fn(db)
+	if err != nil {
+		db.mu.Lock()
+		for name := range db.storages {
+			delete(db.storages, name)
+		}
+		for name, storage := range snapshot {
+			db.storages[name] = storage
+		}
+		db.mu.Unlock()
+	}
+	return err
+}
+
+// storageTestRouter serves storage handlers of server backed by test database.
+type storageTestRouter struct {
+	t   *testing.T
+	e   *gin.Engine
+	srv *server.Server
+}
+
+// newStorageTestRouter sets up request validation and serves storage handlers of new server over db.
+// Server must be closed by test.
+func newStorageTestRouter(t *testing.T, db database.DB, srvCfg server.Config, cfg Config) *storageTestRouter {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, cfg)
+	if srvCfg.ReservedStorageNames == nil {
+		srvCfg.ReservedStorageNames = r.StorageNameReserved
+	}
+	srv := server.NewServer(db, &server.Clients{}, nil, srvCfg)
+	r.SetupStorageHandlers(srv)
+	return &storageTestRouter{t: t, e: e, srv: srv}
+}
+
+// do sends request with headers and body encoded to JSON, body is not sent if it is nil.
+func (tr *storageTestRouter) do(method, path string, h gofight.H, body interface{}) gofight.HTTPResponse {
+	req := gofight.New()
+	switch method {
+	case http.MethodPost:
+		req = req.POST(path)
+	case http.MethodPut:
+		req = req.PUT(path)
+	case http.MethodPatch:
+		req = req.PATCH(path)
+	case http.MethodDelete:
+		req = req.DELETE(path)
+	case http.MethodHead:
+		req = req.HEAD(path)
+	default:
+		req = req.GET(path)
+	}
+	reqHeaders := gofight.H{}
+	for k, v := range h {
+		reqHeaders[k] = v
+	}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			tr.t.Fatalf("marshal %s %s request body failed: %v", method, path, err)
+		}
+		req.SetBody(string(data))
+		reqHeaders["Content-Type"] = "application/json"
+	}
+
+	var ret gofight.HTTPResponse
+	req.SetHeader(reqHeaders).
+		Run(tr.e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			ret = r
+		})
+	return ret
+}
+
+func TestCreateStorage(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	adminHeaders := gofight.H{
+		headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+		headers.UserRoleXHeader: "admin",
+	}
+	createRaw := func(body gofight.D) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().POST("/storages").
+			SetHeader(adminHeaders).
+			SetJSON(body).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	create := func(name string) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().POST("/storages").
+			SetHeader(adminHeaders).
+			SetJSON(gofight.D{"name": name, "size": 10}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	createOrGet := func(name string, size int) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().POST("/storages").
+			SetHeader(adminHeaders).
+			SetQuery(gofight.H{"get_if_exists": "true"}).
+			SetJSON(gofight.D{"name": name, "size": size}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	list := func(h gofight.H) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().GET("/storages").
+			SetHeader(h).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	count := func(method string, query gofight.H) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		r := gofight.New()
+		if method == http.MethodHead {
+			r = r.HEAD("/storages")
+		} else {
+			r = r.GET("/storages")
+		}
+		r.SetHeader(adminHeaders).
+			SetQuery(query).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	bulkUpdate := func(atomic bool, body gofight.D) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().POST("/storages/bulk-update").
+			SetHeader(adminHeaders).
+			SetQuery(gofight.H{"atomic": fmt.Sprint(atomic)}).
+			SetJSON(body).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	importStorages := func(atomic bool, names ...string) model.StorageImportResponse {
+		var ret model.StorageImportResponse
+		body, err := json.Marshal(names)
+		So(err, ShouldBeNil)
+		gofight.New().POST("/import/storages").
+			SetHeader(adminHeaders).
+			SetQuery(gofight.H{"atomic": fmt.Sprint(atomic)}).
+			SetBody(string(body)).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				So(r.Code, ShouldEqual, http.StatusAccepted)
+				So(json.Unmarshal(r.Body.Bytes(), &ret), ShouldBeNil)
+			})
+		return ret
+	}
+	export := func(format string) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().GET("/export/storages").
+			SetHeader(adminHeaders).
+			SetQuery(gofight.H{"format": format}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test storage creation", t, func() {
+		Convey("Check duplicate storage is rejected with 409", func() {
+			So(create("storage-1").Code, ShouldEqual, http.StatusCreated)
+
+			resp := create("storage-1")
+			So(resp.Code, ShouldEqual, http.StatusConflict)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherry.Equals(&cherryErr, errors.ErrStorageAlreadyExists()), ShouldBeTrue)
+			So(cherryErr.Details, ShouldContain, "storage storage-1 already exists")
+		})
+		Convey("Check created storage is available by generated ID", func() {
+			resp := create("storage-id")
+			So(resp.Code, ShouldEqual, http.StatusCreated)
+			defer delete(db.storages, "storage-id")
+			var created model.Storage
+			So(json.Unmarshal(resp.Body.Bytes(), &created), ShouldBeNil)
+			So(created.Name, ShouldEqual, "storage-id")
+			_, err := uuid.FromString(created.ID)
+			So(err, ShouldBeNil)
+
+			get := func(path string) (ret gofight.HTTPResponse) {
+				gofight.New().GET(path).
+					SetHeader(adminHeaders).
+					Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+						ret = r
+					})
+				return ret
+			}
+			resp = get("/storages/by-id/" + created.ID)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var storage model.Storage
+			So(json.Unmarshal(resp.Body.Bytes(), &storage), ShouldBeNil)
+			So(storage.Name, ShouldEqual, "storage-id")
+
+			So(get("/storages/by-id/"+uuid.NewV4().String()).Code, ShouldEqual, http.StatusNotFound)
+			So(get("/storages/by-id/storage-id").Code, ShouldEqual, http.StatusBadRequest)
+			So(get("/storages/storage-id/unknown").Code, ShouldEqual, http.StatusNotFound)
+		})
+		Convey("Check existing storage is returned with get_if_exists", func() {
+			resp := createOrGet("storage-1", 20)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var storage model.Storage
+			So(json.Unmarshal(resp.Body.Bytes(), &storage), ShouldBeNil)
+			So(storage.Name, ShouldEqual, "storage-1")
+			So(storage.Size, ShouldEqual, 10)
+		})
+		Convey("Check concurrent create or get calls return the same storage", func() {
+			defer delete(db.storages, "storage-cog")
+			var wg sync.WaitGroup
+			responses := make([]gofight.HTTPResponse, 4)
+			for i := range responses {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					responses[i] = createOrGet("storage-cog", 10+i)
+				}(i)
+			}
+			wg.Wait()
+
+			var created int
+			var sizes []int
+			for _, resp := range responses {
+				So(resp.Code, ShouldBeIn, http.StatusOK, http.StatusCreated)
+				if resp.Code == http.StatusCreated {
+					created++
+				}
+				var storage model.Storage
+				So(json.Unmarshal(resp.Body.Bytes(), &storage), ShouldBeNil)
+				sizes = append(sizes, storage.Size)
+			}
+			So(created, ShouldEqual, 1)
+			for _, size := range sizes {
+				So(size, ShouldEqual, sizes[0])
+			}
+		})
+		Convey("Check storage size limits", func() {
+			resp := createRaw(gofight.D{"name": "storage-huge", "size": server.DefaultMaxStorageSize + 1})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherryErr.Fields, ShouldContainKey, "size")
+			So(cherryErr.Details[0], ShouldContainSubstring, "must be in range [1, 1048576]")
+		})
+		Convey("Check size with unit is normalized", func() {
+			resp := createRaw(gofight.D{"name": "storage-units", "size": "2Ti"})
+			So(resp.Code, ShouldEqual, http.StatusCreated)
+			defer delete(db.storages, "storage-units")
+			var created model.Storage
+			So(json.Unmarshal(resp.Body.Bytes(), &created), ShouldBeNil)
+			So(created.Size, ShouldEqual, 2048)
+			So(resp.Body.String(), ShouldContainSubstring, `"size":2048`)
+
+			So(createRaw(gofight.D{"name": "storage-units-bad", "size": "2Tb"}).Code, ShouldEqual, http.StatusBadRequest)
+			So(createRaw(gofight.D{"name": "storage-units-bad", "size": "512Mi"}).Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Check annotations", func() {
+			description := strings.Repeat("long description ", 10)
+			resp := createRaw(gofight.D{"name": "storage-annotations", "size": 10, "annotations": gofight.H{"description": description}})
+			So(resp.Code, ShouldEqual, http.StatusCreated)
+			defer delete(db.storages, "storage-annotations")
+			So(db.storages["storage-annotations"].Annotations, ShouldResemble, map[string]string{"description": description})
+
+			patch := func(annotations gofight.D) int {
+				var code int
+				gofight.New().PATCH("/storages/storage-annotations").
+					SetHeader(adminHeaders).
+					SetJSON(gofight.D{"annotations": annotations}).
+					Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+						code = r.Code
+					})
+				return code
+			}
+			So(patch(gofight.D{"ticket": "https://tracker.example.com/PAY-1", "description": nil}), ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-annotations"].Annotations, ShouldResemble, map[string]string{"ticket": "https://tracker.example.com/PAY-1"})
+			So(patch(gofight.D{"bad key": "value"}), ShouldEqual, http.StatusBadRequest)
+			So(patch(gofight.D{"notes": strings.Repeat("x", labels.MaxAnnotationsSize)}), ShouldEqual, http.StatusBadRequest)
+			So(db.storages["storage-annotations"].Annotations, ShouldHaveLength, 1)
+		})
+		Convey("Check description", func() {
+			So(createRaw(gofight.D{"name": "storage-description", "size": 10, "description": strings.Repeat("x", 1025)}).Code,
+				ShouldEqual, http.StatusBadRequest)
+			resp := createRaw(gofight.D{"name": "storage-description", "size": 10, "description": "Payments team storage"})
+			So(resp.Code, ShouldEqual, http.StatusCreated)
+			defer delete(db.storages, "storage-description")
+			So(db.storages["storage-description"].Description, ShouldEqual, "Payments team storage")
+
+			patch := func(description string) int {
+				var code int
+				gofight.New().PATCH("/storages/storage-description").
+					SetHeader(adminHeaders).
+					SetJSON(gofight.D{"description": description}).
+					Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+						code = r.Code
+					})
+				return code
+			}
+			So(patch(strings.Repeat("ж", 1024)), ShouldEqual, http.StatusAccepted)
+			So(patch(strings.Repeat("ж", 1025)), ShouldEqual, http.StatusBadRequest)
+			So(patch(""), ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-description"].Description, ShouldBeEmpty)
+		})
+		Convey("Check reserved capacity", func() {
+			So(createRaw(gofight.D{"name": "storage-reserved", "size": 10, "reserved": 10}).Code, ShouldEqual, http.StatusBadRequest)
+			So(createRaw(gofight.D{"name": "storage-reserved", "size": 10, "reserved": -1}).Code, ShouldEqual, http.StatusBadRequest)
+			resp := createRaw(gofight.D{"name": "storage-reserved", "size": 10, "reserved": 2})
+			So(resp.Code, ShouldEqual, http.StatusCreated)
+			defer delete(db.storages, "storage-reserved")
+			var created map[string]interface{}
+			So(json.Unmarshal(resp.Body.Bytes(), &created), ShouldBeNil)
+			So(created["allocatable"], ShouldEqual, 8)
+			So(db.storages["storage-reserved"].Capacity(), ShouldEqual, 8)
+
+			patch := func(body gofight.D) int {
+				var code int
+				gofight.New().PATCH("/storages/storage-reserved").
+					SetHeader(adminHeaders).
+					SetJSON(body).
+					Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+						code = r.Code
+					})
+				return code
+			}
+			So(patch(gofight.D{"size": 2}), ShouldEqual, http.StatusBadRequest)
+			So(patch(gofight.D{"reserved": 0}), ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-reserved"].Reserved, ShouldEqual, 0)
+		})
+		Convey("Check unknown driver is rejected", func() {
+			resp := createRaw(gofight.D{"name": "storage-driver", "size": 10, "driver": "tape"})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherryErr.Fields["driver"], ShouldContainSubstring, "supported drivers: nfs, ceph-rbd, local")
+			var envelope errors.Envelope
+			So(json.Unmarshal(resp.Body.Bytes(), &envelope), ShouldBeNil)
+			So(envelope.Code, ShouldEqual, errors.CodeRequestValidationFailed)
+		})
+		Convey("Check all field problems are reported at once", func() {
+			resp := createRaw(gofight.D{"size": 0, "used": -1, "labels": gofight.D{"bad key": "v"}})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherryErr.Message, ShouldEqual, errors.ErrRequestValidationFailed().Message)
+			So(cherryErr.Fields, ShouldContainKey, "name")
+			So(cherryErr.Fields, ShouldContainKey, "size")
+			So(cherryErr.Fields, ShouldContainKey, "used")
+			So(cherryErr.Fields, ShouldContainKey, "labels")
+			So(cherryErr.Details, ShouldHaveLength, 4)
+		})
+		Convey("Check users see only storages available in their namespaces", func() {
+			So(createRaw(gofight.D{"name": "storage-ns-1", "size": 10, "namespaces": []string{"ns-1"}}).Code, ShouldEqual, http.StatusCreated)
+			So(createRaw(gofight.D{"name": "storage-ns-2", "size": 10, "namespaces": []string{"ns-2"}}).Code, ShouldEqual, http.StatusCreated)
+			defer func() {
+				delete(db.storages, "storage-ns-1")
+				delete(db.storages, "storage-ns-2")
+			}()
+			names := func(resp gofight.HTTPResponse) []string {
+				var storages []model.Storage
+				So(json.Unmarshal(resp.Body.Bytes(), &storages), ShouldBeNil)
+				var ret []string
+				for _, storage := range storages {
+					ret = append(ret, storage.Name)
+				}
+				return ret
+			}
+
+			resp := list(gofight.H{
+				headers.UserIDXHeader:         "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader:       "user",
+				headers.UserNamespacesXHeader: base64.StdEncoding.EncodeToString([]byte(`[{"id": "ns-1", "access": "owner"}]`)),
+			})
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(names(resp), ShouldContain, "storage-1")
+			So(names(resp), ShouldContain, "storage-ns-1")
+			So(names(resp), ShouldNotContain, "storage-ns-2")
+
+			resp = list(adminHeaders)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(names(resp), ShouldHaveLength, 3)
+		})
+		Convey("Check storages count", func() {
+			resp := count(http.MethodGet, gofight.H{"count_only": "true"})
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var cnt model.StoragesCount
+			So(json.Unmarshal(resp.Body.Bytes(), &cnt), ShouldBeNil)
+			So(cnt.Total, ShouldEqual, 1)
+
+			resp = count(http.MethodHead, gofight.H{})
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.HeaderMap.Get("X-Total-Count"), ShouldEqual, "1")
+			So(resp.Body.Len(), ShouldEqual, 0)
+
+			So(count(http.MethodHead, gofight.H{"min_size": "-1"}).Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Check bulk update", func() {
+			body := gofight.D{"updates": []gofight.D{
+				{"name": "storage-1", "patch": gofight.D{"warn_threshold": 80}},
+				{"name": "storage-missing", "patch": gofight.D{"warn_threshold": 80}},
+			}}
+			defer func() {
+				storage := db.storages["storage-1"]
+				storage.WarnThreshold = 0
+				db.storages["storage-1"] = storage
+			}()
+
+			resp := bulkUpdate(true, body)
+			So(resp.Code, ShouldEqual, http.StatusAccepted)
+			var result model.StorageBulkUpdateResponse
+			So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+			So(result.Updated, ShouldBeEmpty)
+			So(result.Failed, ShouldHaveLength, 2)
+			So(result.Failed[0].Status, ShouldEqual, model.StorageNotFound)
+			So(result.Failed[1].Status, ShouldEqual, model.StorageRolledBack)
+
+			resp = bulkUpdate(false, body)
+			So(resp.Code, ShouldEqual, http.StatusAccepted)
+			result = model.StorageBulkUpdateResponse{}
+			So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+			So(result.Updated, ShouldHaveLength, 1)
+			So(result.Updated[0].Name, ShouldEqual, "storage-1")
+			So(result.Failed, ShouldHaveLength, 1)
+			So(db.storages["storage-1"].WarnThreshold, ShouldEqual, 80)
+
+			resp = bulkUpdate(false, gofight.D{"updates": []gofight.D{
+				{"name": "storage-1", "patch": gofight.D{}},
+				{"name": "storage-1", "patch": gofight.D{"size": 0}},
+			}})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherryErr.Fields, ShouldContainKey, "updates[0].patch")
+			So(cherryErr.Fields, ShouldContainKey, "updates[1].name")
+			So(cherryErr.Fields, ShouldContainKey, "updates[1].patch.size")
+		})
+		Convey("Check list fields projection", func() {
+			listFields := func(fields string) gofight.HTTPResponse {
+				var ret gofight.HTTPResponse
+				gofight.New().GET("/storages").
+					SetHeader(adminHeaders).
+					SetQuery(gofight.H{"fields": fields}).
+					Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+						ret = r
+					})
+				return ret
+			}
+			resp := listFields("name,size")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var storages []map[string]interface{}
+			So(json.Unmarshal(resp.Body.Bytes(), &storages), ShouldBeNil)
+			So(storages, ShouldNotBeEmpty)
+			for _, storage := range storages {
+				So(storage, ShouldHaveLength, 2)
+				So(storage, ShouldContainKey, "name")
+				So(storage, ShouldContainKey, "size")
+			}
+
+			resp = listFields("name,color")
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherryErr.Fields, ShouldContainKey, "fields")
+		})
+		Convey("Check import failure on third of five entries", func() {
+			names := []string{"storage-imp-1", "storage-imp-2", "storage-imp-3", "storage-imp-4", "storage-imp-5"}
+			defer func() {
+				for _, name := range names {
+					delete(db.storages, name)
+				}
+			}()
+			So(create("storage-imp-3").Code, ShouldEqual, http.StatusCreated)
+
+			resp := importStorages(true, names...)
+			So(resp.RolledBack, ShouldBeTrue)
+			So(resp.Imported, ShouldBeEmpty)
+			So(resp.Failed, ShouldHaveLength, 5)
+			So(resp.Failed[0].Name, ShouldEqual, "storage-imp-3")
+			So(resp.Failed[0].Status, ShouldEqual, model.StorageImportAlreadyExists)
+			So(resp.Failed[0].StatusCode, ShouldEqual, http.StatusConflict)
+			for _, result := range resp.Failed[1:] {
+				So(result.Status, ShouldEqual, model.StorageImportRolledBack)
+				So(result.StatusCode, ShouldEqual, http.StatusFailedDependency)
+			}
+			for _, name := range []string{"storage-imp-1", "storage-imp-2", "storage-imp-4", "storage-imp-5"} {
+				So(db.storages, ShouldNotContainKey, name)
+			}
+
+			resp = importStorages(false, names...)
+			So(resp.RolledBack, ShouldBeFalse)
+			So(resp.Imported, ShouldHaveLength, 4)
+			for _, result := range resp.Imported {
+				So(result.StatusCode, ShouldEqual, http.StatusCreated)
+			}
+			So(resp.Failed, ShouldHaveLength, 1)
+			So(resp.Failed[0].Name, ShouldEqual, "storage-imp-3")
+			So(resp.Failed[0].StatusCode, ShouldEqual, http.StatusConflict)
+			for _, name := range names {
+				So(db.storages, ShouldContainKey, name)
+			}
+		})
+		Convey("Check atomic import is rolled back on invalid entry", func() {
+			resp := importStorages(true, "storage-imp-ok", "Invalid_Name")
+			So(resp.RolledBack, ShouldBeTrue)
+			So(resp.Imported, ShouldBeEmpty)
+			So(resp.Failed[0].Name, ShouldEqual, "Invalid_Name")
+			So(resp.Failed[0].StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(db.storages, ShouldNotContainKey, "storage-imp-ok")
+		})
+		Convey("Check CSV import", func() {
+			names := []string{"storage-csv-1", "storage-csv-2"}
+			defer func() {
+				for _, name := range names {
+					delete(db.storages, name)
+				}
+			}()
+			body := "name,labels,size\n" +
+				"storage-csv-1,\"team=payments,tier=gold\",100Gi\n" +
+				"storage-csv-bad,,ten\n" +
+				"storage-csv-2,,2Ti\n" +
+				"Invalid_Name,,10\n"
+			var resp model.StorageImportResponse
+			gofight.New().POST("/import/storages").
+				SetHeader(gofight.H{
+					headers.UserIDXHeader:   adminHeaders[headers.UserIDXHeader],
+					headers.UserRoleXHeader: "admin",
+					"Content-Type":          "text/csv",
+				}).
+				SetBody(body).
+				Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+					So(r.Code, ShouldEqual, http.StatusAccepted)
+					So(json.Unmarshal(r.Body.Bytes(), &resp), ShouldBeNil)
+				})
+			So(resp.Imported, ShouldHaveLength, 2)
+			So(db.storages["storage-csv-1"].Size, ShouldEqual, 100)
+			So(db.storages["storage-csv-1"].Labels, ShouldResemble, map[string]string{"team": "payments", "tier": "gold"})
+			So(db.storages["storage-csv-2"].Size, ShouldEqual, 2048)
+			So(resp.Failed, ShouldHaveLength, 2)
+			So(resp.Failed[0].Name, ShouldEqual, "storage-csv-bad")
+			So(resp.Failed[0].Line, ShouldEqual, 3)
+			So(resp.Failed[0].StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(resp.Failed[1].Name, ShouldEqual, "Invalid_Name")
+			So(resp.Failed[1].Line, ShouldEqual, 5)
+		})
+		Convey("Check export returns entries accepted by import", func() {
+			resp := export("json")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var entries []model.StorageImportEntry
+			So(json.Unmarshal(resp.Body.Bytes(), &entries), ShouldBeNil)
+			So(entries, ShouldHaveLength, 1)
+			So(entries[0].Storage(), ShouldResemble, model.Storage{Name: "storage-1", Size: 10})
+
+			resp = export("yaml")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.HeaderMap.Get("Content-Type"), ShouldStartWith, "application/yaml")
+
+			So(export("xml").Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestStorageQuotas(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{
+		StorageQuotas: []model.StorageQuota{
+			{Tenant: model.QuotaTenantUser, ID: model.QuotaAnyTenant, MaxSize: 30},
+			{Tenant: model.QuotaTenantNamespace, ID: "ns-1", MaxSize: 15},
+		},
+	})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{
+		RoleOperations: middleware.RoleOperations{middleware.RoleUser: {"quota"}},
+	})
+	r.SetupStorageHandlers(srv)
+
+	adminHeaders := gofight.H{
+		headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+		headers.UserRoleXHeader: "admin",
+	}
+	request := func(method, path string, body gofight.D) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		req := gofight.New()
+		switch method {
+		case http.MethodPost:
+			req = req.POST(path).SetJSON(body)
+		case http.MethodPatch:
+			req = req.PATCH(path).SetJSON(body)
+		default:
+			req = req.GET(path)
+		}
+		req.SetHeader(adminHeaders).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test storage quotas", t, func() {
+		Convey("Check creation and size increase are limited", func() {
+			So(request(http.MethodPost, "/storages", gofight.D{"name": "storage-1", "size": 10, "namespaces": []string{"ns-1"}}).Code,
+				ShouldEqual, http.StatusCreated)
+			So(request(http.MethodPost, "/storages", gofight.D{"name": "storage-2", "size": 10, "namespaces": []string{"ns-1"}}).Code,
+				ShouldEqual, http.StatusForbidden)
+			So(request(http.MethodPost, "/storages", gofight.D{"name": "storage-2", "size": 15}).Code, ShouldEqual, http.StatusCreated)
+
+			resp := request(http.MethodPatch, "/storages/storage-2", gofight.D{"size": 25})
+			So(resp.Code, ShouldEqual, http.StatusForbidden)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherry.Equals(&cherryErr, errors.ErrStorageQuotaExceeded()), ShouldBeTrue)
+			So(request(http.MethodPatch, "/storages/storage-2", gofight.D{"size": 20}).Code, ShouldEqual, http.StatusAccepted)
+		})
+		Convey("Check quotas usage", func() {
+			resp := request(http.MethodGet, "/storages/quota?namespace=ns-1&namespace=ns-2", nil)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var quotas model.StorageQuotasResponse
+			So(json.Unmarshal(resp.Body.Bytes(), &quotas), ShouldBeNil)
+			So(quotas.Quotas, ShouldResemble, []model.StorageQuotaUsage{
+				{Tenant: model.QuotaTenantUser, ID: "20b616d8-1ea7-4842-b8ec-c6e8226fda5b", Used: 30, Limit: 30},
+				{Tenant: model.QuotaTenantNamespace, ID: "ns-1", Used: 10, Limit: 15},
+			})
+		})
+		Convey("Check users get quotas only of own namespaces", func() {
+			userQuotas := func(ownNamespace string) []model.StorageQuotaUsage {
+				var resp gofight.HTTPResponse
+				gofight.New().GET("/storages/quota?namespace=ns-1&namespace=ns-2").
+					SetHeader(gofight.H{
+						headers.UserIDXHeader:         "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+						headers.UserRoleXHeader:       "user",
+						headers.UserNamespacesXHeader: base64.StdEncoding.EncodeToString([]byte(`[{"id": "` + ownNamespace + `", "access": "owner"}]`)),
+					}).
+					Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+						resp = r
+					})
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				var quotas model.StorageQuotasResponse
+				So(json.Unmarshal(resp.Body.Bytes(), &quotas), ShouldBeNil)
+				return quotas.Quotas
+			}
+			userQuota := model.StorageQuotaUsage{Tenant: model.QuotaTenantUser, ID: "20b616d8-1ea7-4842-b8ec-c6e8226fda5b", Used: 30, Limit: 30}
+			So(userQuotas("ns-2"), ShouldResemble, []model.StorageQuotaUsage{userQuota})
+			So(userQuotas("ns-1"), ShouldResemble, []model.StorageQuotaUsage{
+				userQuota,
+				{Tenant: model.QuotaTenantNamespace, ID: "ns-1", Used: 10, Limit: 15},
+			})
+		})
+	})
+}
+
+func TestCaseInsensitiveStorageNames(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	setup := func(caseInsensitive bool) *gin.Engine {
+		e := gin.New()
+		r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+		var acts server.StorageActions = srv
+		if caseInsensitive {
+			acts = server.CaseInsensitiveStorageNames(acts)
+		}
+		r.SetupStorageHandlers(acts)
+		return e
+	}
+	strict, insensitive := setup(false), setup(true)
+
+	adminHeaders := gofight.H{
+		headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+		headers.UserRoleXHeader: "admin",
+	}
+	request := func(e *gin.Engine, method, path string, body gofight.D) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		req := gofight.New()
+		switch method {
+		case http.MethodPost:
+			req = req.POST(path).SetJSON(body)
+		default:
+			req = req.GET(path)
+		}
+		req.SetHeader(adminHeaders).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test case-insensitive storage names", t, func() {
+		Convey("Check mixed-case names are rejected by default", func() {
+			So(request(strict, http.MethodPost, "/storages", gofight.D{"name": "Storage-Case", "size": 10}).Code,
+				ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Check names are canonicalized", func() {
+			So(request(insensitive, http.MethodPost, "/storages", gofight.D{"name": "Storage-Case", "size": 10}).Code,
+				ShouldEqual, http.StatusCreated)
+			defer delete(db.storages, "storage-case")
+			So(db.storages, ShouldContainKey, "storage-case")
+			So(request(insensitive, http.MethodPost, "/storages", gofight.D{"name": "STORAGE-case", "size": 10}).Code,
+				ShouldEqual, http.StatusConflict)
+
+			resp := request(insensitive, http.MethodGet, "/storages/STORAGE-CASE", nil)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var storage model.Storage
+			So(json.Unmarshal(resp.Body.Bytes(), &storage), ShouldBeNil)
+			So(storage.Name, ShouldEqual, "storage-case")
+
+			So(request(strict, http.MethodGet, "/storages/STORAGE-CASE", nil).Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestReservedStorageNames(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-1": {Name: "storage-1", Size: 10, Version: 1},
+	}}
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{ReservedStorageNames: r.StorageNameReserved})
+	defer srv.Close()
+	r.SetupStorageHandlers(srv)
+
+	adminHeaders := gofight.H{
+		headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+		headers.UserRoleXHeader: "admin",
+	}
+	post := func(path string, body interface{}) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		req := gofight.New().POST(path).SetHeader(adminHeaders)
+		switch b := body.(type) {
+		case gofight.D:
+			req = req.SetJSON(b)
+		default:
+			data, _ := json.Marshal(b)
+			req = req.SetBody(string(data))
+		}
+		req.Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			ret = r
+		})
+		return ret
+	}
+
+	Convey("Test names reserved by path segments", t, func() {
+		Convey("Check reserved names are rejected on create, rename and clone", func() {
+			for _, name := range []string{"stats", "idle", "quota", "schema", "webhooks", "overutilized", "by-id", "by-type"} {
+				resp := post("/storages", gofight.D{"name": name, "size": 10})
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "reserved")
+				So(post("/storages/storage-1/rename", gofight.D{"new_name": name}).Code, ShouldEqual, http.StatusBadRequest)
+				So(post("/storages/storage-1/clone", gofight.D{"target_name": name}).Code, ShouldEqual, http.StatusBadRequest)
+				So(db.storages, ShouldNotContainKey, name)
+			}
+			So(post("/storages", gofight.D{"name": "statistics", "size": 10}).Code, ShouldEqual, http.StatusCreated)
+		})
+		Convey("Check reserved names are not imported", func() {
+			resp := post("/import/storages", []model.StorageImportEntry{{Name: "quota"}, {Name: "imported"}})
+			So(resp.Code, ShouldEqual, http.StatusAccepted)
+			var result model.StorageImportResponse
+			So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+			So(result.Failed, ShouldHaveLength, 1)
+			So(result.Failed[0].Name, ShouldEqual, "quota")
+			So(db.storages, ShouldNotContainKey, "quota")
+		})
+	})
+}
+
+func TestStorageShrink(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-shrink": {Name: "storage-shrink", Size: 20, Used: 10},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	request := func(method, path, role string, body gofight.D) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		req := gofight.New()
+		if method == http.MethodPatch {
+			req = req.PATCH(path)
+		} else {
+			// used capacity from request is only validated against requested size, stored one must be checked by server
+			body["used"] = 0
+			req = req.PUT(path)
+		}
+		req.SetJSON(body).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:         "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader:       role,
+				headers.UserNamespacesXHeader: base64.StdEncoding.EncodeToString([]byte(`[]`)),
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test storage shrink", t, func() {
+		Convey("Check shrink below used capacity is rejected", func() {
+			for _, method := range []string{http.MethodPut, http.MethodPatch} {
+				resp := request(method, "/storages/storage-shrink", "admin", gofight.D{"size": 5, "used": 0})
+				So(resp.Code, ShouldEqual, http.StatusConflict)
+				var cherryErr cherry.Err
+				So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+				So(cherry.Equals(&cherryErr, errors.ErrStorageShrinkBelowUsed()), ShouldBeTrue)
+			}
+			So(db.storages["storage-shrink"].Size, ShouldEqual, 20)
+		})
+		Convey("Check update preview", func() {
+			So(request(http.MethodPut, "/storages/storage-shrink?preview=true", "admin", gofight.D{"size": 5, "used": 0}).Code,
+				ShouldEqual, http.StatusConflict)
+
+			resp := request(http.MethodPut, "/storages/storage-shrink?preview=true", "admin", gofight.D{"size": 25, "read_only": false})
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var preview model.StorageUpdatePreview
+			So(json.Unmarshal(resp.Body.Bytes(), &preview), ShouldBeNil)
+			So(preview.Name, ShouldEqual, "storage-shrink")
+			So(preview.Changes, ShouldHaveLength, 1)
+			So(preview.Changes[0].Field, ShouldEqual, "size")
+			So(preview.Changes[0].Old, ShouldEqual, 20)
+			So(preview.Changes[0].New, ShouldEqual, 25)
+			So(db.storages["storage-shrink"].Size, ShouldEqual, 20)
+		})
+		Convey("Check shrink above used capacity and grow", func() {
+			So(request(http.MethodPut, "/storages/storage-shrink", "admin", gofight.D{"size": 15}).Code, ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-shrink"].Size, ShouldEqual, 15)
+			So(request(http.MethodPatch, "/storages/storage-shrink", "admin", gofight.D{"size": 30}).Code, ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-shrink"].Size, ShouldEqual, 30)
+		})
+		Convey("Check admin override", func() {
+			So(request(http.MethodPut, "/storages/storage-shrink?allow_shrink=true", "user", gofight.D{"size": 5, "used": 0}).Code,
+				ShouldEqual, http.StatusForbidden)
+			So(request(http.MethodPut, "/storages/storage-shrink?allow_shrink=true", "admin", gofight.D{"size": 5, "used": 0}).Code,
+				ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-shrink"].Size, ShouldEqual, 5)
+		})
+	})
+}
+
+func TestStreamStorages(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("storage-%d", i)
+		db.storages[name] = model.Storage{Name: name, Size: 10}
+	}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{StreamBatchSize: 2})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	request := func(path string) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().GET(path).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+				"Accept":                mimeNDJSON,
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test storages streaming", t, func() {
+		Convey("Check storages are streamed by lines", func() {
+			resp := request("/storages")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.HeaderMap.Get("Content-Type"), ShouldStartWith, mimeNDJSON)
+			lines := strings.Split(strings.TrimSuffix(resp.Body.String(), "\n"), "\n")
+			So(lines, ShouldHaveLength, 5)
+			for i, line := range lines {
+				var storage model.Storage
+				So(json.Unmarshal([]byte(line), &storage), ShouldBeNil)
+				So(storage.Name, ShouldEqual, fmt.Sprintf("storage-%d", i))
+			}
+		})
+		Convey("Check fields projection", func() {
+			resp := request("/storages?fields=name")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(strings.SplitN(resp.Body.String(), "\n", 2)[0], ShouldEqual, `{"name":"storage-0"}`)
+		})
+		Convey("Check pagination is rejected", func() {
+			So(request("/storages?limit=2").Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestGetStoragesByType(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"nfs-1":  {Name: "nfs-1", Size: 10, Driver: model.StorageDriverNFS},
+		"nfs-2":  {Name: "nfs-2", Size: 10, Driver: model.StorageDriverNFS},
+		"ceph-1": {Name: "ceph-1", Size: 10, Driver: model.StorageDriverCephRBD},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	request := func(path string) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().GET(path).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test storages list by type", t, func() {
+		Convey("Check only storages with driver are returned", func() {
+			resp := request("/storages/by-type/nfs")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var storages []model.Storage
+			So(json.Unmarshal(resp.Body.Bytes(), &storages), ShouldBeNil)
+			So(storages, ShouldHaveLength, 2)
+			So(storages[0].Name, ShouldEqual, "nfs-1")
+			So(storages[1].Name, ShouldEqual, "nfs-2")
+		})
+		Convey("Check pagination", func() {
+			resp := request("/storages/by-type/nfs?limit=1")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var page model.StoragesPage
+			So(json.Unmarshal(resp.Body.Bytes(), &page), ShouldBeNil)
+			So(page.Storages, ShouldHaveLength, 1)
+			So(page.NextCursor, ShouldNotBeEmpty)
+		})
+		Convey("Check unknown type is rejected", func() {
+			resp := request("/storages/by-type/floppy")
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			So(resp.Body.String(), ShouldContainSubstring, strings.Join(model.StorageDrivers, ", "))
+		})
+	})
+}
+
+func TestGetIdleStorages(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	ago := func(d time.Duration) *time.Time {
+		ret := time.Now().Add(-d)
+		return &ret
+	}
+	db := &storagesDB{storages: map[string]model.Storage{
+		"forgotten": {Name: "forgotten", Size: 10, CreatedAt: *ago(90 * 24 * time.Hour), LastUsedAt: ago(60 * 24 * time.Hour)},
+		"unused":    {Name: "unused", Size: 10, CreatedAt: *ago(90 * 24 * time.Hour)},
+		"active":    {Name: "active", Size: 10, CreatedAt: *ago(90 * 24 * time.Hour), LastUsedAt: ago(time.Hour)},
+		"new":       {Name: "new", Size: 10, CreatedAt: *ago(time.Hour)},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	request := func(since string) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		gofight.New().GET("/storages/idle").
+			SetQuery(gofight.H{"since": since}).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test idle storages", t, func() {
+		Convey("Check storages without activity are returned", func() {
+			resp := request("720h")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			var storages []model.Storage
+			So(json.Unmarshal(resp.Body.Bytes(), &storages), ShouldBeNil)
+			So(storages, ShouldHaveLength, 2)
+			So(storages[0].Name, ShouldEqual, "forgotten")
+			So(storages[1].Name, ShouldEqual, "unused")
+		})
+		Convey("Check invalid period is rejected", func() {
+			So(request("").Code, ShouldEqual, http.StatusBadRequest)
+			So(request("month").Code, ShouldEqual, http.StatusBadRequest)
+			So(request("-1h").Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestCamelCaseJSON(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate},
+		Config{CamelCaseJSON: true})
+	r.SetupStorageHandlers(srv)
+
+	adminHeaders := gofight.H{
+		headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+		headers.UserRoleXHeader: "admin",
+	}
+
+	Convey("Test camelCase field names", t, func() {
+		Convey("Check both forms are accepted and response is camelCase", func() {
+			var resp gofight.HTTPResponse
+			gofight.New().POST("/storages").
+				SetHeader(adminHeaders).
+				SetJSON(gofight.D{
+					"name":            "camel",
+					"size":            10,
+					"overcommitRatio": 2,
+					"warn_threshold":  80,
+					"labels":          gofight.D{"team_name": "storage"},
+				}).
+				Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+					resp = r
+				})
+			So(resp.Code, ShouldEqual, http.StatusCreated)
+			var created map[string]interface{}
+			So(json.Unmarshal(resp.Body.Bytes(), &created), ShouldBeNil)
+			So(created["overcommitRatio"], ShouldEqual, 2)
+			So(created["warnThreshold"], ShouldEqual, 80)
+			So(created, ShouldNotContainKey, "overcommit_ratio")
+			So(created["labels"], ShouldResemble, map[string]interface{}{"team_name": "storage"})
+		})
+		Convey("Check projection fields", func() {
+			var resp gofight.HTTPResponse
+			gofight.New().GET("/storages").
+				SetHeader(adminHeaders).
+				SetQuery(gofight.H{"fields": "name,overcommitRatio"}).
+				Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+					resp = r
+				})
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.Body.String(), ShouldEqual, `[{"name":"camel","overcommitRatio":2}]`)
+		})
+	})
+}
+
+func TestImpersonationAudit(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	const (
+		adminID = "20b616d8-1ea7-4842-b8ec-c6e8226fda5b"
+		userID  = "6c2a4a5e-3ae4-4b8f-9d2b-8d9e1f4e1a7c"
+	)
+	create := func(role, name string) int {
+		var code int
+		gofight.New().POST("/storages?user-id="+userID).
+			SetJSON(gofight.D{"name": name, "size": 10}).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:         adminID,
+				headers.UserRoleXHeader:       role,
+				headers.UserNamespacesXHeader: base64.StdEncoding.EncodeToString([]byte(`[]`)),
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				code = r.Code
+			})
+		return code
+	}
+
+	Convey("Test impersonation audit", t, func() {
+		So(create("user", "storage-user"), ShouldEqual, http.StatusForbidden)
+		So(db.storages, ShouldNotContainKey, "storage-user")
+
+		So(create("admin", "storage-admin"), ShouldEqual, http.StatusCreated)
+		srv.Close() // flushes audit records
+		So(db.audit, ShouldHaveLength, 1)
+		So(db.audit[0].Operation, ShouldEqual, model.AuditCreate)
+		So(db.audit[0].UserID, ShouldEqual, userID)
+		So(db.audit[0].ImpersonatorID, ShouldEqual, adminID)
+	})
+}
+
+func TestIdempotentDeleteStorage(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-delete": {Name: "storage-delete", Size: 10},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	del := func(path string) int {
+		var code int
+		gofight.New().DELETE(path).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				code = r.Code
+			})
+		return code
+	}
+
+	Convey("Test idempotent storage deletion", t, func() {
+		So(del("/storages/storage-delete?idempotent=true"), ShouldEqual, http.StatusAccepted)
+		So(db.storages, ShouldNotContainKey, "storage-delete")
+		So(del("/storages/storage-delete?idempotent=true"), ShouldEqual, http.StatusNoContent)
+		So(del("/storages/storage-delete"), ShouldEqual, http.StatusNotFound)
+	})
+}
+
+func TestMigrateVolumes(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	now := time.Now()
+	volume := func(label string) model.Volume {
+		return model.Volume{
+			Resource:    model.Resource{ID: uuid.NewV4().String(), Label: label, CreateTime: &now},
+			Capacity:    2,
+			NamespaceID: "ns",
+			StorageName: "storage-source",
+		}
+	}
+	vols := []model.Volume{volume("vol-1"), volume("vol-2"), volume("vol-3")}
+	db := &storagesDB{
+		storages: map[string]model.Storage{
+			"storage-source":    {Name: "storage-source", Size: 10, Used: 6},
+			"storage-target":    {Name: "storage-target", Size: 10, Used: 2},
+			"storage-small":     {Name: "storage-small", Size: 5},
+			"storage-read-only": {Name: "storage-read-only", Size: 10, ReadOnly: true},
+		},
+		volumes: vols,
+	}
+	srv := server.NewServer(db, &server.Clients{KubeAPI: clients.NewKubeAPIDummyClient()}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	migrate := func(req model.StorageMigrateRequest) (int, model.StorageMigrateResponse) {
+		var code int
+		var resp model.StorageMigrateResponse
+		body, _ := json.Marshal(req)
+		gofight.New().POST("/storages/storage-source/migrate").
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			SetBody(string(body)).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				code = r.Code
+				json.Unmarshal(r.Body.Bytes(), &resp)
+			})
+		return code, resp
+	}
+
+	Convey("Test storage volumes migration", t, func() {
+		Convey("Check unsuitable targets rejected", func() {
+			code, _ := migrate(model.StorageMigrateRequest{Target: "storage-read-only"})
+			So(code, ShouldEqual, errors.ErrStorageReadOnly().StatusHTTP)
+			code, _ = migrate(model.StorageMigrateRequest{Target: "storage-small"})
+			So(code, ShouldEqual, errors.ErrStorageOvercommitted().StatusHTTP)
+			code, _ = migrate(model.StorageMigrateRequest{Target: "storage-source"})
+			So(code, ShouldEqual, http.StatusBadRequest)
+			So(db.storages["storage-source"].Used, ShouldEqual, 6)
+		})
+		Convey("Check selected volumes migrated", func() {
+			unknown := uuid.NewV4().String()
+			code, resp := migrate(model.StorageMigrateRequest{
+				Target:    "storage-target",
+				VolumeIDs: []string{vols[0].ID, vols[1].ID, unknown},
+			})
+			So(code, ShouldEqual, http.StatusAccepted)
+			So(resp.Migrated, ShouldHaveLength, 2)
+			So(resp.Failed, ShouldHaveLength, 1)
+			So(resp.Failed[0].VolumeID, ShouldEqual, unknown)
+			So(db.storages["storage-source"].Used, ShouldEqual, 2)
+			So(db.storages["storage-target"].Used, ShouldEqual, 6)
+
+			code, resp = migrate(model.StorageMigrateRequest{Target: "storage-target"})
+			So(code, ShouldEqual, http.StatusAccepted)
+			So(resp.Migrated, ShouldHaveLength, 1)
+			So(resp.Migrated[0].Label, ShouldEqual, "vol-3")
+			So(db.storages["storage-source"].Used, ShouldEqual, 0)
+			So(db.storages["storage-target"].Used, ShouldEqual, 8)
+		})
+	})
+}
+
+func TestRequiredLabels(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-unlabeled": {Name: "storage-unlabeled", Size: 10},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{RequiredLabels: []string{"team", "cost-center"}})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	request := func(method, path string, body gofight.D) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		req := gofight.New()
+		if method == http.MethodPost {
+			req = req.POST(path)
+		} else {
+			req = req.PATCH(path)
+		}
+		req.SetJSON(body).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test required labels", t, func() {
+		Convey("Check storage without required labels is rejected", func() {
+			resp := request(http.MethodPost, "/storages", gofight.D{"name": "storage-labels", "size": 10, "labels": gofight.H{"team": "payments"}})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			var cherryErr cherry.Err
+			So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+			So(cherryErr.Fields["labels"], ShouldContainSubstring, "cost-center")
+			So(cherryErr.Fields["labels"], ShouldNotContainSubstring, "team")
+			So(db.storages, ShouldNotContainKey, "storage-labels")
+		})
+		Convey("Check labels update can't remove required labels", func() {
+			So(request(http.MethodPost, "/storages", gofight.D{
+				"name":   "storage-labels",
+				"size":   10,
+				"labels": gofight.H{"team": "payments", "cost-center": "cc-42"},
+			}).Code, ShouldEqual, http.StatusCreated)
+			defer delete(db.storages, "storage-labels")
+
+			So(request(http.MethodPatch, "/storages/storage-labels", gofight.D{"labels": gofight.D{"team": nil}}).Code,
+				ShouldEqual, http.StatusBadRequest)
+			So(request(http.MethodPatch, "/storages/storage-labels", gofight.D{"labels": gofight.D{"team": "billing"}}).Code,
+				ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-labels"].Labels["team"], ShouldEqual, "billing")
+
+			update := model.UpdateStorageRequest{Labels: map[string]string{"team": "billing"}}
+			So(srv.UpdateStorage(context.Background(), "storage-labels", update, nil, false), ShouldNotBeNil)
+			update.Labels["cost-center"] = "cc-43"
+			So(srv.UpdateStorage(context.Background(), "storage-labels", update, nil, false), ShouldBeNil)
+			So(db.storages["storage-labels"].Labels, ShouldResemble, update.Labels)
+		})
+		Convey("Check updates without labels are allowed for existing storages", func() {
+			So(request(http.MethodPatch, "/storages/storage-unlabeled", gofight.D{"size": 20}).Code, ShouldEqual, http.StatusAccepted)
+			So(request(http.MethodPatch, "/storages/storage-unlabeled", gofight.D{"labels": gofight.D{"team": "payments"}}).Code,
+				ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestStorageLabels(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-labels": {Name: "storage-labels", Size: 10, Description: "fast",
+			Labels: map[string]string{"team": "payments", "tier": "gold"}},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{RequiredLabels: []string{"team"}})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	request := func(method string, body gofight.D, hdrs gofight.H) gofight.HTTPResponse {
+		var ret gofight.HTTPResponse
+		req := gofight.New()
+		if method == http.MethodPut {
+			req = req.PUT("/storages/storage-labels/labels")
+		} else {
+			req = req.PATCH("/storages/storage-labels/labels")
+		}
+		h := gofight.H{
+			headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader: "admin",
+		}
+		for k, v := range hdrs {
+			h[k] = v
+		}
+		req.SetJSON(body).
+			SetHeader(h).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	labelsOf := func(resp gofight.HTTPResponse) map[string]string {
+		var ret model.StorageLabels
+		So(json.Unmarshal(resp.Body.Bytes(), &ret), ShouldBeNil)
+		return ret.Labels
+	}
+
+	Convey("Test storage labels update", t, func() {
+		Convey("Check patch merges and removes labels", func() {
+			resp := request(http.MethodPatch, gofight.D{"tier": nil, "zone": "eu-1"}, nil)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(labelsOf(resp), ShouldResemble, map[string]string{"team": "payments", "zone": "eu-1"})
+			So(db.storages["storage-labels"].Description, ShouldEqual, "fast")
+			So(db.storages["storage-labels"].Size, ShouldEqual, 10)
+		})
+		Convey("Check put replaces labels", func() {
+			resp := request(http.MethodPut, gofight.D{"team": "billing"}, nil)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(labelsOf(resp), ShouldResemble, map[string]string{"team": "billing"})
+			So(db.storages["storage-labels"].Labels, ShouldResemble, map[string]string{"team": "billing"})
+		})
+		Convey("Check required labels can't be removed", func() {
+			So(request(http.MethodPut, gofight.D{}, nil).Code, ShouldEqual, http.StatusBadRequest)
+			So(request(http.MethodPatch, gofight.D{"team": nil}, nil).Code, ShouldEqual, http.StatusBadRequest)
+			So(db.storages["storage-labels"].Labels["team"], ShouldNotBeEmpty)
+		})
+		Convey("Check invalid labels and stale ETag are rejected", func() {
+			So(request(http.MethodPatch, gofight.D{"bad key": "x"}, nil).Code, ShouldEqual, http.StatusBadRequest)
+			So(request(http.MethodPatch, gofight.D{}, nil).Code, ShouldEqual, http.StatusBadRequest)
+			So(request(http.MethodPatch, gofight.D{"zone": "eu-2"}, gofight.H{"If-Match": `"100"`}).Code,
+				ShouldEqual, http.StatusPreconditionFailed)
+		})
+	})
+}
+
+func TestApplyStorages(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-same":    {Name: "storage-same", Size: 10, OvercommitRatio: 1},
+		"storage-changed": {Name: "storage-changed", Size: 10, OvercommitRatio: 1},
+		"storage-extra":   {Name: "storage-extra", Size: 10, OvercommitRatio: 1},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	apply := func(query string, entries []gofight.D) (code int, resp model.StorageApplyResponse) {
+		body, err := json.Marshal(entries)
+		So(err, ShouldBeNil)
+		gofight.New().POST("/storages/apply"+query).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			SetBody(string(body)).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				code = r.Code
+				So(json.Unmarshal(r.Body.Bytes(), &resp), ShouldBeNil)
+			})
+		return
+	}
+	statuses := func(results []model.StorageApplyResult) map[string]string {
+		ret := make(map[string]string)
+		for _, result := range results {
+			ret[result.Name] = result.Status
+		}
+		return ret
+	}
+	desired := []gofight.D{
+		{"name": "storage-same", "size": 10},
+		{"name": "storage-changed", "size": 20, "labels": gofight.H{"team": "payments"}},
+		{"name": "storage-new", "size": 5},
+	}
+
+	Convey("Test storages apply", t, func() {
+		Convey("Check atomic apply is rolled back on failure", func() {
+			code, resp := apply("?atomic=true&prune=true", append(desired, gofight.D{"name": "storage-big", "size": 10, "reserved": 10}))
+			So(code, ShouldEqual, http.StatusAccepted)
+			So(resp.Applied, ShouldBeEmpty)
+			So(statuses(resp.Failed)["storage-big"], ShouldEqual, model.StorageUpdateInvalid)
+			So(statuses(resp.Failed)["storage-new"], ShouldEqual, model.StorageRolledBack)
+			So(db.storages, ShouldNotContainKey, "storage-new")
+			So(db.storages, ShouldContainKey, "storage-extra")
+		})
+		Convey("Check storages are created, updated and pruned", func() {
+			code, resp := apply("?prune=true", desired)
+			So(code, ShouldEqual, http.StatusAccepted)
+			So(resp.Failed, ShouldBeEmpty)
+			So(statuses(resp.Applied), ShouldResemble, map[string]string{
+				"storage-same":    model.StorageUnchanged,
+				"storage-changed": model.StorageUpdated,
+				"storage-new":     model.StorageCreated,
+				"storage-extra":   model.StorageDeleted,
+			})
+			So(db.storages["storage-changed"].Size, ShouldEqual, 20)
+			So(db.storages["storage-changed"].Labels["team"], ShouldEqual, "payments")
+			So(db.storages, ShouldNotContainKey, "storage-extra")
+
+			_, resp = apply("", desired)
+			So(statuses(resp.Applied), ShouldResemble, map[string]string{
+				"storage-same":    model.StorageUnchanged,
+				"storage-changed": model.StorageUnchanged,
+				"storage-new":     model.StorageUnchanged,
+			})
+		})
+		Convey("Check duplicate names are rejected", func() {
+			code, _ := apply("", []gofight.D{{"name": "storage-same"}, {"name": "storage-same"}})
+			So(code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestStorageRoles(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-roles": {Name: "storage-roles", Size: 10},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e, restricted := gin.New(), gin.New()
+	tv := &TranslateValidate{UniversalTranslator: translate, Validate: validate}
+	NewRouter(e, &kubeModel.ServiceStatus{}, tv, Config{}).SetupStorageHandlers(srv)
+	NewRouter(restricted, &kubeModel.ServiceStatus{}, tv,
+		Config{RoleOperations: middleware.RoleOperations{middleware.RoleViewer: {"list"}}}).SetupStorageHandlers(srv)
+
+	request := func(e *gin.Engine, method, path, role string) (ret gofight.HTTPResponse) {
+		req := gofight.New()
+		switch method {
+		case http.MethodGet:
+			req = req.GET(path)
+		case http.MethodDelete:
+			req = req.DELETE(path)
+		}
+		req.SetHeader(gofight.H{
+			headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader: role,
+		}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test storage operations role checks", t, func() {
+		Convey("Check viewer can read storages", func() {
+			So(request(e, http.MethodGet, "/storages/storage-roles", "viewer").Code, ShouldEqual, http.StatusOK)
+			resp := request(e, http.MethodGet, "/storages", "viewer")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.Body.String(), ShouldContainSubstring, "storage-roles")
+		})
+		Convey("Check viewer can't change storages", func() {
+			resp := request(e, http.MethodDelete, "/storages/storage-roles", "viewer")
+			So(resp.Code, ShouldEqual, http.StatusForbidden)
+			var envelope errors.Envelope
+			So(json.Unmarshal(resp.Body.Bytes(), &envelope), ShouldBeNil)
+			So(envelope.Code, ShouldEqual, errors.CodeRoleRequired)
+			So(resp.Body.String(), ShouldContainSubstring, "operation delete requires role admin")
+			So(db.storages, ShouldContainKey, "storage-roles")
+		})
+		Convey("Check role operations are configurable", func() {
+			So(request(restricted, http.MethodGet, "/storages", "viewer").Code, ShouldEqual, http.StatusOK)
+			So(request(restricted, http.MethodGet, "/storages/storage-roles", "viewer").Code, ShouldEqual, http.StatusForbidden)
+			So(request(restricted, http.MethodGet, "/storages/storage-roles", "admin").Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
+
+func TestStorageSizeHistory(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	const userID = "20b616d8-1ea7-4842-b8ec-c6e8226fda5b"
+	adminHeaders := gofight.H{
+		headers.UserIDXHeader:   userID,
+		headers.UserRoleXHeader: "admin",
+	}
+	history := func(query string) (ret gofight.HTTPResponse) {
+		gofight.New().GET("/storages/storage-history/history"+query).
+			SetHeader(adminHeaders).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	records := func(resp gofight.HTTPResponse) []model.StorageSizeRecord {
+		var ret []model.StorageSizeRecord
+		So(json.Unmarshal(resp.Body.Bytes(), &ret), ShouldBeNil)
+		return ret
+	}
+
+	var codes []int
+	gofight.New().POST("/storages").
+		SetJSON(gofight.D{"name": "storage-history", "size": 10}).
+		SetHeader(adminHeaders).
+		Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+			codes = append(codes, r.Code)
+		})
+	for _, patch := range []gofight.D{{"size": 20}, {"description": "no size change"}, {"size": 30}} {
+		gofight.New().PATCH("/storages/storage-history").
+			SetJSON(patch).
+			SetHeader(adminHeaders).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				codes = append(codes, r.Code)
+			})
+	}
+	srv.Close() // flushes audit and size records
+
+	Convey("Test storage size history", t, func() {
+		So(codes, ShouldResemble, []int{http.StatusCreated, http.StatusAccepted, http.StatusAccepted, http.StatusAccepted})
+
+		Convey("Check only size changes are recorded", func() {
+			resp := history("")
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			ret := records(resp)
+			So(ret, ShouldHaveLength, 3)
+			So(ret[0].Operation, ShouldEqual, model.AuditCreate)
+			So(ret[0].OldSize, ShouldEqual, 0)
+			So(ret[2].OldSize, ShouldEqual, 20)
+			So(ret[2].Size, ShouldEqual, 30)
+			So(ret[2].UserID, ShouldEqual, userID)
+		})
+		Convey("Check limit returns latest changes", func() {
+			ret := records(history("?limit=1"))
+			So(ret, ShouldHaveLength, 1)
+			So(ret[0].Size, ShouldEqual, 30)
+		})
+		Convey("Check time range filter", func() {
+			from := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+			So(records(history("?from="+from)), ShouldBeEmpty)
+		})
+		Convey("Check invalid params are rejected", func() {
+			So(history("?limit=0").Code, ShouldEqual, http.StatusBadRequest)
+			So(history("?from=yesterday").Code, ShouldEqual, http.StatusBadRequest)
+			So(history("?from=2026-01-02T00:00:00Z&to=2026-01-01T00:00:00Z").Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestStorageSizeGranularity(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-blocks": {Name: "storage-blocks", Size: 8},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{MinStorageSize: 4, StorageSizeGranularity: 4})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	request := func(method, path string, body gofight.D) (ret gofight.HTTPResponse) {
+		req := gofight.New()
+		if method == http.MethodPost {
+			req = req.POST(path)
+		} else {
+			req = req.PATCH(path)
+		}
+		req.SetJSON(body).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+	sizeReason := func(resp gofight.HTTPResponse) string {
+		So(resp.Code, ShouldEqual, http.StatusBadRequest)
+		var cherryErr cherry.Err
+		So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+		return cherryErr.Fields["size"]
+	}
+
+	Convey("Test storage size granularity", t, func() {
+		Convey("Check created storage size must be a multiple of granularity", func() {
+			So(sizeReason(request(http.MethodPost, "/storages", gofight.D{"name": "storage-odd", "size": 10})),
+				ShouldEqual, "must be a multiple of 4 GiB, nearest valid sizes: 8, 12 GiB")
+			So(sizeReason(request(http.MethodPost, "/storages", gofight.D{"name": "storage-odd", "size": 5})),
+				ShouldEqual, "must be a multiple of 4 GiB, nearest valid sizes: 4, 8 GiB")
+			So(db.storages, ShouldNotContainKey, "storage-odd")
+
+			So(request(http.MethodPost, "/storages", gofight.D{"name": "storage-even", "size": 12}).Code, ShouldEqual, http.StatusCreated)
+			delete(db.storages, "storage-even")
+		})
+		Convey("Check updated storage size must be a multiple of granularity", func() {
+			So(sizeReason(request(http.MethodPatch, "/storages/storage-blocks", gofight.D{"size": 13})),
+				ShouldContainSubstring, "nearest valid sizes: 12, 16 GiB")
+			So(db.storages["storage-blocks"].Size, ShouldEqual, 8)
+			So(request(http.MethodPatch, "/storages/storage-blocks", gofight.D{"size": 16}).Code, ShouldEqual, http.StatusAccepted)
+		})
+	})
+}
+
+func TestTouchStorage(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-touch": {Name: "storage-touch", Size: 10, Version: 3},
+	}}
+	publisher := events.NewMemoryPublisher()
+	srv := server.NewServer(db, &server.Clients{}, publisher, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	touch := func(path string) (code int) {
+		gofight.New().POST(path).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				code = r.Code
+			})
+		return code
+	}
+
+	Convey("Test storage touch", t, func() {
+		Convey("Check only update time is changed", func() {
+			So(touch("/storages/storage-touch/touch"), ShouldEqual, http.StatusAccepted)
+			storage := db.storages["storage-touch"]
+			So(storage.UpdatedAt, ShouldHappenWithin, time.Minute, time.Now())
+			So(storage.Version, ShouldEqual, 3)
+			So(storage.Size, ShouldEqual, 10)
+			So(publisher.Events(), ShouldBeEmpty)
+		})
+		Convey("Check reconcile event is published", func() {
+			So(touch("/storages/storage-touch/touch?reconciled=true"), ShouldEqual, http.StatusAccepted)
+			So(publisher.Events(), ShouldHaveLength, 1)
+			So(publisher.Events()[0].Operation, ShouldEqual, events.StorageReconciled)
+		})
+		Convey("Check missing storage", func() {
+			So(touch("/storages/storage-missing/touch"), ShouldEqual, http.StatusNotFound)
+			So(touch("/storages/storage-touch/touch?reconciled=maybe"), ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestTransferStorage(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	const (
+		oldOwner = "6c2a4a5e-3ae4-4b8f-9d2b-8d9e1f4e1a7c"
+		newOwner = "9b7e2c1d-4f3a-4e8b-a6d5-0c1b2a3f4e5d"
+	)
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-transfer": {Name: "storage-transfer", Size: 10, OwnerUserID: oldOwner},
+		"storage-large":    {Name: "storage-large", Size: 100, OwnerUserID: oldOwner},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{StorageQuotas: []model.StorageQuota{
+		{Tenant: model.QuotaTenantUser, ID: model.QuotaAnyTenant, MaxSize: 50},
+	}})
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	transfer := func(name, role string, body gofight.D) (code int) {
+		gofight.New().POST("/storages/"+name+"/transfer").
+			SetJSON(body).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: role,
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				code = r.Code
+			})
+		return code
+	}
+
+	codes := []int{
+		transfer("storage-transfer", "admin", gofight.D{"owner_user_id": newOwner}),
+		transfer("storage-large", "admin", gofight.D{"owner_user_id": newOwner}),
+		transfer("storage-transfer", "viewer", gofight.D{"owner_user_id": oldOwner}),
+		transfer("storage-transfer", "admin", gofight.D{"owner_user_id": "nobody"}),
+		transfer("storage-missing", "admin", gofight.D{"owner_user_id": newOwner}),
+	}
+	srv.Close() // flushes audit records
+
+	Convey("Test storage ownership transfer", t, func() {
+		Convey("Check owner is changed and audited", func() {
+			So(codes[0], ShouldEqual, http.StatusAccepted)
+			So(db.storages["storage-transfer"].OwnerUserID, ShouldEqual, newOwner)
+			So(db.audit, ShouldHaveLength, 1)
+			So(db.audit[0].Operation, ShouldEqual, model.AuditTransfer)
+			So(db.audit[0].Before.OwnerUserID, ShouldEqual, oldOwner)
+			So(db.audit[0].After.OwnerUserID, ShouldEqual, newOwner)
+		})
+		Convey("Check new owner quota is checked", func() {
+			So(codes[1], ShouldEqual, http.StatusForbidden)
+			So(db.storages["storage-large"].OwnerUserID, ShouldEqual, oldOwner)
+		})
+		Convey("Check invalid requests", func() {
+			So(codes[2], ShouldEqual, http.StatusForbidden)
+			So(codes[3], ShouldEqual, http.StatusBadRequest)
+			So(codes[4], ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestConditionalGetStorage(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-etag": {Name: "storage-etag", Size: 10, Version: 2},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	get := func(ifNoneMatch string) (ret gofight.HTTPResponse) {
+		h := gofight.H{
+			headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader: "admin",
+		}
+		if ifNoneMatch != "" {
+			h["If-None-Match"] = ifNoneMatch
+		}
+		gofight.New().GET("/storages/storage-etag").
+			SetHeader(h).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	Convey("Test conditional storage get", t, func() {
+		Convey("Check matching revision returns 304 without body", func() {
+			for _, tag := range []string{`"2"`, `W/"2"`, `"1", "2"`, `*`} {
+				resp := get(tag)
+				So(resp.Code, ShouldEqual, http.StatusNotModified)
+				So(resp.Body.Len(), ShouldEqual, 0)
+				So(resp.HeaderMap.Get("ETag"), ShouldEqual, `"2"`)
+			}
+		})
+		Convey("Check other revision returns storage", func() {
+			for _, tag := range []string{"", `"1"`, `2`} {
+				resp := get(tag)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.HeaderMap.Get("ETag"), ShouldEqual, `"2"`)
+				So(resp.Body.String(), ShouldContainSubstring, "storage-etag")
+			}
+		})
+		Convey("Check changed storage is returned", func() {
+			storage := db.storages["storage-etag"]
+			storage.Version = 3
+			db.storages["storage-etag"] = storage
+			resp := get(`"2"`)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			So(resp.HeaderMap.Get("ETag"), ShouldEqual, `"3"`)
+		})
+	})
+}
+
+func TestStoragesPaginationLinks(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+
+	db := &storagesDB{storages: map[string]model.Storage{
+		"nfs-1":  {Name: "nfs-1", Size: 10, Driver: model.StorageDriverNFS},
+		"nfs-2":  {Name: "nfs-2", Size: 10, Driver: model.StorageDriverNFS},
+		"nfs-3":  {Name: "nfs-3", Size: 10, Driver: model.StorageDriverNFS},
+		"ceph-1": {Name: "ceph-1", Size: 10, Driver: model.StorageDriverCephRBD},
+	}}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	e := gin.New()
+	r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, Config{})
+	r.SetupStorageHandlers(srv)
+
+	// get returns page storages names and page links by relation
+	get := func(path string) (names []string, links map[string]string) {
+		gofight.New().GET(path).
+			SetHeader(gofight.H{
+				headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+				headers.UserRoleXHeader: "admin",
+			}).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				So(r.Code, ShouldEqual, http.StatusOK)
+				var page model.StoragesPage
+				So(json.Unmarshal(r.Body.Bytes(), &page), ShouldBeNil)
+				for _, storage := range page.Storages {
+					names = append(names, storage.Name)
+				}
+				links = make(map[string]string)
+				for _, link := range strings.Split(r.HeaderMap.Get("Link"), ", ") {
+					var target, rel string
+					_, err := fmt.Sscanf(link, "<%s rel=%q", &target, &rel)
+					So(err, ShouldBeNil)
+					links[rel] = strings.TrimSuffix(target, ">;")
+				}
+			})
+		return
+	}
+
+	Convey("Test storages pagination links", t, func() {
+		names, first := get("/storages/by-type/nfs?limit=1&skip_usage=true")
+		So(names, ShouldResemble, []string{"nfs-1"})
+		So(first["first"], ShouldEqual, "/storages/by-type/nfs?limit=1&skip_usage=true")
+		So(first, ShouldNotContainKey, "prev")
+		So(first["next"], ShouldContainSubstring, "skip_usage=true")
+
+		names, second := get(first["next"])
+		So(names, ShouldResemble, []string{"nfs-2"})
+		So(second["first"], ShouldEqual, first["first"])
+		So(second["prev"], ShouldEqual, first["first"])
+
+		names, third := get(second["next"])
+		So(names, ShouldResemble, []string{"nfs-3"})
+		So(third, ShouldNotContainKey, "next")
+
+		names, _ = get(third["prev"])
+		So(names, ShouldResemble, []string{"nfs-2"})
+	})
+}
+
+func TestStrictJSONFields(t *testing.T) {
+	translate := ut.New(en.New(), en.New())
+	validate := validation.StandardPermissionsValidator(translate)
+	binding.Validator = &validation.GinValidatorV9{Validate: validate}
+
+	db := &storagesDB{storages: make(map[string]model.Storage)}
+	srv := server.NewServer(db, &server.Clients{}, nil, server.Config{})
+	defer srv.Close()
+
+	newEngine := func(cfg Config) *gin.Engine {
+		e := gin.New()
+		r := NewRouter(e, &kubeModel.ServiceStatus{}, &TranslateValidate{UniversalTranslator: translate, Validate: validate}, cfg)
+		r.SetupStorageHandlers(srv)
+		return e
+	}
+	strict, lax := newEngine(Config{StrictJSONFields: true}), newEngine(Config{})
+	camel := newEngine(Config{StrictJSONFields: true, CamelCaseJSON: true})
+
+	request := func(e *gin.Engine, method, path string, body gofight.D) (ret gofight.HTTPResponse) {
+		r := gofight.New()
+		switch method {
+		case http.MethodPost:
+			r.POST(path)
+		case http.MethodPut:
+			r.PUT(path)
+		case http.MethodPatch:
+			r.PATCH(path)
+		}
+		r.SetHeader(gofight.H{
+			headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader: "admin",
+		}).
+			SetJSON(body).
+			Run(e, func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+				ret = r
+			})
+		return ret
+	}
+
+	codes := []int{
+		request(strict, http.MethodPost, "/storages", gofight.D{"name": "strict", "size": 10, "labels": gofight.D{"any": "label"}}).Code,
+		request(lax, http.MethodPost, "/storages", gofight.D{"name": "lax", "siez": 10, "size": 10}).Code,
+		request(camel, http.MethodPost, "/storages", gofight.D{"name": "camel", "size": 10, "warnThreshold": 80}).Code,
+	}
+	createTypo := request(strict, http.MethodPost, "/storages", gofight.D{"name": "typo", "siez": 10, "size": 10, "lables": gofight.D{}})
+	updateTypo := request(strict, http.MethodPut, "/storages/strict", gofight.D{"read_onyl": true})
+	patchTypo := request(strict, http.MethodPatch, "/storages/strict", gofight.D{"size": 20, "read_onyl": true})
+
+	Convey("Test strict JSON fields", t, func() {
+		Convey("Check known fields are accepted", func() {
+			So(codes, ShouldResemble, []int{http.StatusCreated, http.StatusCreated, http.StatusCreated})
+			So(db.storages, ShouldContainKey, "lax")
+		})
+		Convey("Check unknown fields are rejected on create", func() {
+			So(createTypo.Code, ShouldEqual, http.StatusBadRequest)
+			var envelope errors.Envelope
+			So(json.Unmarshal(createTypo.Body.Bytes(), &envelope), ShouldBeNil)
+			So(envelope.Fields, ShouldContainKey, "siez")
+			So(envelope.Fields, ShouldContainKey, "lables")
+			So(db.storages, ShouldNotContainKey, "typo")
+		})
+		Convey("Check unknown fields are rejected on update", func() {
+			So(updateTypo.Code, ShouldEqual, http.StatusBadRequest)
+			So(updateTypo.Body.String(), ShouldContainSubstring, "read_onyl")
+			So(db.storages["strict"].ReadOnly, ShouldBeFalse)
+
+			So(patchTypo.Code, ShouldEqual, http.StatusBadRequest)
+			So(patchTypo.Body.String(), ShouldContainSubstring, "read_onyl")
+			So(db.storages["strict"].Size, ShouldEqual, 10)
+		})
+	})
+}
+
+func TestGetStorageTenantIsolation(t *testing.T) {
+	const otherTenantID = "5d0e1a8c-7f4b-4a39-9a55-0b6c3f3e2d11"
+	db := &storagesDB{storages: map[string]model.Storage{
+		"shared":       {Name: "shared", Size: 10},
+		"own-tenant":   {Name: "own-tenant", Size: 10, Namespaces: []string{"ns-1"}},
+		"other-tenant": {ID: otherTenantID, Name: "other-tenant", Size: 10, Version: 1, Namespaces: []string{"ns-2"}},
+	}}
+	tr := newStorageTestRouter(t, db, server.Config{}, Config{RoleOperations: middleware.RoleOperations{
+		middleware.RoleUser:   {"list", "get", "get_by_id", "audit", "history", "forecast", "volumes"},
+		middleware.RoleViewer: middleware.DefaultRoleOperations[middleware.RoleViewer],
+	}})
+	defer tr.srv.Close()
+
+	get := func(path, role string, h gofight.H) gofight.HTTPResponse {
+		if h == nil {
+			h = gofight.H{}
+		}
+		h[headers.UserIDXHeader] = "20b616d8-1ea7-4842-b8ec-c6e8226fda5b"
+		h[headers.UserRoleXHeader] = role
+		if role == "user" {
+			h[headers.UserNamespacesXHeader] = base64.StdEncoding.EncodeToString([]byte(`[{"id": "ns-1", "access": "owner"}]`))
+		}
+		return tr.do(http.MethodGet, path, h, nil)
+	}
+
+	Convey("Test storage get tenant isolation", t, func() {
+		Convey("Check user can read storages available in own namespaces", func() {
+			So(get("/storages/shared", "user", nil).Code, ShouldEqual, http.StatusOK)
+			So(get("/storages/own-tenant", "user", nil).Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Check other tenant storage looks like missing one", func() {
+			missing := get("/storages/no-such-storage", "user", nil)
+			for _, resp := range []gofight.HTTPResponse{
+				get("/storages/other-tenant", "user", nil),
+				get("/storages/other-tenant", "user", gofight.H{"If-None-Match": "*"}),
+			} {
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				So(resp.HeaderMap.Get("ETag"), ShouldBeEmpty)
+				So(resp.Body.String(), ShouldNotContainSubstring, "ns-2")
+				So(strings.Replace(resp.Body.String(), "other-tenant", "no-such-storage", -1), ShouldEqual, missing.Body.String())
+			}
+			So(get("/storages/by-id/"+otherTenantID, "user", nil).Code, ShouldEqual, http.StatusNotFound)
+		})
+		Convey("Check nested resources of other tenant storage look like missing ones", func() {
+			for _, action := range []string{"audit", "history", "forecast", "volumes"} {
+				So(get("/storages/other-tenant/"+action, "user", nil).Code, ShouldEqual, http.StatusNotFound)
+			}
+			So(get("/storages/own-tenant/history", "user", nil).Code, ShouldEqual, http.StatusOK)
+			So(get("/storages/own-tenant/volumes", "user", nil).Code, ShouldEqual, http.StatusOK)
+			So(get("/storages/other-tenant/volumes", "admin", nil).Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Check admins and viewers bypass isolation", func() {
+			So(get("/storages/other-tenant", "admin", nil).Code, ShouldEqual, http.StatusOK)
+			So(get("/storages/other-tenant", "viewer", nil).Code, ShouldEqual, http.StatusOK)
+			So(get("/storages/by-id/"+otherTenantID, "admin", nil).Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
+
+func TestStorageValidationHooks(t *testing.T) {
+	db := &storagesDB{storages: map[string]model.Storage{
+		"team-storage": {Name: "team-storage", Size: 10},
+	}}
+	tr := newStorageTestRouter(t, db, server.Config{
+		ValidationHooks: []server.ValidationHook{
+			server.ValidationHookFunc(func(ctx context.Context, before *model.Storage, storage model.Storage) error {
+				if !strings.HasPrefix(storage.Name, "team-") {
+					return errors.ErrRequestValidationFailed().AddDetailF("storage name must start with team-")
+				}
+				return nil
+			}),
+			server.SizeBoundsHook{Min: 1, Max: 100},
+			server.ValidationHookFunc(func(ctx context.Context, before *model.Storage, storage model.Storage) error {
+				if before != nil && storage.Size < before.Size {
+					return fmt.Errorf("storages can't be shrunk")
+				}
+				return nil
+			}),
+		},
+	}, Config{})
+	defer tr.srv.Close()
+
+	adminHeaders := gofight.H{
+		headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+		headers.UserRoleXHeader: "admin",
+	}
+	request := func(method, path string, body gofight.D) gofight.HTTPResponse {
+		return tr.do(method, path, adminHeaders, body)
+	}
+	details := func(resp gofight.HTTPResponse) []string {
+		var cherryErr cherry.Err
+		So(json.Unmarshal(resp.Body.Bytes(), &cherryErr), ShouldBeNil)
+		return cherryErr.Details
+	}
+
+	Convey("Test storage validation hooks", t, func() {
+		Convey("Check custom hook rejects created storage", func() {
+			resp := request(http.MethodPost, "/storages", gofight.D{"name": "storage-hooks", "size": 10})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			So(details(resp), ShouldContain, "storage name must start with team-")
+			So(db.storages, ShouldNotContainKey, "storage-hooks")
+		})
+		Convey("Check built-in hooks run with custom ones", func() {
+			resp := request(http.MethodPost, "/storages", gofight.D{"name": "team-huge", "size": 200})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			So(details(resp)[0], ShouldContainSubstring, "must be in range [1, 100]")
+
+			So(request(http.MethodPost, "/storages", gofight.D{"name": "team-hooks", "size": 10}).Code, ShouldEqual, http.StatusCreated)
+			delete(db.storages, "team-hooks")
+		})
+		Convey("Check hooks run on update and plain errors are validation errors", func() {
+			resp := request(http.MethodPut, "/storages/team-storage", gofight.D{"size": 5, "used": 0})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			So(details(resp), ShouldContain, "storages can't be shrunk")
+			So(db.storages["team-storage"].Size, ShouldEqual, 10)
+
+			So(request(http.MethodPut, "/storages/team-storage", gofight.D{"name": "storage-renamed"}).Code, ShouldEqual, http.StatusBadRequest)
+			So(request(http.MethodPut, "/storages/team-storage", gofight.D{"size": 20, "used": 0}).Code, ShouldEqual, http.StatusAccepted)
+			So(db.storages["team-storage"].Size, ShouldEqual, 20)
+		})
+		Convey("Check hooks run on rename", func() {
+			resp := request(http.MethodPost, "/storages/team-storage/rename", gofight.D{"new_name": "storage-renamed"})
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			So(details(resp), ShouldContain, "storage name must start with team-")
+			So(db.storages, ShouldContainKey, "team-storage")
+		})
+		Convey("Check server rejects reserved names and invalid fields without transport checks", func() {
+			for _, storage := range []model.Storage{
+				{Name: "stats", Size: 10},
+				{Name: "team-driver", Size: 10, Driver: "tape"},
+				{Name: "team-labels", Size: 10, Labels: map[string]string{"bad key": "v"}},
+				{Name: "team-annotations", Size: 10, Annotations: map[string]string{"bad key": "v"}},
+				{Name: "team-description", Size: 10, Description: strings.Repeat("d", model.MaxStorageDescriptionLength+1)},
+			} {
+				_, err := tr.srv.CreateStorage(context.Background(), storage)
+				So(cherry.Equals(err, errors.ErrRequestValidationFailed()), ShouldBeTrue)
+				So(db.storages, ShouldNotContainKey, storage.Name)
+			}
+		})
+	})
+}
+
+func TestPinStorage(t *testing.T) {
+	unused := time.Now().Add(-90 * 24 * time.Hour)
+	db := &storagesDB{storages: map[string]model.Storage{
+		"storage-pin": {Name: "storage-pin", Size: 10, CreatedAt: unused},
+	}}
+	tr := newStorageTestRouter(t, db, server.Config{}, Config{
+		RoleOperations: middleware.RoleOperations{middleware.RoleUser: {"list", "pin"}},
+	})
+	defer tr.srv.Close()
+
+	request := func(method, path, role string) gofight.HTTPResponse {
+		return tr.do(method, path, gofight.H{
+			headers.UserIDXHeader:         "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader:       role,
+			headers.UserNamespacesXHeader: base64.StdEncoding.EncodeToString([]byte(`[]`)),
+		}, nil)
+	}
+	idle := func() []model.Storage {
+		resp := request(http.MethodGet, "/storages/idle?since=720h", "admin")
+		So(resp.Code, ShouldEqual, http.StatusOK)
+		var storages []model.Storage
+		So(json.Unmarshal(resp.Body.Bytes(), &storages), ShouldBeNil)
+		return storages
+	}
+
+	Convey("Test storage pinning", t, func() {
+		So(request(http.MethodPut, "/storages/storage-pin/pin", "user").Code, ShouldEqual, http.StatusForbidden)
+		So(db.storages["storage-pin"].Pinned, ShouldBeFalse)
+		So(idle(), ShouldHaveLength, 1)
+
+		So(request(http.MethodPut, "/storages/storage-pin/pin", "admin").Code, ShouldEqual, http.StatusAccepted)
+		So(request(http.MethodPut, "/storages/storage-pin/pin", "admin").Code, ShouldEqual, http.StatusAccepted)
+		So(db.storages["storage-pin"].Pinned, ShouldBeTrue)
+		So(request(http.MethodGet, "/storages/storage-pin", "admin").Body.String(), ShouldContainSubstring, `"pinned":true`)
+		So(idle(), ShouldBeEmpty)
+
+		So(request(http.MethodDelete, "/storages/storage-pin/pin", "admin").Code, ShouldEqual, http.StatusAccepted)
+		So(db.storages["storage-pin"].Pinned, ShouldBeFalse)
+		So(request(http.MethodPut, "/storages/missing/pin", "admin").Code, ShouldEqual, http.StatusNotFound)
+
+		tr.srv.FlushAudit()
+		So(db.audit, ShouldHaveLength, 2)
+		So(db.audit[0].Operation, ShouldEqual, model.AuditPin)
+		So(db.audit[1].Operation, ShouldEqual, model.AuditUnpin)
+	})
+}
+
+func TestCreateStorageDryRun(t *testing.T) {
+	db := &storagesDB{storages: map[string]model.Storage{}}
+	tr := newStorageTestRouter(t, db, server.Config{}, Config{})
+	defer tr.srv.Close()
+
+	Convey("Test storage creation dry run", t, func() {
+		resp := tr.do(http.MethodPost, "/storages?dry_run=true", gofight.H{
+			headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader: "admin",
+		}, map[string]interface{}{"name": "storage-dry", "size": 10, "replicas": 1, "is_default": true, "pinned": true})
+		So(resp.Code, ShouldEqual, http.StatusOK)
+		var storage model.Storage
+		So(json.Unmarshal(resp.Body.Bytes(), &storage), ShouldBeNil)
+		So(storage.IsDefault, ShouldBeFalse)
+		So(storage.Pinned, ShouldBeFalse)
+		So(db.storages, ShouldBeEmpty)
+	})
+}
+
+func TestCreateStorageServerFields(t *testing.T) {
+	db := &storagesDB{storages: map[string]model.Storage{}}
+	tr := newStorageTestRouter(t, db, server.Config{}, Config{})
+	defer tr.srv.Close()
+
+	Convey("Test server managed fields are not set on storage creation", t, func() {
+		resp := tr.do(http.MethodPost, "/storages", gofight.H{
+			headers.UserIDXHeader:   "20b616d8-1ea7-4842-b8ec-c6e8226fda5b",
+			headers.UserRoleXHeader: "admin",
+		}, map[string]interface{}{
+			"name":               "storage-managed",
+			"size":               10,
+			"replicas":           1,
+			"maintenance":        true,
+			"maintenance_reason": "upgrade",
+			"version":            42,
+			"created_at":         "2001-01-01T00:00:00Z",
+			"updated_at":         "2001-01-01T00:00:00Z",
+			"last_used_at":       "2001-01-01T00:00:00Z",
+			"terminating":        true,
+			"terminating_since":  "2001-01-01T00:00:00Z",
+			"termination_status": "waiting for volumes",
+			"deleted":            true,
+			"delete_time":        "2001-01-01T00:00:00Z",
+		})
+		So(resp.Code, ShouldEqual, http.StatusCreated)
+		storage := db.storages["storage-managed"]
+		So(storage.Maintenance, ShouldBeFalse)
+		So(storage.MaintenanceReason, ShouldBeEmpty)
+		So(storage.Version, ShouldBeZeroValue)
+		So(storage.CreatedAt, ShouldBeZeroValue)
+		So(storage.UpdatedAt, ShouldBeZeroValue)
+		So(storage.LastUsedAt, ShouldBeNil)
+		So(storage.Terminating, ShouldBeFalse)
+		So(storage.TerminatingSince, ShouldBeNil)
+		So(storage.TerminationStatus, ShouldBeEmpty)
+		So(storage.Deleted, ShouldBeFalse)
+		So(storage.DeleteTime, ShouldBeNil)
+	})
+}
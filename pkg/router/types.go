@@ -1,16 +1,24 @@
 package router
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/textproto"
+	"time"
 
 	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/metrics"
 	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
+	"git.containerum.net/ch/volume-manager/pkg/tracing"
 	"git.containerum.net/ch/volume-manager/static"
 	"github.com/containerum/cherry"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/containerum/utils/httputil"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/universal-translator"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/go-playground/validator.v9"
 )
 
@@ -19,29 +27,50 @@ type TranslateValidate struct {
 	*validator.Validate
 }
 
-func (tv *TranslateValidate) HandleError(err error) (int, *cherry.Err) {
-	switch err.(type) {
-	case *cherry.Err:
-		e := err.(*cherry.Err)
-		return e.StatusHTTP, e
-	default:
-		return errors.ErrInternal().StatusHTTP, errors.ErrInternal().AddDetailsErr(err)
+// HandleError builds error response envelope, errors of other types than cherry.Err are reported as internal errors.
+func (tv *TranslateValidate) HandleError(err error) (int, errors.Envelope) {
+	envelope := errors.NewEnvelope(err)
+	return envelope.Status(), envelope
+}
+
+// BadRequest builds validation error response. All field problems are listed in details
+// and also put to error fields (field name -> reason).
+// Body reading errors caused by MaxBodySize middleware are reported with 413 status.
+func (tv *TranslateValidate) BadRequest(ctx *gin.Context, err error) (int, errors.Envelope) {
+	if tooLarge, ok := err.(*middleware.BodyTooLargeError); ok {
+		return tv.HandleError(tooLarge.CherryErr())
 	}
+	ret := errors.ErrRequestValidationFailed()
+	tv.addValidationErrors(ctx, ret, err)
+	return tv.HandleError(ret)
 }
 
-func (tv *TranslateValidate) BadRequest(ctx *gin.Context, err error) (int, *cherry.Err) {
-	if validationErr, ok := err.(validator.ValidationErrors); ok {
-		ret := errors.ErrRequestValidationFailed()
-		for _, fieldErr := range validationErr {
+func (tv *TranslateValidate) addValidationErrors(ctx *gin.Context, ret *cherry.Err, err error) {
+	switch e := err.(type) {
+	case requestErrors:
+		for _, reqErr := range e {
+			tv.addValidationErrors(ctx, ret, reqErr)
+		}
+	case validator.ValidationErrors:
+		t, _ := tv.FindTranslator(httputil.GetAcceptedLanguages(ctx.Request.Context())...)
+		for _, fieldErr := range e {
 			if fieldErr == nil {
 				continue
 			}
-			t, _ := tv.FindTranslator(httputil.GetAcceptedLanguages(ctx.Request.Context())...)
-			ret.AddDetailF("Field %s: %s", fieldErr.Namespace(), fieldErr.Translate(t))
+			reason := fieldErr.Translate(t)
+			ret.AddDetailF("Field %s: %s", fieldErr.Namespace(), reason)
+			addFieldReason(ret, fieldPath(fieldErr.Namespace()), reason)
 		}
-		return ret.StatusHTTP, ret
+	case *json.UnmarshalTypeError:
+		reason := fmt.Sprintf("must be %s", e.Type)
+		ret.AddDetailF("Field %s: %s", e.Field, reason)
+		addFieldReason(ret, e.Field, reason)
+	case fieldError:
+		ret.AddDetailF("Field %s: %s", e.field, e.reason)
+		addFieldReason(ret, e.field, e.reason)
+	default:
+		ret.AddDetailsErr(err)
 	}
-	return errors.ErrRequestValidationFailed().StatusHTTP, errors.ErrRequestValidationFailed().AddDetailsErr(err)
 }
 
 func (tv *TranslateValidate) ValidateHeaders(headerTagMap map[string]string) gin.HandlerFunc {
@@ -64,38 +93,213 @@ func (tv *TranslateValidate) ValidateHeaders(headerTagMap map[string]string) gin
 					ret.AddDetailF("Header %s: %s", header, fieldErr.Translate(t))
 				}
 			}
-			ctx.AbortWithStatusJSON(ret.StatusHTTP, ret)
+			middleware.AbortWithError(ctx, ret)
 			return
 		}
 	}
 }
 
-func (tv *TranslateValidate) ValidateURLParams(paramTagMap map[string]string) gin.HandlerFunc {
-	return httputil.ValidateURLParamsMiddleware(paramTagMap, tv.Validate, tv.UniversalTranslator, errors.ErrRequestValidationFailed)
+// Config contains router settings
+type Config struct {
+	// IdempotencyTTL is a time to keep responses for requests with Idempotency-Key header
+	IdempotencyTTL time.Duration
+
+	// RateLimits contains per user rate limits for storage operations (by metrics label).
+	// Operations without limit are not limited.
+	RateLimits map[string]middleware.RateLimit
+	// RateLimitExemptAdmins disables rate limiting for requests with admin role
+	RateLimitExemptAdmins bool
+
+	// OperationTimeout limits storage operations processing time, zero means no limit
+	OperationTimeout time.Duration
+
+	// HandlerTimeout limits storage handlers time budgets, handlers exceeded budget get 504 response
+	// even if they don't observe context cancellation. Handlers are not limited if config is empty.
+	HandlerTimeout middleware.HandlerTimeoutConfig
+
+	// ImportConcurrency is a number of storages imported in parallel
+	ImportConcurrency int
+
+	// BodyLimits contains maximal request body sizes (bytes) for storage operations (by metrics label),
+	// DefaultBodyLimits are used for operations not listed here. Non-positive value disables limit.
+	BodyLimits map[string]int64
+
+	// TracerProvider is used to trace requests, spans are not recorded if not set
+	TracerProvider tracing.TracerProvider
+
+	// StrictJSONFields enables rejection of storage create and update requests with unknown fields in body,
+	// unknown fields are ignored if not set
+	StrictJSONFields bool
+
+	// CamelCaseJSON enables camelCase field names in responses (e.g. "usedSize" instead of "used_size").
+	// Requests are accepted with both camelCase and canonical field names then. Error responses are not changed.
+	CamelCaseJSON bool
+
+	// CORS enables cross-origin requests handling, it is disabled if not set. Config must be valid (see CORSConfig.Validate).
+	CORS *CORSConfig
+
+	// Compression enables responses compression, it is disabled if not set. Config must be valid (see CompressionConfig.Validate).
+	Compression *middleware.CompressionConfig
+
+	// Deprecations contains deprecated storage operations (by metrics label).
+	// Responses of these operations get deprecation warning headers.
+	Deprecations map[string]middleware.Deprecation
+
+	// RoleOperations contains storage operations (by metrics label) allowed for non-admin roles,
+	// middleware.DefaultRoleOperations is used if nil. Admins may perform all operations.
+	RoleOperations middleware.RoleOperations
+
+	// Pprof enables runtime profiling endpoints under /debug/pprof for admins
+	Pprof bool
+}
+
+// DefaultBodyLimits contains request body size limits used if operation limit is not configured.
+var DefaultBodyLimits = map[string]int64{
+	"create": 1 << 20,
+	"import": 16 << 20,
+	"apply":  16 << 20,
 }
 
 type Router struct {
-	engine gin.IRouter
-	tv     *TranslateValidate
+	engine      gin.IRouter
+	tv          *TranslateValidate
+	idempotency *middleware.IdempotencyStore
+	readiness   func(ctx context.Context) error
+	drainer     *middleware.Drainer
+
+	rateLimits            map[string]middleware.RateLimit
+	rateLimitExemptAdmins bool
+	operationTimeout      time.Duration
+	handlerTimeout        middleware.HandlerTimeoutConfig
+	importConcurrency     int
+	bodyLimits            map[string]int64
+	strictJSON            bool
+	roleOperations        middleware.RoleOperations
+	// storageSegments are dispatchers of fixed path segments registered in place of storage name,
+	// storages with such names would be shadowed by them
+	storageSegments []*segmentDispatcher
 }
 
-func NewRouter(engine gin.IRouter, status *model.ServiceStatus, tv *TranslateValidate) *Router {
+func NewRouter(engine gin.IRouter, status *model.ServiceStatus, tv *TranslateValidate, cfg Config) *Router {
 	engine.StaticFS("/static", static.HTTP)
 
 	engine.GET("/status", httputil.ServiceStatus(status))
 
+	// registered before headers checking middlewares to be available for metrics scrapers
+	engine.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = tracing.NopTracerProvider{}
+	}
+	if cfg.RoleOperations == nil {
+		cfg.RoleOperations = middleware.DefaultRoleOperations
+	}
 	ret := &Router{
-		engine: engine,
-		tv:     tv,
+		engine:      engine,
+		tv:          tv,
+		idempotency: middleware.NewIdempotencyStore(cfg.IdempotencyTTL),
+		drainer:     middleware.NewDrainer(),
+
+		rateLimits:            cfg.RateLimits,
+		rateLimitExemptAdmins: cfg.RateLimitExemptAdmins,
+		operationTimeout:      cfg.OperationTimeout,
+		handlerTimeout:        cfg.HandlerTimeout,
+		importConcurrency:     cfg.ImportConcurrency,
+		bodyLimits:            cfg.BodyLimits,
+		strictJSON:            cfg.StrictJSONFields,
+		roleOperations:        cfg.RoleOperations,
 	}
+
+	// probes registered before headers checking middlewares too
+	engine.GET("/healthz", ret.livenessHandler)
+	engine.GET("/readyz", ret.readinessHandler)
+	ret.engine.Use(ret.drainer.Middleware())
+	// before headers checking middlewares because preflight requests have no user headers
+	if cfg.CORS != nil {
+		ret.engine.Use(newCORSMiddleware(*cfg.CORS))
+	}
+	if cfg.CamelCaseJSON {
+		ret.engine.Use(camelCaseJSON)
+	}
+	if cfg.Compression != nil {
+		ret.engine.Use(middleware.Compression(*cfg.Compression))
+	}
+	ret.engine.Use(middleware.Tracing(cfg.TracerProvider))
+	ret.engine.Use(middleware.Deprecations(cfg.Deprecations))
+	ret.engine.Use(middleware.RequestLogger(logrus.WithField("component", "router")))
 	ret.engine.Use(httputil.SaveHeaders)
 	ret.engine.Use(httputil.PrepareContext)
-	ret.engine.Use(httputil.RequireHeaders(errors.ErrRequiredHeadersNotProvided, httputil.UserIDXHeader, httputil.UserRoleXHeader))
+	ret.engine.Use(middleware.RequireHeaders(httputil.UserIDXHeader, httputil.UserRoleXHeader))
 	ret.engine.Use(tv.ValidateHeaders(map[string]string{
 		httputil.UserIDXHeader:   "uuid",
-		httputil.UserRoleXHeader: "eq=admin|eq=user",
+		httputil.UserRoleXHeader: "eq=admin|eq=user|eq=viewer",
 	}))
-	ret.engine.Use(httputil.SubstituteUserMiddleware(tv.Validate, tv.UniversalTranslator, errors.ErrRequestValidationFailed))
+	ret.engine.Use(impersonationAudit)
+	ret.engine.Use(middleware.SubstituteUser(tv.Validate, tv.UniversalTranslator))
 	ret.engine.Use(middleware.RequiredUserHeaders())
+	ret.engine.Use(saveUserNamespaces)
+	if cfg.Pprof {
+		ret.setupPprof()
+	}
 	return ret
 }
+
+// livenessHandler reports that process is up.
+func (r *Router) livenessHandler(ctx *gin.Context) {
+	ctx.Status(http.StatusOK)
+}
+
+// Drain stops accepting requests and waits for in-flight requests until ctx is done. Readiness probe fails during draining.
+// If requests were not finished in time, their contexts are cancelled (e.g. import stops and reports not imported storages
+// as failed) and ctx error is returned. Drain must be called before http.Server shutdown, which waits for aborted requests.
+func (r *Router) Drain(ctx context.Context) error {
+	return r.drainer.Drain(ctx)
+}
+
+// readinessHandler reports that service can serve requests, i.e. storage backend is reachable.
+func (r *Router) readinessHandler(ctx *gin.Context) {
+	if r.drainer.Draining() {
+		ctx.AbortWithStatusJSON(r.tv.HandleError(errors.ErrServiceShuttingDown()))
+		return
+	}
+	if r.readiness == nil {
+		ctx.AbortWithStatusJSON(r.tv.HandleError(errors.ErrServiceNotReady().AddDetails("handlers are not set up")))
+		return
+	}
+	if err := r.readiness(ctx.Request.Context()); err != nil {
+		ctx.AbortWithStatusJSON(r.tv.HandleError(err))
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// bodyLimited returns request body size limiting middleware for operation according to config.
+func (r *Router) bodyLimited(operation string) gin.HandlerFunc {
+	limit, ok := r.bodyLimits[operation]
+	if !ok {
+		limit = DefaultBodyLimits[operation]
+	}
+	return middleware.MaxBodySize(limit)
+}
+
+// rateLimited returns rate limiting middleware for operation according to config.
+func (r *Router) rateLimited(operation string) gin.HandlerFunc {
+	limit, ok := r.rateLimits[operation]
+	if !ok {
+		return middleware.RateLimited(nil)
+	}
+	return middleware.RateLimited(middleware.NewRateLimiter(limit, r.rateLimitExemptAdmins))
+}
+
+// authorized returns middleware checking that user role is allowed to perform operation according to config.
+func (r *Router) authorized(operation string) gin.HandlerFunc {
+	return middleware.RequireRole(operation, r.roleOperations)
+}
+
+// timeLimited returns handlers time budget middleware according to config, it does nothing if budgets are not configured.
+func (r *Router) timeLimited() gin.HandlerFunc {
+	if r.handlerTimeout.Default <= 0 && len(r.handlerTimeout.Operations) == 0 {
+		return func(ctx *gin.Context) {}
+	}
+	return middleware.HandlerTimeout(r.handlerTimeout)
+}
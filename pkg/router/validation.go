@@ -0,0 +1,119 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"git.containerum.net/ch/volume-manager/pkg/utils/jsoncase"
+	"git.containerum.net/ch/volume-manager/pkg/utils/projection"
+	"github.com/containerum/cherry"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// requestErrors collects request validation errors to report all of them at once.
+type requestErrors []error
+
+func (errs requestErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add appends error if it is not nil.
+func (errs *requestErrors) add(err error) {
+	if err != nil {
+		*errs = append(*errs, err)
+	}
+}
+
+// fieldError describes invalid value of request field.
+type fieldError struct {
+	field  string
+	reason string
+}
+
+func (e fieldError) Error() string {
+	return e.field + ": " + e.reason
+}
+
+// newFieldError returns fieldError with err as reason or nil if err is nil.
+func newFieldError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fieldError{field: field, reason: err.Error()}
+}
+
+// fieldPath converts validator namespace like "Storage.labels[team]" to request field path "labels[team]".
+func fieldPath(namespace string) string {
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func addFieldReason(err *cherry.Err, field, reason string) {
+	if prev, ok := err.Fields[field]; ok {
+		reason = prev + "; " + reason
+	}
+	err.WithField(field, reason)
+}
+
+// bindJSON binds request body. Fields validation errors are added to errs to be reported
+// together with other request problems, other errors (e.g. malformed body) are returned.
+func bindJSON(ctx *gin.Context, obj interface{}, errs *requestErrors) error {
+	err := ctx.ShouldBindWith(obj, jsonBinding(ctx))
+	if _, ok := err.(validator.ValidationErrors); ok {
+		errs.add(err)
+		return nil
+	}
+	return err
+}
+
+// bindStrictJSON binds request body same as bindJSON and adds error for each unknown field of body object to errs.
+// Only top-level fields are checked, nested objects are free-form maps (e.g. labels).
+func bindStrictJSON(ctx *gin.Context, obj interface{}, errs *requestErrors) error {
+	data, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return err
+	}
+	ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err := bindJSON(ctx, obj, errs); err != nil {
+		return err
+	}
+	if ctx.GetBool(camelCaseJSONKey) {
+		// already decoded successfully, so body is valid JSON
+		data, _ = jsoncase.Normalize(data, reflect.TypeOf(obj))
+	}
+	for _, field := range unknownFields(data, reflect.TypeOf(obj)) {
+		errs.add(fieldError{field: field, reason: "unknown field"})
+	}
+	return nil
+}
+
+// unknownFields returns sorted names of JSON object fields which are not JSON fields of t.
+func unknownFields(data []byte, t reflect.Type) []string {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	known := make(map[string]bool)
+	for _, field := range projection.Fields(t) {
+		known[field] = true
+	}
+	var ret []string
+	for field := range object {
+		if !known[field] {
+			ret = append(ret, field)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -9,11 +9,9 @@ import (
 	"git.containerum.net/ch/volume-manager/pkg/models"
 	"git.containerum.net/ch/volume-manager/pkg/router/middleware"
 	"git.containerum.net/ch/volume-manager/pkg/server"
-	"github.com/containerum/cherry/adaptors/gonic"
 	"github.com/containerum/utils/httputil"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
-	"github.com/sirupsen/logrus"
 )
 
 type volumeHandlers struct {
@@ -23,7 +21,7 @@ type volumeHandlers struct {
 
 func (vh *volumeHandlers) directCreateVolumeHandler(ctx *gin.Context) {
 	var req model.DirectVolumeCreateRequest
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
 		ctx.AbortWithStatusJSON(vh.tv.BadRequest(ctx, err))
 		return
 	}
@@ -37,7 +35,7 @@ func (vh *volumeHandlers) directCreateVolumeHandler(ctx *gin.Context) {
 
 func (vh *volumeHandlers) importVolumesHandler(ctx *gin.Context) {
 	var req kubeClientModel.VolumesList
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
 		ctx.AbortWithStatusJSON(vh.tv.BadRequest(ctx, err))
 		return
 	}
@@ -49,19 +47,19 @@ func (vh *volumeHandlers) importVolumesHandler(ctx *gin.Context) {
 
 	for _, vol := range req.Volumes {
 		if err := vh.acts.ImportVolume(ctx.Request.Context(), vol.Namespace, vol); err != nil {
-			logrus.Warn(err)
+			middleware.GetLogger(ctx).WithError(err).WithField("name", vol.Name).Warn("volume import failed")
 			resp.ImportFailed(vol.Name, vol.Namespace, err.Error())
 		} else {
 			resp.ImportSuccessful(vol.Name, vol.Namespace)
 		}
 	}
 
-	ctx.JSON(http.StatusAccepted, resp)
+	renderFormat(ctx, http.StatusAccepted, binding.MIMEJSON, resp)
 }
 
 func (vh *volumeHandlers) createVolumeHandler(ctx *gin.Context) {
 	var req model.VolumeCreateRequest
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
 		ctx.AbortWithStatusJSON(vh.tv.BadRequest(ctx, err))
 		return
 	}
@@ -82,7 +80,7 @@ func (vh *volumeHandlers) getVolumeHandler(ctx *gin.Context) {
 
 	httputil.MaskForNonAdmin(ctx, &ret)
 
-	ctx.JSON(http.StatusOK, ret)
+	renderFormat(ctx, http.StatusOK, binding.MIMEJSON, ret)
 }
 
 func (vh *volumeHandlers) getNamespaceVolumesHandler(ctx *gin.Context) {
@@ -97,7 +95,7 @@ func (vh *volumeHandlers) getNamespaceVolumesHandler(ctx *gin.Context) {
 		httputil.MaskForNonAdmin(ctx, &ret.Volumes[i])
 	}
 
-	ctx.JSON(http.StatusOK, ret)
+	renderFormat(ctx, http.StatusOK, binding.MIMEJSON, ret)
 }
 
 func (vh *volumeHandlers) getUserVolumesHandler(ctx *gin.Context) {
@@ -112,13 +110,13 @@ func (vh *volumeHandlers) getUserVolumesHandler(ctx *gin.Context) {
 		httputil.MaskForNonAdmin(ctx, &ret.Volumes[i])
 	}
 
-	ctx.JSON(http.StatusOK, ret)
+	renderFormat(ctx, http.StatusOK, binding.MIMEJSON, ret)
 }
 
 func (vh *volumeHandlers) getAllVolumesHandler(ctx *gin.Context) {
 	page, perPage, err := getPaginationParams(ctx.Request.URL.Query())
 	if err != nil {
-		gonic.Gonic(errors.ErrRequestValidationFailed().AddDetailsErr(err), ctx)
+		middleware.AbortWithError(ctx, errors.ErrRequestValidationFailed().AddDetailsErr(err))
 		return
 	}
 
@@ -128,7 +126,7 @@ func (vh *volumeHandlers) getAllVolumesHandler(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, ret)
+	renderFormat(ctx, http.StatusOK, binding.MIMEJSON, ret)
 }
 
 func (vh *volumeHandlers) deleteVolumeHandler(ctx *gin.Context) {
@@ -160,7 +158,7 @@ func (vh *volumeHandlers) deleteAllNamespaceVolumesHandler(ctx *gin.Context) {
 
 func (vh *volumeHandlers) resizeVolumeHandler(ctx *gin.Context) {
 	var req model.VolumeResizeRequest
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
 		ctx.AbortWithStatusJSON(vh.tv.BadRequest(ctx, err))
 		return
 	}
@@ -174,7 +172,7 @@ func (vh *volumeHandlers) resizeVolumeHandler(ctx *gin.Context) {
 
 func (vh *volumeHandlers) adminResizeVolumeHandler(ctx *gin.Context) {
 	var req model.AdminVolumeResizeRequest
-	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+	if err := ctx.ShouldBindWith(&req, jsonBinding(ctx)); err != nil {
 		ctx.AbortWithStatusJSON(vh.tv.BadRequest(ctx, err))
 		return
 	}
@@ -190,7 +188,7 @@ func (r *Router) SetupVolumeHandlers(acts server.VolumeActions) {
 	handlers := &volumeHandlers{tv: r.tv, acts: acts}
 
 	group := r.engine.Group("/namespaces/:ns_id/volumes")
-	adminGroup := r.engine.Group("/admin/namespaces/:ns_id/volumes", httputil.RequireAdminRole(errors.ErrAdminRequired))
+	adminGroup := r.engine.Group("/admin/namespaces/:ns_id/volumes", middleware.IsAdmin)
 
 	// swagger:operation POST /limits/namespaces/{ns_id}/volumes Volumes DirectCreateVolume
 	//
@@ -323,7 +321,7 @@ func (r *Router) SetupVolumeHandlers(acts server.VolumeActions) {
 	//         $ref: '#/definitions/Volume'
 	//   default:
 	//     $ref: '#/responses/error'
-	r.engine.GET("/admin/volumes", httputil.RequireAdminRole(errors.ErrAdminRequired), handlers.getAllVolumesHandler)
+	r.engine.GET("/admin/volumes", middleware.IsAdmin, handlers.getAllVolumesHandler)
 
 	// swagger:operation DELETE /namespaces/{ns_id}/volumes/{label} Volumes DeleteVolume
 	//
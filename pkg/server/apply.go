@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"github.com/containerum/cherry"
+	"github.com/sirupsen/logrus"
+)
+
+// appliedStorage describes change made by apply to one storage.
+type appliedStorage struct {
+	name          string
+	status        string
+	before, after *model.Storage
+}
+
+// ApplyStorages makes storages match desired list: missing storages are created and changed ones are updated.
+// If prune is set, storages not listed are deleted, storages with volumes are reported as in use then.
+// In atomic mode all changes are made in one transaction and discarded if any of them failed.
+func (s *Server) ApplyStorages(ctx context.Context, entries []model.StorageImportEntry, prune, atomic bool) (model.StorageApplyResponse, error) {
+	s.log.WithFields(logrus.Fields{
+		"count":  len(entries),
+		"prune":  prune,
+		"atomic": atomic,
+	}).Infof("apply storages")
+
+	resp := model.NewStorageApplyResponse()
+
+	if !atomic {
+		for _, entry := range entries {
+			change, err := s.applyStorageLocked(ctx, entry)
+			s.reportApplied(ctx, &resp, entry.Name, change, err)
+		}
+		if !prune {
+			return resp, nil
+		}
+		names, err := pruneCandidates(ctx, s.db, entries)
+		if err != nil {
+			return model.StorageApplyResponse{}, err
+		}
+		for _, name := range names {
+			var change appliedStorage
+			err := s.transactional(ctx, "apply_prune", func(tx database.DB) (err error) {
+				change, err = pruneStorage(ctx, tx, name)
+				return err
+			})
+			s.reportApplied(ctx, &resp, name, change, err)
+		}
+		return resp, nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	defer s.locks.lock(names...)()
+
+	var changes []appliedStorage
+	err := s.transactional(ctx, "apply", func(tx database.DB) error {
+		resp, changes = model.NewStorageApplyResponse(), nil
+		for _, entry := range entries {
+			change, err := s.applyStorage(ctx, tx, entry)
+			if err != nil {
+				if isTransientError(err) {
+					return err
+				}
+				resp.ApplyFailed(entry.Name, err)
+				continue
+			}
+			resp.ApplySuccessful(entry.Name, change.status)
+			changes = append(changes, change)
+		}
+		if prune {
+			pruned, err := pruneCandidates(ctx, tx, entries)
+			if err != nil {
+				return err
+			}
+			for _, name := range pruned {
+				change, err := pruneStorage(ctx, tx, name)
+				if err != nil {
+					if isTransientError(err) {
+						return err
+					}
+					resp.ApplyFailed(name, err)
+					continue
+				}
+				resp.ApplySuccessful(name, change.status)
+				changes = append(changes, change)
+			}
+		}
+		if len(resp.Failed) > 0 {
+			return errBulkRollback
+		}
+		return nil
+	})
+	switch err {
+	case nil:
+		for _, change := range changes {
+			s.recordApplied(ctx, change)
+		}
+		return resp, nil
+	case errBulkRollback:
+		resp.RollBack()
+		return resp, nil
+	default:
+		return model.StorageApplyResponse{}, err
+	}
+}
+
+func (s *Server) applyStorageLocked(ctx context.Context, entry model.StorageImportEntry) (change appliedStorage, err error) {
+	defer s.locks.lock(entry.Name)()
+
+	err = s.transactional(ctx, "apply", func(tx database.DB) (err error) {
+		change, err = s.applyStorage(ctx, tx, entry)
+		return err
+	})
+	return
+}
+
+// reportApplied adds result of non-atomic apply to response, successful changes are recorded immediately.
+func (s *Server) reportApplied(ctx context.Context, resp *model.StorageApplyResponse, name string, change appliedStorage, err error) {
+	if err != nil {
+		resp.ApplyFailed(name, err)
+		return
+	}
+	resp.ApplySuccessful(name, change.status)
+	s.recordApplied(ctx, change)
+}
+
+// recordApplied writes audit record and publishes event for committed change.
+func (s *Server) recordApplied(ctx context.Context, change appliedStorage) {
+	switch change.status {
+	case model.StorageCreated:
+		s.audit(ctx, model.AuditCreate, change.name, nil, change.after)
+		s.publishStorageEvent(ctx, events.StorageCreated, change.name)
+	case model.StorageUpdated:
+		s.audit(ctx, model.AuditUpdate, change.name, change.before, change.after)
+		s.publishStorageEvent(ctx, events.StorageUpdated, change.name)
+	case model.StorageDeleted:
+		s.audit(ctx, model.AuditDelete, change.name, change.before, nil)
+		s.publishStorageEvent(ctx, events.StorageDeleted, change.name)
+	}
+}
+
+// applyStorage creates storage from entry or updates existing storage to match it.
+// Size, overcommit ratio and warn threshold of existing storage are kept if not set in entry.
+func (s *Server) applyStorage(ctx context.Context, tx database.DB, entry model.StorageImportEntry) (appliedStorage, error) {
+	change := appliedStorage{name: entry.Name}
+	desired := entry.Storage()
+
+	existing, err := tx.StorageByName(ctx, entry.Name)
+	switch {
+	case cherry.Equals(err, errors.ErrResourceNotExists()):
+		if err = s.checkNewStorage(ctx, nil, desired); err != nil {
+			return change, err
+		}
+		desired.OwnerUserID = storageOwner(ctx)
+		if err = s.checkStorageQuota(ctx, tx, desired, desired.Size); err != nil {
+			return change, err
+		}
+		if err = tx.CreateStorage(ctx, &desired); err != nil {
+			return change, err
+		}
+		created, err := tx.StorageByName(ctx, entry.Name)
+		change.status, change.after = model.StorageCreated, &created
+		return change, err
+	case err != nil:
+		return change, err
+	}
+
+	if entry.Driver != "" && entry.Driver != existing.Driver {
+		return change, errors.ErrRequestValidationFailed().AddDetailF("storage %s driver is %s and can't be changed", entry.Name, existing.Driver)
+	}
+	storage := existing
+	if entry.Size != nil {
+		storage.Size = desired.Size
+	}
+	if entry.OvercommitRatio != nil {
+		storage.OvercommitRatio = desired.OvercommitRatio
+	}
+	if entry.WarnThreshold != nil {
+		storage.WarnThreshold = desired.WarnThreshold
+	}
+	storage.Description = desired.Description
+	storage.Reserved = desired.Reserved
+	storage.ReadOnly = desired.ReadOnly
+	storage.Labels = desired.Labels
+	storage.Annotations = desired.Annotations
+	storage.Namespaces = desired.Namespaces
+	normalizeEmpty(&existing)
+	normalizeEmpty(&storage)
+	if len(model.DiffStorages(existing, storage)) == 0 {
+		change.status = model.StorageUnchanged
+		return change, nil
+	}
+
+	if err = s.checkNewStorage(ctx, &existing, storage); err != nil {
+		return change, err
+	}
+	if validErr := labels.ValidateAnnotations(storage.Annotations); validErr != nil {
+		return change, errors.ErrRequestValidationFailed().AddDetailsErr(validErr)
+	}
+	if err = checkStorageShrink(existing, storage, false); err != nil {
+		return change, err
+	}
+	if err = s.checkStorageQuota(ctx, tx, storage, storage.Size-existing.Size); err != nil {
+		return change, err
+	}
+	if err = tx.UpdateStorage(ctx, entry.Name, storage); err != nil {
+		return change, err
+	}
+	updated, err := tx.StorageByName(ctx, entry.Name)
+	change.status, change.before, change.after = model.StorageUpdated, &existing, &updated
+	return change, err
+}
+
+// normalizeEmpty replaces empty labels, annotations and namespaces with nil, so empty and omitted values are not a change.
+func normalizeEmpty(storage *model.Storage) {
+	if len(storage.Labels) == 0 {
+		storage.Labels = nil
+	}
+	if len(storage.Annotations) == 0 {
+		storage.Annotations = nil
+	}
+	if len(storage.Namespaces) == 0 {
+		storage.Namespaces = nil
+	}
+}
+
+// checkNewStorage runs validation hooks and checks storage reservation and required labels.
+func (s *Server) checkNewStorage(ctx context.Context, before *model.Storage, storage model.Storage) error {
+	if err := s.validateStorage(ctx, before, storage); err != nil {
+		return err
+	}
+	if err := checkStorageReserved(storage); err != nil {
+		return err
+	}
+	return s.checkRequiredLabels(storage.Labels)
+}
+
+// pruneCandidates returns names of not deleted storages missing in entries.
+func pruneCandidates(ctx context.Context, db database.DB, entries []model.StorageImportEntry) ([]string, error) {
+	listed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		listed[entry.Name] = true
+	}
+	storages, err := db.AllStorages(ctx, database.StorageFilter{})
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, storage := range storages {
+		if !listed[storage.Name] {
+			ret = append(ret, storage.Name)
+		}
+	}
+	return ret, nil
+}
+
+func pruneStorage(ctx context.Context, tx database.DB, name string) (appliedStorage, error) {
+	change := appliedStorage{name: name}
+	storage, err := tx.StorageByName(ctx, name)
+	if err != nil {
+		return change, err
+	}
+	deleted := storage
+	if err = tx.DeleteStorage(ctx, &deleted); err != nil {
+		return change, err
+	}
+	change.status, change.before = model.StorageDeleted, &storage
+	return change, nil
+}
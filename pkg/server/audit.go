@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+)
+
+const (
+	auditFlushInterval = time.Second
+	auditBatchSize     = 100
+	auditWriteAttempts = 3
+)
+
+// auditWriter writes storage audit records asynchronously in batches.
+// Records are queued in memory without size limit, so slow audit backend never blocks storage operations.
+// Records which were not written after several attempts are logged with error level.
+// Storage size history records are derived from written audit records.
+type auditWriter struct {
+	db  database.DB
+	log *cherrylog.LogrusAdapter
+
+	mu     sync.Mutex
+	queue  []model.StorageAuditRecord
+	notify chan struct{}
+	// flushes receives channels which are closed after queued records are written
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newAuditWriter(db database.DB, log *cherrylog.LogrusAdapter) *auditWriter {
+	w := &auditWriter{
+		db:      db,
+		log:     log,
+		notify:  make(chan struct{}, 1),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *auditWriter) write(record model.StorageAuditRecord) {
+	w.mu.Lock()
+	w.queue = append(w.queue, record)
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *auditWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.notify:
+		case <-ticker.C:
+		case flushed := <-w.flushes:
+			w.flush()
+			close(flushed)
+			continue
+		case <-w.stop:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// flush writes all queued records.
+func (w *auditWriter) flush() {
+	for {
+		w.mu.Lock()
+		n := len(w.queue)
+		if n > auditBatchSize {
+			n = auditBatchSize
+		}
+		batch := w.queue[:n:n]
+		w.queue = w.queue[n:]
+		w.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		w.writeBatch(batch)
+	}
+}
+
+func (w *auditWriter) writeBatch(batch []model.StorageAuditRecord) {
+	err := retryAuditWrite(func() error {
+		return w.db.CreateStorageAuditRecords(context.Background(), batch)
+	})
+	if err != nil {
+		for _, record := range batch {
+			data, _ := json.Marshal(record)
+			w.log.WithError(err).WithField("record", string(data)).Errorf("audit record write failed")
+		}
+	}
+
+	var sizeRecords []model.StorageSizeRecord
+	for _, record := range batch {
+		if sizeRecord, changed := record.SizeRecord(); changed {
+			sizeRecords = append(sizeRecords, sizeRecord)
+		}
+	}
+	if len(sizeRecords) == 0 {
+		return
+	}
+	err = retryAuditWrite(func() error {
+		return w.db.CreateStorageSizeRecords(context.Background(), sizeRecords)
+	})
+	if err != nil {
+		for _, record := range sizeRecords {
+			data, _ := json.Marshal(record)
+			w.log.WithError(err).WithField("record", string(data)).Errorf("storage size record write failed")
+		}
+	}
+}
+
+func retryAuditWrite(write func() error) (err error) {
+	for attempt := 0; attempt < auditWriteAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * auditFlushInterval)
+		}
+		if err = write(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Flush waits until records queued before call are written. Records are written by writer goroutine,
+// so they stay in order.
+func (w *auditWriter) Flush() {
+	flushed := make(chan struct{})
+	select {
+	case w.flushes <- flushed:
+		<-flushed
+	case <-w.done:
+	}
+}
+
+// Close writes remaining records and stops writer.
+func (w *auditWriter) Close() error {
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+// audit queues audit record for storage operation. Must be called after transaction commit.
+func (s *Server) audit(ctx context.Context, operation, name string, before, after *model.Storage) {
+	userID, _ := ctx.Value(httputil.UserIDContextKey).(string)
+	s.auditWriter.write(model.StorageAuditRecord{
+		StorageName:    name,
+		Operation:      operation,
+		UserID:         userID,
+		ImpersonatorID: impersonatorFromContext(ctx),
+		Before:         before,
+		After:          after,
+		Timestamp:      time.Now().UTC(),
+	})
+}
+
+type impersonatorContextKey struct{}
+
+// ContextWithImpersonator returns context of request made by admin with provided ID on behalf of another user
+// (user ID in context is already substituted). Impersonator is saved to audit records of storage operations.
+func ContextWithImpersonator(ctx context.Context, adminID string) context.Context {
+	return context.WithValue(ctx, impersonatorContextKey{}, adminID)
+}
+
+func impersonatorFromContext(ctx context.Context) string {
+	adminID, _ := ctx.Value(impersonatorContextKey{}).(string)
+	return adminID
+}
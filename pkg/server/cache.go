@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/metrics"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+)
+
+const DefaultStoragesCacheTTL = 2 * time.Second
+
+var storagesCacheRequestsTotal = metrics.NewCounterVec(
+	"volume_manager_storages_cache_requests_total",
+	"Number of storage list requests served by cache (hit) or database (miss).",
+	"result",
+)
+
+func init() {
+	metrics.MustRegister(storagesCacheRequestsTotal)
+}
+
+type storagesCacheEntry struct {
+	page    model.StoragesPage
+	expires time.Time
+}
+
+// cachedStorageActions caches storage lists and drops cache on each storage mutation made through it.
+type cachedStorageActions struct {
+	StorageActions
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]storagesCacheEntry
+	// generation is incremented on each invalidation,
+	// lists loaded concurrently with mutation are not cached
+	generation uint64
+}
+
+// CacheStorageLists returns StorageActions which keep results of GetStorages and GetStoragesFiltered for ttl.
+// Cache is dropped after each storage mutation. Volume operations changing storages usage don't invalidate cache,
+// so usage in lists may be stale for ttl. Non-positive ttl disables cache.
+func CacheStorageLists(acts StorageActions, ttl time.Duration) StorageActions {
+	if ttl <= 0 {
+		return acts
+	}
+	return &cachedStorageActions{
+		StorageActions: acts,
+		ttl:            ttl,
+		entries:        make(map[string]storagesCacheEntry),
+	}
+}
+
+func storagesCacheKey(filtered bool, filter model.StorageListFilter, pages model.StoragePagination) string {
+	key, _ := json.Marshal(struct {
+		Filtered bool
+		Filter   model.StorageListFilter
+		Pages    model.StoragePagination
+	}{filtered, filter, pages})
+	return string(key)
+}
+
+func (c *cachedStorageActions) lookup(key string) (model.StoragesPage, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if ok && time.Now().Before(entry.expires) {
+		return entry.page, c.generation, true
+	}
+	return model.StoragesPage{}, c.generation, false
+}
+
+func (c *cachedStorageActions) store(key string, generation uint64, page model.StoragesPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	now := time.Now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = storagesCacheEntry{page: page, expires: now.Add(c.ttl)}
+}
+
+func (c *cachedStorageActions) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]storagesCacheEntry)
+}
+
+func (c *cachedStorageActions) list(key string, load func() (model.StoragesPage, error)) (model.StoragesPage, error) {
+	page, generation, ok := c.lookup(key)
+	if ok {
+		storagesCacheRequestsTotal.Inc("hit")
+		return page, nil
+	}
+	storagesCacheRequestsTotal.Inc("miss")
+	page, err := load()
+	if err != nil {
+		return model.StoragesPage{}, err
+	}
+	c.store(key, generation, page)
+	return page, nil
+}
+
+func (c *cachedStorageActions) GetStorages(ctx context.Context, pages model.StoragePagination) (model.StoragesPage, error) {
+	return c.list(storagesCacheKey(false, model.StorageListFilter{}, pages), func() (model.StoragesPage, error) {
+		return c.StorageActions.GetStorages(ctx, pages)
+	})
+}
+
+func (c *cachedStorageActions) GetStoragesFiltered(ctx context.Context, filter model.StorageListFilter, pages model.StoragePagination) (model.StoragesPage, error) {
+	return c.list(storagesCacheKey(true, filter, pages), func() (model.StoragesPage, error) {
+		return c.StorageActions.GetStoragesFiltered(ctx, filter, pages)
+	})
+}
+
+func (c *cachedStorageActions) CreateStorage(ctx context.Context, storage model.Storage) (model.Storage, error) {
+	defer c.invalidate()
+	return c.StorageActions.CreateStorage(ctx, storage)
+}
+
+func (c *cachedStorageActions) CreateOrGetStorage(ctx context.Context, storage model.Storage) (model.Storage, bool, error) {
+	defer c.invalidate()
+	return c.StorageActions.CreateOrGetStorage(ctx, storage)
+}
+
+func (c *cachedStorageActions) ImportStorage(ctx context.Context, storage model.Storage) error {
+	defer c.invalidate()
+	return c.StorageActions.ImportStorage(ctx, storage)
+}
+
+func (c *cachedStorageActions) ImportStoragesAtomic(ctx context.Context, storages []model.Storage) (model.StorageImportResponse, error) {
+	defer c.invalidate()
+	return c.StorageActions.ImportStoragesAtomic(ctx, storages)
+}
+
+func (c *cachedStorageActions) UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) error {
+	defer c.invalidate()
+	return c.StorageActions.UpdateStorage(ctx, name, req, cond, allowShrink)
+}
+
+func (c *cachedStorageActions) PatchStorage(ctx context.Context, name string, req model.PatchStorageRequest, cond model.ETagCondition, allowShrink bool) error {
+	defer c.invalidate()
+	return c.StorageActions.PatchStorage(ctx, name, req, cond, allowShrink)
+}
+
+func (c *cachedStorageActions) ReplaceStorageLabels(ctx context.Context, name string, labels map[string]string, cond model.ETagCondition) (model.Storage, error) {
+	defer c.invalidate()
+	return c.StorageActions.ReplaceStorageLabels(ctx, name, labels, cond)
+}
+
+func (c *cachedStorageActions) PatchStorageLabels(ctx context.Context, name string, patch map[string]*string, cond model.ETagCondition) (model.Storage, error) {
+	defer c.invalidate()
+	return c.StorageActions.PatchStorageLabels(ctx, name, patch, cond)
+}
+
+func (c *cachedStorageActions) TransferStorage(ctx context.Context, name, ownerUserID string) error {
+	defer c.invalidate()
+	return c.StorageActions.TransferStorage(ctx, name, ownerUserID)
+}
+
+func (c *cachedStorageActions) RenameStorage(ctx context.Context, oldName, newName string) error {
+	defer c.invalidate()
+	return c.StorageActions.RenameStorage(ctx, oldName, newName)
+}
+
+func (c *cachedStorageActions) CloneStorage(ctx context.Context, name, targetName string) error {
+	defer c.invalidate()
+	return c.StorageActions.CloneStorage(ctx, name, targetName)
+}
+
+func (c *cachedStorageActions) MigrateVolumes(ctx context.Context, name string, req model.StorageMigrateRequest) (model.StorageMigrateResponse, error) {
+	defer c.invalidate()
+	return c.StorageActions.MigrateVolumes(ctx, name, req)
+}
+
+func (c *cachedStorageActions) SetStorageMaintenance(ctx context.Context, name string, req model.StorageMaintenanceRequest) error {
+	defer c.invalidate()
+	return c.StorageActions.SetStorageMaintenance(ctx, name, req)
+}
+
+func (c *cachedStorageActions) DeleteStorage(ctx context.Context, name string, cascade bool) error {
+	defer c.invalidate()
+	return c.StorageActions.DeleteStorage(ctx, name, cascade)
+}
+
+func (c *cachedStorageActions) DeleteStorages(ctx context.Context, names []string, atomic bool) (model.StorageBulkDeleteResponse, error) {
+	defer c.invalidate()
+	return c.StorageActions.DeleteStorages(ctx, names, atomic)
+}
+
+func (c *cachedStorageActions) UpdateStorages(ctx context.Context, updates []model.StorageBulkUpdateEntry, atomic bool) (model.StorageBulkUpdateResponse, error) {
+	defer c.invalidate()
+	return c.StorageActions.UpdateStorages(ctx, updates, atomic)
+}
+
+func (c *cachedStorageActions) ApplyStorages(ctx context.Context, entries []model.StorageImportEntry, prune, atomic bool) (model.StorageApplyResponse, error) {
+	defer c.invalidate()
+	return c.StorageActions.ApplyStorages(ctx, entries, prune, atomic)
+}
+
+func (c *cachedStorageActions) PurgeStorage(ctx context.Context, name string, cascade bool) error {
+	defer c.invalidate()
+	return c.StorageActions.PurgeStorage(ctx, name, cascade)
+}
+
+func (c *cachedStorageActions) RestoreStorage(ctx context.Context, name string) error {
+	defer c.invalidate()
+	return c.StorageActions.RestoreStorage(ctx, name)
+}
+
+func (c *cachedStorageActions) Touch(ctx context.Context, name string, reconciled bool) error {
+	defer c.invalidate()
+	return c.StorageActions.Touch(ctx, name, reconciled)
+}
+
+func (c *cachedStorageActions) SetDefaultStorage(ctx context.Context, name string) error {
+	defer c.invalidate()
+	return c.StorageActions.SetDefaultStorage(ctx, name)
+}
+
+func (c *cachedStorageActions) SetStoragePinned(ctx context.Context, name string, pinned bool) error {
+	defer c.invalidate()
+	return c.StorageActions.SetStoragePinned(ctx, name, pinned)
+}
+
+func (c *cachedStorageActions) RecomputeUsage(ctx context.Context, name string) ([]model.StorageUsageRecompute, error) {
+	defer c.invalidate()
+	return c.StorageActions.RecomputeUsage(ctx, name)
+}
+
+func (c *cachedStorageActions) SyncStorages(ctx context.Context, apply bool) (model.StorageSyncReport, error) {
+	if apply {
+		defer c.invalidate()
+	}
+	return c.StorageActions.SyncStorages(ctx, apply)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type countingStorageActions struct {
+	StorageActions
+
+	mu    sync.Mutex
+	lists int
+}
+
+func (a *countingStorageActions) GetStoragesFiltered(ctx context.Context, filter model.StorageListFilter, pages model.StoragePagination) (model.StoragesPage, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lists++
+	return model.StoragesPage{Storages: []model.Storage{{Name: filter.NamePrefix}}}, nil
+}
+
+func (a *countingStorageActions) DeleteStorage(ctx context.Context, name string, cascade bool) error {
+	return nil
+}
+
+func (a *countingStorageActions) listCalls() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lists
+}
+
+func TestCacheStorageLists(t *testing.T) {
+	Convey("Test storage lists cache", t, func() {
+		ctx := context.Background()
+		acts := &countingStorageActions{}
+		cached := CacheStorageLists(acts, time.Hour)
+
+		Convey("Check filter is a part of cache key", func() {
+			for i := 0; i < 3; i++ {
+				page, err := cached.GetStoragesFiltered(ctx, model.StorageListFilter{NamePrefix: "a"}, model.StoragePagination{})
+				So(err, ShouldBeNil)
+				So(page.Storages[0].Name, ShouldEqual, "a")
+			}
+			So(acts.listCalls(), ShouldEqual, 1)
+
+			page, err := cached.GetStoragesFiltered(ctx, model.StorageListFilter{NamePrefix: "b"}, model.StoragePagination{})
+			So(err, ShouldBeNil)
+			So(page.Storages[0].Name, ShouldEqual, "b")
+			_, err = cached.GetStoragesFiltered(ctx, model.StorageListFilter{NamePrefix: "a"}, model.StoragePagination{Limit: 1})
+			So(err, ShouldBeNil)
+			So(acts.listCalls(), ShouldEqual, 3)
+		})
+		Convey("Check mutation invalidates cache", func() {
+			_, err := cached.GetStoragesFiltered(ctx, model.StorageListFilter{}, model.StoragePagination{})
+			So(err, ShouldBeNil)
+			So(cached.DeleteStorage(ctx, "a", false), ShouldBeNil)
+			_, err = cached.GetStoragesFiltered(ctx, model.StorageListFilter{}, model.StoragePagination{})
+			So(err, ShouldBeNil)
+			So(acts.listCalls(), ShouldEqual, 2)
+		})
+		Convey("Check zero ttl disables cache", func() {
+			So(CacheStorageLists(acts, 0), ShouldEqual, acts)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+)
+
+// storageCursor is a position of the last storage on page in selected order.
+type storageCursor struct {
+	// Sort spec which cursor was issued for
+	Sort string `json:"s"`
+	// Value of sort column, omitted if sorting is by name
+	Value json.RawMessage `json:"v,omitempty"`
+	Name  string          `json:"n"`
+}
+
+// encodeCursor builds opaque pagination token from the last storage on page.
+func encodeCursor(sort model.StorageSort, last model.Storage) string {
+	cursor := storageCursor{
+		Sort: sort.String(),
+		Name: last.Name,
+	}
+	if value := sortValue(sort, last); value != nil {
+		cursor.Value, _ = json.Marshal(value)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// sortValue returns value of storage sort column, nil if sorting is by name.
+func sortValue(sort model.StorageSort, storage model.Storage) interface{} {
+	switch sort.Field {
+	case model.StorageSortBySize:
+		return storage.Size
+	case model.StorageSortByCreatedAt:
+		return storage.CreatedAt
+	}
+	return nil
+}
+
+// decodeCursor extracts the last storage name and sort column value from pagination token.
+// Cursor must be issued for the same sort order.
+func decodeCursor(cursor string, sort model.StorageSort) (name string, value interface{}, err error) {
+	invalidCursorErr := errors.ErrRequestValidationFailed().AddDetailF("invalid cursor %q", cursor)
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) == 0 {
+		return "", nil, invalidCursorErr
+	}
+
+	var decoded storageCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		// cursors issued before sorting support contain only storage name
+		decoded = storageCursor{Sort: model.StorageSort{}.String(), Name: string(data)}
+	}
+	if decoded.Sort != sort.String() {
+		return "", nil, errors.ErrRequestValidationFailed().AddDetailF("cursor was issued for sort order %q", decoded.Sort)
+	}
+
+	switch sort.Field {
+	case model.StorageSortBySize:
+		var size int
+		err = json.Unmarshal(decoded.Value, &size)
+		value = size
+	case model.StorageSortByCreatedAt:
+		var createdAt time.Time
+		err = json.Unmarshal(decoded.Value, &createdAt)
+		value = createdAt
+	}
+	if err != nil || decoded.Name == "" {
+		return "", nil, invalidCursorErr
+	}
+	return decoded.Name, value, nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultForecastWindow = 30 * 24 * time.Hour
+
+	// minForecastSamples and minForecastSpan are required to compute growth rate
+	minForecastSamples = 3
+	minForecastSpan    = 24 * time.Hour
+
+	maxForecastDays = 100 * 365
+)
+
+// GetStorageForecast projects storage used capacity growth from volumes created and deleted during window.
+func (s *Server) GetStorageForecast(ctx context.Context, name string, window time.Duration) (model.StorageCapacityForecast, error) {
+	s.log.WithFields(logrus.Fields{
+		"name":   name,
+		"window": window,
+	}).Infof("get storage capacity forecast")
+
+	storage, err := s.visibleStorage(ctx, name)
+	if err != nil {
+		return model.StorageCapacityForecast{}, err
+	}
+	now := time.Now()
+	since := now.Add(-window)
+	if storage.CreatedAt.After(since) {
+		since = storage.CreatedAt
+	}
+	volumes, err := s.db.StorageVolumesChangedSince(ctx, name, since)
+	if err != nil {
+		return model.StorageCapacityForecast{}, err
+	}
+
+	ret := forecastCapacity(usageSamples(storage.Used, volumes, since, now), storage.Capacity())
+	ret.StorageName = name
+	ret.Since = since
+	return ret, nil
+}
+
+// usageSamples restores storage used capacity history from current usage and volumes created or deleted since.
+// Samples are ordered by time, first one is at since and last one is current usage at now.
+func usageSamples(used int, volumes []model.Volume, since, now time.Time) []model.StorageUsageSample {
+	type change struct {
+		time  time.Time
+		delta int
+	}
+	var changes []change
+	for _, volume := range volumes {
+		if volume.CreateTime != nil && volume.CreateTime.After(since) {
+			changes = append(changes, change{time: *volume.CreateTime, delta: volume.Capacity})
+		}
+		if volume.Deleted && volume.DeleteTime != nil && volume.DeleteTime.After(since) {
+			changes = append(changes, change{time: *volume.DeleteTime, delta: -volume.Capacity})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].time.After(changes[j].time) })
+
+	// walk back from current usage, each sample is usage right after change
+	ret := make([]model.StorageUsageSample, 0, len(changes)+2)
+	ret = append(ret, model.StorageUsageSample{Time: now, Used: used})
+	for _, c := range changes {
+		ret = append(ret, model.StorageUsageSample{Time: c.time, Used: used})
+		used -= c.delta
+	}
+	ret = append(ret, model.StorageUsageSample{Time: since, Used: used})
+
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
+
+// forecastCapacity fits usage samples (ordered by time) with line using least squares
+// and projects when used capacity reaches capacity. The last sample is current usage.
+func forecastCapacity(samples []model.StorageUsageSample, capacity int) model.StorageCapacityForecast {
+	ret := model.StorageCapacityForecast{
+		Capacity: capacity,
+		Samples:  len(samples),
+		Status:   model.ForecastInsufficientData,
+	}
+	if len(samples) == 0 {
+		ret.Message = "storage has no usage history"
+		return ret
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	ret.Used = last.Used
+	if span := last.Time.Sub(first.Time); len(samples) < minForecastSamples || span < minForecastSpan {
+		ret.Message = fmt.Sprintf("not enough usage history: at least %d usage samples during %v are required, got %d during %v",
+			minForecastSamples, minForecastSpan, len(samples), span.Round(time.Second))
+		return ret
+	}
+
+	// days since first sample and used capacity
+	var meanX, meanY float64
+	for _, sample := range samples {
+		meanX += sample.Time.Sub(first.Time).Hours() / 24
+		meanY += float64(sample.Used)
+	}
+	meanX /= float64(len(samples))
+	meanY /= float64(len(samples))
+	var cov, variance float64
+	for _, sample := range samples {
+		dx := sample.Time.Sub(first.Time).Hours()/24 - meanX
+		cov += dx * (float64(sample.Used) - meanY)
+		variance += dx * dx
+	}
+	growth := cov / variance
+	ret.GrowthPerDay = &growth
+
+	switch free := capacity - last.Used; {
+	case free <= 0:
+		ret.Status = model.ForecastFull
+	case growth <= 0:
+		ret.Status = model.ForecastNotGrowing
+	default:
+		ret.Status = model.ForecastGrowing
+		days := float64(free) / growth
+		ret.DaysUntilFull = &days
+		// far projections are not representable as time
+		if days < maxForecastDays {
+			fullAt := last.Time.Add(time.Duration(days * 24 * float64(time.Hour)))
+			ret.FullAt = &fullAt
+		}
+	}
+	return ret
+}
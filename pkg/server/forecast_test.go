@@ -0,0 +1,74 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCapacityForecast(t *testing.T) {
+	now := time.Date(2018, 6, 30, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		ret := now.Add(-time.Duration(days) * 24 * time.Hour)
+		return &ret
+	}
+	volume := func(capacity int, created, deleted *time.Time) model.Volume {
+		return model.Volume{
+			Resource: model.Resource{CreateTime: created, Deleted: deleted != nil, DeleteTime: deleted},
+			Capacity: capacity,
+		}
+	}
+
+	Convey("Test storage capacity forecast", t, func() {
+		Convey("Check usage history is restored from volumes", func() {
+			samples := usageSamples(30, []model.Volume{
+				volume(10, daysAgo(20), nil),
+				volume(5, daysAgo(15), daysAgo(5)),
+				volume(20, daysAgo(60), nil), // created before window, not a change
+			}, *daysAgo(30), now)
+			So(samples, ShouldResemble, []model.StorageUsageSample{
+				{Time: *daysAgo(30), Used: 20},
+				{Time: *daysAgo(20), Used: 30},
+				{Time: *daysAgo(15), Used: 35},
+				{Time: *daysAgo(5), Used: 30},
+				{Time: now, Used: 30},
+			})
+		})
+		Convey("Check growing storage", func() {
+			var samples []model.StorageUsageSample
+			for day := 10; day >= 0; day-- {
+				samples = append(samples, model.StorageUsageSample{Time: *daysAgo(day), Used: 50 - 2*day})
+			}
+			forecast := forecastCapacity(samples, 100)
+			So(forecast.Status, ShouldEqual, model.ForecastGrowing)
+			So(forecast.Used, ShouldEqual, 50)
+			So(*forecast.GrowthPerDay, ShouldAlmostEqual, 2, 1e-9)
+			So(*forecast.DaysUntilFull, ShouldAlmostEqual, 25, 1e-9)
+			So(forecast.FullAt.Sub(now), ShouldAlmostEqual, 25*24*time.Hour, time.Second)
+		})
+		Convey("Check shrinking and full storages", func() {
+			shrinking := []model.StorageUsageSample{{Time: *daysAgo(2), Used: 30}, {Time: *daysAgo(1), Used: 20}, {Time: now, Used: 10}}
+			forecast := forecastCapacity(shrinking, 100)
+			So(forecast.Status, ShouldEqual, model.ForecastNotGrowing)
+			So(*forecast.GrowthPerDay, ShouldBeLessThan, 0)
+			So(forecast.DaysUntilFull, ShouldBeNil)
+
+			full := []model.StorageUsageSample{{Time: *daysAgo(2), Used: 80}, {Time: *daysAgo(1), Used: 90}, {Time: now, Used: 100}}
+			So(forecastCapacity(full, 100).Status, ShouldEqual, model.ForecastFull)
+		})
+		Convey("Check insufficient history", func() {
+			for _, samples := range [][]model.StorageUsageSample{
+				nil,
+				{{Time: *daysAgo(30), Used: 10}, {Time: now, Used: 10}},
+				{{Time: now.Add(-time.Hour), Used: 10}, {Time: now.Add(-time.Minute), Used: 20}, {Time: now, Used: 30}},
+			} {
+				forecast := forecastCapacity(samples, 100)
+				So(forecast.Status, ShouldEqual, model.ForecastInsufficientData)
+				So(forecast.Message, ShouldNotBeEmpty)
+				So(forecast.GrowthPerDay, ShouldBeNil)
+			}
+		})
+	})
+}
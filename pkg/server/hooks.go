@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"git.containerum.net/ch/volume-manager/pkg/utils/validation"
+	"github.com/containerum/cherry"
+)
+
+// ValidationHook checks storage before it is saved by create or update, storage is not saved if hook returns error.
+// Before is nil if storage is created. Cherry errors are returned to client as is,
+// other errors are reported as ErrRequestValidationFailed with error as detail.
+type ValidationHook interface {
+	ValidateStorage(ctx context.Context, before *model.Storage, storage model.Storage) error
+}
+
+// ValidationHookFunc is a ValidationHook implemented by function.
+type ValidationHookFunc func(ctx context.Context, before *model.Storage, storage model.Storage) error
+
+func (f ValidationHookFunc) ValidateStorage(ctx context.Context, before *model.Storage, storage model.Storage) error {
+	return f(ctx, before, storage)
+}
+
+// NameFormatHook checks that names of created and renamed storages are DNS labels.
+type NameFormatHook struct{}
+
+func (NameFormatHook) ValidateStorage(ctx context.Context, before *model.Storage, storage model.Storage) error {
+	if before != nil && before.Name == storage.Name {
+		return nil
+	}
+	if err := validation.DNSLabel(storage.Name); err != nil {
+		return fieldValidationError("name", err)
+	}
+	return nil
+}
+
+// ReservedNamesHook rejects names of created and renamed storages for which Reserved returns true,
+// e.g. fixed path segments of HTTP API which would shadow storages with such names.
+type ReservedNamesHook struct {
+	Reserved func(name string) bool
+}
+
+func (h ReservedNamesHook) ValidateStorage(ctx context.Context, before *model.Storage, storage model.Storage) error {
+	if before != nil && before.Name == storage.Name {
+		return nil
+	}
+	if h.Reserved(storage.Name) {
+		return fieldValidationError("name", fmt.Errorf("name %q is reserved", storage.Name))
+	}
+	return nil
+}
+
+// StorageFieldsHook checks labels, annotations, driver and description of created storages and changed fields of updated ones.
+type StorageFieldsHook struct{}
+
+func (StorageFieldsHook) ValidateStorage(ctx context.Context, before *model.Storage, storage model.Storage) error {
+	if before == nil {
+		before = &model.Storage{}
+	}
+	if !reflect.DeepEqual(before.Labels, storage.Labels) {
+		if err := labels.Validate(storage.Labels); err != nil {
+			return fieldValidationError("labels", err)
+		}
+	}
+	if !reflect.DeepEqual(before.Annotations, storage.Annotations) {
+		if err := labels.ValidateAnnotations(storage.Annotations); err != nil {
+			return fieldValidationError("annotations", err)
+		}
+	}
+	if before.Driver != storage.Driver {
+		if err := model.ValidateStorageDriver(storage.Driver); err != nil {
+			return fieldValidationError("driver", err)
+		}
+	}
+	if length := utf8.RuneCountInString(storage.Description); before.Description != storage.Description && length > model.MaxStorageDescriptionLength {
+		return fieldValidationError("description", fmt.Errorf("must not exceed %d characters, got %d", model.MaxStorageDescriptionLength, length))
+	}
+	return nil
+}
+
+// SizeBoundsHook checks that sizes of created and resized storages are in range [Min, Max] GiB and are multiples of Granularity.
+type SizeBoundsHook struct {
+	Min, Max    int
+	Granularity int
+}
+
+func (h SizeBoundsHook) ValidateStorage(ctx context.Context, before *model.Storage, storage model.Storage) error {
+	if before != nil && before.Size == storage.Size {
+		return nil
+	}
+	return h.checkSize(storage.Size)
+}
+
+func (h SizeBoundsHook) checkSize(size int) error {
+	var reason string
+	switch {
+	case size < h.Min || size > h.Max:
+		reason = fmt.Sprintf("must be in range [%d, %d] GiB", h.Min, h.Max)
+	case h.Granularity > 0 && size%h.Granularity != 0:
+		var nearest []string
+		for _, valid := range []int{size - size%h.Granularity, size - size%h.Granularity + h.Granularity} {
+			if valid >= h.Min && valid <= h.Max {
+				nearest = append(nearest, strconv.Itoa(valid))
+			}
+		}
+		reason = fmt.Sprintf("must be a multiple of %d GiB", h.Granularity)
+		if len(nearest) > 0 {
+			reason += fmt.Sprintf(", nearest valid sizes: %s GiB", strings.Join(nearest, ", "))
+		}
+	default:
+		return nil
+	}
+	return fieldValidationError("size", fmt.Errorf("%s", reason))
+}
+
+// fieldValidationError reports invalid field of storage, same as request validation errors of HTTP handlers.
+func fieldValidationError(field string, err error) error {
+	return errors.ErrRequestValidationFailed().
+		AddDetailF("Field %s: %v", field, err).
+		WithField(field, err.Error())
+}
+
+// DefaultValidationHooks returns built-in hooks checking storage name format, reserved names, fields format and size limits of cfg.
+// Server runs them before hooks from Config.ValidationHooks.
+func DefaultValidationHooks(cfg Config) []ValidationHook {
+	hooks := []ValidationHook{NameFormatHook{}}
+	if cfg.ReservedStorageNames != nil {
+		hooks = append(hooks, ReservedNamesHook{Reserved: cfg.ReservedStorageNames})
+	}
+	return append(hooks,
+		StorageFieldsHook{},
+		SizeBoundsHook{Min: cfg.MinStorageSize, Max: cfg.MaxStorageSize, Granularity: cfg.StorageSizeGranularity},
+	)
+}
+
+// validateStorage runs validation hooks until first error.
+func (s *Server) validateStorage(ctx context.Context, before *model.Storage, storage model.Storage) error {
+	for _, hook := range s.validationHooks {
+		err := hook.ValidateStorage(ctx, before, storage)
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(*cherry.Err); ok {
+			return err
+		}
+		return errors.ErrRequestValidationFailed().AddDetailsErr(err)
+	}
+	return nil
+}
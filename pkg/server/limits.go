@@ -0,0 +1,55 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+)
+
+// Default storage size limits, GiB
+const (
+	DefaultMinStorageSize         = 1
+	DefaultMaxStorageSize         = 1024 * 1024
+	DefaultStorageSizeGranularity = 1
+)
+
+// checkRequiredLabels checks that all configured required labels are set.
+func (s *Server) checkRequiredLabels(labels map[string]string) error {
+	var missing []string
+	for _, key := range s.cfg.RequiredLabels {
+		if labels[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	reason := fmt.Sprintf("missing required labels: %s", strings.Join(missing, ", "))
+	return errors.ErrRequestValidationFailed().
+		AddDetailF("Field labels: %s", reason).
+		WithField("labels", reason)
+}
+
+// checkStorageReserved checks that reserved capacity is not negative and less than storage size.
+func checkStorageReserved(storage model.Storage) error {
+	if storage.Reserved >= 0 && storage.Reserved < storage.Size {
+		return nil
+	}
+	reason := fmt.Sprintf("must be in range [0, %d) GiB", storage.Size)
+	return errors.ErrRequestValidationFailed().
+		AddDetailF("Field reserved: %s", reason).
+		WithField("reserved", reason)
+}
+
+// checkStorageShrink checks that decreased allocatable capacity (size or reservation change) still fits used capacity
+// unless shrink is explicitly allowed.
+func checkStorageShrink(before, after model.Storage, allowShrink bool) error {
+	allocatable := after.Size - after.Reserved
+	if allowShrink || allocatable >= before.Size-before.Reserved || allocatable >= before.Used {
+		return nil
+	}
+	return errors.ErrStorageShrinkBelowUsed().
+		AddDetailF("storage %s uses %d GiB, requested size %d GiB with %d GiB reserved", before.Name, before.Used, after.Size, after.Reserved)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"sort"
+	"sync"
+)
+
+// storageLocks serializes mutations of the same storage within process, mutations of different storages run in parallel.
+// Locks are not shared between service instances, ETag conditions still should be used to detect concurrent changes.
+type storageLocks struct {
+	// all is held for reading by named locks and for writing by operations on all storages
+	all sync.RWMutex
+
+	mu    sync.Mutex
+	names map[string]*storageLock
+}
+
+type storageLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newStorageLocks() *storageLocks {
+	return &storageLocks{names: make(map[string]*storageLock)}
+}
+
+// lock locks storages with provided names. Names are locked in sorted order, so concurrent calls can't deadlock.
+// Returned function releases locks.
+func (l *storageLocks) lock(names ...string) (unlock func()) {
+	names = uniqueSorted(names)
+
+	l.all.RLock()
+	locks := make([]*storageLock, 0, len(names))
+	for _, name := range names {
+		l.mu.Lock()
+		lock, ok := l.names[name]
+		if !ok {
+			lock = &storageLock{}
+			l.names[name] = lock
+		}
+		lock.refs++
+		l.mu.Unlock()
+
+		lock.Lock()
+		locks = append(locks, lock)
+	}
+
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+
+			l.mu.Lock()
+			if locks[i].refs--; locks[i].refs == 0 {
+				delete(l.names, names[i])
+			}
+			l.mu.Unlock()
+		}
+		l.all.RUnlock()
+	}
+}
+
+// lockAll waits until all named locks are released and blocks new ones until returned function is called.
+func (l *storageLocks) lockAll() (unlock func()) {
+	l.all.Lock()
+	return l.all.Unlock
+}
+
+func uniqueSorted(names []string) []string {
+	ret := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// slowStoragesDB runs transactions without isolation and slows down reads and writes, so concurrent
+// read-modify-write cycles on the same storage interleave unless they are serialized by server.
+type slowStoragesDB struct {
+	database.DB
+
+	mu       sync.Mutex
+	storages map[string]model.Storage
+	writing  map[string]int
+	// maxWriting is a maximum number of concurrent writes of the same storage and of all storages
+	maxWritingSame, maxWritingAll int
+}
+
+func (db *slowStoragesDB) Transactional(ctx context.Context, fn func(tx database.DB) error) error {
+	return fn(db)
+}
+
+func (db *slowStoragesDB) StorageByName(ctx context.Context, name string) (model.Storage, error) {
+	db.mu.Lock()
+	storage, ok := db.storages[name]
+	db.mu.Unlock()
+	if !ok {
+		return model.Storage{}, errors.ErrResourceNotExists()
+	}
+	time.Sleep(time.Millisecond)
+	return storage, nil
+}
+
+// startWriting counts concurrent writes of storages with provided names.
+func (db *slowStoragesDB) startWriting(names ...string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, name := range names {
+		db.writing[name]++
+		if db.writing[name] > db.maxWritingSame {
+			db.maxWritingSame = db.writing[name]
+		}
+	}
+	all := 0
+	for _, n := range db.writing {
+		all += n
+	}
+	if all > db.maxWritingAll {
+		db.maxWritingAll = all
+	}
+}
+
+func (db *slowStoragesDB) UpdateStorage(ctx context.Context, name string, storage model.Storage) error {
+	db.startWriting(name)
+
+	time.Sleep(5 * time.Millisecond)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.writing[name]--
+	db.storages[storage.Name] = storage
+	return nil
+}
+
+func (db *slowStoragesDB) DefaultStorage(ctx context.Context) (model.Storage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, storage := range db.storages {
+		if storage.IsDefault {
+			return storage, nil
+		}
+	}
+	return model.Storage{}, errors.ErrResourceNotExists()
+}
+
+func (db *slowStoragesDB) SetDefaultStorage(ctx context.Context, name string) error {
+	current, err := db.DefaultStorage(ctx)
+	if err != nil {
+		return err
+	}
+	names := uniqueSorted([]string{name, current.Name})
+	db.startWriting(names...)
+
+	time.Sleep(5 * time.Millisecond)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, name := range names {
+		db.writing[name]--
+	}
+	for storageName, storage := range db.storages {
+		storage.IsDefault = storageName == name
+		db.storages[storageName] = storage
+	}
+	return nil
+}
+
+func (db *slowStoragesDB) CreateStorageAuditRecords(ctx context.Context, records []model.StorageAuditRecord) error {
+	return nil
+}
+
+func (db *slowStoragesDB) CreateStorageSizeRecords(ctx context.Context, records []model.StorageSizeRecord) error {
+	return nil
+}
+
+func TestStorageLocks(t *testing.T) {
+	Convey("Test concurrent storage updates", t, func() {
+		db := &slowStoragesDB{
+			storages: map[string]model.Storage{
+				"storage-1": {Name: "storage-1", Size: 10},
+				"storage-2": {Name: "storage-2", Size: 10},
+			},
+			writing: make(map[string]int),
+		}
+		srv := NewServer(db, &Clients{}, nil, Config{})
+		defer srv.Close()
+
+		const updates = 10
+		var wg sync.WaitGroup
+		for i := 0; i < updates; i++ {
+			for _, name := range []string{"storage-1", "storage-2"} {
+				wg.Add(1)
+				go func(name string, i int) {
+					defer wg.Done()
+					value := "v"
+					size := 20 + i
+					// each patch adds own label, interleaved patches would lose labels of each other
+					err := srv.PatchStorage(context.Background(), name, model.PatchStorageRequest{
+						Size:   &size,
+						Labels: map[string]*string{fmt.Sprintf("update-%d", i): &value},
+					}, nil, false)
+					if err != nil {
+						t.Error(err)
+					}
+				}(name, i)
+			}
+		}
+		wg.Wait()
+
+		for _, name := range []string{"storage-1", "storage-2"} {
+			So(db.storages[name].Labels, ShouldHaveLength, updates)
+			So(db.storages[name].Size, ShouldBeBetweenOrEqual, 20, 20+updates-1)
+		}
+		So(db.maxWritingSame, ShouldEqual, 1)
+		So(db.maxWritingAll, ShouldBeGreaterThan, 1)
+	})
+}
+
+func TestStorageMaintenanceLocks(t *testing.T) {
+	Convey("Test concurrent storage updates and maintenance switches", t, func() {
+		db := &slowStoragesDB{
+			storages: map[string]model.Storage{"storage-1": {Name: "storage-1", Size: 10}},
+			writing:  make(map[string]int),
+		}
+		srv := NewServer(db, &Clients{}, nil, Config{})
+		defer srv.Close()
+
+		const updates = 5
+		var wg sync.WaitGroup
+		for i := 0; i < updates; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				value := "v"
+				err := srv.PatchStorage(context.Background(), "storage-1", model.PatchStorageRequest{
+					Labels: map[string]*string{fmt.Sprintf("update-%d", i): &value},
+				}, nil, false)
+				if err != nil {
+					t.Error(err)
+				}
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				enabled := i%2 == 0
+				err := srv.SetStorageMaintenance(context.Background(), "storage-1", model.StorageMaintenanceRequest{Enabled: &enabled})
+				if err != nil {
+					t.Error(err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		// maintenance switch writes whole storage, interleaved with patch it would lose patch labels
+		So(db.storages["storage-1"].Labels, ShouldHaveLength, updates)
+		So(db.maxWritingSame, ShouldEqual, 1)
+	})
+}
+
+func TestSetDefaultStorageLocks(t *testing.T) {
+	Convey("Test concurrent default storage switches and updates", t, func() {
+		db := &slowStoragesDB{
+			storages: map[string]model.Storage{
+				"storage-1": {Name: "storage-1", Size: 10, IsDefault: true},
+				"storage-2": {Name: "storage-2", Size: 10},
+			},
+			writing: make(map[string]int),
+		}
+		srv := NewServer(db, &Clients{}, nil, Config{})
+		defer srv.Close()
+
+		const updates = 5
+		var wg sync.WaitGroup
+		for i := 0; i < updates; i++ {
+			wg.Add(3)
+			go func(i int) {
+				defer wg.Done()
+				if err := srv.SetDefaultStorage(context.Background(), fmt.Sprintf("storage-%d", i%2+1)); err != nil {
+					t.Error(err)
+				}
+			}(i)
+			for _, name := range []string{"storage-1", "storage-2"} {
+				go func(name string, i int) {
+					defer wg.Done()
+					value := "v"
+					err := srv.PatchStorage(context.Background(), name, model.PatchStorageRequest{
+						Labels: map[string]*string{fmt.Sprintf("update-%d", i): &value},
+					}, nil, false)
+					if err != nil {
+						t.Error(err)
+					}
+				}(name, i)
+			}
+		}
+		wg.Wait()
+
+		// patch of previous default storage interleaved with switch would restore its default flag
+		defaults := 0
+		for _, storage := range db.storages {
+			if storage.IsDefault {
+				defaults++
+			}
+			So(storage.Labels, ShouldHaveLength, updates)
+		}
+		So(defaults, ShouldEqual, 1)
+		So(db.maxWritingSame, ShouldEqual, 1)
+	})
+}
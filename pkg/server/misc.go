@@ -2,6 +2,9 @@ package server
 
 import (
 	"context"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/events"
 
 	"github.com/containerum/bill-external/errors"
 	billing "github.com/containerum/bill-external/models"
@@ -16,6 +19,23 @@ func IsAdminRole(ctx context.Context) bool {
 	return false
 }
 
+type userNamespacesContextKey struct{}
+
+// WithUserNamespaces returns context with IDs of namespaces available to user with "user" role.
+// Transports must save namespaces from user headers this way, so storages visibility is checked by server.
+func WithUserNamespaces(ctx context.Context, namespaces []string) context.Context {
+	return context.WithValue(ctx, userNamespacesContextKey{}, namespaces)
+}
+
+// UserNamespaces returns namespaces saved by WithUserNamespaces, ok is false if request user role is not "user".
+func UserNamespaces(ctx context.Context) (namespaces []string, ok bool) {
+	if role, _ := ctx.Value(httputil.UserRoleContextKey).(string); role != "user" {
+		return nil, false
+	}
+	namespaces, _ = ctx.Value(userNamespacesContextKey{}).([]string)
+	return namespaces, true
+}
+
 // CheckTariff checks if user has permissions to use tariff
 func CheckTariff(tariff billing.Tariff, isAdmin bool) error {
 	if !tariff.Active {
@@ -27,3 +47,18 @@ func CheckTariff(tariff billing.Tariff, isAdmin bool) error {
 
 	return nil
 }
+
+// publishStorageEvent publishes storage event. Must be called after transaction commit.
+// Publishing errors are only logged because change is already committed.
+func (s *Server) publishStorageEvent(ctx context.Context, op events.Operation, name string) {
+	userID, _ := ctx.Value(httputil.UserIDContextKey).(string)
+	event := events.StorageEvent{
+		Operation: op,
+		Name:      name,
+		UserID:    userID,
+		Timestamp: time.Now().UTC(),
+	}
+	if err := s.events.Publish(ctx, event); err != nil {
+		s.log.WithError(err).WithField("name", name).Errorf("publish %s event failed", op)
+	}
+}
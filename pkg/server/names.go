@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	kubeClientModel "github.com/containerum/kube-client/pkg/model"
+)
+
+// lowercaseStorageActions converts storage names in arguments to lower case before calling wrapped actions.
+type lowercaseStorageActions struct {
+	StorageActions
+}
+
+// CaseInsensitiveStorageNames returns StorageActions which convert names of storages in arguments to lower case,
+// so names differing only by case refer to the same storage and created storages get canonical lowercase names.
+// Stored names must be already lowercase. Arguments are not modified.
+func CaseInsensitiveStorageNames(acts StorageActions) StorageActions {
+	return &lowercaseStorageActions{StorageActions: acts}
+}
+
+func lowercaseNames(names []string) []string {
+	ret := make([]string, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, strings.ToLower(name))
+	}
+	return ret
+}
+
+func (l *lowercaseStorageActions) CreateStorage(ctx context.Context, storage model.Storage) (model.Storage, error) {
+	storage.Name = strings.ToLower(storage.Name)
+	return l.StorageActions.CreateStorage(ctx, storage)
+}
+
+func (l *lowercaseStorageActions) CreateOrGetStorage(ctx context.Context, storage model.Storage) (model.Storage, bool, error) {
+	storage.Name = strings.ToLower(storage.Name)
+	return l.StorageActions.CreateOrGetStorage(ctx, storage)
+}
+
+func (l *lowercaseStorageActions) ImportStorage(ctx context.Context, storage model.Storage) error {
+	storage.Name = strings.ToLower(storage.Name)
+	return l.StorageActions.ImportStorage(ctx, storage)
+}
+
+func (l *lowercaseStorageActions) ImportStoragesAtomic(ctx context.Context, storages []model.Storage) (model.StorageImportResponse, error) {
+	lowercased := make([]model.Storage, 0, len(storages))
+	for _, storage := range storages {
+		storage.Name = strings.ToLower(storage.Name)
+		lowercased = append(lowercased, storage)
+	}
+	return l.StorageActions.ImportStoragesAtomic(ctx, lowercased)
+}
+
+func (l *lowercaseStorageActions) CreateStorageDryRun(ctx context.Context, storage model.Storage) (model.Storage, error) {
+	storage.Name = strings.ToLower(storage.Name)
+	return l.StorageActions.CreateStorageDryRun(ctx, storage)
+}
+
+func (l *lowercaseStorageActions) GetStorage(ctx context.Context, name string) (model.Storage, error) {
+	return l.StorageActions.GetStorage(ctx, strings.ToLower(name))
+}
+
+// lowercaseUpdate returns copy of req with lowercase new name.
+func lowercaseUpdate(req model.UpdateStorageRequest) model.UpdateStorageRequest {
+	if req.Name != nil {
+		name := strings.ToLower(*req.Name)
+		req.Name = &name
+	}
+	return req
+}
+
+func (l *lowercaseStorageActions) UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) error {
+	return l.StorageActions.UpdateStorage(ctx, strings.ToLower(name), lowercaseUpdate(req), cond, allowShrink)
+}
+
+func (l *lowercaseStorageActions) PreviewStorageUpdate(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) (model.StorageUpdatePreview, error) {
+	return l.StorageActions.PreviewStorageUpdate(ctx, strings.ToLower(name), lowercaseUpdate(req), cond, allowShrink)
+}
+
+func (l *lowercaseStorageActions) PatchStorage(ctx context.Context, name string, req model.PatchStorageRequest, cond model.ETagCondition, allowShrink bool) error {
+	return l.StorageActions.PatchStorage(ctx, strings.ToLower(name), req, cond, allowShrink)
+}
+
+func (l *lowercaseStorageActions) ReplaceStorageLabels(ctx context.Context, name string, labels map[string]string, cond model.ETagCondition) (model.Storage, error) {
+	return l.StorageActions.ReplaceStorageLabels(ctx, strings.ToLower(name), labels, cond)
+}
+
+func (l *lowercaseStorageActions) PatchStorageLabels(ctx context.Context, name string, patch map[string]*string, cond model.ETagCondition) (model.Storage, error) {
+	return l.StorageActions.PatchStorageLabels(ctx, strings.ToLower(name), patch, cond)
+}
+
+func (l *lowercaseStorageActions) RenameStorage(ctx context.Context, oldName, newName string) error {
+	return l.StorageActions.RenameStorage(ctx, strings.ToLower(oldName), strings.ToLower(newName))
+}
+
+func (l *lowercaseStorageActions) TransferStorage(ctx context.Context, name, ownerUserID string) error {
+	return l.StorageActions.TransferStorage(ctx, strings.ToLower(name), ownerUserID)
+}
+
+func (l *lowercaseStorageActions) CloneStorage(ctx context.Context, name, targetName string) error {
+	return l.StorageActions.CloneStorage(ctx, strings.ToLower(name), strings.ToLower(targetName))
+}
+
+func (l *lowercaseStorageActions) MigrateVolumes(ctx context.Context, name string, req model.StorageMigrateRequest) (model.StorageMigrateResponse, error) {
+	req.Target = strings.ToLower(req.Target)
+	return l.StorageActions.MigrateVolumes(ctx, strings.ToLower(name), req)
+}
+
+func (l *lowercaseStorageActions) SetStorageMaintenance(ctx context.Context, name string, req model.StorageMaintenanceRequest) error {
+	return l.StorageActions.SetStorageMaintenance(ctx, strings.ToLower(name), req)
+}
+
+func (l *lowercaseStorageActions) DeleteStorage(ctx context.Context, name string, cascade bool) error {
+	return l.StorageActions.DeleteStorage(ctx, strings.ToLower(name), cascade)
+}
+
+func (l *lowercaseStorageActions) DeleteStorages(ctx context.Context, names []string, atomic bool) (model.StorageBulkDeleteResponse, error) {
+	return l.StorageActions.DeleteStorages(ctx, lowercaseNames(names), atomic)
+}
+
+func (l *lowercaseStorageActions) UpdateStorages(ctx context.Context, updates []model.StorageBulkUpdateEntry, atomic bool) (model.StorageBulkUpdateResponse, error) {
+	lowercased := make([]model.StorageBulkUpdateEntry, 0, len(updates))
+	for _, update := range updates {
+		update.Name = strings.ToLower(update.Name)
+		lowercased = append(lowercased, update)
+	}
+	return l.StorageActions.UpdateStorages(ctx, lowercased, atomic)
+}
+
+func (l *lowercaseStorageActions) ApplyStorages(ctx context.Context, entries []model.StorageImportEntry, prune, atomic bool) (model.StorageApplyResponse, error) {
+	lowercased := make([]model.StorageImportEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Name = strings.ToLower(entry.Name)
+		lowercased = append(lowercased, entry)
+	}
+	return l.StorageActions.ApplyStorages(ctx, lowercased, prune, atomic)
+}
+
+func (l *lowercaseStorageActions) PurgeStorage(ctx context.Context, name string, cascade bool) error {
+	return l.StorageActions.PurgeStorage(ctx, strings.ToLower(name), cascade)
+}
+
+func (l *lowercaseStorageActions) RestoreStorage(ctx context.Context, name string) error {
+	return l.StorageActions.RestoreStorage(ctx, strings.ToLower(name))
+}
+
+func (l *lowercaseStorageActions) Touch(ctx context.Context, name string, reconciled bool) error {
+	return l.StorageActions.Touch(ctx, strings.ToLower(name), reconciled)
+}
+
+func (l *lowercaseStorageActions) SetDefaultStorage(ctx context.Context, name string) error {
+	return l.StorageActions.SetDefaultStorage(ctx, strings.ToLower(name))
+}
+
+func (l *lowercaseStorageActions) SetStoragePinned(ctx context.Context, name string, pinned bool) error {
+	return l.StorageActions.SetStoragePinned(ctx, strings.ToLower(name), pinned)
+}
+
+func (l *lowercaseStorageActions) GetStorageAudit(ctx context.Context, name string) ([]model.StorageAuditRecord, error) {
+	return l.StorageActions.GetStorageAudit(ctx, strings.ToLower(name))
+}
+
+func (l *lowercaseStorageActions) GetStorageSizeHistory(ctx context.Context, name string, filter model.StorageSizeHistoryFilter) ([]model.StorageSizeRecord, error) {
+	return l.StorageActions.GetStorageSizeHistory(ctx, strings.ToLower(name), filter)
+}
+
+func (l *lowercaseStorageActions) GetStorageForecast(ctx context.Context, name string, window time.Duration) (model.StorageCapacityForecast, error) {
+	return l.StorageActions.GetStorageForecast(ctx, strings.ToLower(name), window)
+}
+
+func (l *lowercaseStorageActions) GetStorageVolumes(ctx context.Context, name string) (kubeClientModel.VolumesList, error) {
+	return l.StorageActions.GetStorageVolumes(ctx, strings.ToLower(name))
+}
+
+func (l *lowercaseStorageActions) RecomputeUsage(ctx context.Context, name string) ([]model.StorageUsageRecompute, error) {
+	return l.StorageActions.RecomputeUsage(ctx, strings.ToLower(name))
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// namesStorageActions records storage names passed to actions.
+type namesStorageActions struct {
+	StorageActions
+
+	names []string
+}
+
+func (a *namesStorageActions) CreateStorage(ctx context.Context, storage model.Storage) (model.Storage, error) {
+	a.names = append(a.names, storage.Name)
+	return storage, nil
+}
+
+func (a *namesStorageActions) RenameStorage(ctx context.Context, oldName, newName string) error {
+	a.names = append(a.names, oldName, newName)
+	return nil
+}
+
+func (a *namesStorageActions) DeleteStorages(ctx context.Context, names []string, atomic bool) (model.StorageBulkDeleteResponse, error) {
+	a.names = append(a.names, names...)
+	return model.StorageBulkDeleteResponse{}, nil
+}
+
+func TestCaseInsensitiveStorageNames(t *testing.T) {
+	Convey("Test case-insensitive storage names", t, func() {
+		acts := &namesStorageActions{}
+		lowercase := CaseInsensitiveStorageNames(acts)
+
+		_, err := lowercase.CreateStorage(context.Background(), model.Storage{Name: "Storage-A"})
+		So(err, ShouldBeNil)
+		So(lowercase.RenameStorage(context.Background(), "Storage-A", "STORAGE-B"), ShouldBeNil)
+		names := []string{"Storage-C"}
+		_, err = lowercase.DeleteStorages(context.Background(), names, false)
+		So(err, ShouldBeNil)
+
+		So(acts.names, ShouldResemble, []string{"storage-a", "storage-a", "storage-b", "storage-c"})
+		So(names, ShouldResemble, []string{"Storage-C"})
+	})
+}
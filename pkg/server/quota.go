@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/containerum/utils/httputil"
+)
+
+// storageQuotas contains max total storages size by tenant kind and ID.
+type storageQuotas map[model.QuotaTenant]map[string]int
+
+func newStorageQuotas(quotas []model.StorageQuota) storageQuotas {
+	ret := make(storageQuotas)
+	for _, quota := range quotas {
+		if ret[quota.Tenant] == nil {
+			ret[quota.Tenant] = make(map[string]int)
+		}
+		ret[quota.Tenant][quota.ID] = quota.MaxSize
+	}
+	return ret
+}
+
+// limit returns quota of tenant or quota for any tenant of the same kind.
+func (q storageQuotas) limit(tenant model.QuotaTenant, id string) (int, bool) {
+	if limit, ok := q[tenant][id]; ok {
+		return limit, true
+	}
+	limit, ok := q[tenant][model.QuotaAnyTenant]
+	return limit, ok
+}
+
+type quotaTenant struct {
+	tenant model.QuotaTenant
+	id     string
+}
+
+func (t quotaTenant) filter() database.StorageFilter {
+	if t.tenant == model.QuotaTenantUser {
+		return database.StorageFilter{OwnerUserID: t.id}
+	}
+	return database.StorageFilter{ExplicitNamespace: t.id}
+}
+
+// storageTenants returns tenants whose quotas include storage size.
+func storageTenants(storage model.Storage) []quotaTenant {
+	ret := make([]quotaTenant, 0, len(storage.Namespaces)+1)
+	if storage.OwnerUserID != "" {
+		ret = append(ret, quotaTenant{tenant: model.QuotaTenantUser, id: storage.OwnerUserID})
+	}
+	for _, ns := range storage.Namespaces {
+		ret = append(ret, quotaTenant{tenant: model.QuotaTenantNamespace, id: ns})
+	}
+	return ret
+}
+
+// storageOwner returns ID of user made request.
+func storageOwner(ctx context.Context) string {
+	userID, _ := ctx.Value(httputil.UserIDContextKey).(string)
+	return userID
+}
+
+// checkStorageQuota checks that increasing storage size by sizeIncrease GiB fits quotas of storage tenants.
+// Storage with new size must not be saved yet.
+func (s *Server) checkStorageQuota(ctx context.Context, tx database.DB, storage model.Storage, sizeIncrease int) error {
+	if sizeIncrease <= 0 || len(s.quotas) == 0 {
+		return nil
+	}
+	for _, t := range storageTenants(storage) {
+		limit, ok := s.quotas.limit(t.tenant, t.id)
+		if !ok {
+			continue
+		}
+		used, err := tx.StoragesTotalSize(ctx, t.filter())
+		if err != nil {
+			return err
+		}
+		if used+sizeIncrease > limit {
+			return errors.ErrStorageQuotaExceeded().
+				AddDetailF("%s %s storages quota exceeded: used %d GiB of %d GiB, requested %d GiB", t.tenant, t.id, used, limit, sizeIncrease)
+		}
+	}
+	return nil
+}
+
+// GetStorageQuotas returns usage of quotas applied to request user and provided namespaces.
+func (s *Server) GetStorageQuotas(ctx context.Context, namespaces []string) (model.StorageQuotasResponse, error) {
+	tenants := storageTenants(model.Storage{OwnerUserID: storageOwner(ctx), Namespaces: namespaces})
+	ret := model.StorageQuotasResponse{Quotas: make([]model.StorageQuotaUsage, 0, len(tenants))}
+	for _, t := range tenants {
+		limit, ok := s.quotas.limit(t.tenant, t.id)
+		if !ok {
+			continue
+		}
+		used, err := s.db.StoragesTotalSize(ctx, t.filter())
+		if err != nil {
+			return model.StorageQuotasResponse{}, err
+		}
+		ret.Quotas = append(ret.Quotas, model.StorageQuotaUsage{
+			Tenant: t.tenant,
+			ID:     t.id,
+			Used:   used,
+			Limit:  limit,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/metrics"
+	"github.com/containerum/cherry"
+)
+
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBaseDelay   = 50 * time.Millisecond
+)
+
+var dbRetriesTotal = metrics.NewCounterVec(
+	"volume_manager_db_retries_total",
+	"Total number of database operations retried after transient errors.",
+	"operation",
+)
+
+func init() {
+	metrics.MustRegister(dbRetriesTotal)
+}
+
+// isTransientError checks if error is produced by database on transaction which may be safely repeated.
+func isTransientError(err error) bool {
+	return cherry.Equals(err, errors.ErrDatabaseTemporary())
+}
+
+// retryDelay returns exponential backoff delay with jitter for attempt (starting from 1):
+// random value in [base*2^(attempt-1)/2, base*2^(attempt-1)).
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base << uint(attempt-1)
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retry runs fn until it succeeds, returns non-transient error or attempts are exhausted.
+func (s *Server) retry(ctx context.Context, operation string, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !isTransientError(err) || attempt >= s.cfg.RetryMaxAttempts {
+			return err
+		}
+
+		dbRetriesTotal.Inc(operation)
+		delay := retryDelay(s.cfg.RetryBaseDelay, attempt)
+		s.log.WithError(err).Warnf("%s: transient database error, retry %d in %v", operation, attempt, delay)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// transactional runs fn in transaction retrying it on transient errors.
+// fn may be called several times so it must not keep state between calls.
+func (s *Server) transactional(ctx context.Context, operation string, fn func(tx database.DB) error) error {
+	attempt := 0
+	return s.retry(ctx, operation, func() error {
+		attempt++
+		return s.tracedTransaction(ctx, operation, attempt, fn)
+	})
+}
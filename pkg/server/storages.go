@@ -2,63 +2,1452 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/events"
 	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/utils/labels"
+	"github.com/containerum/cherry"
+	kubeClientModel "github.com/containerum/kube-client/pkg/model"
+	"github.com/sirupsen/logrus"
 )
 
 type StorageActions interface {
-	CreateStorage(ctx context.Context, storage model.Storage) error
-	GetStorages(ctx context.Context) ([]model.Storage, error)
-	UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest) error
-	DeleteStorage(ctx context.Context, name string) error
+	CreateStorage(ctx context.Context, storage model.Storage) (model.Storage, error)
+	CreateOrGetStorage(ctx context.Context, storage model.Storage) (ret model.Storage, created bool, err error)
+	ImportStorage(ctx context.Context, storage model.Storage) error
+	ImportStoragesAtomic(ctx context.Context, storages []model.Storage) (model.StorageImportResponse, error)
+	CreateStorageDryRun(ctx context.Context, storage model.Storage) (model.Storage, error)
+	GetStorages(ctx context.Context, pages model.StoragePagination) (model.StoragesPage, error)
+	GetStoragesFiltered(ctx context.Context, filter model.StorageListFilter, pages model.StoragePagination) (model.StoragesPage, error)
+	CountStorages(ctx context.Context, filter model.StorageListFilter) (int, error)
+	StreamStorages(ctx context.Context, filter model.StorageListFilter, fn func(batch []model.Storage) error) error
+	GetStorage(ctx context.Context, name string) (model.Storage, error)
+	GetStorageByID(ctx context.Context, id string) (model.Storage, error)
+	UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) error
+	PreviewStorageUpdate(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) (model.StorageUpdatePreview, error)
+	PatchStorage(ctx context.Context, name string, req model.PatchStorageRequest, cond model.ETagCondition, allowShrink bool) error
+	ReplaceStorageLabels(ctx context.Context, name string, labels map[string]string, cond model.ETagCondition) (model.Storage, error)
+	PatchStorageLabels(ctx context.Context, name string, patch map[string]*string, cond model.ETagCondition) (model.Storage, error)
+	RenameStorage(ctx context.Context, oldName, newName string) error
+	TransferStorage(ctx context.Context, name, ownerUserID string) error
+	CloneStorage(ctx context.Context, name, targetName string) error
+	MigrateVolumes(ctx context.Context, name string, req model.StorageMigrateRequest) (model.StorageMigrateResponse, error)
+	SetStorageMaintenance(ctx context.Context, name string, req model.StorageMaintenanceRequest) error
+	DeleteStorage(ctx context.Context, name string, cascade bool) error
+	DeleteStorages(ctx context.Context, names []string, atomic bool) (model.StorageBulkDeleteResponse, error)
+	UpdateStorages(ctx context.Context, updates []model.StorageBulkUpdateEntry, atomic bool) (model.StorageBulkUpdateResponse, error)
+	ApplyStorages(ctx context.Context, entries []model.StorageImportEntry, prune, atomic bool) (model.StorageApplyResponse, error)
+	PurgeStorage(ctx context.Context, name string, cascade bool) error
+	RestoreStorage(ctx context.Context, name string) error
+	Touch(ctx context.Context, name string, reconciled bool) error
+	SetDefaultStorage(ctx context.Context, name string) error
+	SetStoragePinned(ctx context.Context, name string, pinned bool) error
+	GetOverutilizedStorages(ctx context.Context) ([]model.Storage, error)
+	GetIdleStorages(ctx context.Context, since time.Duration) ([]model.Storage, error)
+	ExportStorages(ctx context.Context, includeDeleted bool) ([]model.StorageImportEntry, error)
+	GetStorageAudit(ctx context.Context, name string) ([]model.StorageAuditRecord, error)
+	GetStorageSizeHistory(ctx context.Context, name string, filter model.StorageSizeHistoryFilter) ([]model.StorageSizeRecord, error)
+	GetStorageForecast(ctx context.Context, name string, window time.Duration) (model.StorageCapacityForecast, error)
+	GetStorageVolumes(ctx context.Context, name string) (kubeClientModel.VolumesList, error)
+	GetStorageQuotas(ctx context.Context, namespaces []string) (model.StorageQuotasResponse, error)
+	GetStorageStats(ctx context.Context) (model.StorageStats, error)
+	RecomputeUsage(ctx context.Context, name string) ([]model.StorageUsageRecompute, error)
+	SyncStorages(ctx context.Context, apply bool) (model.StorageSyncReport, error)
+	CreateStorageWebhook(ctx context.Context, req model.CreateStorageWebhookRequest) (model.StorageWebhook, error)
+	GetStorageWebhooks(ctx context.Context) ([]model.StorageWebhook, error)
+	Ping(ctx context.Context) error
 }
 
-func (s *Server) CreateStorage(ctx context.Context, storage model.Storage) error {
+// CreateStorage creates storage and returns it with generated ID.
+func (s *Server) CreateStorage(ctx context.Context, storage model.Storage) (model.Storage, error) {
 	s.log.Infof("create storage %+v", storage)
 
-	err := s.db.Transactional(func(tx database.DB) error {
+	return s.createStorage(ctx, storage, model.AuditCreate)
+}
+
+// CreateOrGetStorage creates storage or returns existing storage with the same name.
+// Storage created concurrently by other request is returned as existing.
+func (s *Server) CreateOrGetStorage(ctx context.Context, storage model.Storage) (ret model.Storage, created bool, err error) {
+	s.log.Infof("create or get storage %+v", storage)
+
+	if err = s.prepareNewStorage(ctx, &storage); err != nil {
+		return model.Storage{}, false, err
+	}
+	err = s.transactional(ctx, "create", func(tx database.DB) error {
+		if quotaErr := s.checkStorageQuota(ctx, tx, storage, storage.Size); quotaErr != nil {
+			return quotaErr
+		}
+		if createErr := tx.CreateStorage(ctx, &storage); createErr != nil {
+			return createErr
+		}
+		var getErr error
+		ret, getErr = tx.StorageByName(ctx, storage.Name)
+		return getErr
+	})
+	if err == nil {
+		s.audit(ctx, model.AuditCreate, storage.Name, nil, &ret)
+		s.publishStorageEvent(ctx, events.StorageCreated, storage.Name)
+		return ret, true, nil
+	}
+	if !cherry.Equals(err, errors.ErrStorageAlreadyExists()) {
+		return model.Storage{}, false, err
+	}
+
+	// transaction is already finished, so concurrently created storage is visible here
+	ret, err = s.db.StorageByName(ctx, storage.Name)
+	if err != nil {
+		return model.Storage{}, false, err
+	}
+	return ret, false, nil
+}
+
+// ImportStorage creates storage same as CreateStorage but recorded as import in audit log.
+func (s *Server) ImportStorage(ctx context.Context, storage model.Storage) error {
+	s.log.Infof("import storage %+v", storage)
+
+	_, err := s.createStorage(ctx, storage, model.AuditImport)
+	return err
+}
+
+// ImportStoragesAtomic imports all storages in one transaction.
+// Import stops on first failure, in this case all storages are discarded.
+func (s *Server) ImportStoragesAtomic(ctx context.Context, storages []model.Storage) (model.StorageImportResponse, error) {
+	s.log.WithField("count", len(storages)).Infof("import storages (atomic)")
+
+	var imported []model.Storage
+	var failedName string
+	var failErr error
+	err := s.transactional(ctx, "import", func(tx database.DB) error {
+		imported, failedName, failErr = nil, "", nil
+		for _, storage := range storages {
+			err := s.prepareNewStorage(ctx, &storage)
+			if err == nil {
+				err = s.checkStorageQuota(ctx, tx, storage, storage.Size)
+			}
+			if err == nil {
+				err = tx.CreateStorage(ctx, &storage)
+			}
+			if isTransientError(err) {
+				return err
+			}
+			if err != nil {
+				failedName, failErr = storage.Name, err
+				return errBulkRollback
+			}
+			imported = append(imported, storage)
+		}
+		return nil
+	})
+
+	resp := model.NewStorageImportResponse()
+	switch err {
+	case nil:
+		for i := range imported {
+			s.audit(ctx, model.AuditImport, imported[i].Name, nil, &imported[i])
+			s.publishStorageEvent(ctx, events.StorageCreated, imported[i].Name)
+			resp.ImportSuccessful(imported[i].Name)
+		}
+		return resp, nil
+	case errBulkRollback:
+		names := make([]string, 0, len(storages))
+		for _, storage := range storages {
+			names = append(names, storage.Name)
+		}
+		resp.RollBack(names, failedName, failErr)
+		return resp, nil
+	default:
+		return model.StorageImportResponse{}, err
+	}
+}
+
+// prepareNewStorage validates storage before creation and resets fields which can't be set by client.
+// It must be used by all operations creating storages from request, so their checks don't diverge.
+func (s *Server) prepareNewStorage(ctx context.Context, storage *model.Storage) error {
+	if err := s.validateStorage(ctx, nil, *storage); err != nil {
+		return err
+	}
+	if err := checkStorageReserved(*storage); err != nil {
+		return err
+	}
+	if err := s.checkRequiredLabels(storage.Labels); err != nil {
+		return err
+	}
+	storage.IsDefault = false // default storage can be set only by SetDefaultStorage
+	storage.Pinned = false    // storage can be pinned only by SetStoragePinned
+	// maintenance, version and timestamps are managed by server and database
+	storage.Maintenance, storage.MaintenanceReason = false, ""
+	storage.Version = 0
+	storage.CreatedAt, storage.UpdatedAt, storage.LastUsedAt = time.Time{}, time.Time{}, nil
+	// new storage is neither terminating nor in trash
+	storage.Terminating, storage.TerminatingSince, storage.TerminationStatus = false, nil, ""
+	storage.Deleted, storage.DeleteTime = false, nil
+	storage.OwnerUserID = storageOwner(ctx)
+	return nil
+}
+
+func (s *Server) createStorage(ctx context.Context, storage model.Storage, auditOperation string) (model.Storage, error) {
+	if err := s.prepareNewStorage(ctx, &storage); err != nil {
+		return model.Storage{}, err
+	}
+	err := s.transactional(ctx, "create", func(tx database.DB) error {
+		if err := s.checkStorageQuota(ctx, tx, storage, storage.Size); err != nil {
+			return err
+		}
 		return tx.CreateStorage(ctx, &storage)
 	})
+	if err != nil {
+		return model.Storage{}, err
+	}
+
+	s.audit(ctx, auditOperation, storage.Name, nil, &storage)
+	s.publishStorageEvent(ctx, events.StorageCreated, storage.Name)
+	return storage, nil
+}
+
+// errDryRunRollback is returned from transaction to discard dry run changes.
+var errDryRunRollback = errors.ErrInternal().AddDetails("dry run rollback")
+
+func (s *Server) CreateStorageDryRun(ctx context.Context, storage model.Storage) (model.Storage, error) {
+	s.log.Infof("create storage (dry run) %+v", storage)
+
+	if err := s.prepareNewStorage(ctx, &storage); err != nil {
+		return model.Storage{}, err
+	}
+	err := s.transactional(ctx, "create_dry_run", func(tx database.DB) error {
+		if err := s.checkStorageQuota(ctx, tx, storage, storage.Size); err != nil {
+			return err
+		}
+		if err := tx.CreateStorage(ctx, &storage); err != nil {
+			return err
+		}
+		return errDryRunRollback
+	})
+	if err != errDryRunRollback {
+		return model.Storage{}, err
+	}
+	return storage, nil
+}
+
+func (s *Server) GetStorages(ctx context.Context, pages model.StoragePagination) (model.StoragesPage, error) {
+	return s.GetStoragesFiltered(ctx, model.StorageListFilter{}, pages)
+}
+
+// CountStorages returns number of storages matching filter without fetching them.
+func (s *Server) CountStorages(ctx context.Context, listFilter model.StorageListFilter) (int, error) {
+	s.log.WithField("filter", listFilter).Infof("count storages")
+
+	filter := storageFilter(listFilter)
+	filter.WithUsage = false
+	return s.db.CountStorages(ctx, filter)
+}
+
+// StreamStorages calls fn for consecutive batches of storages matching filter. Batches of Config.StreamBatchSize
+// storages are fetched using keyset pagination, so memory usage doesn't depend on number of storages. Batches are fetched by separate queries,
+// so storages changed during streaming may be skipped or included twice. Streaming stops on fn error or context cancellation.
+func (s *Server) StreamStorages(ctx context.Context, listFilter model.StorageListFilter, fn func(batch []model.Storage) error) error {
+	s.log.WithField("filter", listFilter).Infof("stream storages")
+
+	filter := storageFilter(listFilter)
+	filter.Limit = s.cfg.StreamBatchSize
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		storages, err := s.db.AllStorages(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if len(storages) > 0 {
+			if err := fn(storages); err != nil {
+				return err
+			}
+		}
+		if len(storages) < filter.Limit {
+			return nil
+		}
+		last := storages[len(storages)-1]
+		filter.After = last.Name
+		filter.AfterValue = sortValue(listFilter.Sort, last)
+	}
+}
+
+func storageFilter(listFilter model.StorageListFilter) database.StorageFilter {
+	return database.StorageFilter{
+		NamePrefix:    listFilter.NamePrefix,
+		MinSize:       listFilter.MinSize,
+		MaxSize:       listFilter.MaxSize,
+		Driver:        listFilter.Driver,
+		WithUsage:     !listFilter.SkipUsage,
+		WithDeleted:   listFilter.ShowDeleted,
+		LabelSelector: listFilter.LabelSelector,
+		CreatedAfter:  listFilter.CreatedAfter,
+		CreatedBefore: listFilter.CreatedBefore,
+		SortBy:        listFilter.Sort.Field,
+		SortDesc:      listFilter.Sort.Desc,
+
+		NamespaceScoped: listFilter.NamespaceScoped,
+		Namespaces:      listFilter.Namespaces,
+	}
+}
+
+func (s *Server) GetStoragesFiltered(ctx context.Context, listFilter model.StorageListFilter, pages model.StoragePagination) (model.StoragesPage, error) {
+	s.log.WithFields(logrus.Fields{
+		"limit":  pages.Limit,
+		"cursor": pages.Cursor,
+		"filter": listFilter,
+	}).Infof("get storages")
+
+	filter := storageFilter(listFilter)
+	if pages.Cursor != "" {
+		after, afterValue, err := decodeCursor(pages.Cursor, listFilter.Sort)
+		if err != nil {
+			return model.StoragesPage{}, err
+		}
+		filter.After = after
+		filter.AfterValue = afterValue
+	}
+	if pages.Limit > 0 {
+		filter.Limit = pages.Limit + 1 // fetch one extra storage to detect next page
+	}
+
+	storages, err := s.db.AllStorages(ctx, filter)
+	if err != nil {
+		return model.StoragesPage{}, err
+	}
+	if storages == nil {
+		storages = make([]model.Storage, 0)
+	}
+
+	ret := model.StoragesPage{
+		Storages: storages,
+		Total:    len(storages),
+	}
+	if pages.Limit <= 0 {
+		return ret, nil
+	}
+
+	if len(storages) > pages.Limit {
+		ret.Storages = storages[:pages.Limit]
+		ret.NextCursor = encodeCursor(listFilter.Sort, ret.Storages[pages.Limit-1])
+	}
+	if pages.Cursor != "" && len(ret.Storages) > 0 {
+		if err = s.prevStoragesPage(ctx, filter, listFilter.Sort, pages.Limit, ret.Storages[0], &ret); err != nil {
+			return model.StoragesPage{}, err
+		}
+	}
+	ret.Total, err = s.db.CountStorages(ctx, filter)
+	return ret, err
+}
+
+// prevStoragesPage finds cursor of page preceding page starting with first storage.
+// Storages before the first one are selected in reversed order, previous page is the first page if there are no more than limit of them.
+func (s *Server) prevStoragesPage(ctx context.Context, filter database.StorageFilter, sort model.StorageSort, limit int, first model.Storage, page *model.StoragesPage) error {
+	filter.SortDesc = !filter.SortDesc
+	filter.After = first.Name
+	filter.AfterValue = sortValue(sort, first)
+	filter.Limit = limit + 1
+	filter.WithUsage = false
+	preceding, err := s.db.AllStorages(ctx, filter)
+	if err != nil {
+		return err
+	}
+	page.HasPrev = len(preceding) > 0
+	if len(preceding) > limit {
+		page.PrevCursor = encodeCursor(sort, preceding[limit])
+	}
+	return nil
+}
+
+func (s *Server) GetStorage(ctx context.Context, name string) (model.Storage, error) {
+	s.log.WithField("name", name).Infof("get storage")
+
+	return s.visibleStorage(ctx, name)
+}
+
+func (s *Server) GetStorageByID(ctx context.Context, id string) (model.Storage, error) {
+	s.log.WithField("id", id).Infof("get storage by id")
+
+	storage, err := s.db.StorageByID(ctx, id)
+	if err == nil && !storageVisible(ctx, storage) {
+		return model.Storage{}, errors.ErrResourceNotExists().AddDetailF("storage with id %s not exists", id)
+	}
+	return storage, err
+}
+
+// storageVisible checks that storage may be shown to request user, same as in storages list:
+// users see only storages available in their namespaces, other roles see all storages.
+func storageVisible(ctx context.Context, storage model.Storage) bool {
+	namespaces, ok := UserNamespaces(ctx)
+	if !ok || len(storage.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range namespaces {
+		if storage.AvailableIn(ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleStorage returns storage by name. Storages invisible to request user are reported as not existing,
+// so their names are not disclosed.
+func (s *Server) visibleStorage(ctx context.Context, name string) (model.Storage, error) {
+	storage, err := s.db.StorageByName(ctx, name)
+	if err == nil && !storageVisible(ctx, storage) {
+		return model.Storage{}, errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return storage, err
+}
+
+// checkStorageVisible checks that storage with provided name is visible to request user.
+// Storage is not loaded for roles which see all storages.
+func (s *Server) checkStorageVisible(ctx context.Context, name string) error {
+	if _, ok := UserNamespaces(ctx); !ok {
+		return nil
+	}
+	_, err := s.visibleStorage(ctx, name)
 	return err
 }
 
-func (s *Server) GetStorages(ctx context.Context) ([]model.Storage, error) {
-	s.log.Infof("get storages")
-	storages, err := s.db.AllStorages(ctx)
-	if err == nil && storages == nil {
+// ExportStorages returns all storages in format accepted by ImportStorage.
+func (s *Server) ExportStorages(ctx context.Context, includeDeleted bool) ([]model.StorageImportEntry, error) {
+	s.log.WithField("include_deleted", includeDeleted).Infof("export storages")
+
+	storages, err := s.db.AllStorages(ctx, database.StorageFilter{WithDeleted: includeDeleted})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]model.StorageImportEntry, len(storages))
+	for i := range storages {
+		ret[i] = model.NewStorageImportEntry(storages[i])
+	}
+	return ret, nil
+}
+
+// GetOverutilizedStorages returns storages which usage reached warning threshold.
+func (s *Server) GetOverutilizedStorages(ctx context.Context) ([]model.Storage, error) {
+	s.log.Infof("get overutilized storages")
+
+	storages, err := s.db.AllStorages(ctx, database.StorageFilter{
+		Overutilized: true,
+		WithUsage:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if storages == nil {
+		storages = make([]model.Storage, 0)
+	}
+	return storages, nil
+}
+
+// GetIdleStorages returns reclaim candidates: storages without volume operations during provided period.
+// Pinned storages are never reclaimed, so they are not returned.
+func (s *Server) GetIdleStorages(ctx context.Context, since time.Duration) ([]model.Storage, error) {
+	s.log.WithField("since", since).Infof("get idle storages")
+
+	idleBefore := time.Now().Add(-since)
+	storages, err := s.db.AllStorages(ctx, database.StorageFilter{
+		IdleBefore: &idleBefore,
+		Unpinned:   true,
+		WithUsage:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if storages == nil {
 		storages = make([]model.Storage, 0)
 	}
-	return storages, err
+	return storages, nil
 }
 
-func (s *Server) UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest) error {
+// GetStorageVolumes returns volumes placed on storage with their namespaces.
+func (s *Server) GetStorageVolumes(ctx context.Context, name string) (kubeClientModel.VolumesList, error) {
+	s.log.WithField("name", name).Infof("get storage volumes")
+
+	if _, err := s.visibleStorage(ctx, name); err != nil {
+		return kubeClientModel.VolumesList{}, err
+	}
+
+	vols, err := s.db.StorageVolumes(ctx, name)
+	if err != nil {
+		return kubeClientModel.VolumesList{}, err
+	}
+
+	ret := make([]kubeClientModel.Volume, len(vols))
+	for i := range vols {
+		ret[i] = vols[i].ToKube()
+		ret[i].Namespace = vols[i].NamespaceID
+	}
+
+	return kubeClientModel.VolumesList{Volumes: ret}, nil
+}
+
+func (s *Server) UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) error {
 	s.log.Infof("update storage")
 
-	return s.db.Transactional(func(tx database.DB) error {
-		storage, getErr := tx.StorageByName(ctx, name)
-		if getErr != nil {
-			return getErr
+	names := []string{name}
+	if req.Name != nil {
+		names = append(names, *req.Name)
+	}
+	defer s.locks.lock(names...)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "update", func(tx database.DB) (err error) {
+		before, after, err = s.updateStorage(ctx, tx, name, req, cond, allowShrink)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditUpdate, after.Name, &before, &after)
+	s.publishStorageEvent(ctx, events.StorageUpdated, after.Name)
+	return nil
+}
+
+// PreviewStorageUpdate validates and applies update same as UpdateStorage but discards it and returns changed fields.
+func (s *Server) PreviewStorageUpdate(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition,
+	allowShrink bool) (model.StorageUpdatePreview, error) {
+	s.log.WithField("name", name).Infof("preview storage update")
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "update_preview", func(tx database.DB) (err error) {
+		if before, after, err = s.updateStorage(ctx, tx, name, req, cond, allowShrink); err != nil {
+			return err
+		}
+		return errDryRunRollback
+	})
+	if err != errDryRunRollback {
+		return model.StorageUpdatePreview{}, err
+	}
+	return model.StorageUpdatePreview{Name: before.Name, Changes: model.DiffStorages(before, after)}, nil
+}
+
+// updateStorage replaces storage fields set in request and returns storage states before and after update.
+func (s *Server) updateStorage(ctx context.Context, tx database.DB, name string, req model.UpdateStorageRequest,
+	cond model.ETagCondition, allowShrink bool) (before, after model.Storage, err error) {
+	storage, err := tx.StorageByName(ctx, name)
+	if err != nil {
+		return
+	}
+	if !cond.Matches(storage) {
+		err = errors.ErrPreconditionFailed().AddDetailF("storage %s version is %d", name, storage.Version)
+		return
+	}
+	before = storage
+	if req.Name != nil {
+		storage.Name = *req.Name
+	}
+	if req.Size != nil {
+		storage.Size = *req.Size
+	}
+	if req.Reserved != nil {
+		storage.Reserved = *req.Reserved
+	}
+	if req.OvercommitRatio != nil {
+		storage.OvercommitRatio = *req.OvercommitRatio
+	}
+	if req.WarnThreshold != nil {
+		storage.WarnThreshold = *req.WarnThreshold
+	}
+	if req.ReadOnly != nil {
+		storage.ReadOnly = *req.ReadOnly
+	}
+	if req.Namespaces != nil {
+		storage.Namespaces = *req.Namespaces
+	}
+	if req.Labels != nil {
+		storage.Labels = req.Labels
+		if err = s.checkRequiredLabels(storage.Labels); err != nil {
+			return
+		}
+	}
+
+	if err = s.validateStorage(ctx, &before, storage); err != nil {
+		return
+	}
+	if err = checkStorageReserved(storage); err != nil {
+		return
+	}
+	if err = checkStorageShrink(before, storage, allowShrink); err != nil {
+		return
+	}
+	if err = s.checkStorageQuota(ctx, tx, storage, storage.Size-before.Size); err != nil {
+		return
+	}
+	if err = tx.UpdateStorage(ctx, name, storage); err != nil {
+		return
+	}
+	after, err = tx.StorageByName(ctx, storage.Name)
+	return
+}
+
+func (s *Server) PatchStorage(ctx context.Context, name string, req model.PatchStorageRequest, cond model.ETagCondition, allowShrink bool) error {
+	s.log.WithField("name", name).Infof("patch storage")
+
+	defer s.locks.lock(name)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "patch", func(tx database.DB) (err error) {
+		before, after, err = s.patchStorage(ctx, tx, name, req, cond, allowShrink)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditPatch, after.Name, &before, &after)
+	s.publishStorageEvent(ctx, events.StorageUpdated, after.Name)
+	return nil
+}
+
+// patchStorage applies non-nil request fields to storage and returns storage states before and after patch.
+func (s *Server) patchStorage(ctx context.Context, tx database.DB, name string, req model.PatchStorageRequest,
+	cond model.ETagCondition, allowShrink bool) (before, after model.Storage, err error) {
+	storage, err := tx.StorageByName(ctx, name)
+	if err != nil {
+		return
+	}
+	if !cond.Matches(storage) {
+		err = errors.ErrPreconditionFailed().AddDetailF("storage %s version is %d", name, storage.Version)
+		return
+	}
+	before = storage
+	if req.Size != nil {
+		storage.Size = *req.Size
+	}
+	if req.Description != nil {
+		storage.Description = *req.Description
+	}
+	if req.Reserved != nil {
+		storage.Reserved = *req.Reserved
+	}
+	if req.OvercommitRatio != nil {
+		storage.OvercommitRatio = *req.OvercommitRatio
+	}
+	if req.WarnThreshold != nil {
+		storage.WarnThreshold = *req.WarnThreshold
+	}
+	if req.ReadOnly != nil {
+		storage.ReadOnly = *req.ReadOnly
+	}
+	if req.Namespaces != nil {
+		storage.Namespaces = *req.Namespaces
+	}
+	if req.Labels != nil {
+		storage.Labels = patchLabels(storage.Labels, req.Labels)
+		if err = s.checkRequiredLabels(storage.Labels); err != nil {
+			return
+		}
+	}
+	if req.Annotations != nil {
+		storage.Annotations = patchLabels(storage.Annotations, req.Annotations)
+		if validErr := labels.ValidateAnnotations(storage.Annotations); validErr != nil {
+			err = errors.ErrRequestValidationFailed().AddDetailsErr(validErr)
+			return
+		}
+	}
+
+	if err = s.validateStorage(ctx, &before, storage); err != nil {
+		return
+	}
+	if err = checkStorageReserved(storage); err != nil {
+		return
+	}
+	if err = checkStorageShrink(before, storage, allowShrink); err != nil {
+		return
+	}
+	if err = s.checkStorageQuota(ctx, tx, storage, storage.Size-before.Size); err != nil {
+		return
+	}
+	if err = tx.UpdateStorage(ctx, name, storage); err != nil {
+		return
+	}
+	after, err = tx.StorageByName(ctx, storage.Name)
+	return
+}
+
+// ReplaceStorageLabels replaces all storage labels. Other fields are not changed.
+func (s *Server) ReplaceStorageLabels(ctx context.Context, name string, labels map[string]string, cond model.ETagCondition) (model.Storage, error) {
+	s.log.WithField("name", name).Infof("replace storage labels")
+
+	return s.updateStorageLabels(ctx, name, cond, func(tx database.DB) error {
+		return tx.SetStorageLabels(ctx, name, labels)
+	})
+}
+
+// PatchStorageLabels sets non-nil and removes nil labels from patch. Other labels and fields are not changed.
+func (s *Server) PatchStorageLabels(ctx context.Context, name string, patch map[string]*string, cond model.ETagCondition) (model.Storage, error) {
+	s.log.WithField("name", name).Infof("patch storage labels")
+
+	return s.updateStorageLabels(ctx, name, cond, func(tx database.DB) error {
+		return tx.PatchStorageLabels(ctx, name, patch)
+	})
+}
+
+// updateStorageLabels runs labels update and checks required labels of result, update is rolled back if check failed.
+func (s *Server) updateStorageLabels(ctx context.Context, name string, cond model.ETagCondition, update func(tx database.DB) error) (model.Storage, error) {
+	defer s.locks.lock(name)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "update_labels", func(tx database.DB) (err error) {
+		if before, err = tx.StorageByName(ctx, name); err != nil {
+			return err
+		}
+		if !cond.Matches(before) {
+			return errors.ErrPreconditionFailed().AddDetailF("storage %s version is %d", name, before.Version)
+		}
+		if err = update(tx); err != nil {
+			return err
+		}
+		if after, err = tx.StorageByName(ctx, name); err != nil {
+			return err
+		}
+		return s.checkRequiredLabels(after.Labels)
+	})
+	if err != nil {
+		return model.Storage{}, err
+	}
+
+	s.audit(ctx, model.AuditLabels, name, &before, &after)
+	s.publishStorageEvent(ctx, events.StorageUpdated, name)
+	return after, nil
+}
+
+// UpdateStorages patches storages and reports result for each name.
+// In atomic mode all patches are applied in one transaction and discarded if any of them failed.
+func (s *Server) UpdateStorages(ctx context.Context, updates []model.StorageBulkUpdateEntry, atomic bool) (model.StorageBulkUpdateResponse, error) {
+	s.log.WithFields(logrus.Fields{
+		"count":  len(updates),
+		"atomic": atomic,
+	}).Infof("update storages")
+
+	resp := model.NewStorageBulkUpdateResponse()
+
+	if !atomic {
+		for _, update := range updates {
+			if err := s.PatchStorage(ctx, update.Name, update.Patch, nil, false); err != nil {
+				resp.UpdateFailed(update.Name, err)
+			} else {
+				resp.UpdateSuccessful(update.Name)
+			}
+		}
+		return resp, nil
+	}
+
+	names := make([]string, 0, len(updates))
+	for _, update := range updates {
+		names = append(names, update.Name)
+	}
+	defer s.locks.lock(names...)()
+
+	var befores, afters []model.Storage
+	err := s.transactional(ctx, "bulk_update", func(tx database.DB) error {
+		resp, befores, afters = model.NewStorageBulkUpdateResponse(), nil, nil
+		for _, update := range updates {
+			before, after, err := s.patchStorage(ctx, tx, update.Name, update.Patch, nil, false)
+			if err != nil {
+				resp.UpdateFailed(update.Name, err)
+			} else {
+				resp.UpdateSuccessful(update.Name)
+				befores, afters = append(befores, before), append(afters, after)
+			}
 		}
-		if req.Name != nil {
-			storage.Name = *req.Name
+		if len(resp.Failed) > 0 {
+			return errBulkRollback
 		}
-		if req.Size != nil {
-			storage.Size = *req.Size
+		return nil
+	})
+	switch err {
+	case nil:
+		for i := range afters {
+			s.audit(ctx, model.AuditPatch, afters[i].Name, &befores[i], &afters[i])
+			s.publishStorageEvent(ctx, events.StorageUpdated, afters[i].Name)
 		}
+		return resp, nil
+	case errBulkRollback:
+		resp.RollBack()
+		return resp, nil
+	default:
+		return model.StorageBulkUpdateResponse{}, err
+	}
+}
 
-		return tx.UpdateStorage(ctx, name, storage)
+// RenameStorage changes storage name, volumes are moved to new name in the same transaction.
+func (s *Server) RenameStorage(ctx context.Context, oldName, newName string) error {
+	s.log.WithFields(logrus.Fields{
+		"old_name": oldName,
+		"new_name": newName,
+	}).Infof("rename storage")
+
+	defer s.locks.lock(oldName, newName)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "rename", func(tx database.DB) (err error) {
+		if before, err = tx.StorageByName(ctx, oldName); err != nil {
+			return err
+		}
+		renamed := before
+		renamed.Name = newName
+		if err = s.validateStorage(ctx, &before, renamed); err != nil {
+			return err
+		}
+		if err = tx.RenameStorage(ctx, oldName, newName); err != nil {
+			return err
+		}
+		after, err = tx.StorageByName(ctx, newName)
+		return err
 	})
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditRename, newName, &before, &after)
+	s.publishStorageEvent(ctx, events.StorageUpdated, newName)
+	return nil
 }
 
-func (s *Server) DeleteStorage(ctx context.Context, name string) error {
-	s.log.WithField("name", name).Infof("delete storage")
+// TransferStorage makes user new storage owner. Storage must fit quota of new owner.
+func (s *Server) TransferStorage(ctx context.Context, name, ownerUserID string) error {
+	s.log.WithFields(logrus.Fields{
+		"name":  name,
+		"owner": ownerUserID,
+	}).Infof("transfer storage")
+
+	defer s.locks.lock(name)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "transfer", func(tx database.DB) (err error) {
+		if before, err = tx.StorageByName(ctx, name); err != nil {
+			return err
+		}
+		if before.OwnerUserID == ownerUserID {
+			after = before
+			return nil
+		}
+		if err = s.checkStorageQuota(ctx, tx, model.Storage{OwnerUserID: ownerUserID}, before.Size); err != nil {
+			return err
+		}
+		if err = tx.SetStorageOwner(ctx, name, ownerUserID); err != nil {
+			return err
+		}
+		after, err = tx.StorageByName(ctx, name)
+		return err
+	})
+	if err != nil || before.OwnerUserID == ownerUserID {
+		return err
+	}
 
-	return s.db.Transactional(func(tx database.DB) error {
-		storage, err := tx.StorageByName(ctx, name)
+	s.audit(ctx, model.AuditTransfer, name, &before, &after)
+	s.publishStorageEvent(ctx, events.StorageUpdated, name)
+	return nil
+}
+
+// CloneStorage creates new storage with configuration of existing one. Volumes are not copied.
+func (s *Server) CloneStorage(ctx context.Context, name, targetName string) error {
+	s.log.WithFields(logrus.Fields{
+		"name":        name,
+		"target_name": targetName,
+	}).Infof("clone storage")
+
+	defer s.locks.lock(name, targetName)()
+
+	var clone model.Storage
+	err := s.transactional(ctx, "clone", func(tx database.DB) error {
+		source, err := tx.StorageByName(ctx, name)
 		if err != nil {
 			return err
 		}
-		return tx.DeleteStorage(ctx, &storage)
+		clone = source.Clone(targetName)
+		clone.OwnerUserID = storageOwner(ctx)
+		if err := s.validateStorage(ctx, nil, clone); err != nil {
+			return err
+		}
+		if err := s.checkRequiredLabels(clone.Labels); err != nil {
+			return err
+		}
+		if err := s.checkStorageQuota(ctx, tx, clone, clone.Size); err != nil {
+			return err
+		}
+		return tx.CreateStorage(ctx, &clone)
 	})
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditClone, targetName, nil, &clone)
+	s.publishStorageEvent(ctx, events.StorageCreated, targetName)
+	return nil
+}
+
+// MigrateVolumes moves volumes of storage (all or selected by IDs) to target storage and reports result for each volume.
+// Target must accept new volumes and have enough free capacity for all selected volumes. Every volume is moved
+// in own transaction, so failed volumes stay on source storage and do not discard already moved ones.
+// Only volume records are reassigned, volumes data is not copied.
+func (s *Server) MigrateVolumes(ctx context.Context, name string, req model.StorageMigrateRequest) (model.StorageMigrateResponse, error) {
+	s.log.WithFields(logrus.Fields{
+		"name":       name,
+		"target":     req.Target,
+		"volume_ids": req.VolumeIDs,
+	}).Infof("migrate storage volumes")
+
+	resp := model.NewStorageMigrateResponse(name, req.Target)
+	if name == req.Target {
+		return resp, errors.ErrRequestValidationFailed().AddDetailF("volumes can't be migrated to the same storage")
+	}
+
+	defer s.locks.lock(name, req.Target)()
+
+	sourceBefore, err := s.db.StorageByName(ctx, name)
+	if err != nil {
+		return resp, err
+	}
+	targetBefore, err := s.db.StorageByName(ctx, req.Target)
+	if err != nil {
+		return resp, err
+	}
+	if targetBefore.ReadOnly {
+		return resp, errors.ErrStorageReadOnly().AddDetailF("storage %s does not accept new volumes", targetBefore.Name)
+	}
+	if targetBefore.Maintenance {
+		return resp, targetBefore.MaintenanceError()
+	}
+	if targetBefore.Terminating {
+		return resp, targetBefore.TerminatingError()
+	}
+
+	vols, err := s.db.StorageVolumes(ctx, name)
+	if err != nil {
+		return resp, err
+	}
+	if len(req.VolumeIDs) > 0 {
+		vols = selectVolumes(vols, req.VolumeIDs, &resp)
+	}
+
+	required := 0
+	for _, vol := range vols {
+		required += vol.Capacity
+	}
+	if free := targetBefore.Capacity() - targetBefore.Used; required > free {
+		return resp, errors.ErrStorageOvercommitted().
+			AddDetailF("storage %s has %d GiB free, %d GiB required for migration", targetBefore.Name, free, required)
+	}
+
+	for _, vol := range vols {
+		if !targetBefore.SupportsAccessMode(vol.AccessMode) {
+			resp.MigrateFailed(vol, errors.ErrRequestValidationFailed().
+				AddDetailF("storage %s (%s) does not support %s volumes", targetBefore.Name, targetBefore.Driver, vol.AccessMode))
+			continue
+		}
+		err := s.transactional(ctx, "migrate_volumes", func(tx database.DB) error {
+			moved := vol
+			if err := tx.MoveVolume(ctx, &moved, req.Target); err != nil {
+				return err
+			}
+			kubeVol := moved.ToKube()
+			return s.clients.KubeAPI.UpdateVolume(ctx, moved.NamespaceID, &kubeVol)
+		})
+		if err != nil {
+			resp.MigrateFailed(vol, err)
+		} else {
+			resp.MigrateSuccessful(vol)
+		}
+	}
+	if len(resp.Migrated) == 0 {
+		return resp, nil
+	}
+
+	if sourceAfter, err := s.db.StorageByName(ctx, name); err == nil {
+		s.audit(ctx, model.AuditMigrate, name, &sourceBefore, &sourceAfter)
+	}
+	if targetAfter, err := s.db.StorageByName(ctx, req.Target); err == nil {
+		s.audit(ctx, model.AuditMigrate, req.Target, &targetBefore, &targetAfter)
+	}
+	s.publishStorageEvent(ctx, events.StorageUpdated, name)
+	s.publishStorageEvent(ctx, events.StorageUpdated, req.Target)
+	return resp, nil
+}
+
+// selectVolumes returns volumes with provided IDs. IDs not found among volumes are reported as failed in resp.
+func selectVolumes(vols []model.Volume, ids []string, resp *model.StorageMigrateResponse) []model.Volume {
+	byID := make(map[string]model.Volume, len(vols))
+	for _, vol := range vols {
+		byID[vol.ID] = vol
+	}
+	ret := make([]model.Volume, 0, len(ids))
+	for _, id := range uniqueSorted(ids) {
+		vol, ok := byID[id]
+		if !ok {
+			vol.ID = id
+			resp.MigrateFailed(vol, errors.ErrResourceNotExists().AddDetailF("volume %s not exists on storage %s", id, resp.Source))
+			continue
+		}
+		ret = append(ret, vol)
+	}
+	return ret
+}
+
+// deleteStorageVolumes deletes all volumes placed on storage.
+func (s *Server) deleteStorageVolumes(ctx context.Context, tx database.DB, name string) error {
+	vols, err := tx.StorageVolumes(ctx, name)
+	if err != nil {
+		return err
+	}
+	if len(vols) == 0 {
+		return nil
+	}
+
+	if delErr := tx.DeleteVolumes(ctx, vols); delErr != nil {
+		return delErr
+	}
+
+	var resourceIDs []string
+	for _, v := range vols {
+		if delErr := s.clients.KubeAPI.DeleteVolume(ctx, v.NamespaceID, v.Label); delErr != nil {
+			return delErr
+		}
+		resourceIDs = append(resourceIDs, v.ID)
+	}
+	if unsubErr := s.clients.Billing.MassiveUnsubscribe(ctx, resourceIDs); unsubErr != nil {
+		return unsubErr
+	}
+
+	return nil
+}
+
+// patchLabels applies labels (or annotations) patch: non-nil values are set, nil values remove labels.
+func patchLabels(current map[string]string, patch map[string]*string) map[string]string {
+	ret := make(map[string]string, len(current)+len(patch))
+	for k, v := range current {
+		ret[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(ret, k)
+		} else {
+			ret[k] = *v
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// DeleteStorage deletes storage. If storage still has volumes and cascade is not set, storage is marked as terminating:
+// it stops accepting new volumes and is deleted by termination reconciler after its volumes are gone.
+func (s *Server) DeleteStorage(ctx context.Context, name string, cascade bool) error {
+	return s.deleteStorage(ctx, name, cascade, true)
+}
+
+// deleteStorage deletes storage. If terminate is set, storage with volumes is marked as terminating instead of failure.
+func (s *Server) deleteStorage(ctx context.Context, name string, cascade, terminate bool) error {
+	s.log.WithFields(logrus.Fields{
+		"name":    name,
+		"cascade": cascade,
+	}).Infof("delete storage")
+
+	defer s.locks.lock(name)()
+
+	var storage, terminating model.Storage
+	var deleted bool
+	err := s.transactional(ctx, "delete", func(tx database.DB) (err error) {
+		storage, err = tx.StorageByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		if cascade {
+			if err := s.deleteStorageVolumes(ctx, tx, name); err != nil {
+				return err
+			}
+		}
+		toDelete := storage
+		err = tx.DeleteStorage(ctx, &toDelete)
+		deleted = err == nil
+		if !terminate || !cherry.Equals(err, errors.ErrStorageHasVolumes()) {
+			return err
+		}
+		if storage.Terminating {
+			// repeated delete request, termination is already in progress
+			return nil
+		}
+		if err = tx.SetStorageTerminating(ctx, name); err != nil {
+			return err
+		}
+		terminating, err = tx.StorageByName(ctx, name)
+		return err
+	})
+	switch {
+	case err != nil:
+		return err
+	case deleted:
+		s.audit(ctx, model.AuditDelete, name, &storage, nil)
+		s.publishStorageEvent(ctx, events.StorageDeleted, name)
+	case !storage.Terminating:
+		s.log.WithField("name", name).Infof("storage has volumes, marked as terminating")
+		s.audit(ctx, model.AuditTerminate, name, &storage, &terminating)
+		s.publishStorageEvent(ctx, events.StorageTerminating, name)
+	}
+	return nil
+}
+
+// errBulkRollback is returned from transaction to discard changes of failed atomic bulk operation.
+var errBulkRollback = errors.ErrInternal().AddDetails("bulk operation rollback")
+
+// DeleteStorages deletes storages by names and reports result for each name.
+// Storages with volumes are reported as failed, they are not marked as terminating.
+// If atomic is set all storages deleted in one transaction which is rolled back on any failure.
+func (s *Server) DeleteStorages(ctx context.Context, names []string, atomic bool) (model.StorageBulkDeleteResponse, error) {
+	s.log.WithFields(logrus.Fields{
+		"names":  names,
+		"atomic": atomic,
+	}).Infof("delete storages")
+
+	resp := model.NewStorageBulkDeleteResponse()
+
+	if !atomic {
+		for _, name := range names {
+			if err := s.deleteStorage(ctx, name, false, false); err != nil {
+				resp.DeleteFailed(name, err)
+			} else {
+				resp.DeleteSuccessful(name)
+			}
+		}
+		return resp, nil
+	}
+
+	defer s.locks.lock(names...)()
+
+	var deletedStorages []model.Storage
+	err := s.transactional(ctx, "bulk_delete", func(tx database.DB) error {
+		resp, deletedStorages = model.NewStorageBulkDeleteResponse(), nil
+		for _, name := range names {
+			storage, err := tx.StorageByName(ctx, name)
+			if err == nil {
+				deleted := storage
+				err = tx.DeleteStorage(ctx, &deleted)
+			}
+			if err != nil {
+				resp.DeleteFailed(name, err)
+			} else {
+				resp.DeleteSuccessful(name)
+				deletedStorages = append(deletedStorages, storage)
+			}
+		}
+		if len(resp.Failed) > 0 {
+			return errBulkRollback
+		}
+		return nil
+	})
+	switch err {
+	case nil:
+		for i := range deletedStorages {
+			s.audit(ctx, model.AuditDelete, deletedStorages[i].Name, &deletedStorages[i], nil)
+			s.publishStorageEvent(ctx, events.StorageDeleted, deletedStorages[i].Name)
+		}
+		return resp, nil
+	case errBulkRollback:
+		resp.RollBack()
+		return resp, nil
+	default:
+		return model.StorageBulkDeleteResponse{}, err
+	}
+}
+
+func (s *Server) PurgeStorage(ctx context.Context, name string, cascade bool) error {
+	s.log.WithFields(logrus.Fields{
+		"name":    name,
+		"cascade": cascade,
+	}).Infof("purge storage")
+
+	defer s.locks.lock(name)()
+
+	err := s.transactional(ctx, "purge", func(tx database.DB) error {
+		if cascade {
+			if err := s.deleteStorageVolumes(ctx, tx, name); err != nil {
+				return err
+			}
+		}
+		return tx.PurgeStorage(ctx, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	// snapshot is not available for storages in trash
+	s.audit(ctx, model.AuditPurge, name, nil, nil)
+	s.publishStorageEvent(ctx, events.StorageDeleted, name)
+	return nil
+}
+
+func (s *Server) RestoreStorage(ctx context.Context, name string) error {
+	s.log.WithField("name", name).Infof("restore storage")
+
+	defer s.locks.lock(name)()
+
+	var storage model.Storage
+	err := s.transactional(ctx, "restore", func(tx database.DB) (err error) {
+		if err = tx.RestoreStorage(ctx, name); err != nil {
+			return err
+		}
+		storage, err = tx.StorageByName(ctx, name)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditRestore, name, nil, &storage)
+	return nil
+}
+
+// Touch refreshes storage update time without changing other fields, validation and audit.
+// If reconciled is set, StorageReconciled event is published.
+func (s *Server) Touch(ctx context.Context, name string, reconciled bool) error {
+	s.log.WithField("name", name).Infof("touch storage")
+
+	if err := s.retry(ctx, "touch", func() error {
+		return s.db.TouchStorage(ctx, name)
+	}); err != nil {
+		return err
+	}
+
+	if reconciled {
+		s.publishStorageEvent(ctx, events.StorageReconciled, name)
+	}
+	return nil
+}
+
+// errDefaultStorageChanged is returned from transaction if default storage was changed after it was locked.
+var errDefaultStorageChanged = errors.ErrInternal().AddDetails("default storage changed")
+
+// SetDefaultStorage makes storage default for volumes created without storage name.
+// Previous default storage loses its flag in the same transaction, so it is locked too.
+func (s *Server) SetDefaultStorage(ctx context.Context, name string) error {
+	s.log.WithField("name", name).Infof("set default storage")
+
+	for {
+		current, err := s.db.DefaultStorage(ctx)
+		if err != nil && !cherry.Equals(err, errors.ErrResourceNotExists()) {
+			return err
+		}
+		err = s.setDefaultStorage(ctx, name, current.Name)
+		if err != errDefaultStorageChanged {
+			return err
+		}
+	}
+}
+
+// setDefaultStorage makes storage default if current default storage is still the same.
+// Empty current means that default storage is not set.
+func (s *Server) setDefaultStorage(ctx context.Context, name, current string) error {
+	names := []string{name}
+	if current != "" {
+		names = append(names, current)
+	}
+	defer s.locks.lock(names...)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "set_default", func(tx database.DB) (err error) {
+		actual, err := tx.DefaultStorage(ctx)
+		switch {
+		case cherry.Equals(err, errors.ErrResourceNotExists()):
+			actual, err = model.Storage{}, nil
+		case err != nil:
+			return err
+		}
+		if actual.Name != current {
+			return errDefaultStorageChanged
+		}
+		if before, err = tx.StorageByName(ctx, name); err != nil {
+			return err
+		}
+		if err = tx.SetDefaultStorage(ctx, name); err != nil {
+			return err
+		}
+		after, err = tx.StorageByName(ctx, name)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !before.IsDefault {
+		s.audit(ctx, model.AuditSetDefault, name, &before, &after)
+		s.publishStorageEvent(ctx, events.StorageUpdated, name)
+	}
+	return nil
+}
+
+// SetStoragePinned pins or unpins storage. Pinned storage is not purged from trash after retention and can't be purged by request.
+func (s *Server) SetStoragePinned(ctx context.Context, name string, pinned bool) error {
+	s.log.WithFields(logrus.Fields{
+		"name":   name,
+		"pinned": pinned,
+	}).Infof("set storage pinned")
+
+	defer s.locks.lock(name)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "pin", func(tx database.DB) (err error) {
+		if before, err = tx.StorageByName(ctx, name); err != nil {
+			return err
+		}
+		if before.Pinned == pinned {
+			after = before
+			return nil
+		}
+		if err = tx.SetStoragePinned(ctx, name, pinned); err != nil {
+			return err
+		}
+		after, err = tx.StorageByName(ctx, name)
+		return err
+	})
+	if err != nil || before.Pinned == pinned {
+		return err
+	}
+
+	operation := model.AuditPin
+	if !pinned {
+		operation = model.AuditUnpin
+	}
+	s.audit(ctx, operation, name, &before, &after)
+	s.publishStorageEvent(ctx, events.StorageUpdated, name)
+	return nil
+}
+
+// SetStorageMaintenance switches storage maintenance mode. Disabling maintenance clears reason.
+func (s *Server) SetStorageMaintenance(ctx context.Context, name string, req model.StorageMaintenanceRequest) error {
+	s.log.WithFields(logrus.Fields{
+		"name":    name,
+		"enabled": *req.Enabled,
+		"reason":  req.Reason,
+	}).Infof("set storage maintenance")
+
+	defer s.locks.lock(name)()
+
+	var before, after model.Storage
+	err := s.transactional(ctx, "maintenance", func(tx database.DB) (err error) {
+		if before, err = tx.StorageByName(ctx, name); err != nil {
+			return err
+		}
+		storage := before
+		storage.Maintenance = *req.Enabled
+		storage.MaintenanceReason = ""
+		if storage.Maintenance {
+			storage.MaintenanceReason = req.Reason
+		}
+		if err = tx.UpdateStorage(ctx, name, storage); err != nil {
+			return err
+		}
+		after, err = tx.StorageByName(ctx, name)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditMaintenance, name, &before, &after)
+	s.publishStorageEvent(ctx, events.StorageUpdated, name)
+	return nil
+}
+
+// RecomputeUsage recalculates used capacity of storage (or all storages if name is empty) from its volumes.
+// Audit records are written only for storages which used capacity actually changed.
+func (s *Server) RecomputeUsage(ctx context.Context, name string) ([]model.StorageUsageRecompute, error) {
+	s.log.WithField("name", name).Infof("recompute storages usage")
+
+	if name != "" {
+		defer s.locks.lock(name)()
+	} else {
+		defer s.locks.lockAll()()
+	}
+
+	var ret []model.StorageUsageRecompute
+	var before, after []model.Storage
+	err := s.transactional(ctx, "recompute_usage", func(tx database.DB) error {
+		ret, before, after = []model.StorageUsageRecompute{}, nil, nil
+		var storages []model.Storage
+		if name != "" {
+			storage, err := tx.StorageByName(ctx, name)
+			if err != nil {
+				return err
+			}
+			storages = []model.Storage{storage}
+		} else {
+			var err error
+			if storages, err = tx.AllStorages(ctx, database.StorageFilter{}); err != nil {
+				return err
+			}
+		}
+		for _, storage := range storages {
+			used, err := tx.RecomputeStorageUsed(ctx, storage.Name)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, model.StorageUsageRecompute{
+				Name:       storage.Name,
+				UsedBefore: storage.Used,
+				UsedAfter:  used,
+				Drift:      storage.Used - used,
+			})
+			if used != storage.Used {
+				corrected := storage
+				corrected.Used = used
+				before, after = append(before, storage), append(after, corrected)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range before {
+		s.audit(ctx, model.AuditRecompute, before[i].Name, &before[i], &after[i])
+	}
+	return ret, nil
+}
+
+// GetStorageStats returns cluster-wide aggregates of storages sizes with breakdown by driver.
+func (s *Server) GetStorageStats(ctx context.Context) (model.StorageStats, error) {
+	s.log.Infof("get storage stats")
+
+	drivers, err := s.db.StorageDriverStats(ctx)
+	if err != nil {
+		return model.StorageStats{}, err
+	}
+	return model.NewStorageStats(drivers), nil
+}
+
+func (s *Server) GetStorageAudit(ctx context.Context, name string) ([]model.StorageAuditRecord, error) {
+	s.log.WithField("name", name).Infof("get storage audit")
+
+	if err := s.checkStorageVisible(ctx, name); err != nil {
+		return nil, err
+	}
+	return s.db.StorageAuditRecords(ctx, name)
+}
+
+func (s *Server) GetStorageSizeHistory(ctx context.Context, name string, filter model.StorageSizeHistoryFilter) ([]model.StorageSizeRecord, error) {
+	s.log.WithField("name", name).Infof("get storage size history")
+
+	if err := s.checkStorageVisible(ctx, name); err != nil {
+		return nil, err
+	}
+	return s.db.StorageSizeHistory(ctx, name, filter)
+}
+
+// Ping checks that storage backend is reachable.
+func (s *Server) Ping(ctx context.Context) error {
+	if err := s.db.Ping(ctx); err != nil {
+		s.log.WithError(err).Warnf("database ping failed")
+		return errors.ErrServiceNotReady().AddDetailF("database is not reachable: %v", err)
+	}
+	return nil
 }
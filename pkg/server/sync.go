@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"git.containerum.net/ch/volume-manager/pkg/clients"
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// diffStorages compares storages in database with storages on backend. Entries are sorted by name.
+// Drivers are compared only if both are set because some backends don't report driver.
+func diffStorages(stored []model.Storage, provisioned []clients.ProvisionedStorage) []model.StorageSyncEntry {
+	actual := make(map[string]clients.ProvisionedStorage, len(provisioned))
+	for _, storage := range provisioned {
+		actual[storage.Name] = storage
+	}
+
+	ret := make([]model.StorageSyncEntry, 0)
+	for _, storage := range stored {
+		expected := &model.StorageSyncState{Driver: storage.Driver, Size: storage.Size}
+		backend, ok := actual[storage.Name]
+		if !ok {
+			ret = append(ret, model.StorageSyncEntry{Name: storage.Name, Status: model.StorageSyncMissing, Expected: expected})
+			continue
+		}
+		delete(actual, storage.Name)
+
+		var fields []string
+		if storage.Driver != "" && backend.Driver != "" && storage.Driver != backend.Driver {
+			fields = append(fields, "driver")
+		}
+		if storage.Size != backend.Size {
+			fields = append(fields, "size")
+		}
+		if len(fields) > 0 {
+			ret = append(ret, model.StorageSyncEntry{
+				Name:     storage.Name,
+				Status:   model.StorageSyncMismatched,
+				Expected: expected,
+				Actual:   &model.StorageSyncState{Driver: backend.Driver, Size: backend.Size},
+				Fields:   fields,
+			})
+		}
+	}
+	for _, backend := range actual {
+		ret = append(ret, model.StorageSyncEntry{
+			Name:   backend.Name,
+			Status: model.StorageSyncExtra,
+			Actual: &model.StorageSyncState{Driver: backend.Driver, Size: backend.Size},
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// SyncStorages compares storages in database with storages reported by provisioner.
+// If apply is set database is corrected to match backend: missing storages are deleted (storages with volumes are kept),
+// extra storages are created and mismatched sizes are updated. Driver can't be changed, so driver mismatch is only reported.
+// Each correction is made in own transaction, failures are reported in entries.
+func (s *Server) SyncStorages(ctx context.Context, apply bool) (model.StorageSyncReport, error) {
+	s.log.WithField("apply", apply).Infof("sync storages")
+
+	if s.clients == nil || s.clients.Provisioner == nil {
+		return model.StorageSyncReport{}, errors.ErrProvisionerNotConfigured()
+	}
+	provisioned, err := s.clients.Provisioner.ListStorages(ctx)
+	if err != nil {
+		return model.StorageSyncReport{}, err
+	}
+	stored, err := s.db.AllStorages(ctx, database.StorageFilter{})
+	if err != nil {
+		return model.StorageSyncReport{}, err
+	}
+
+	report := model.StorageSyncReport{Apply: apply, Entries: diffStorages(stored, provisioned)}
+	if !apply {
+		return report, nil
+	}
+	for i := range report.Entries {
+		entry := &report.Entries[i]
+		if err := s.applyStorageSync(ctx, entry); err != nil {
+			s.log.WithError(err).WithFields(logrus.Fields{
+				"name":   entry.Name,
+				"status": entry.Status,
+			}).Warnf("storage sync correction failed")
+			entry.Error = err.Error()
+			continue
+		}
+		entry.Applied = entry.Status != model.StorageSyncMismatched || contains(entry.Fields, "size")
+	}
+	return report, nil
+}
+
+func (s *Server) applyStorageSync(ctx context.Context, entry *model.StorageSyncEntry) error {
+	switch entry.Status {
+	case model.StorageSyncMissing:
+		var storage model.Storage
+		err := s.transactional(ctx, "sync", func(tx database.DB) (err error) {
+			if storage, err = tx.StorageByName(ctx, entry.Name); err != nil {
+				return err
+			}
+			deleted := storage
+			return tx.DeleteStorage(ctx, &deleted)
+		})
+		if err != nil {
+			return err
+		}
+		s.audit(ctx, model.AuditSync, entry.Name, &storage, nil)
+		s.publishStorageEvent(ctx, events.StorageDeleted, entry.Name)
+	case model.StorageSyncExtra:
+		storage := model.Storage{
+			Name:            entry.Name,
+			Driver:          entry.Actual.Driver,
+			Size:            entry.Actual.Size,
+			OvercommitRatio: 1,
+		}
+		err := s.transactional(ctx, "sync", func(tx database.DB) error {
+			return tx.CreateStorage(ctx, &storage)
+		})
+		if err != nil {
+			return err
+		}
+		s.audit(ctx, model.AuditSync, entry.Name, nil, &storage)
+		s.publishStorageEvent(ctx, events.StorageCreated, entry.Name)
+	case model.StorageSyncMismatched:
+		if !contains(entry.Fields, "size") {
+			return nil
+		}
+		var before, after model.Storage
+		err := s.transactional(ctx, "sync", func(tx database.DB) (err error) {
+			if before, err = tx.StorageByName(ctx, entry.Name); err != nil {
+				return err
+			}
+			updated := before
+			updated.Size = entry.Actual.Size
+			if err = tx.UpdateStorage(ctx, entry.Name, updated); err != nil {
+				return err
+			}
+			after, err = tx.StorageByName(ctx, entry.Name)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		s.audit(ctx, model.AuditSync, entry.Name, &before, &after)
+		s.publishStorageEvent(ctx, events.StorageUpdated, entry.Name)
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+
+	"git.containerum.net/ch/volume-manager/pkg/clients"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiffStorages(t *testing.T) {
+	Convey("Test storages diff", t, func() {
+		stored := []model.Storage{
+			{Name: "same", Driver: "nfs", Size: 10},
+			{Name: "missing", Driver: "nfs", Size: 10},
+			{Name: "resized", Driver: "nfs", Size: 10},
+			{Name: "no-driver", Size: 10},
+			{Name: "other-driver", Driver: "nfs", Size: 10},
+		}
+		provisioned := []clients.ProvisionedStorage{
+			{Name: "same", Driver: "nfs", Size: 10},
+			{Name: "resized", Driver: "nfs", Size: 20},
+			{Name: "no-driver", Driver: "ceph-rbd", Size: 10},
+			{Name: "other-driver", Driver: "ceph-rbd", Size: 10},
+			{Name: "extra", Driver: "local", Size: 5},
+		}
+
+		So(diffStorages(stored, provisioned), ShouldResemble, []model.StorageSyncEntry{
+			{
+				Name:   "extra",
+				Status: model.StorageSyncExtra,
+				Actual: &model.StorageSyncState{Driver: "local", Size: 5},
+			},
+			{
+				Name:     "missing",
+				Status:   model.StorageSyncMissing,
+				Expected: &model.StorageSyncState{Driver: "nfs", Size: 10},
+			},
+			{
+				Name:     "other-driver",
+				Status:   model.StorageSyncMismatched,
+				Expected: &model.StorageSyncState{Driver: "nfs", Size: 10},
+				Actual:   &model.StorageSyncState{Driver: "ceph-rbd", Size: 10},
+				Fields:   []string{"driver"},
+			},
+			{
+				Name:     "resized",
+				Status:   model.StorageSyncMismatched,
+				Expected: &model.StorageSyncState{Driver: "nfs", Size: 10},
+				Actual:   &model.StorageSyncState{Driver: "nfs", Size: 20},
+				Fields:   []string{"size"},
+			},
+		})
+	})
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultTerminationCheckInterval = time.Minute
+	DefaultTerminationTimeout       = time.Hour
+)
+
+// terminationReconciler periodically deletes terminating storages which volumes are gone.
+// If storage still has volumes after timeout, its termination status describes blocking volumes.
+type terminationReconciler struct {
+	srv      *Server
+	log      *cherrylog.LogrusAdapter
+	interval time.Duration
+	timeout  time.Duration
+
+	// running is set while reconciliation is in progress, overlapping reconciliations are skipped
+	running int32
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newTerminationReconciler(srv *Server, log *cherrylog.LogrusAdapter, interval, timeout time.Duration) *terminationReconciler {
+	r := &terminationReconciler{
+		srv:      srv,
+		log:      log,
+		interval: interval,
+		timeout:  timeout,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *terminationReconciler) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.reconcile(context.Background())
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// reconcile deletes terminating storages without volumes and returns names of deleted ones.
+// It returns immediately if other reconciliation is running.
+func (r *terminationReconciler) reconcile(ctx context.Context) []string {
+	if !atomic.CompareAndSwapInt32(&r.running, 0, 1) {
+		r.log.Debugf("termination reconciliation is already running, skipped")
+		return nil
+	}
+	defer atomic.StoreInt32(&r.running, 0)
+
+	storages, err := r.srv.db.AllStorages(ctx, database.StorageFilter{Terminating: true})
+	if err != nil {
+		r.log.WithError(err).Warnf("terminating storages listing failed")
+		return nil
+	}
+
+	var deleted []string
+	for _, storage := range storages {
+		entry := r.log.WithFields(logrus.Fields{
+			"name":              storage.Name,
+			"terminating_since": storage.TerminatingSince,
+		})
+		err := r.srv.finishStorageTermination(ctx, storage.Name, r.timeout)
+		switch {
+		case err == nil:
+			entry.Infof("terminating storage deleted")
+			deleted = append(deleted, storage.Name)
+		case cherry.Equals(err, errors.ErrStorageHasVolumes()):
+			entry.WithError(err).Debugf("terminating storage is kept because it has volumes")
+		case cherry.Equals(err, errors.ErrResourceNotExists()):
+			entry.Debugf("terminating storage was deleted concurrently")
+		default:
+			entry.WithError(err).Errorf("terminating storage deletion failed")
+		}
+	}
+	return deleted
+}
+
+// Close stops termination reconciliation.
+func (r *terminationReconciler) Close() error {
+	close(r.stop)
+	<-r.done
+	return nil
+}
+
+// finishStorageTermination deletes terminating storage. If storage still has volumes, ErrStorageHasVolumes is returned
+// and termination status is set when storage is terminating longer than timeout.
+func (s *Server) finishStorageTermination(ctx context.Context, name string, timeout time.Duration) error {
+	defer s.locks.lock(name)()
+
+	var storage model.Storage
+	err := s.transactional(ctx, "finish_termination", func(tx database.DB) (err error) {
+		storage, err = tx.StorageByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !storage.Terminating {
+			return errors.ErrResourceNotExists().AddDetailF("storage %s is not terminating", name)
+		}
+		toDelete := storage
+		return tx.DeleteStorage(ctx, &toDelete)
+	})
+	if cherry.Equals(err, errors.ErrStorageHasVolumes()) {
+		if status := terminationStatus(storage, timeout, err); status != storage.TerminationStatus {
+			if statusErr := s.retry(ctx, "termination_status", func() error {
+				return s.db.SetStorageTerminationStatus(ctx, name, status)
+			}); statusErr != nil {
+				s.log.WithError(statusErr).WithField("name", name).Warnf("termination status update failed")
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditDelete, name, &storage, nil)
+	s.publishStorageEvent(ctx, events.StorageDeleted, name)
+	return nil
+}
+
+// terminationStatus describes volumes blocking deletion of storage terminating longer than timeout, it is empty before timeout.
+func terminationStatus(storage model.Storage, timeout time.Duration, blockErr error) string {
+	if storage.TerminatingSince == nil || time.Since(*storage.TerminatingSince) < timeout {
+		return ""
+	}
+	reason := blockErr.Error()
+	if cherryErr, ok := blockErr.(*cherry.Err); ok && len(cherryErr.Details) > 0 {
+		reason = strings.Join(cherryErr.Details, "; ")
+	}
+	return fmt.Sprintf("storage still has volumes after termination timeout %v: %s", timeout, reason)
+}
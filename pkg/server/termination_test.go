@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// terminationDB keeps storages and labels of their volumes in memory.
+type terminationDB struct {
+	database.DB
+
+	mu       sync.Mutex
+	storages map[string]model.Storage
+	volumes  map[string][]string
+}
+
+func (db *terminationDB) Transactional(ctx context.Context, fn func(tx database.DB) error) error {
+	return fn(db)
+}
+
+func (db *terminationDB) StorageByName(ctx context.Context, name string) (model.Storage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage, ok := db.storages[name]
+	if !ok {
+		return model.Storage{}, errors.ErrResourceNotExists().AddDetailF("storage %s not exists", name)
+	}
+	return storage, nil
+}
+
+func (db *terminationDB) AllStorages(ctx context.Context, filter database.StorageFilter) ([]model.Storage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var ret []model.Storage
+	for _, storage := range db.storages {
+		if !filter.Terminating || storage.Terminating {
+			ret = append(ret, storage)
+		}
+	}
+	return ret, nil
+}
+
+func (db *terminationDB) DeleteStorage(ctx context.Context, storage *model.Storage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.volumes[storage.Name]) > 0 {
+		return errors.ErrStorageHasVolumes().AddDetailF("storage %s is used by volumes: %v", storage.Name, db.volumes[storage.Name])
+	}
+	delete(db.storages, storage.Name)
+	return nil
+}
+
+func (db *terminationDB) SetStorageTerminating(ctx context.Context, name string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage := db.storages[name]
+	now := time.Now()
+	storage.Terminating, storage.TerminatingSince, storage.TerminationStatus = true, &now, ""
+	storage.Version++
+	db.storages[name] = storage
+	return nil
+}
+
+func (db *terminationDB) SetStorageTerminationStatus(ctx context.Context, name, status string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	storage := db.storages[name]
+	storage.TerminationStatus = status
+	db.storages[name] = storage
+	return nil
+}
+
+func (db *terminationDB) CreateStorageAuditRecords(ctx context.Context, records []model.StorageAuditRecord) error {
+	return nil
+}
+
+func (db *terminationDB) CreateStorageSizeRecords(ctx context.Context, records []model.StorageSizeRecord) error {
+	return nil
+}
+
+func TestStorageTermination(t *testing.T) {
+	Convey("Test terminating storages deletion", t, func() {
+		db := &terminationDB{
+			storages: map[string]model.Storage{
+				"unused": {Name: "unused", Size: 10},
+				"bound":  {Name: "bound", Size: 10},
+			},
+			volumes: map[string][]string{"bound": {"vol-1"}},
+		}
+		srv := NewServer(db, &Clients{}, nil, Config{})
+		defer srv.Close()
+		reconciler := &terminationReconciler{
+			srv:     srv,
+			log:     cherrylog.NewLogrusAdapter(logrus.WithField("test", "termination")),
+			timeout: time.Hour,
+		}
+		ctx := context.Background()
+
+		So(srv.DeleteStorage(ctx, "unused", false), ShouldBeNil)
+		So(db.storages, ShouldNotContainKey, "unused")
+
+		So(srv.DeleteStorage(ctx, "bound", false), ShouldBeNil)
+		So(db.storages["bound"].Terminating, ShouldBeTrue)
+		So(db.storages["bound"].TerminatingSince, ShouldNotBeNil)
+
+		_, err := srv.volumeStorage(ctx, "ns", "bound", 1)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "being deleted")
+
+		Convey("Check storage is kept while it has volumes", func() {
+			So(reconciler.reconcile(ctx), ShouldBeEmpty)
+			So(db.storages["bound"].TerminationStatus, ShouldBeEmpty)
+		})
+		Convey("Check status is set after timeout", func() {
+			reconciler.timeout = 0
+			So(reconciler.reconcile(ctx), ShouldBeEmpty)
+			So(db.storages["bound"].TerminationStatus, ShouldContainSubstring, "vol-1")
+		})
+		Convey("Check storage is deleted after volumes are gone", func() {
+			db.volumes["bound"] = nil
+			So(reconciler.reconcile(ctx), ShouldResemble, []string{"bound"})
+			So(db.storages, ShouldNotContainKey, "bound")
+		})
+		Convey("Check bulk delete does not mark storages terminating", func() {
+			db.storages["bound-2"] = model.Storage{Name: "bound-2", Size: 10}
+			db.volumes["bound-2"] = []string{"vol-2"}
+			resp, err := srv.DeleteStorages(ctx, []string{"bound-2"}, false)
+			So(err, ShouldBeNil)
+			So(resp.Failed, ShouldHaveLength, 1)
+			So(db.storages["bound-2"].Terminating, ShouldBeFalse)
+		})
+	})
+}
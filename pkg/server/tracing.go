@@ -0,0 +1,325 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/tracing"
+	kubeClientModel "github.com/containerum/kube-client/pkg/model"
+)
+
+// startSpan starts span of storage operation. Span must be finished with endSpan.
+func startSpan(ctx context.Context, tracer tracing.Tracer, operation, name string) (context.Context, tracing.Span) {
+	attrs := []tracing.Attribute{tracing.String(tracing.AttributeOperation, operation)}
+	if name != "" {
+		attrs = append(attrs, tracing.String(tracing.AttributeStorageName, name))
+	}
+	return tracer.Start(ctx, operation, attrs...)
+}
+
+// endSpan tags span with operation outcome and ends it.
+func endSpan(span tracing.Span, err error) {
+	outcome := tracing.OutcomeSuccess
+	if err != nil {
+		outcome = tracing.OutcomeError
+		span.RecordError(err)
+	}
+	span.SetAttributes(tracing.String(tracing.AttributeOutcome, outcome))
+	span.End()
+}
+
+// tracedTransaction runs single attempt of transaction in span.
+func (s *Server) tracedTransaction(ctx context.Context, operation string, attempt int, fn func(tx database.DB) error) (err error) {
+	ctx, span := s.tracer.Start(ctx, "db.transaction",
+		tracing.String(tracing.AttributeOperation, operation),
+		tracing.String("db.attempt", strconv.Itoa(attempt)))
+	defer func() { endSpan(span, err) }()
+	return s.db.Transactional(ctx, fn)
+}
+
+type tracedStorageActions struct {
+	acts   StorageActions
+	tracer tracing.Tracer
+}
+
+// TraceStorageActions returns StorageActions which run each method of acts in child span of context span.
+func TraceStorageActions(acts StorageActions, provider tracing.TracerProvider) StorageActions {
+	return &tracedStorageActions{acts: acts, tracer: provider.Tracer("storage_actions")}
+}
+
+func (t *tracedStorageActions) CreateStorage(ctx context.Context, storage model.Storage) (ret model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "CreateStorage", storage.Name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.CreateStorage(ctx, storage)
+}
+
+func (t *tracedStorageActions) CreateOrGetStorage(ctx context.Context, storage model.Storage) (ret model.Storage, created bool, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "CreateOrGetStorage", storage.Name)
+	defer func() {
+		span.SetAttributes(tracing.String("created", strconv.FormatBool(created)))
+		endSpan(span, err)
+	}()
+	return t.acts.CreateOrGetStorage(ctx, storage)
+}
+
+func (t *tracedStorageActions) ImportStorage(ctx context.Context, storage model.Storage) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "ImportStorage", storage.Name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.ImportStorage(ctx, storage)
+}
+
+func (t *tracedStorageActions) ImportStoragesAtomic(ctx context.Context, storages []model.Storage) (ret model.StorageImportResponse, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "ImportStoragesAtomic", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.ImportStoragesAtomic(ctx, storages)
+}
+
+func (t *tracedStorageActions) CreateStorageDryRun(ctx context.Context, storage model.Storage) (ret model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "CreateStorageDryRun", storage.Name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.CreateStorageDryRun(ctx, storage)
+}
+
+func (t *tracedStorageActions) GetStorages(ctx context.Context, pages model.StoragePagination) (ret model.StoragesPage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorages(ctx, pages)
+}
+
+func (t *tracedStorageActions) GetStoragesFiltered(ctx context.Context, filter model.StorageListFilter, pages model.StoragePagination) (ret model.StoragesPage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStoragesFiltered", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStoragesFiltered(ctx, filter, pages)
+}
+
+func (t *tracedStorageActions) CountStorages(ctx context.Context, filter model.StorageListFilter) (ret int, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "CountStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.CountStorages(ctx, filter)
+}
+
+func (t *tracedStorageActions) StreamStorages(ctx context.Context, filter model.StorageListFilter, fn func(batch []model.Storage) error) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "StreamStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.StreamStorages(ctx, filter, fn)
+}
+
+func (t *tracedStorageActions) GetStorage(ctx context.Context, name string) (ret model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorage(ctx, name)
+}
+
+func (t *tracedStorageActions) GetStorageByID(ctx context.Context, id string) (ret model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageByID", "")
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(tracing.String("storage.id", id))
+	return t.acts.GetStorageByID(ctx, id)
+}
+
+func (t *tracedStorageActions) UpdateStorage(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition, allowShrink bool) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "UpdateStorage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.UpdateStorage(ctx, name, req, cond, allowShrink)
+}
+
+func (t *tracedStorageActions) PreviewStorageUpdate(ctx context.Context, name string, req model.UpdateStorageRequest, cond model.ETagCondition,
+	allowShrink bool) (ret model.StorageUpdatePreview, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "PreviewStorageUpdate", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.PreviewStorageUpdate(ctx, name, req, cond, allowShrink)
+}
+
+func (t *tracedStorageActions) PatchStorage(ctx context.Context, name string, req model.PatchStorageRequest, cond model.ETagCondition, allowShrink bool) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "PatchStorage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.PatchStorage(ctx, name, req, cond, allowShrink)
+}
+
+func (t *tracedStorageActions) ReplaceStorageLabels(ctx context.Context, name string, labels map[string]string, cond model.ETagCondition) (ret model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "ReplaceStorageLabels", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.ReplaceStorageLabels(ctx, name, labels, cond)
+}
+
+func (t *tracedStorageActions) PatchStorageLabels(ctx context.Context, name string, patch map[string]*string, cond model.ETagCondition) (ret model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "PatchStorageLabels", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.PatchStorageLabels(ctx, name, patch, cond)
+}
+
+func (t *tracedStorageActions) TransferStorage(ctx context.Context, name, ownerUserID string) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "TransferStorage", name)
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(tracing.String("storage.owner_user_id", ownerUserID))
+	return t.acts.TransferStorage(ctx, name, ownerUserID)
+}
+
+func (t *tracedStorageActions) RenameStorage(ctx context.Context, oldName, newName string) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "RenameStorage", oldName)
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(tracing.String("storage.new_name", newName))
+	return t.acts.RenameStorage(ctx, oldName, newName)
+}
+
+func (t *tracedStorageActions) CloneStorage(ctx context.Context, name, targetName string) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "CloneStorage", name)
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(tracing.String("storage.target_name", targetName))
+	return t.acts.CloneStorage(ctx, name, targetName)
+}
+
+func (t *tracedStorageActions) MigrateVolumes(ctx context.Context, name string, req model.StorageMigrateRequest) (resp model.StorageMigrateResponse, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "MigrateVolumes", name)
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(tracing.String("storage.target_name", req.Target))
+	return t.acts.MigrateVolumes(ctx, name, req)
+}
+
+func (t *tracedStorageActions) SetStorageMaintenance(ctx context.Context, name string, req model.StorageMaintenanceRequest) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "SetStorageMaintenance", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.SetStorageMaintenance(ctx, name, req)
+}
+
+func (t *tracedStorageActions) DeleteStorage(ctx context.Context, name string, cascade bool) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "DeleteStorage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.DeleteStorage(ctx, name, cascade)
+}
+
+func (t *tracedStorageActions) DeleteStorages(ctx context.Context, names []string, atomic bool) (ret model.StorageBulkDeleteResponse, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "DeleteStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.DeleteStorages(ctx, names, atomic)
+}
+
+func (t *tracedStorageActions) UpdateStorages(ctx context.Context, updates []model.StorageBulkUpdateEntry, atomic bool) (ret model.StorageBulkUpdateResponse, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "UpdateStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.UpdateStorages(ctx, updates, atomic)
+}
+
+func (t *tracedStorageActions) ApplyStorages(ctx context.Context, entries []model.StorageImportEntry, prune, atomic bool) (ret model.StorageApplyResponse, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "ApplyStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.ApplyStorages(ctx, entries, prune, atomic)
+}
+
+func (t *tracedStorageActions) PurgeStorage(ctx context.Context, name string, cascade bool) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "PurgeStorage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.PurgeStorage(ctx, name, cascade)
+}
+
+func (t *tracedStorageActions) Touch(ctx context.Context, name string, reconciled bool) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "Touch", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.Touch(ctx, name, reconciled)
+}
+
+func (t *tracedStorageActions) RestoreStorage(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "RestoreStorage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.RestoreStorage(ctx, name)
+}
+
+func (t *tracedStorageActions) SetDefaultStorage(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "SetDefaultStorage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.SetDefaultStorage(ctx, name)
+}
+
+func (t *tracedStorageActions) SetStoragePinned(ctx context.Context, name string, pinned bool) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "SetStoragePinned", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.SetStoragePinned(ctx, name, pinned)
+}
+
+func (t *tracedStorageActions) GetIdleStorages(ctx context.Context, since time.Duration) (ret []model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetIdleStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetIdleStorages(ctx, since)
+}
+
+func (t *tracedStorageActions) GetOverutilizedStorages(ctx context.Context) (ret []model.Storage, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetOverutilizedStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetOverutilizedStorages(ctx)
+}
+
+func (t *tracedStorageActions) ExportStorages(ctx context.Context, includeDeleted bool) (ret []model.StorageImportEntry, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "ExportStorages", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.ExportStorages(ctx, includeDeleted)
+}
+
+func (t *tracedStorageActions) GetStorageAudit(ctx context.Context, name string) (ret []model.StorageAuditRecord, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageAudit", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorageAudit(ctx, name)
+}
+
+func (t *tracedStorageActions) GetStorageSizeHistory(ctx context.Context, name string, filter model.StorageSizeHistoryFilter) (ret []model.StorageSizeRecord, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageSizeHistory", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorageSizeHistory(ctx, name, filter)
+}
+
+func (t *tracedStorageActions) GetStorageForecast(ctx context.Context, name string, window time.Duration) (ret model.StorageCapacityForecast, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageForecast", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorageForecast(ctx, name, window)
+}
+
+func (t *tracedStorageActions) GetStorageVolumes(ctx context.Context, name string) (ret kubeClientModel.VolumesList, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageVolumes", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorageVolumes(ctx, name)
+}
+
+func (t *tracedStorageActions) GetStorageQuotas(ctx context.Context, namespaces []string) (ret model.StorageQuotasResponse, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageQuotas", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorageQuotas(ctx, namespaces)
+}
+
+func (t *tracedStorageActions) GetStorageStats(ctx context.Context) (ret model.StorageStats, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageStats", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorageStats(ctx)
+}
+
+func (t *tracedStorageActions) RecomputeUsage(ctx context.Context, name string) (ret []model.StorageUsageRecompute, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "RecomputeUsage", name)
+	defer func() { endSpan(span, err) }()
+	return t.acts.RecomputeUsage(ctx, name)
+}
+
+func (t *tracedStorageActions) SyncStorages(ctx context.Context, apply bool) (ret model.StorageSyncReport, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "SyncStorages", "")
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(tracing.String("sync.apply", strconv.FormatBool(apply)))
+	return t.acts.SyncStorages(ctx, apply)
+}
+
+func (t *tracedStorageActions) CreateStorageWebhook(ctx context.Context, req model.CreateStorageWebhookRequest) (ret model.StorageWebhook, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "CreateStorageWebhook", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.CreateStorageWebhook(ctx, req)
+}
+
+func (t *tracedStorageActions) GetStorageWebhooks(ctx context.Context) (ret []model.StorageWebhook, err error) {
+	ctx, span := startSpan(ctx, t.tracer, "GetStorageWebhooks", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.GetStorageWebhooks(ctx)
+}
+
+func (t *tracedStorageActions) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, t.tracer, "Ping", "")
+	defer func() { endSpan(span, err) }()
+	return t.acts.Ping(ctx)
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultTrashPurgeInterval = time.Hour
+	DefaultTrashRetention     = 30 * 24 * time.Hour
+)
+
+// trashCollector periodically purges storages which are soft-deleted longer than retention period.
+// Storages still referenced by volumes are kept until volumes are removed, pinned storages are never purged.
+type trashCollector struct {
+	srv       *Server
+	log       *cherrylog.LogrusAdapter
+	interval  time.Duration
+	retention time.Duration
+
+	// running is set while collection is in progress, overlapping collections are skipped
+	running int32
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newTrashCollector(srv *Server, log *cherrylog.LogrusAdapter, interval, retention time.Duration) *trashCollector {
+	c := &trashCollector{
+		srv:       srv,
+		log:       log,
+		interval:  interval,
+		retention: retention,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *trashCollector) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.collect(context.Background())
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// collect purges expired storages and returns names of purged ones. It returns immediately if other collection is running.
+func (c *trashCollector) collect(ctx context.Context) []string {
+	if !atomic.CompareAndSwapInt32(&c.running, 0, 1) {
+		c.log.Debugf("trash collection is already running, skipped")
+		return nil
+	}
+	defer atomic.StoreInt32(&c.running, 0)
+
+	deletedBefore := time.Now().Add(-c.retention)
+	storages, err := c.srv.db.AllStorages(ctx, database.StorageFilter{DeletedBefore: &deletedBefore, Unpinned: true})
+	if err != nil {
+		c.log.WithError(err).Warnf("expired storages listing failed")
+		return nil
+	}
+
+	var purged []string
+	for _, storage := range storages {
+		entry := c.log.WithFields(logrus.Fields{
+			"name":        storage.Name,
+			"delete_time": storage.DeleteTime,
+		})
+		err := c.srv.purgeExpiredStorage(ctx, storage.Name, deletedBefore)
+		switch {
+		case err == nil:
+			entry.Infof("expired storage purged")
+			purged = append(purged, storage.Name)
+		case cherry.Equals(err, errors.ErrStorageHasVolumes()):
+			entry.WithError(err).Warnf("expired storage is kept because it has volumes")
+		case cherry.Equals(err, errors.ErrStoragePinned()):
+			entry.Debugf("expired storage was pinned concurrently")
+		case cherry.Equals(err, errors.ErrResourceNotExists()):
+			entry.Debugf("expired storage was restored or purged concurrently")
+		default:
+			entry.WithError(err).Errorf("expired storage purge failed")
+		}
+	}
+	return purged
+}
+
+// Close stops trash collection.
+func (c *trashCollector) Close() error {
+	close(c.stop)
+	<-c.done
+	return nil
+}
+
+// purgeExpiredStorage permanently removes storage if it is still in trash since before deletedBefore.
+func (s *Server) purgeExpiredStorage(ctx context.Context, name string, deletedBefore time.Time) error {
+	defer s.locks.lock(name)()
+
+	err := s.transactional(ctx, "purge_expired", func(tx database.DB) error {
+		// storage could be restored or purged by other request or service instance after listing
+		storages, err := tx.AllStorages(ctx, database.StorageFilter{DeletedBefore: &deletedBefore, NamePrefix: name})
+		if err != nil {
+			return err
+		}
+		for _, storage := range storages {
+			if storage.Name == name {
+				return tx.PurgeStorage(ctx, name)
+			}
+		}
+		return errors.ErrResourceNotExists().AddDetailF("storage %s is not in trash", name)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, model.AuditPurge, name, nil, nil)
+	s.publishStorageEvent(ctx, events.StorageDeleted, name)
+	return nil
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/errors"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// trashDB keeps storages in memory and counts purges. Purge is slowed down, so concurrent collections overlap.
+type trashDB struct {
+	database.DB
+
+	mu       sync.Mutex
+	storages map[string]model.Storage
+	// volumes contains names of storages which have volumes
+	volumes map[string]bool
+	purges  map[string]int
+}
+
+func (db *trashDB) Transactional(ctx context.Context, fn func(tx database.DB) error) error {
+	return fn(db)
+}
+
+func (db *trashDB) AllStorages(ctx context.Context, filter database.StorageFilter) ([]model.Storage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var ret []model.Storage
+	for _, storage := range db.storages {
+		if !strings.HasPrefix(storage.Name, filter.NamePrefix) {
+			continue
+		}
+		if filter.Unpinned && storage.Pinned {
+			continue
+		}
+		if storage.Deleted && storage.DeleteTime.Before(*filter.DeletedBefore) {
+			ret = append(ret, storage)
+		}
+	}
+	return ret, nil
+}
+
+func (db *trashDB) PurgeStorage(ctx context.Context, name string) error {
+	time.Sleep(5 * time.Millisecond)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.storages[name].Pinned {
+		return errors.ErrStoragePinned().AddDetailF("storage %s is pinned and can't be purged", name)
+	}
+	if db.volumes[name] {
+		return errors.ErrStorageHasVolumes().AddDetailF("storage %s is used by volumes", name)
+	}
+	db.purges[name]++
+	delete(db.storages, name)
+	return nil
+}
+
+func (db *trashDB) CreateStorageAuditRecords(ctx context.Context, records []model.StorageAuditRecord) error {
+	return nil
+}
+
+func TestTrashCollector(t *testing.T) {
+	Convey("Test expired storages purge", t, func() {
+		deletedAt := func(ago time.Duration) *time.Time {
+			ret := time.Now().Add(-ago)
+			return &ret
+		}
+		db := &trashDB{
+			storages: map[string]model.Storage{
+				"expired":       {Name: "expired", Deleted: true, DeleteTime: deletedAt(48 * time.Hour)},
+				"expired-bound": {Name: "expired-bound", Deleted: true, DeleteTime: deletedAt(48 * time.Hour)},
+				"expired-pin":   {Name: "expired-pin", Deleted: true, DeleteTime: deletedAt(48 * time.Hour), Pinned: true},
+				"recent":        {Name: "recent", Deleted: true, DeleteTime: deletedAt(time.Hour)},
+				"active":        {Name: "active"},
+			},
+			volumes: map[string]bool{"expired-bound": true},
+			purges:  make(map[string]int),
+		}
+		srv := NewServer(db, &Clients{}, nil, Config{})
+		defer srv.Close()
+		collector := &trashCollector{
+			srv:       srv,
+			log:       cherrylog.NewLogrusAdapter(logrus.WithField("test", "trash")),
+			retention: 24 * time.Hour,
+		}
+
+		var wg sync.WaitGroup
+		purged := make([][]string, 3)
+		for i := range purged {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				purged[i] = collector.collect(context.Background())
+			}(i)
+		}
+		wg.Wait()
+
+		So(append(append(purged[0], purged[1]...), purged[2]...), ShouldResemble, []string{"expired"})
+		So(db.purges, ShouldResemble, map[string]int{"expired": 1})
+		So(db.storages, ShouldContainKey, "expired-bound")
+		So(db.storages, ShouldContainKey, "expired-pin")
+		So(db.storages, ShouldContainKey, "recent")
+		So(db.storages, ShouldContainKey, "active")
+
+		So(collector.collect(context.Background()), ShouldBeEmpty)
+
+		Convey("Check storage pinned after listing is kept", func() {
+			So(srv.purgeExpiredStorage(context.Background(), "expired-pin", time.Now()), ShouldNotBeNil)
+			So(db.storages, ShouldContainKey, "expired-pin")
+		})
+	})
+}
@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"time"
 
 	"git.containerum.net/ch/volume-manager/pkg/clients"
 	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+	"git.containerum.net/ch/volume-manager/pkg/tracing"
 	"github.com/containerum/cherry/adaptors/cherrylog"
 	"github.com/sirupsen/logrus"
 )
@@ -14,6 +18,8 @@ import (
 type Clients struct {
 	Billing clients.BillingClient
 	KubeAPI clients.KubeAPIClient
+	// Provisioner is used to sync storages with backend, sync is not available if not set
+	Provisioner clients.ProvisionerClient
 }
 
 func (c *Clients) Close() error {
@@ -33,16 +39,138 @@ func (c *Clients) Close() error {
 	return nil
 }
 
+type Config struct {
+	// RetryMaxAttempts is a maximal number of attempts to run transaction failed with transient error
+	RetryMaxAttempts int
+	// RetryBaseDelay is a delay before first retry, it doubles with each next attempt
+	RetryBaseDelay time.Duration
+	// UsageCheckInterval is an interval of storages usage checks, zero disables checks
+	UsageCheckInterval time.Duration
+	// MinStorageSize and MaxStorageSize limits sizes of created and updated storages (GiB), defaults are used if not set
+	MinStorageSize int
+	MaxStorageSize int
+	// StorageSizeGranularity is an allocation unit of storages (GiB), sizes must be multiples of it.
+	// DefaultStorageSizeGranularity (no constraint) is used if not set.
+	StorageSizeGranularity int
+	// StorageQuotas limits total size of users and namespaces storages, tenants without quota are not limited
+	StorageQuotas []model.StorageQuota
+	// RequiredLabels are label keys which created storages must have with non-empty values.
+	// Updates of labels can't remove them, updates not touching labels are allowed for storages without them.
+	RequiredLabels []string
+	// TracerProvider is used to trace database transactions, spans are not recorded if not set
+	TracerProvider tracing.TracerProvider
+	// StreamBatchSize is a number of storages fetched by one query while streaming storages list, default is used if not set
+	StreamBatchSize int
+	// TrashPurgeInterval is an interval of permanent removal of storages deleted more than TrashRetention ago,
+	// zero disables removal
+	TrashPurgeInterval time.Duration
+	// TrashRetention is a time soft-deleted storages are kept in trash, default is used if not set
+	TrashRetention time.Duration
+	// TerminationCheckInterval is an interval of terminating storages deletion attempts, zero disables deletion
+	TerminationCheckInterval time.Duration
+	// TerminationTimeout is a time after which terminating storage with volumes gets termination status, default is used if not set
+	TerminationTimeout time.Duration
+	// ReservedStorageNames reports names which can't be used by created and renamed storages,
+	// e.g. fixed path segments of HTTP API. No names are reserved if not set.
+	ReservedStorageNames func(name string) bool
+	// ValidationHooks are custom checks of created and updated storages, they run after DefaultValidationHooks
+	ValidationHooks []ValidationHook
+}
+
+// DefaultStreamBatchSize is a default number of storages fetched by one query while streaming storages list
+const DefaultStreamBatchSize = 500
+
 type Server struct {
+	cfg     Config
 	clients *Clients
 	db      database.DB
+	events  events.Publisher
 	log     *cherrylog.LogrusAdapter
+	tracer  tracing.Tracer
+	quotas  storageQuotas
+	locks   *storageLocks
+
+	validationHooks []ValidationHook
+
+	auditWriter           *auditWriter
+	usageReconciler       *usageReconciler
+	trashCollector        *trashCollector
+	terminationReconciler *terminationReconciler
 }
 
-func NewServer(db database.DB, clients *Clients) *Server {
-	return &Server{
-		db:      db,
-		log:     cherrylog.NewLogrusAdapter(logrus.WithField("component", "volume_manager")),
-		clients: clients,
+// NewServer creates server. If publisher is nil storage events are dropped.
+func NewServer(db database.DB, clients *Clients, publisher events.Publisher, cfg Config) *Server {
+	if publisher == nil {
+		publisher = events.NopPublisher{}
+	}
+	if cfg.RetryMaxAttempts < 1 {
+		cfg.RetryMaxAttempts = 1
+	}
+	if cfg.MinStorageSize <= 0 {
+		cfg.MinStorageSize = DefaultMinStorageSize
+	}
+	if cfg.MaxStorageSize <= 0 {
+		cfg.MaxStorageSize = DefaultMaxStorageSize
+	}
+	if cfg.StorageSizeGranularity <= 0 {
+		cfg.StorageSizeGranularity = DefaultStorageSizeGranularity
+	}
+	if cfg.StreamBatchSize <= 0 {
+		cfg.StreamBatchSize = DefaultStreamBatchSize
+	}
+	if cfg.TrashRetention <= 0 {
+		cfg.TrashRetention = DefaultTrashRetention
+	}
+	if cfg.TerminationTimeout <= 0 {
+		cfg.TerminationTimeout = DefaultTerminationTimeout
+	}
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = tracing.NopTracerProvider{}
+	}
+	log := cherrylog.NewLogrusAdapter(logrus.WithField("component", "volume_manager"))
+	s := &Server{
+		cfg:         cfg,
+		db:          db,
+		log:         log,
+		tracer:      cfg.TracerProvider.Tracer("server"),
+		quotas:      newStorageQuotas(cfg.StorageQuotas),
+		clients:     clients,
+		events:      publisher,
+		auditWriter: newAuditWriter(db, cherrylog.NewLogrusAdapter(log.WithField("subcomponent", "audit"))),
+		locks:       newStorageLocks(),
+
+		validationHooks: append(DefaultValidationHooks(cfg), cfg.ValidationHooks...),
+	}
+	if cfg.UsageCheckInterval > 0 {
+		s.usageReconciler = newUsageReconciler(db, publisher,
+			cherrylog.NewLogrusAdapter(log.WithField("subcomponent", "usage")), cfg.UsageCheckInterval)
+	}
+	if cfg.TrashPurgeInterval > 0 {
+		s.trashCollector = newTrashCollector(s,
+			cherrylog.NewLogrusAdapter(log.WithField("subcomponent", "trash")), cfg.TrashPurgeInterval, cfg.TrashRetention)
+	}
+	if cfg.TerminationCheckInterval > 0 {
+		s.terminationReconciler = newTerminationReconciler(s,
+			cherrylog.NewLogrusAdapter(log.WithField("subcomponent", "termination")), cfg.TerminationCheckInterval, cfg.TerminationTimeout)
+	}
+	return s
+}
+
+// FlushAudit waits until audit records of finished operations are written. Audit records are written asynchronously.
+func (s *Server) FlushAudit() {
+	s.auditWriter.Flush()
+}
+
+// Close stops background storages usage checks, trash removal and termination and flushes pending audit records.
+func (s *Server) Close() error {
+	if s.usageReconciler != nil {
+		s.usageReconciler.Close()
+	}
+	if s.trashCollector != nil {
+		s.trashCollector.Close()
+	}
+	if s.terminationReconciler != nil {
+		s.terminationReconciler.Close()
 	}
+	return s.auditWriter.Close()
 }
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultUsageCheckInterval = time.Minute
+
+// usageReconciler periodically checks storages usage and warns when storage reaches its threshold.
+// Warning is emitted once when storage crosses threshold and again only after usage drops below it.
+type usageReconciler struct {
+	db        database.DB
+	publisher events.Publisher
+	log       *cherrylog.LogrusAdapter
+	interval  time.Duration
+
+	// names of storages already alerted, accessed only from run goroutine
+	alerted map[string]bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newUsageReconciler(db database.DB, publisher events.Publisher, log *cherrylog.LogrusAdapter, interval time.Duration) *usageReconciler {
+	r := &usageReconciler{
+		db:        db,
+		publisher: publisher,
+		log:       log,
+		interval:  interval,
+		alerted:   make(map[string]bool),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *usageReconciler) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.check(context.Background())
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *usageReconciler) check(ctx context.Context) {
+	storages, err := r.db.AllStorages(ctx, database.StorageFilter{})
+	if err != nil {
+		r.log.WithError(err).Warnf("storages usage check failed")
+		return
+	}
+
+	alerted := make(map[string]bool)
+	for _, storage := range storages {
+		entry := r.log.WithFields(logrus.Fields{
+			"name":      storage.Name,
+			"usage":     storage.UsagePercent(),
+			"threshold": storage.WarnThreshold,
+		})
+
+		if !storage.Overutilized() {
+			if r.alerted[storage.Name] {
+				entry.Infof("storage usage dropped below threshold")
+			}
+			continue
+		}
+
+		alerted[storage.Name] = true
+		if r.alerted[storage.Name] {
+			continue
+		}
+		entry.Warnf("storage usage reached threshold")
+		event := events.StorageEvent{
+			Operation: events.StorageUsageWarning,
+			Name:      storage.Name,
+			Timestamp: time.Now().UTC(),
+		}
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			entry.WithError(err).Errorf("publish %s event failed", event.Operation)
+		}
+	}
+	r.alerted = alerted
+}
+
+// Close stops usage checks.
+func (r *usageReconciler) Close() error {
+	close(r.stop)
+	<-r.done
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"git.containerum.net/ch/volume-manager/pkg/errors"
 	"git.containerum.net/ch/volume-manager/pkg/models"
 	billing "github.com/containerum/bill-external/models"
+	"github.com/containerum/cherry"
 	kubeClientModel "github.com/containerum/kube-client/pkg/model"
 	"github.com/containerum/utils/httputil"
 	"github.com/satori/go.uuid"
@@ -40,6 +41,45 @@ const (
 	ZeroUUID = "00000000-0000-0000-0000-000000000000"
 )
 
+// volumeStorage returns storage to place volume on. If storage name is not specified
+// default storage is used, if there is no default storage available in namespace least used storage is chosen.
+// Read-only, terminating storages, storages under maintenance and storages not available in namespace are rejected.
+func (s *Server) volumeStorage(ctx context.Context, nsID, name string, volumeSize int) (storage model.Storage, err error) {
+	if name != "" {
+		storage, err = s.db.StorageByName(ctx, name)
+	} else {
+		storage, err = s.db.DefaultStorage(ctx)
+		switch {
+		case err != nil:
+		case !storage.AvailableIn(nsID):
+			err = errors.ErrResourceNotExists().AddDetailF("default storage is not available in namespace %s", nsID)
+		case storage.Maintenance:
+			err = errors.ErrResourceNotExists().AddDetailF("default storage is under maintenance")
+		case storage.Terminating:
+			err = errors.ErrResourceNotExists().AddDetailF("default storage is being deleted")
+		}
+		if cherry.Equals(err, errors.ErrResourceNotExists()) {
+			storage, err = s.db.LeastUsedStorage(ctx, nsID, volumeSize)
+		}
+	}
+	if err != nil {
+		return model.Storage{}, err
+	}
+	if !storage.AvailableIn(nsID) {
+		return model.Storage{}, errors.ErrStorageNamespaceForbidden().AddDetailF("storage %s is not available in namespace %s", storage.Name, nsID)
+	}
+	if storage.ReadOnly {
+		return model.Storage{}, errors.ErrStorageReadOnly().AddDetailF("storage %s does not accept new volumes", storage.Name)
+	}
+	if storage.Maintenance {
+		return model.Storage{}, storage.MaintenanceError()
+	}
+	if storage.Terminating {
+		return model.Storage{}, storage.TerminatingError()
+	}
+	return storage, nil
+}
+
 func (s *Server) DirectCreateVolume(ctx context.Context, nsID string, req model.DirectVolumeCreateRequest) error {
 	userID := httputil.MustGetUserID(ctx)
 	s.log.WithFields(logrus.Fields{
@@ -49,22 +89,13 @@ func (s *Server) DirectCreateVolume(ctx context.Context, nsID string, req model.
 		"user_id":  userID,
 	}).Infof("create volume")
 
-	var storage model.Storage
-	var err error
-	if req.Storage != "" {
-		storage, err = s.db.StorageByName(ctx, req.Storage)
-		if err != nil {
-			return err
-		}
-	} else {
-		storage, err = s.db.LeastUsedStorage(ctx, req.Capacity)
-		if err != nil {
-			return err
-		}
+	storage, err := s.volumeStorage(ctx, nsID, req.Storage, req.Capacity)
+	if err != nil {
+		return err
 	}
 
-	if storage.Size-storage.Used-req.Capacity < 0 {
-		return errors.ErrNoFreeStorages()
+	if storage.Capacity()-storage.Used-req.Capacity < 0 {
+		return errors.ErrStorageOvercommitted().AddDetailF("storage %s has no space for volume", storage.Name)
 	}
 
 	volume := model.Volume{
@@ -75,9 +106,10 @@ func (s *Server) DirectCreateVolume(ctx context.Context, nsID string, req model.
 		Capacity:    req.Capacity,
 		NamespaceID: nsID,
 		StorageName: storage.Name,
+		AccessMode:  storage.VolumeAccessMode(),
 	}
 
-	return s.db.Transactional(func(tx database.DB) error {
+	return s.db.Transactional(ctx, func(tx database.DB) error {
 		if createErr := tx.CreateVolume(ctx, &volume); createErr != nil {
 			return createErr
 		}
@@ -99,15 +131,24 @@ func (s *Server) ImportVolume(ctx context.Context, nsID string, req kubeClientMo
 		"label":    req.Name,
 	}).Infof("import volume")
 
-	err := s.db.Transactional(func(tx database.DB) error {
+	err := s.db.Transactional(ctx, func(tx database.DB) error {
 		storage, getErr := tx.StorageByName(ctx, req.StorageName)
 		if getErr != nil {
 			return getErr
 		}
+		if !storage.AvailableIn(nsID) {
+			return errors.ErrStorageNamespaceForbidden().AddDetailF("storage %s is not available in namespace %s", storage.Name, nsID)
+		}
+		if !storage.SupportsAccessMode(req.AccessMode) {
+			return errors.ErrRequestValidationFailed().AddDetailF("storage %s (%s) does not support %s volumes", storage.Name, storage.Driver, req.AccessMode)
+		}
 
 		if req.Owner == "" {
 			req.Owner = ZeroUUID
 		}
+		if req.AccessMode == "" {
+			req.AccessMode = storage.VolumeAccessMode()
+		}
 
 		volume := model.Volume{
 			Resource: model.Resource{
@@ -117,6 +158,7 @@ func (s *Server) ImportVolume(ctx context.Context, nsID string, req kubeClientMo
 			Capacity:    int(req.Capacity),
 			NamespaceID: nsID,
 			StorageName: storage.Name,
+			AccessMode:  req.AccessMode,
 		}
 
 		if createErr := tx.CreateVolume(ctx, &volume); createErr != nil {
@@ -163,25 +205,17 @@ func (s *Server) CreateVolume(ctx context.Context, nsID string, req model.Volume
 		volumeSize = nsTariff.VolumeSize
 	}
 
-	var storage model.Storage
-	if req.Storage != "" {
-		storage, err = s.db.StorageByName(ctx, req.Storage)
-		if err != nil {
-			return err
-		}
-	} else {
-		storage, err = s.db.LeastUsedStorage(ctx, volumeSize)
-		if err != nil {
-			return err
-		}
+	storage, err := s.volumeStorage(ctx, nsID, req.Storage, volumeSize)
+	if err != nil {
+		return err
 	}
 
 	if volumeSize == 0 {
 		return errors.ErrQuotaExceeded()
 	}
 
-	if storage.Size-storage.Used-volumeSize < 0 {
-		return errors.ErrNoFreeStorages()
+	if storage.Capacity()-storage.Used-volumeSize < 0 {
+		return errors.ErrStorageOvercommitted().AddDetailF("storage %s has no space for volume", storage.Name)
 	}
 
 	volume := model.Volume{
@@ -194,6 +228,7 @@ func (s *Server) CreateVolume(ctx context.Context, nsID string, req model.Volume
 		Capacity:    volumeSize,
 		NamespaceID: nsID,
 		StorageName: storage.Name,
+		AccessMode:  storage.VolumeAccessMode(),
 	}
 
 	if !freeVolume {
@@ -208,7 +243,7 @@ func (s *Server) CreateVolume(ctx context.Context, nsID string, req model.Volume
 		}
 	}
 
-	return s.db.Transactional(func(tx database.DB) error {
+	return s.db.Transactional(ctx, func(tx database.DB) error {
 		if createErr := tx.CreateVolume(ctx, &volume); createErr != nil {
 			return createErr
 		}
@@ -310,7 +345,7 @@ func (s *Server) DeleteVolume(ctx context.Context, nsID, label string) error {
 		"label":   label,
 	}).Infof("delete volume")
 
-	err := s.db.Transactional(func(tx database.DB) error {
+	err := s.db.Transactional(ctx, func(tx database.DB) error {
 		vol, getErr := tx.VolumeByLabel(ctx, nsID, label)
 		if getErr != nil {
 			return getErr
@@ -339,7 +374,7 @@ func (s *Server) DeleteAllUserVolumes(ctx context.Context) error {
 	userID := httputil.MustGetUserID(ctx)
 	s.log.WithField("user_id", userID).Infof("delete all user volumes")
 
-	err := s.db.Transactional(func(tx database.DB) error {
+	err := s.db.Transactional(ctx, func(tx database.DB) error {
 		vols, err := s.db.UserVolumes(ctx, userID)
 		switch {
 		case err == nil:
@@ -375,7 +410,7 @@ func (s *Server) DeleteAllNamespaceVolumes(ctx context.Context, nsID string) err
 		"namespace_id": nsID,
 	}).Infof("delete all user volumes")
 
-	err := s.db.Transactional(func(tx database.DB) error {
+	err := s.db.Transactional(ctx, func(tx database.DB) error {
 		vols, err := s.db.NamespaceVolumes(ctx, nsID)
 		switch {
 		case err == nil:
@@ -413,7 +448,7 @@ func (s *Server) AdminResizeVolume(ctx context.Context, nsID, label string, newC
 		"new_capacity": newCapacity,
 	}).Infof("resize volume")
 
-	err := s.db.Transactional(func(tx database.DB) error {
+	err := s.db.Transactional(ctx, func(tx database.DB) error {
 		vol, getErr := tx.VolumeByLabel(ctx, nsID, label)
 		if getErr != nil {
 			return getErr
@@ -460,7 +495,7 @@ func (s *Server) ResizeVolume(ctx context.Context, nsID, label string, newTariff
 		return chkErr
 	}
 
-	err = s.db.Transactional(func(tx database.DB) error {
+	err = s.db.Transactional(ctx, func(tx database.DB) error {
 		vol, getErr := tx.VolumeByLabel(ctx, nsID, label)
 		if getErr != nil {
 			return getErr
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"git.containerum.net/ch/volume-manager/pkg/database"
+	"git.containerum.net/ch/volume-manager/pkg/events"
+	"git.containerum.net/ch/volume-manager/pkg/models"
+)
+
+const webhookSecretLength = 32
+
+// CreateStorageWebhook registers webhook. Returned webhook contains its secret.
+func (s *Server) CreateStorageWebhook(ctx context.Context, req model.CreateStorageWebhookRequest) (model.StorageWebhook, error) {
+	s.log.WithField("url", req.URL).Infof("create storage webhook")
+
+	webhook := model.StorageWebhook{
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: req.Secret,
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, webhookSecretLength)
+		if _, err := rand.Read(secret); err != nil {
+			return model.StorageWebhook{}, err
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	err := s.transactional(ctx, "create_webhook", func(tx database.DB) error {
+		return tx.CreateStorageWebhook(ctx, &webhook)
+	})
+	return webhook, err
+}
+
+// GetStorageWebhooks returns registered webhooks without secrets.
+func (s *Server) GetStorageWebhooks(ctx context.Context) ([]model.StorageWebhook, error) {
+	s.log.Infof("get storage webhooks")
+
+	ret, err := s.db.StorageWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ret {
+		ret[i].Secret = ""
+	}
+	return ret, nil
+}
+
+// WebhookSource returns source of webhooks stored in db for events.WebhookSink.
+func WebhookSource(db database.DB) events.WebhookSource {
+	return func(ctx context.Context) ([]events.Webhook, error) {
+		webhooks, err := db.StorageWebhooks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]events.Webhook, 0, len(webhooks))
+		for _, webhook := range webhooks {
+			operations := make([]events.Operation, 0, len(webhook.Events))
+			for _, event := range webhook.Events {
+				operations = append(operations, events.Operation(event))
+			}
+			ret = append(ret, events.Webhook{
+				ID:         webhook.ID,
+				URL:        webhook.URL,
+				Secret:     webhook.Secret,
+				Operations: operations,
+			})
+		}
+		return ret, nil
+	}
+}
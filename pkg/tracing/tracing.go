@@ -0,0 +1,334 @@
+// Package tracing contains minimal OpenTelemetry-compatible tracing API: spans with W3C trace context
+// propagation (traceparent header) and pluggable exporters for finished spans.
+//
+// TracerProvider is an extension point: NopTracerProvider is used if tracing is not configured,
+// NewTracerProvider records spans and passes them to exporter (e.g. adaptor to OpenTelemetry collector).
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Common span attribute keys
+const (
+	AttributeStorageName = "storage.name"
+	AttributeOperation   = "operation"
+	AttributeOutcome     = "outcome"
+	AttributeError       = "error"
+)
+
+// Span outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// TraceParentHeader is a W3C trace context header.
+const TraceParentHeader = "traceparent"
+
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext identifies span in trace.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Attribute is a key-value pair attached to span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span represents single operation in trace. Span must be ended exactly once.
+type Span interface {
+	SpanContext() SpanContext
+	SetName(name string)
+	SetAttributes(attrs ...Attribute)
+	// RecordError marks span as failed. Nil errors are ignored.
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. Span is a child of span stored in context (local or remote).
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// TracerProvider provides named tracers.
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns context with span context used as parent of started spans.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns span context stored in context or invalid span context.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// ParseTraceParent parses W3C traceparent header value ("00-<trace id>-<span id>-<flags>").
+func ParseTraceParent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+	sc := SpanContext{Remote: true}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent flags %q", parts[3])
+	}
+	if n, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || n != len(sc.TraceID) || len(parts[1]) != 2*len(sc.TraceID) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent trace id %q", parts[1])
+	}
+	if n, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || n != len(sc.SpanID) || len(parts[2]) != 2*len(sc.SpanID) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent span id %q", parts[2])
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: zero ids", value)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// FormatTraceParent formats span context as W3C traceparent header value.
+func FormatTraceParent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Extract returns context with remote span context from request headers. Invalid headers are ignored.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceParent(header.Get(TraceParentHeader))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// Inject sets trace context headers for span stored in context.
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(TraceParentHeader, FormatTraceParent(sc))
+	}
+}
+
+// NopTracerProvider provides tracers which don't record spans. Trace context is still propagated.
+type NopTracerProvider struct{}
+
+func (NopTracerProvider) Tracer(name string) Tracer { return nopTracer{} }
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := nopSpan{sc: SpanContextFromContext(ctx)}
+	return ctx, span
+}
+
+type nopSpan struct {
+	sc SpanContext
+}
+
+func (s nopSpan) SpanContext() SpanContext       { return s.sc }
+func (nopSpan) SetName(name string)              {}
+func (nopSpan) SetAttributes(attrs ...Attribute) {}
+func (nopSpan) RecordError(err error)            {}
+func (nopSpan) End()                             {}
+
+// SpanData is a finished span passed to exporter.
+type SpanData struct {
+	Tracer     string
+	Name       string
+	Context    SpanContext
+	Parent     SpanContext
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      error
+}
+
+// Exporter delivers finished spans to tracing backend. It must be safe for concurrent use.
+type Exporter interface {
+	ExportSpan(span SpanData)
+}
+
+type recordingProvider struct {
+	exporter Exporter
+}
+
+// NewTracerProvider creates provider which records all spans and passes them to exporter when ended.
+func NewTracerProvider(exporter Exporter) TracerProvider {
+	return recordingProvider{exporter: exporter}
+}
+
+func (p recordingProvider) Tracer(name string) Tracer {
+	return recordingTracer{name: name, exporter: p.exporter}
+}
+
+type recordingTracer struct {
+	name     string
+	exporter Exporter
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: true}
+	if !parent.IsValid() {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+
+	span := &recordingSpan{
+		exporter: t.exporter,
+		data: SpanData{
+			Tracer:     t.name,
+			Name:       name,
+			Context:    sc,
+			Parent:     parent,
+			Start:      time.Now(),
+			Attributes: make(map[string]string, len(attrs)),
+		},
+	}
+	span.SetAttributes(attrs...)
+	return ContextWithSpanContext(ctx, sc), span
+}
+
+type recordingSpan struct {
+	exporter Exporter
+
+	mu    sync.Mutex
+	ended bool
+	data  SpanData
+}
+
+func (s *recordingSpan) SpanContext() SpanContext { return s.data.Context }
+
+func (s *recordingSpan) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attr := range attrs {
+		s.data.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err
+	s.data.Attributes[AttributeError] = err.Error()
+}
+
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = make(map[string]string, len(s.data.Attributes))
+	for k, v := range s.data.Attributes {
+		data.Attributes[k] = v
+	}
+	s.mu.Unlock()
+
+	s.exporter.ExportSpan(data)
+}
+
+// LogExporter writes finished spans to log.
+type LogExporter struct {
+	Entry *logrus.Entry
+}
+
+func (e LogExporter) ExportSpan(span SpanData) {
+	fields := logrus.Fields{
+		"trace_id": span.Context.TraceID.String(),
+		"span_id":  span.Context.SpanID.String(),
+		"duration": span.End.Sub(span.Start),
+	}
+	if span.Parent.IsValid() {
+		fields["parent_span_id"] = span.Parent.SpanID.String()
+	}
+	for k, v := range span.Attributes {
+		fields[k] = v
+	}
+	e.Entry.WithFields(fields).Debugf("span %s", span.Name)
+}
+
+// MemoryExporter keeps exported spans in memory. Useful for tests.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (e *MemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns copy of exported spans in order of ending.
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset drops exported spans.
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTracing(t *testing.T) {
+	Convey("Test tracing", t, func() {
+		Convey("Check traceparent parsing", func() {
+			sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			So(err, ShouldBeNil)
+			So(sc.TraceID.String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(sc.SpanID.String(), ShouldEqual, "00f067aa0ba902b7")
+			So(sc.Sampled, ShouldBeTrue)
+			So(sc.Remote, ShouldBeTrue)
+			So(FormatTraceParent(sc), ShouldEqual, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+			for _, value := range []string{
+				"",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+				"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+				"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			} {
+				_, err := ParseTraceParent(value)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Check spans are linked to parents", func() {
+			exporter := NewMemoryExporter()
+			tracer := NewTracerProvider(exporter).Tracer("test")
+
+			header := http.Header{}
+			header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			ctx := Extract(context.Background(), header)
+
+			ctx, parent := tracer.Start(ctx, "parent")
+			_, child := tracer.Start(ctx, "child", String(AttributeStorageName, "storage"))
+			child.RecordError(fmt.Errorf("failed"))
+			child.End()
+			parent.End()
+			parent.End()
+
+			spans := exporter.Spans()
+			So(spans, ShouldHaveLength, 2)
+			So(spans[0].Name, ShouldEqual, "child")
+			So(spans[0].Parent, ShouldResemble, parent.SpanContext())
+			So(spans[0].Attributes, ShouldContainKey, AttributeError)
+			So(spans[0].Attributes[AttributeStorageName], ShouldEqual, "storage")
+			So(spans[1].Parent.SpanID.String(), ShouldEqual, "00f067aa0ba902b7")
+			So(spans[1].Context.TraceID, ShouldResemble, spans[0].Context.TraceID)
+
+			injected := http.Header{}
+			Inject(ctx, injected)
+			So(injected.Get(TraceParentHeader), ShouldEqual, FormatTraceParent(parent.SpanContext()))
+		})
+	})
+}
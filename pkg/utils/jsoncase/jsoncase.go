@@ -0,0 +1,150 @@
+// Package jsoncase converts JSON object keys produced from struct fields between canonical names
+// (from "json" struct tags) and camelCase. Conversion is guided by Go type of value, so only keys of struct fields
+// are converted, while keys of maps (e.g. labels) are kept as is.
+package jsoncase
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ToCamel converts snake_case name to camelCase, e.g. "used_size" to "usedSize".
+func ToCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// ToSnake converts camelCase name to snake_case, e.g. "usedSize" to "used_size". Lowercase names are not changed.
+func ToSnake(name string) string {
+	var ret strings.Builder
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			ret.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		ret.WriteRune(r)
+	}
+	return ret.String()
+}
+
+// Marshal returns JSON encoding of v with camelCase keys of struct fields.
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Camelize(data, reflect.TypeOf(v))
+}
+
+// Camelize converts keys of struct fields in JSON data encoded from value of type t to camelCase.
+func Camelize(data []byte, t reflect.Type) ([]byte, error) {
+	return convert(data, t, func(fields map[string]reflect.Type, key string) (string, reflect.Type, bool) {
+		fieldType, ok := fields[key]
+		return ToCamel(key), fieldType, ok
+	})
+}
+
+// Normalize converts camelCase keys of struct fields in JSON data to be decoded to value of type t to canonical names.
+// Keys already matching canonical names are kept, so data may use both forms.
+func Normalize(data []byte, t reflect.Type) ([]byte, error) {
+	return convert(data, t, func(fields map[string]reflect.Type, key string) (string, reflect.Type, bool) {
+		if fieldType, ok := fields[key]; ok {
+			return key, fieldType, true
+		}
+		for name, fieldType := range fields {
+			if ToCamel(name) == key {
+				return name, fieldType, true
+			}
+		}
+		return key, nil, false
+	})
+}
+
+// renameFunc returns new name and type for struct field key, ok is false if struct has no such field.
+type renameFunc func(fields map[string]reflect.Type, key string) (name string, fieldType reflect.Type, ok bool)
+
+func convert(data []byte, t reflect.Type, rename renameFunc) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(walk(generic, t, rename))
+}
+
+func walk(v interface{}, t reflect.Type, rename renameFunc) interface{} {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := structFields(t)
+			ret := make(map[string]interface{}, len(v))
+			for key, value := range v {
+				name, fieldType, ok := rename(fields, key)
+				if !ok {
+					ret[key] = value
+					continue
+				}
+				ret[name] = walk(value, fieldType, rename)
+			}
+			return ret
+		case reflect.Map:
+			for key, value := range v {
+				v[key] = walk(value, t.Elem(), rename)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i := range v {
+				v[i] = walk(v[i], t.Elem(), rename)
+			}
+		}
+	}
+	return v
+}
+
+// structFields returns types of struct fields by JSON names. Fields of embedded structs without JSON name are included.
+func structFields(t reflect.Type) map[string]reflect.Type {
+	ret := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				for embeddedName, embeddedType := range structFields(fieldType) {
+					if _, ok := ret[embeddedName]; !ok {
+						ret[embeddedName] = embeddedType
+					}
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" || name == "-" { // unexported or ignored
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		ret[name] = field.Type
+	}
+	return ret
+}
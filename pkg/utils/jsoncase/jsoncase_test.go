@@ -0,0 +1,46 @@
+package jsoncase
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type inner struct {
+	FreeSize int `json:"free_size"`
+}
+
+type outer struct {
+	inner
+	UsedSize int               `json:"used_size"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Items    []inner           `json:"items"`
+}
+
+func TestCamelCase(t *testing.T) {
+	Convey("Test camelCase conversion", t, func() {
+		Convey("Check names conversion", func() {
+			So(ToCamel("used_size"), ShouldEqual, "usedSize")
+			So(ToCamel("name"), ShouldEqual, "name")
+			So(ToSnake("usedSize"), ShouldEqual, "used_size")
+			So(ToSnake("used_size"), ShouldEqual, "used_size")
+		})
+		Convey("Check struct fields are converted and map keys are kept", func() {
+			data, err := Marshal(outer{
+				inner:    inner{FreeSize: 1},
+				UsedSize: 2,
+				Labels:   map[string]string{"team_name": "storage"},
+				Items:    []inner{{FreeSize: 3}},
+			})
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"freeSize":1,"items":[{"freeSize":3}],"labels":{"team_name":"storage"},"usedSize":2}`)
+		})
+		Convey("Check both forms are normalized", func() {
+			data, err := Normalize([]byte(`{"freeSize":1,"used_size":2,"labels":{"teamName":"storage"},"items":[{"freeSize":3}]}`),
+				reflect.TypeOf(&outer{}))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"free_size":1,"items":[{"free_size":3}],"labels":{"teamName":"storage"},"used_size":2}`)
+		})
+	})
+}
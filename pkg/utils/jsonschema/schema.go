@@ -0,0 +1,219 @@
+// Package jsonschema generates JSON Schema (draft-07) documents from Go types.
+//
+// Schema is built from "json" and "binding" (validator) struct tags. Constraints which can't be expressed
+// by validator tags (patterns, read-only fields) are attached by "schema" tag with comma-separated names
+// of fragments passed to generator.
+package jsonschema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Draft07 = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a subset of JSON Schema draft-07 used to describe API models.
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	ReadOnly    bool               `json:"readOnly,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+	PropertyNames        *Schema `json:"propertyNames,omitempty"`
+
+	Enum      []string `json:"enum,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+
+	Definitions map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// merge copies non-zero constraints of fragment to schema.
+func (s *Schema) merge(fragment Schema) {
+	if fragment.Format != "" {
+		s.Format = fragment.Format
+	}
+	if fragment.Description != "" {
+		s.Description = fragment.Description
+	}
+	s.ReadOnly = s.ReadOnly || fragment.ReadOnly
+	if fragment.AdditionalProperties != nil {
+		s.AdditionalProperties = fragment.AdditionalProperties
+	}
+	if fragment.PropertyNames != nil {
+		s.PropertyNames = fragment.PropertyNames
+	}
+	if fragment.Enum != nil {
+		s.Enum = fragment.Enum
+	}
+	if fragment.Pattern != "" {
+		s.Pattern = fragment.Pattern
+	}
+	if fragment.MinLength != nil {
+		s.MinLength = fragment.MinLength
+	}
+	if fragment.MaxLength != nil {
+		s.MaxLength = fragment.MaxLength
+	}
+}
+
+// Generator builds schemas of types. Fragments are looked up by names from "schema" struct tags.
+type Generator struct {
+	Fragments map[string]Schema
+}
+
+// Definitions returns schema document with definitions of provided values types named by keys.
+func (g Generator) Definitions(values map[string]interface{}) *Schema {
+	ret := &Schema{
+		Schema:      Draft07,
+		Definitions: make(map[string]*Schema, len(values)),
+	}
+	for name, v := range values {
+		ret.Definitions[name] = g.Reflect(v)
+	}
+	return ret
+}
+
+// Reflect returns schema of value type.
+func (g Generator) Reflect(v interface{}) *Schema {
+	return g.reflectType(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g Generator) reflectType(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.reflectType(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.reflectType(t.Elem(), visiting)}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if visiting[t] { // recursive type
+			return &Schema{Type: "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		ret := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		g.reflectFields(ret, t, visiting)
+		return ret
+	default:
+		return &Schema{}
+	}
+}
+
+func (g Generator) reflectFields(object *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonTag := strings.Split(field.Tag.Get("json"), ",")
+		if field.Anonymous && jsonTag[0] == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				g.reflectFields(object, fieldType, visiting)
+				continue
+			}
+		}
+		if field.PkgPath != "" || jsonTag[0] == "-" { // unexported or ignored
+			continue
+		}
+		name := jsonTag[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := g.reflectType(field.Type, visiting)
+		if applyBinding(prop, field.Tag.Get("binding")) {
+			object.Required = append(object.Required, name)
+		}
+		if tag := field.Tag.Get("schema"); tag != "" {
+			for _, fragment := range strings.Split(tag, ",") {
+				prop.merge(g.Fragments[fragment])
+			}
+		}
+		object.Properties[name] = prop
+	}
+}
+
+// applyBinding converts validator constraints to schema, returns true if field is required.
+// Constraints without schema equivalent are ignored.
+func applyBinding(s *Schema, tag string) (required bool) {
+	if tag == "" || tag == "-" {
+		return false
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "dive" { // following rules are applied to elements
+			break
+		}
+		kv := strings.SplitN(rule, "=", 2)
+		if kv[0] == "required" {
+			required = true
+			continue
+		}
+		if len(kv) < 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil { // field comparisons like "gtecsfield=Used"
+			continue
+		}
+		intValue := int(value)
+		switch {
+		case s.Type == "string":
+			switch kv[0] {
+			case "min", "gte":
+				s.MinLength = &intValue
+			case "max", "lte":
+				s.MaxLength = &intValue
+			case "gt":
+				intValue++
+				s.MinLength = &intValue
+			case "lt":
+				intValue--
+				s.MaxLength = &intValue
+			}
+		case s.Type == "integer" || s.Type == "number":
+			switch kv[0] {
+			case "min", "gte":
+				s.Minimum = &value
+			case "max", "lte":
+				s.Maximum = &value
+			case "gt":
+				s.ExclusiveMinimum = &value
+			case "lt":
+				s.ExclusiveMaximum = &value
+			}
+		}
+	}
+	return required
+}
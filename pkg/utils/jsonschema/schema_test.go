@@ -0,0 +1,64 @@
+package jsonschema
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testNested struct {
+	Value string `json:"value"`
+}
+
+type testEmbedded struct {
+	ID string `json:"id"`
+}
+
+type testModel struct {
+	hidden struct{}
+
+	testEmbedded
+
+	Name    string            `json:"name" binding:"required" schema:"name"`
+	Size    int               `json:"size" binding:"gt=0,lte=100"`
+	Ratio   *float64          `json:"ratio,omitempty" binding:"omitempty,gte=0.5"`
+	Other   int               `json:"other" binding:"gtecsfield=Size"`
+	Labels  map[string]string `json:"labels"`
+	Nested  []*testNested     `json:"nested"`
+	Created time.Time         `json:"created" schema:"read_only"`
+	Skipped string            `json:"-"`
+}
+
+func TestReflect(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+	g := Generator{Fragments: map[string]Schema{
+		"name":      {Pattern: "^[a-z]+$"},
+		"read_only": {ReadOnly: true},
+	}}
+
+	Convey("Test schema generation", t, func() {
+		s := g.Reflect(testModel{})
+		So(s.Type, ShouldEqual, "object")
+		So(s.Required, ShouldResemble, []string{"name"})
+		So(s.Properties, ShouldHaveLength, 8)
+
+		Convey("Check embedded struct fields are inlined", func() {
+			So(s.Properties["id"], ShouldResemble, &Schema{Type: "string"})
+		})
+		Convey("Check binding constraints", func() {
+			So(s.Properties["size"], ShouldResemble, &Schema{Type: "integer", ExclusiveMinimum: ptr(0), Maximum: ptr(100)})
+			So(s.Properties["ratio"], ShouldResemble, &Schema{Type: "number", Minimum: ptr(0.5)})
+			So(s.Properties["other"], ShouldResemble, &Schema{Type: "integer"})
+		})
+		Convey("Check fragments", func() {
+			So(s.Properties["name"], ShouldResemble, &Schema{Type: "string", Pattern: "^[a-z]+$"})
+			So(s.Properties["created"], ShouldResemble, &Schema{Type: "string", Format: "date-time", ReadOnly: true})
+		})
+		Convey("Check containers", func() {
+			So(s.Properties["labels"], ShouldResemble, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}})
+			So(s.Properties["nested"].Type, ShouldEqual, "array")
+			So(s.Properties["nested"].Items.Properties, ShouldContainKey, "value")
+		})
+	})
+}
@@ -0,0 +1,22 @@
+package labels
+
+import "fmt"
+
+// MaxAnnotationsSize is a maximum total length of annotations keys and values.
+// Annotations are not used for selection, so values are not limited individually.
+const MaxAnnotationsSize = 64 * 1024
+
+// ValidateAnnotations checks that annotations keys are valid label keys and total size of annotations is within limit.
+func ValidateAnnotations(annotations map[string]string) error {
+	size := 0
+	for key, value := range annotations {
+		if err := ValidateKey(key); err != nil {
+			return fmt.Errorf("annotation: %v", err)
+		}
+		size += len(key) + len(value)
+	}
+	if size > MaxAnnotationsSize {
+		return fmt.Errorf("total size of annotations is %d bytes, must not exceed %d bytes", size, MaxAnnotationsSize)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package labels
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	Convey("Test annotations validation", t, func() {
+		So(ValidateAnnotations(nil), ShouldBeNil)
+		So(ValidateAnnotations(map[string]string{
+			"description": "Storage for payments team: https://tracker.example.com/PAY-1234 (owner: team@example.com)",
+			"notes":       strings.Repeat("x", 4*MaxValueLength),
+		}), ShouldBeNil)
+		So(ValidateAnnotations(map[string]string{"bad key": "value"}), ShouldNotBeNil)
+		So(ValidateAnnotations(map[string]string{
+			"a": strings.Repeat("x", MaxAnnotationsSize/2),
+			"b": strings.Repeat("x", MaxAnnotationsSize/2),
+		}), ShouldNotBeNil)
+	})
+}
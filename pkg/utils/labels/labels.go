@@ -0,0 +1,72 @@
+// Package labels contains storage labels and annotations validation and Kubernetes-style label selectors.
+package labels
+
+import (
+	"fmt"
+)
+
+const (
+	MaxKeyLength   = 63
+	MaxValueLength = 63
+
+	// KeyPattern and ValuePattern are regular expression equivalents of ValidateKey and ValidateValue checks (without length limits)
+	KeyPattern   = `^[a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	ValuePattern = `^([a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?)?$`
+)
+
+func isLabelChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.'
+}
+
+func isAlphanumeric(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// ValidateKey checks that label key is 1-63 alphanumeric characters, '-', '_' or '.',
+// starting and ending with alphanumeric character.
+func ValidateKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("label key must not be empty")
+	case len(key) > MaxKeyLength:
+		return fmt.Errorf("label key %q is longer than %d characters", key, MaxKeyLength)
+	}
+	return validateChars("label key", key)
+}
+
+// ValidateValue checks that label value is empty or up to 63 alphanumeric characters, '-', '_' or '.',
+// starting and ending with alphanumeric character.
+func ValidateValue(value string) error {
+	switch {
+	case value == "":
+		return nil
+	case len(value) > MaxValueLength:
+		return fmt.Errorf("label value %q is longer than %d characters", value, MaxValueLength)
+	}
+	return validateChars("label value", value)
+}
+
+func validateChars(what, str string) error {
+	for _, r := range str {
+		if !isLabelChar(r) {
+			return fmt.Errorf("%s %q contains invalid character %q", what, str, r)
+		}
+	}
+	if !isAlphanumeric(str[0]) || !isAlphanumeric(str[len(str)-1]) {
+		return fmt.Errorf("%s %q must start and end with alphanumeric character", what, str)
+	}
+	return nil
+}
+
+// Validate checks all labels keys and values.
+func Validate(labels map[string]string) error {
+	for key, value := range labels {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+		if err := ValidateValue(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package labels
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a single condition of selector, like "tier!=bronze" or "team in (a,b)".
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Matches checks that labels satisfy requirement.
+// As in Kubernetes, "!=" and "notin" match labels without key.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, exists := labels[r.Key]
+	switch r.Operator {
+	case Equals:
+		return exists && value == r.Values[0]
+	case NotEquals:
+		return !exists || value != r.Values[0]
+	case In:
+		return exists && contains(r.Values, value)
+	case NotIn:
+		return !exists || !contains(r.Values, value)
+	case Exists:
+		return exists
+	case DoesNotExist:
+		return !exists
+	default:
+		return false
+	}
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Equals, NotEquals:
+		return r.Key + string(r.Operator) + r.Values[0]
+	case In, NotIn:
+		return r.Key + " " + string(r.Operator) + " (" + strings.Join(r.Values, ",") + ")"
+	case DoesNotExist:
+		return "!" + r.Key
+	default:
+		return r.Key
+	}
+}
+
+// Selector is a set of requirements joined with AND. Empty selector matches everything.
+type Selector []Requirement
+
+// Matches checks that labels satisfy all selector requirements.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Matcher is a selector compiled for matching of many label sets.
+type Matcher struct {
+	requirements []compiledRequirement
+}
+
+type compiledRequirement struct {
+	Requirement
+	values map[string]struct{}
+}
+
+// Compile returns matcher equivalent to selector. Value sets of "in" and "notin" requirements are looked up in constant time.
+func (s Selector) Compile() Matcher {
+	ret := Matcher{requirements: make([]compiledRequirement, len(s))}
+	for i, r := range s {
+		ret.requirements[i].Requirement = r
+		if r.Operator == In || r.Operator == NotIn {
+			ret.requirements[i].values = make(map[string]struct{}, len(r.Values))
+			for _, value := range r.Values {
+				ret.requirements[i].values[value] = struct{}{}
+			}
+		}
+	}
+	return ret
+}
+
+// Matches checks that labels satisfy all selector requirements.
+func (m Matcher) Matches(labels map[string]string) bool {
+	for _, r := range m.requirements {
+		if r.values == nil {
+			if !r.Matches(labels) {
+				return false
+			}
+			continue
+		}
+		value, exists := labels[r.Key]
+		_, found := r.values[value]
+		if r.Operator == In && !(exists && found) || r.Operator == NotIn && exists && found {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse parses Kubernetes-style label selector, e.g. "team=payments,tier!=bronze,env in (prod,stage),!legacy".
+// Supported operators: "=", "==", "!=", "in", "notin", key existence ("key") and absence ("!key").
+// Value sets of "in" and "notin" must not be empty or contain empty values.
+func Parse(selector string) (Selector, error) {
+	p := &parser{selector: selector}
+	ret, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+	}
+	return ret, nil
+}
+
+// parser is a recursive descent parser of label selectors:
+//
+//	selector    = requirement { "," requirement }
+//	requirement = "!" key | key [ ( "=" | "==" | "!=" ) value | ( "in" | "notin" ) "(" value { "," value } ")" ]
+//
+// Whitespace is allowed between tokens.
+type parser struct {
+	selector string
+	pos      int
+}
+
+func (p *parser) parse() (Selector, error) {
+	var ret Selector
+	for {
+		req, err := p.requirement()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, req)
+
+		p.skipSpaces()
+		if p.done() {
+			return ret, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected \",\"")
+		}
+	}
+}
+
+func (p *parser) requirement() (Requirement, error) {
+	p.skipSpaces()
+	if p.consume("!") {
+		key, err := p.key()
+		if err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key, Operator: DoesNotExist}, nil
+	}
+
+	key, err := p.key()
+	if err != nil {
+		return Requirement{}, err
+	}
+	p.skipSpaces()
+	req := Requirement{Key: key}
+	switch {
+	case p.consume("!="):
+		req.Operator = NotEquals
+	case p.consume("=="), p.consume("="):
+		req.Operator = Equals
+	case p.consumeWord(string(In)):
+		req.Operator = In
+	case p.consumeWord(string(NotIn)):
+		req.Operator = NotIn
+	default:
+		req.Operator = Exists
+		return req, nil
+	}
+
+	if req.Operator == In || req.Operator == NotIn {
+		req.Values, err = p.valueSet()
+		return req, err
+	}
+	value, err := p.value()
+	if err != nil {
+		return Requirement{}, err
+	}
+	req.Values = []string{value}
+	return req, nil
+}
+
+func (p *parser) valueSet() ([]string, error) {
+	p.skipSpaces()
+	if !p.consume("(") {
+		return nil, p.errorf("expected \"(\"")
+	}
+	var ret []string
+	for {
+		p.skipSpaces()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			return nil, p.errorf("empty value in set")
+		}
+		ret = append(ret, value)
+
+		p.skipSpaces()
+		switch {
+		case p.consume(","):
+		case p.consume(")"):
+			return ret, nil
+		case p.done():
+			return nil, p.errorf("unbalanced parentheses")
+		default:
+			return nil, p.errorf("expected \",\" or \")\"")
+		}
+	}
+}
+
+func (p *parser) key() (string, error) {
+	p.skipSpaces()
+	key := p.word()
+	if err := ValidateKey(key); err != nil {
+		return "", p.errorf("%v", err)
+	}
+	return key, nil
+}
+
+func (p *parser) value() (string, error) {
+	p.skipSpaces()
+	value := p.word()
+	if err := ValidateValue(value); err != nil {
+		return "", p.errorf("%v", err)
+	}
+	return value, nil
+}
+
+// word reads characters until space, delimiter or operator.
+func (p *parser) word() string {
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(" \t,()=!", rune(p.selector[p.pos])) {
+		p.pos++
+	}
+	return p.selector[start:p.pos]
+}
+
+// consumeWord skips word if it is followed by space, delimiter or end of selector.
+func (p *parser) consumeWord(word string) bool {
+	pos := p.pos
+	if p.word() == word {
+		return true
+	}
+	p.pos = pos
+	return false
+}
+
+func (p *parser) consume(token string) bool {
+	if strings.HasPrefix(p.selector[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpaces() {
+	for !p.done() && (p.selector[p.pos] == ' ' || p.selector[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.selector)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package labels
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParse(t *testing.T) {
+	Convey("Test label selector parsing", t, func() {
+		Convey("Check equality requirements", func() {
+			sel, err := Parse("team=payments, tier!=bronze,env==prod")
+			So(err, ShouldBeNil)
+			So(sel, ShouldResemble, Selector{
+				{Key: "team", Operator: Equals, Values: []string{"payments"}},
+				{Key: "tier", Operator: NotEquals, Values: []string{"bronze"}},
+				{Key: "env", Operator: Equals, Values: []string{"prod"}},
+			})
+		})
+		Convey("Check set requirements", func() {
+			sel, err := Parse("env in (prod, stage),tier notin (bronze)")
+			So(err, ShouldBeNil)
+			So(sel, ShouldResemble, Selector{
+				{Key: "env", Operator: In, Values: []string{"prod", "stage"}},
+				{Key: "tier", Operator: NotIn, Values: []string{"bronze"}},
+			})
+		})
+		Convey("Check existence requirements", func() {
+			sel, err := Parse("team,!legacy")
+			So(err, ShouldBeNil)
+			So(sel, ShouldResemble, Selector{
+				{Key: "team", Operator: Exists},
+				{Key: "legacy", Operator: DoesNotExist},
+			})
+		})
+		Convey("Check whitespace between tokens", func() {
+			sel, err := Parse(" env  notin(prod ,stage ) , ! legacy,team = payments ")
+			So(err, ShouldBeNil)
+			So(sel, ShouldResemble, Selector{
+				{Key: "env", Operator: NotIn, Values: []string{"prod", "stage"}},
+				{Key: "legacy", Operator: DoesNotExist},
+				{Key: "team", Operator: Equals, Values: []string{"payments"}},
+			})
+		})
+		Convey("Check empty value of equality requirement", func() {
+			sel, err := Parse("team=")
+			So(err, ShouldBeNil)
+			So(sel, ShouldResemble, Selector{{Key: "team", Operator: Equals, Values: []string{""}}})
+		})
+		Convey("Check invalid selectors", func() {
+			for _, sel := range []string{"", "team=,", "env in (prod", "env is (prod)", "=payments", "team=pay ments", "!"} {
+				_, err := Parse(sel)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Check unbalanced parentheses", func() {
+			for _, sel := range []string{"env in prod)", "env in ((prod))", "env in (prod))", "env in (prod,(stage)", "env=(prod)", "(env)"} {
+				_, err := Parse(sel)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Check empty values in sets", func() {
+			for _, sel := range []string{"env in ()", "env notin ( )", "env in (prod,)", "env in (,prod)", "env in (prod,,stage)", "env in"} {
+				_, err := Parse(sel)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Check misplaced operators", func() {
+			for _, sel := range []string{"!env=prod", "env!", "env=prod=stage", "env!=prod!=stage", "env in (prod) stage", "!!env", "env,,team"} {
+				_, err := Parse(sel)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Check String is parseable", func() {
+			sel, err := Parse("team=payments,env in (prod,stage),!legacy")
+			So(err, ShouldBeNil)
+			reparsed, err := Parse(sel.String())
+			So(err, ShouldBeNil)
+			So(reparsed, ShouldResemble, sel)
+		})
+	})
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"team": "payments", "tier": "gold"}
+	matches := func(selector string) bool {
+		sel, err := Parse(selector)
+		So(err, ShouldBeNil)
+		return sel.Matches(labels)
+	}
+
+	Convey("Test label selector matching", t, func() {
+		Convey("Check equality", func() {
+			So(matches("team=payments"), ShouldBeTrue)
+			So(matches("team=billing"), ShouldBeFalse)
+			So(matches("tier!=bronze"), ShouldBeTrue)
+			So(matches("tier!=gold"), ShouldBeFalse)
+			So(matches("env!=prod"), ShouldBeTrue)
+		})
+		Convey("Check sets", func() {
+			So(matches("tier in (gold,silver)"), ShouldBeTrue)
+			So(matches("tier in (bronze)"), ShouldBeFalse)
+			So(matches("tier notin (bronze)"), ShouldBeTrue)
+			So(matches("env notin (prod)"), ShouldBeTrue)
+			So(matches("env in (prod)"), ShouldBeFalse)
+		})
+		Convey("Check existence", func() {
+			So(matches("team"), ShouldBeTrue)
+			So(matches("env"), ShouldBeFalse)
+			So(matches("!env"), ShouldBeTrue)
+			So(matches("!team"), ShouldBeFalse)
+		})
+		Convey("Check all requirements must match", func() {
+			So(matches("team=payments,tier!=bronze"), ShouldBeTrue)
+			So(matches("team=payments,tier=bronze"), ShouldBeFalse)
+		})
+		Convey("Check empty selector matches everything", func() {
+			So(Selector(nil).Matches(labels), ShouldBeTrue)
+			So(Selector(nil).Matches(nil), ShouldBeTrue)
+		})
+	})
+}
+
+func TestCompile(t *testing.T) {
+	Convey("Test compiled selector matches the same labels as selector", t, func() {
+		labelSets := []map[string]string{
+			nil,
+			{"team": "payments"},
+			{"team": "payments", "tier": "gold"},
+			{"team": "billing", "tier": "bronze", "legacy": ""},
+			{"env": "prod", "tier": "silver"},
+		}
+		for _, selector := range []string{
+			"team=payments",
+			"tier!=bronze",
+			"tier in (gold,silver)",
+			"tier notin (bronze,gold)",
+			"team,!legacy",
+			"env in (prod),tier notin (bronze)",
+			"legacy=",
+		} {
+			sel, err := Parse(selector)
+			So(err, ShouldBeNil)
+			matcher := sel.Compile()
+			for _, labels := range labelSets {
+				So(matcher.Matches(labels), ShouldEqual, sel.Matches(labels))
+			}
+		}
+		So(Selector(nil).Compile().Matches(nil), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,95 @@
+// Package projection selects subsets of JSON object fields. Available fields are taken from "json" struct tags.
+package projection
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Fields returns JSON names of struct fields. Fields of embedded structs without JSON name are included.
+func Fields(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var ret []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				ret = append(ret, Fields(fieldType)...)
+				continue
+			}
+		}
+		if field.PkgPath != "" || name == "-" { // unexported or ignored
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		ret = append(ret, name)
+	}
+	return ret
+}
+
+// Projection keeps only selected fields of JSON objects.
+type Projection struct {
+	fields []string
+}
+
+// Parse parses comma-separated list of fields. All fields must be JSON fields of v type.
+func Parse(list string, v interface{}) (Projection, error) {
+	known := make(map[string]bool)
+	for _, field := range Fields(reflect.TypeOf(v)) {
+		known[field] = true
+	}
+	var ret Projection
+	var unknown []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		switch {
+		case field == "", seen[field]:
+			continue
+		case !known[field]:
+			unknown = append(unknown, field)
+		default:
+			ret.fields = append(ret.fields, field)
+		}
+		seen[field] = true
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return Projection{}, fmt.Errorf("unknown fields: %s", strings.Join(unknown, ", "))
+	}
+	if len(ret.fields) == 0 {
+		return Projection{}, fmt.Errorf("fields list is empty")
+	}
+	return ret, nil
+}
+
+// Apply returns JSON object with only projection fields of v. Fields omitted by v marshaling are omitted too.
+func (p Projection) Apply(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]json.RawMessage, len(p.fields))
+	for _, field := range p.fields {
+		if value, ok := object[field]; ok {
+			ret[field] = value
+		}
+	}
+	return ret, nil
+}
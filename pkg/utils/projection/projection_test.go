@@ -0,0 +1,47 @@
+package projection
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type object struct {
+	base
+	hidden  string
+	Name    string            `json:"name"`
+	Size    int               `json:"size,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Ignored string            `json:"-"`
+	Plain   bool
+}
+
+func TestProjection(t *testing.T) {
+	Convey("Test fields projection", t, func() {
+		Convey("Check fields are taken from JSON tags", func() {
+			So(Fields(reflect.TypeOf(object{})), ShouldResemble, []string{"id", "name", "size", "labels", "Plain"})
+		})
+		Convey("Check unknown fields are rejected", func() {
+			_, err := Parse("name,volume,Ignored", object{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unknown fields: Ignored, volume")
+			_, err = Parse(" , ", object{})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Check projection keeps only requested fields", func() {
+			p, err := Parse("name, size,id,name", object{})
+			So(err, ShouldBeNil)
+			ret, err := p.Apply(object{base: base{ID: "1"}, Name: "obj", Labels: map[string]string{"a": "b"}})
+			So(err, ShouldBeNil)
+			data, err := json.Marshal(ret)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"id":"1","name":"obj"}`)
+		})
+	})
+}
@@ -0,0 +1,72 @@
+// Package units converts human-readable sizes to canonical size unit (GiB) used by storages and volumes.
+package units
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// binary unit suffixes and their size in bytes, number without suffix is treated as GiB
+var suffixes = map[string]float64{
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+}
+
+const gibibyte = 1 << 30
+
+// ParseSize parses size like "100Gi" or "2Ti" and returns it in GiB.
+// Only binary suffixes (Ki, Mi, Gi, Ti, Pi) are accepted, number without suffix is treated as GiB.
+// Size must be a whole number of GiB, so "512Mi" is rejected while "1024Mi" is 1 GiB.
+func ParseSize(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	number, multiplier := s, float64(gibibyte)
+	for suffix, bytes := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(s, suffix)), bytes
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("invalid size %q, expected number with optional unit (Ki, Mi, Gi, Ti, Pi), e.g. \"100Gi\"", s)
+	}
+	gib := value * multiplier / gibibyte
+	if gib != math.Trunc(gib) {
+		return 0, fmt.Errorf("invalid size %q, must be a whole number of Gi", s)
+	}
+	if gib > math.MaxInt32 || gib < math.MinInt32 {
+		return 0, fmt.Errorf("invalid size %q, value is out of range", s)
+	}
+	return int(gib), nil
+}
+
+// Size is size in GiB decoded from JSON number or string with unit (see ParseSize). It is encoded as number.
+type Size int
+
+func (s *Size) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		size, err := ParseSize(str)
+		if err != nil {
+			return err
+		}
+		*s = Size(size)
+		return nil
+	}
+	var size int
+	if err := json.Unmarshal(data, &size); err != nil {
+		return fmt.Errorf("invalid size %s, expected integer number of Gi or string with unit, e.g. \"100Gi\"", data)
+	}
+	*s = Size(size)
+	return nil
+}
@@ -0,0 +1,41 @@
+package units
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseSize(t *testing.T) {
+	Convey("Test size parsing", t, func() {
+		Convey("Check valid sizes", func() {
+			for input, expected := range map[string]int{
+				"100":       100,
+				"100Gi":     100,
+				"2Ti":       2048,
+				"0.5Ti":     512,
+				"1024Mi":    1,
+				" 1 Pi ":    1 << 20,
+				"1048576Ki": 1,
+			} {
+				size, err := ParseSize(input)
+				So(err, ShouldBeNil)
+				So(size, ShouldEqual, expected)
+			}
+		})
+		Convey("Check invalid sizes", func() {
+			for _, input := range []string{"", "Gi", "100GB", "2Tb", "512Mi", "1.5", "ten", "1e300Pi"} {
+				_, err := ParseSize(input)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Check JSON decoding", func() {
+			var sizes []Size
+			So(json.Unmarshal([]byte(`[10, "3Ti", null]`), &sizes), ShouldBeNil)
+			So(sizes, ShouldResemble, []Size{10, 3072, 0})
+			So(json.Unmarshal([]byte(`["3TB"]`), &sizes), ShouldNotBeNil)
+			So(json.Unmarshal([]byte(`[1.5]`), &sizes), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DNSLabelMaxLength = 63
+	// DNSLabelPattern is a regular expression equivalent of DNSLabel check (without length limit)
+	DNSLabelPattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+)
+
+// DNSLabel checks that name is a valid DNS label:
+// 1-63 lowercase alphanumeric characters or '-', not starting or ending with '-'.
+func DNSLabel(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("name must not be empty")
+	case len(name) > DNSLabelMaxLength:
+		return fmt.Errorf("name %q is longer than %d characters", name, DNSLabelMaxLength)
+	}
+
+	var invalid []string
+	seen := make(map[rune]bool)
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			continue
+		}
+		if !seen[r] {
+			seen[r] = true
+			invalid = append(invalid, fmt.Sprintf("%q", r))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("name %q contains invalid characters %s: only lowercase alphanumeric characters and '-' allowed",
+			name, strings.Join(invalid, ", "))
+	}
+
+	if strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") {
+		return fmt.Errorf("name %q must not start or end with '-'", name)
+	}
+
+	return nil
+}
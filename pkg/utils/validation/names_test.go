@@ -0,0 +1,35 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDNSLabel(t *testing.T) {
+	Convey("Test DNSLabel validation", t, func() {
+		Convey("Check valid names", func() {
+			So(DNSLabel("a"), ShouldBeNil)
+			So(DNSLabel("ssd-01"), ShouldBeNil)
+			So(DNSLabel(strings.Repeat("a", 63)), ShouldBeNil)
+		})
+		Convey("Check empty name", func() {
+			So(DNSLabel(""), ShouldNotBeNil)
+		})
+		Convey("Check too long name", func() {
+			So(DNSLabel(strings.Repeat("a", 64)), ShouldNotBeNil)
+		})
+		Convey("Check invalid characters are named", func() {
+			err := DNSLabel("my storage/Ssd")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `' '`)
+			So(err.Error(), ShouldContainSubstring, `'/'`)
+			So(err.Error(), ShouldContainSubstring, `'S'`)
+		})
+		Convey("Check leading and trailing hyphen", func() {
+			So(DNSLabel("-ssd"), ShouldNotBeNil)
+			So(DNSLabel("ssd-"), ShouldNotBeNil)
+		})
+	})
+}
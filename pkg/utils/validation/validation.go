@@ -1,6 +1,9 @@
 package validation
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/en_US"
 	"github.com/go-playground/universal-translator"
@@ -11,6 +14,14 @@ import (
 func StandardPermissionsValidator(uni *ut.UniversalTranslator) *validator.Validate {
 	ret := validator.New()
 	ret.SetTagName("binding")
+	// report fields by names used in requests
+	ret.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 
 	enTranslator, _ := uni.GetTranslator(en.New().Locale())
 	enUSTranslator, _ := uni.GetTranslator(en_US.New().Locale())